@@ -0,0 +1,220 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Command parsec-bench runs workloads concurrently against the parsec service and reports the throughput, latency
+// percentiles and error rate of each operation for each provider.
+//
+// Usage:
+//
+//	parsec-bench [-endpoint unix:/run/parsec/parsec.sock] [-providers mbed,tpm] [-concurrency 4] [-duration 10s]
+//	    [-workloads sign-verify,aead,hash,random,key-churn] [-sizes 64,1024,16384] [-json]
+//
+// Each workload is run for -duration, or for -iterations iterations per worker, by -concurrency workers, each with
+// its own connection.  The aead and hash workloads are run once for each of the -sizes.  Workloads a provider does
+// not support are reported as skipped.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/connection"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/bench"
+)
+
+// signPresets are the presets accepted by -sign-preset.
+var signPresets = map[string]func(opts ...parsec.KeyAttributeOption) *parsec.KeyAttributes{
+	"rsa-sign": parsec.DefaultKeyAttribute().SigningKey,
+	"rsa-pss":  parsec.DefaultKeyAttribute().RsaPssSigningKey,
+	"ecdsa":    parsec.DefaultKeyAttribute().EcdsaSigningKey,
+}
+
+// churnPresets are the presets accepted by -churn-preset.
+var churnPresets = map[string]func(opts ...parsec.KeyAttributeOption) *parsec.KeyAttributes{
+	"rsa-sign": parsec.DefaultKeyAttribute().SigningKey,
+	"ecdsa":    parsec.DefaultKeyAttribute().EcdsaSigningKey,
+	"aes-gcm":  parsec.DefaultKeyAttribute().AesGcmKey,
+	"hmac":     parsec.DefaultKeyAttribute().HmacKey,
+}
+
+var workloadNames = []string{"sign-verify", "aead", "hash", "random", "key-churn"}
+
+func names(presets map[string]func(opts ...parsec.KeyAttributeOption) *parsec.KeyAttributes) string {
+	list := make([]string, 0, len(presets))
+	for name := range presets {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	endpoint := flag.String("endpoint", "", "parsec service endpoint, such as unix:/run/parsec/parsec.sock")
+	providers := flag.String("providers", "", "comma separated providers to benchmark, by name or id (default the implicit provider)")
+	authenticator := flag.String("auth", "", "authenticator to use: none, direct or unix-peer (default chosen by the service)")
+	app := flag.String("app", "", "application name for direct authentication")
+	concurrency := flag.Int("concurrency", 1, "number of workers running each workload at once")
+	duration := flag.Duration("duration", 10*time.Second, "how long to run each workload for")
+	iterations := flag.Int("iterations", 0, "iterations of each workload per worker, instead of -duration")
+	workloads := flag.String("workloads", strings.Join(workloadNames, ","), "comma separated workloads to run: "+strings.Join(workloadNames, ", "))
+	sizes := flag.String("sizes", "64,1024,16384", "comma separated payload sizes in bytes for the aead and hash workloads")
+	randomSize := flag.Int("random-size", 32, "number of bytes requested by the random workload")
+	signPreset := flag.String("sign-preset", "ecdsa", "key used by the sign-verify workload: "+names(signPresets))
+	churnPreset := flag.String("churn-preset", "aes-gcm", "key created and destroyed by the key-churn workload: "+names(churnPresets))
+	jsonOutput := flag.Bool("json", false, "write the report as JSON")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		return 2
+	}
+
+	config := &bench.Config{
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Iterations:  *iterations,
+	}
+	if *iterations != 0 {
+		config.Duration = 0
+	}
+	var err error
+	if config.ClientConfig, err = clientConfig(*endpoint, *authenticator, *app); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	if config.Providers, err = parseProviders(*providers); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	if config.Workloads, err = parseWorkloads(*workloads, *sizes, *randomSize, *signPreset, *churnPreset); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	report, err := bench.Run(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// clientConfig returns the configuration of the client shared by the workers.
+func clientConfig(endpoint, authenticator, app string) (*parsec.ClientConfig, error) {
+	if app != "" && authenticator == "" {
+		authenticator = "direct"
+	}
+	config := parsec.NewClientConfig()
+	if endpoint != "" {
+		conn, err := connection.NewConnection(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		config.Connection(conn)
+	}
+	switch authenticator {
+	case "":
+	case "none":
+		config.Authenticator(parsec.NewNoAuthAuthenticator())
+	case "direct":
+		if app == "" {
+			return nil, fmt.Errorf("-app must be given for direct authentication")
+		}
+		config.Authenticator(parsec.NewDirectAuthenticator(app))
+	case "unix-peer":
+		config.Authenticator(parsec.NewUnixPeerAuthenticator())
+	default:
+		return nil, fmt.Errorf("unknown authenticator %q", authenticator)
+	}
+	return config, nil
+}
+
+func parseProviders(s string) ([]parsec.ProviderID, error) {
+	var providers []parsec.ProviderID
+	for _, name := range splitList(s) {
+		var p parsec.ProviderID
+		if id, err := strconv.ParseUint(name, 10, 8); err == nil {
+			p = parsec.ProviderID(id)
+		} else if p, err = parsec.ParseProviderID(name); err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+func parseWorkloads(s, sizeList string, randomSize int, signPreset, churnPreset string) ([]bench.Workload, error) {
+	var sizes []int
+	for _, size := range splitList(sizeList) {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid size %q", size)
+		}
+		sizes = append(sizes, n)
+	}
+	var workloads []bench.Workload
+	for _, name := range splitList(s) {
+		switch name {
+		case "sign-verify":
+			preset, ok := signPresets[signPreset]
+			if !ok {
+				return nil, fmt.Errorf("-sign-preset must be one of %v", names(signPresets))
+			}
+			workloads = append(workloads, bench.SignVerify(preset()))
+		case "aead":
+			for _, size := range sizes {
+				workloads = append(workloads, bench.AeadEncrypt(size))
+			}
+		case "hash":
+			for _, size := range sizes {
+				workloads = append(workloads, bench.Hash(algorithm.HashAlgorithmTypeSHA256, size))
+			}
+		case "random":
+			workloads = append(workloads, bench.Random(randomSize))
+		case "key-churn":
+			preset, ok := churnPresets[churnPreset]
+			if !ok {
+				return nil, fmt.Errorf("-churn-preset must be one of %v", names(churnPresets))
+			}
+			workloads = append(workloads, bench.KeyChurn(preset()))
+		default:
+			return nil, fmt.Errorf("unknown workload %q, should be one of %v", name, strings.Join(workloadNames, ", "))
+		}
+	}
+	if len(workloads) == 0 {
+		return nil, fmt.Errorf("no workloads given")
+	}
+	return workloads, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
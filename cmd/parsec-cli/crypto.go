@@ -0,0 +1,286 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// keyOperation holds the flags shared by operations using a key.
+type keyOperation struct {
+	key *string
+	in  *string
+	alg *string
+}
+
+func newKeyOperation(flags *flag.FlagSet) *keyOperation {
+	return &keyOperation{
+		key: flags.String("key", "", "name of the key"),
+		in:  flags.String("in", "-", "input file"),
+		alg: flags.String("alg", "", "PSA algorithm name, such as PSA_ALG_RSA_PSS(PSA_ALG_SHA_256) (default the key's policy algorithm)"),
+	}
+}
+
+// open checks the flags and returns the key, the input and the call options for the operation.
+func (o *keyOperation) open(e *env, flags *flag.FlagSet) (*parsec.Key, []byte, []parsec.CallOption, error) {
+	if err := requireFlag(flags, "key", *o.key); err != nil {
+		return nil, nil, nil, err
+	}
+	k, err := e.client.OpenKey(*o.key, e.opts...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	opts := e.opts
+	if *o.alg != "" {
+		var alg *algorithm.Algorithm
+		if alg, err = parseAlgorithm(*o.alg); err != nil {
+			return nil, nil, nil, err
+		}
+		opts = append(opts[:len(opts):len(opts)], parsec.WithAlgorithm(alg))
+	}
+	input, err := e.readInput(*o.in)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return k, input, opts, nil
+}
+
+// isAead returns true if the operation will use an AEAD algorithm.
+func isAead(k *parsec.Key, alg string) bool {
+	if alg != "" {
+		a, err := parseAlgorithm(alg)
+		return err == nil && a.GetAead() != nil
+	}
+	policy := policyAlgorithm(k.Attributes())
+	return policy != nil && policy.GetAead() != nil
+}
+
+// decodeBase64Flag decodes the value of the flag name, which must be given if required.
+func decodeBase64Flag(flags *flag.FlagSet, name, value string, required bool) ([]byte, error) {
+	if value == "" {
+		if required {
+			return nil, requireFlag(flags, name, value)
+		}
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("-%v: %w", name, err)
+	}
+	return data, nil
+}
+
+func runSign(e *env, args []string) error {
+	flags := newFlagSet("sign", "-key name [-in file] [-prehashed] [-alg algorithm] [-out file]")
+	op := newKeyOperation(flags)
+	prehashed := flags.Bool("prehashed", false, "the input is a hash of the message rather than the message")
+	out := flags.String("out", "", "file to write the signature to (default stdout in base64)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	k, input, opts, err := op.open(e, flags)
+	if err != nil {
+		return err
+	}
+	var signature []byte
+	if *prehashed {
+		signature, err = k.SignHash(input, opts...)
+	} else {
+		signature, err = k.Sign(input, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	return e.printData("signature", signature, *out)
+}
+
+func runVerify(e *env, args []string) error {
+	flags := newFlagSet("verify", "-key name -signature base64 [-in file] [-prehashed] [-alg algorithm]")
+	op := newKeyOperation(flags)
+	prehashed := flags.Bool("prehashed", false, "the input is a hash of the message rather than the message")
+	sig := flags.String("signature", "", "signature, in base64")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	signature, err := decodeBase64Flag(flags, "signature", *sig, true)
+	if err != nil {
+		return err
+	}
+	k, input, opts, err := op.open(e, flags)
+	if err != nil {
+		return err
+	}
+	if *prehashed {
+		err = k.VerifyHash(input, signature, opts...)
+	} else {
+		err = k.Verify(input, signature, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	return e.print(map[string]bool{"verified": true}, func(w io.Writer) {
+		fmt.Fprintln(w, "signature verified")
+	})
+}
+
+func runEncrypt(e *env, args []string) error {
+	flags := newFlagSet("encrypt", "-key name [-in file] [-alg algorithm] [-nonce base64] [-ad base64] [-out file]")
+	op := newKeyOperation(flags)
+	nonceFlag := flags.String("nonce", "", "nonce for AEAD algorithms, in base64 (default random)")
+	adFlag := flags.String("ad", "", "additional data for AEAD algorithms, in base64")
+	out := flags.String("out", "", "file to write the ciphertext to (default stdout in base64)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	nonce, err := decodeBase64Flag(flags, "nonce", *nonceFlag, false)
+	if err != nil {
+		return err
+	}
+	ad, err := decodeBase64Flag(flags, "ad", *adFlag, false)
+	if err != nil {
+		return err
+	}
+	k, input, opts, err := op.open(e, flags)
+	if err != nil {
+		return err
+	}
+	var ciphertext []byte
+	if !isAead(k, *op.alg) {
+		if ciphertext, err = k.Encrypt(input, opts...); err != nil {
+			return err
+		}
+		return e.printData("ciphertext", ciphertext, *out)
+	}
+	if nonce == nil {
+		nonce = make([]byte, parsec.AeadNonceLength)
+		if _, err = rand.Read(nonce); err != nil {
+			return err
+		}
+	}
+	if ciphertext, err = k.AeadEncrypt(nonce, ad, input, opts...); err != nil {
+		return err
+	}
+	if *out != "" {
+		if err = e.printData("nonce", nonce, ""); err != nil {
+			return err
+		}
+		return e.printData("ciphertext", ciphertext, *out)
+	}
+	result := struct {
+		Nonce      []byte `json:"nonce"`
+		Ciphertext []byte `json:"ciphertext"`
+	}{nonce, ciphertext}
+	return e.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "nonce: %v\nciphertext: %v\n", base64.StdEncoding.EncodeToString(nonce),
+			base64.StdEncoding.EncodeToString(ciphertext))
+	})
+}
+
+func runDecrypt(e *env, args []string) error {
+	flags := newFlagSet("decrypt", "-key name [-in file] [-alg algorithm] [-nonce base64] [-ad base64] [-out file]")
+	op := newKeyOperation(flags)
+	nonceFlag := flags.String("nonce", "", "nonce used to encrypt, for AEAD algorithms, in base64")
+	adFlag := flags.String("ad", "", "additional data for AEAD algorithms, in base64")
+	out := flags.String("out", "", "file to write the plaintext to (default stdout in base64)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	ad, err := decodeBase64Flag(flags, "ad", *adFlag, false)
+	if err != nil {
+		return err
+	}
+	k, input, opts, err := op.open(e, flags)
+	if err != nil {
+		return err
+	}
+	var plaintext []byte
+	if isAead(k, *op.alg) {
+		var nonce []byte
+		if nonce, err = decodeBase64Flag(flags, "nonce", *nonceFlag, true); err != nil {
+			return err
+		}
+		plaintext, err = k.AeadDecrypt(nonce, ad, input, opts...)
+	} else {
+		plaintext, err = k.Decrypt(input, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	return e.printData("plaintext", plaintext, *out)
+}
+
+func runMac(e *env, args []string) error {
+	flags := newFlagSet("mac", "-key name [-in file] [-alg algorithm] [-verify base64] [-out file]")
+	op := newKeyOperation(flags)
+	verify := flags.String("verify", "", "MAC to verify, in base64, instead of computing one")
+	out := flags.String("out", "", "file to write the MAC to (default stdout in base64)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	expected, err := decodeBase64Flag(flags, "verify", *verify, false)
+	if err != nil {
+		return err
+	}
+	k, input, opts, err := op.open(e, flags)
+	if err != nil {
+		return err
+	}
+	if expected != nil {
+		if err = k.VerifyMAC(input, expected, opts...); err != nil {
+			return err
+		}
+		return e.print(map[string]bool{"verified": true}, func(w io.Writer) {
+			fmt.Fprintln(w, "MAC verified")
+		})
+	}
+	mac, err := k.MAC(input, opts...)
+	if err != nil {
+		return err
+	}
+	return e.printData("mac", mac, *out)
+}
+
+func runHash(e *env, args []string) error {
+	flags := newFlagSet("hash", "[-alg hash] [-in file] [-out file]")
+	alg := flags.String("alg", "sha_256", "hash algorithm")
+	in := flags.String("in", "-", "input file")
+	out := flags.String("out", "", "file to write the hash to (default stdout in base64)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	hash, err := parseHash(*alg)
+	if err != nil {
+		return err
+	}
+	input, err := e.readInput(*in)
+	if err != nil {
+		return err
+	}
+	digest, err := e.client.PsaHashCompute(input, hash, e.opts...)
+	if err != nil {
+		return err
+	}
+	return e.printData("hash", digest, *out)
+}
+
+func runRandom(e *env, args []string) error {
+	flags := newFlagSet("random", "-size n [-out file]")
+	size := flags.Uint64("size", 32, "number of bytes")
+	out := flags.String("out", "", "file to write the bytes to (default stdout in base64)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	data, err := e.client.PsaGenerateRandom(*size, e.opts...)
+	if err != nil {
+		return err
+	}
+	return e.printData("random", data, *out)
+}
@@ -0,0 +1,50 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/wiredecode"
+)
+
+func runDecode(e *env, args []string) error {
+	flags := newFlagSet("decode", "[-in file] [-as request|response]")
+	in := flags.String("in", "-", "file holding the message, as raw bytes, hex or base64")
+	as := flags.String("as", "auto", "decode the message as a request or response, or auto to guess")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	var dir wiredecode.Direction
+	switch *as {
+	case "auto":
+		dir = wiredecode.Auto
+	case "request":
+		dir = wiredecode.Request
+	case "response":
+		dir = wiredecode.Response
+	default:
+		flags.Usage()
+		return fmt.Errorf("-as must be auto, request or response")
+	}
+	data, err := e.readInput(*in)
+	if err != nil {
+		return err
+	}
+	m := wiredecode.Decode(wiredecode.ParseInput(data), dir)
+	var textErr error
+	if err = e.print(m, func(w io.Writer) {
+		textErr = m.WriteText(w)
+	}); err != nil {
+		return err
+	}
+	if textErr != nil {
+		return textErr
+	}
+	if len(m.Errors) != 0 {
+		return fmt.Errorf("message has %d problems", len(m.Errors))
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+// newFlagSet creates the flags for a command, whose arguments are described by synopsis.
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: parsec-cli %v %v\n", name, synopsis)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseFlags parses args, which must not include any positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	return nil
+}
+
+// requireFlag returns an error if value, the value of the flag name, is empty.
+func requireFlag(flags *flag.FlagSet, name, value string) error {
+	if value == "" {
+		flags.Usage()
+		return fmt.Errorf("-%v must be given", name)
+	}
+	return nil
+}
+
+func version(maj, min, rev uint32) string {
+	return fmt.Sprintf("%v.%v.%v", maj, min, rev)
+}
+
+func newTable(w io.Writer, headings ...string) *tabwriter.Writer {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, strings.Join(headings, "\t"))
+	return table
+}
+
+func runPing(e *env, args []string) error {
+	if err := parseFlags(newFlagSet("ping", ""), args); err != nil {
+		return err
+	}
+	maj, min, err := e.client.Ping(e.opts...)
+	if err != nil {
+		return err
+	}
+	result := struct {
+		WireProtocolVersionMaj uint8 `json:"wire_protocol_version_maj"`
+		WireProtocolVersionMin uint8 `json:"wire_protocol_version_min"`
+	}{maj, min}
+	return e.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "wire protocol version %v.%v\n", maj, min)
+	})
+}
+
+type providerResult struct {
+	ID          parsec.ProviderID `json:"id"`
+	Name        string            `json:"name"`
+	UUID        string            `json:"uuid"`
+	Description string            `json:"description"`
+	Vendor      string            `json:"vendor"`
+	Version     string            `json:"version"`
+}
+
+func runListProviders(e *env, args []string) error {
+	if err := parseFlags(newFlagSet("list-providers", ""), args); err != nil {
+		return err
+	}
+	providers, err := e.client.ListProviders(e.opts...)
+	if err != nil {
+		return err
+	}
+	results := make([]providerResult, 0, len(providers))
+	for _, p := range providers {
+		results = append(results, providerResult{
+			ID:          p.ID,
+			Name:        p.ID.String(),
+			UUID:        p.UUID,
+			Description: p.Description,
+			Vendor:      p.Vendor,
+			Version:     version(p.VersionMaj, p.VersionMin, p.VersionRev),
+		})
+	}
+	return e.print(results, func(w io.Writer) {
+		table := newTable(w, "ID", "NAME", "VERSION", "VENDOR", "DESCRIPTION")
+		for _, p := range results {
+			fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\n", uint8(p.ID), p.Name, p.Version, p.Vendor, p.Description)
+		}
+		table.Flush()
+	})
+}
+
+type opcodeResult struct {
+	Opcode parsec.OpCode `json:"opcode"`
+	Name   string        `json:"name"`
+}
+
+func runListOpcodes(e *env, args []string) error {
+	flags := newFlagSet("list-opcodes", "[-for provider]")
+	provider := flags.String("for", "", "provider to list, by name or id (default the provider in use)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	id := e.client.GetImplicitProvider()
+	if *provider != "" {
+		var err error
+		if id, err = parseProvider(*provider); err != nil {
+			return err
+		}
+	}
+	ops, err := e.client.Capabilities(id, e.opts...)
+	if err != nil {
+		return err
+	}
+	results := make([]opcodeResult, 0, len(ops))
+	for _, op := range ops.List() {
+		results = append(results, opcodeResult{Opcode: op, Name: op.String()})
+	}
+	return e.print(results, func(w io.Writer) {
+		for _, op := range results {
+			fmt.Fprintf(w, "%v\t%v\n", uint32(op.Opcode), op.Name)
+		}
+	})
+}
+
+type authenticatorResult struct {
+	ID          parsec.AuthenticatorType `json:"id"`
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Version     string                   `json:"version"`
+}
+
+func runListAuthenticators(e *env, args []string) error {
+	if err := parseFlags(newFlagSet("list-authenticators", ""), args); err != nil {
+		return err
+	}
+	authenticators, err := e.client.ListAuthenticators(e.opts...)
+	if err != nil {
+		return err
+	}
+	results := make([]authenticatorResult, 0, len(authenticators))
+	for _, a := range authenticators {
+		results = append(results, authenticatorResult{
+			ID:          a.ID,
+			Name:        auth.AuthenticationType(a.ID).String(),
+			Description: a.Description,
+			Version:     version(a.VersionMaj, a.VersionMin, a.VersionRev),
+		})
+	}
+	return e.print(results, func(w io.Writer) {
+		table := newTable(w, "ID", "NAME", "VERSION", "DESCRIPTION")
+		for _, a := range results {
+			fmt.Fprintf(table, "%v\t%v\t%v\t%v\n", uint8(a.ID), a.Name, a.Version, a.Description)
+		}
+		table.Flush()
+	})
+}
+
+type keyResult struct {
+	Name       string                `json:"name"`
+	Provider   string                `json:"provider"`
+	Attributes *parsec.KeyAttributes `json:"attributes"`
+}
+
+func runListKeys(e *env, args []string) error {
+	if err := parseFlags(newFlagSet("list-keys", ""), args); err != nil {
+		return err
+	}
+	keys, err := e.client.ListKeys(e.opts...)
+	if err != nil {
+		return err
+	}
+	results := make([]keyResult, 0, len(keys))
+	for _, k := range keys {
+		results = append(results, keyResult{Name: k.Name, Provider: k.ProviderID.String(), Attributes: k.Attributes})
+	}
+	return e.print(results, func(w io.Writer) {
+		table := newTable(w, "NAME", "PROVIDER", "TYPE", "BITS", "ALGORITHM", "USAGE")
+		for _, k := range results {
+			fmt.Fprintf(table, "%v\t%v\t%v\t%v\t%v\t%v\n", k.Name, k.Provider, keyTypeName(k.Attributes.KeyType),
+				k.Attributes.KeyBits, policyAlgorithm(k.Attributes), usageNames(k.Attributes))
+		}
+		table.Flush()
+	})
+}
+
+func runListClients(e *env, args []string) error {
+	if err := parseFlags(newFlagSet("list-clients", ""), args); err != nil {
+		return err
+	}
+	clients, err := e.client.ListClients(e.opts...)
+	if err != nil {
+		return err
+	}
+	if clients == nil {
+		clients = []string{}
+	}
+	return e.print(clients, func(w io.Writer) {
+		for _, c := range clients {
+			fmt.Fprintln(w, c)
+		}
+	})
+}
+
+func runDeleteClient(e *env, args []string) error {
+	flags := newFlagSet("delete-client", "-client name")
+	client := flags.String("client", "", "application to delete")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "client", *client); err != nil {
+		return err
+	}
+	if err := e.client.DeleteClient(*client, e.opts...); err != nil {
+		return err
+	}
+	return e.print(map[string]string{"deleted_client": *client}, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted client %v\n", *client)
+	})
+}
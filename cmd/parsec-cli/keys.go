@@ -0,0 +1,294 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm/gocrypto"
+)
+
+// presets maps the names accepted by -preset to the DefaultKeyAttributeFactory presets.
+var presets = map[string]func(opts ...parsec.KeyAttributeOption) *parsec.KeyAttributes{
+	"rsa-sign":          parsec.DefaultKeyAttribute().SigningKey,
+	"rsa-pss":           parsec.DefaultKeyAttribute().RsaPssSigningKey,
+	"ecdsa":             parsec.DefaultKeyAttribute().EcdsaSigningKey,
+	"rsa-oaep":          parsec.DefaultKeyAttribute().RsaOaepEncryptionKey,
+	"aes-gcm":           parsec.DefaultKeyAttribute().AesGcmKey,
+	"chacha20-poly1305": parsec.DefaultKeyAttribute().Chacha20Poly1305Key,
+	"hmac":              parsec.DefaultKeyAttribute().HmacKey,
+	"cmac":              parsec.DefaultKeyAttribute().CmacKey,
+	"ecdh":              parsec.DefaultKeyAttribute().EcdhKey,
+	"x25519":            parsec.DefaultKeyAttribute().X25519Key,
+	"ffdh":              parsec.DefaultKeyAttribute().FfdhKey,
+}
+
+func presetNames() string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// attributeFlags are the flags used to choose the attributes of a new key.
+type attributeFlags struct {
+	preset     *string
+	bits       *uint
+	hash       *string
+	export     *bool
+	attributes *string
+}
+
+func newAttributeFlags(flags *flag.FlagSet) *attributeFlags {
+	return &attributeFlags{
+		preset:     flags.String("preset", "", "kind of key: "+presetNames()),
+		bits:       flags.Uint("bits", 0, "key size in bits (default depends on -preset)"),
+		hash:       flags.String("hash", "", "hash used by the key's algorithm, such as sha_384 (default depends on -preset)"),
+		export:     flags.Bool("export", false, "allow the key to be exported"),
+		attributes: flags.String("attributes", "", "key attributes as JSON, or @file to read them from a file, instead of -preset"),
+	}
+}
+
+func (f *attributeFlags) keyAttributes() (*parsec.KeyAttributes, error) {
+	if *f.attributes != "" {
+		if *f.preset != "" || *f.bits != 0 || *f.hash != "" || *f.export {
+			return nil, fmt.Errorf("-attributes cannot be used with -preset, -bits, -hash or -export")
+		}
+		data := []byte(*f.attributes)
+		if strings.HasPrefix(*f.attributes, "@") {
+			var err error
+			if data, err = os.ReadFile((*f.attributes)[1:]); err != nil {
+				return nil, err
+			}
+		}
+		attributes := &parsec.KeyAttributes{}
+		if err := attributes.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return attributes, nil
+	}
+	preset, ok := presets[*f.preset]
+	if !ok {
+		return nil, fmt.Errorf("-preset must be one of %v, or -attributes given", presetNames())
+	}
+	var opts []parsec.KeyAttributeOption
+	if *f.bits != 0 {
+		opts = append(opts, parsec.WithKeyBits(uint32(*f.bits)))
+	}
+	if *f.hash != "" {
+		hash, err := parseHash(*f.hash)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, parsec.WithHash(hash))
+	}
+	if *f.export {
+		opts = append(opts, parsec.WithExport())
+	}
+	return preset(opts...), nil
+}
+
+// parseAlgorithm parses a PSA algorithm name.  The PSA_ALG_ prefix may be left off names without arguments.
+func parseAlgorithm(s string) (*algorithm.Algorithm, error) {
+	alg, err := algorithm.Parse(s)
+	if err != nil && !strings.HasPrefix(strings.ToUpper(s), "PSA_ALG_") {
+		if alg, err2 := algorithm.Parse("PSA_ALG_" + s); err2 == nil {
+			return alg, nil
+		}
+	}
+	return alg, err
+}
+
+func parseHash(s string) (algorithm.HashAlgorithmType, error) {
+	alg, err := parseAlgorithm(s)
+	if err != nil {
+		return algorithm.HashAlgorithmTypeNONE, err
+	}
+	if alg.GetHash() == nil {
+		return algorithm.HashAlgorithmTypeNONE, fmt.Errorf("%v is not a hash algorithm", alg)
+	}
+	return alg.GetHash().HashAlg, nil
+}
+
+// keyTypeName returns the name of the key type, as used in JSON, with the ECC or DH family if it has one.
+func keyTypeName(kt *parsec.KeyType) string {
+	if kt == nil {
+		return "none"
+	}
+	wire, ok := kt.ToWireInterface().(*psakeyattributes.KeyType)
+	if !ok || wire == nil || wire.Variant == nil {
+		return "none"
+	}
+	m := wire.ProtoReflect()
+	name := string(m.WhichOneof(m.Descriptor().Oneofs().Get(0)).Name())
+	switch v := wire.Variant.(type) {
+	case *psakeyattributes.KeyType_EccKeyPair_:
+		name += "(" + v.EccKeyPair.CurveFamily.String() + ")"
+	case *psakeyattributes.KeyType_EccPublicKey_:
+		name += "(" + v.EccPublicKey.CurveFamily.String() + ")"
+	case *psakeyattributes.KeyType_DhKeyPair_:
+		name += "(" + v.DhKeyPair.GroupFamily.String() + ")"
+	case *psakeyattributes.KeyType_DhPublicKey_:
+		name += "(" + v.DhPublicKey.GroupFamily.String() + ")"
+	}
+	return name
+}
+
+func policyAlgorithm(attributes *parsec.KeyAttributes) *algorithm.Algorithm {
+	if attributes.KeyPolicy == nil {
+		return nil
+	}
+	return attributes.KeyPolicy.KeyAlgorithm
+}
+
+// usageNames returns the usage flags set in the key's policy, separated by commas.
+func usageNames(attributes *parsec.KeyAttributes) string {
+	if attributes.KeyPolicy == nil || attributes.KeyPolicy.KeyUsageFlags == nil {
+		return "-"
+	}
+	u := attributes.KeyPolicy.KeyUsageFlags
+	var names []string
+	for _, flag := range []struct {
+		name string
+		set  bool
+	}{
+		{"export", u.Export}, {"copy", u.Copy}, {"cache", u.Cache}, {"encrypt", u.Encrypt}, {"decrypt", u.Decrypt},
+		{"sign_message", u.SignMessage}, {"verify_message", u.VerifyMessage}, {"sign_hash", u.SignHash},
+		{"verify_hash", u.VerifyHash}, {"derive", u.Derive},
+	} {
+		if flag.set {
+			names = append(names, flag.name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
+}
+
+func printKey(e *env, verb, name string, attributes *parsec.KeyAttributes) error {
+	result := keyResult{Name: name, Provider: e.client.GetImplicitProvider().String(), Attributes: attributes}
+	return e.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%v %v key %v with %v\n", verb, keyTypeName(attributes.KeyType), name, policyAlgorithm(attributes))
+	})
+}
+
+func runGenerateKey(e *env, args []string) error {
+	flags := newFlagSet("generate-key", "-key name (-preset kind [-bits n] [-hash alg] [-export] | -attributes json)")
+	key := flags.String("key", "", "name of the key")
+	af := newAttributeFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "key", *key); err != nil {
+		return err
+	}
+	attributes, err := af.keyAttributes()
+	if err != nil {
+		return err
+	}
+	if err = e.client.PsaGenerateKey(*key, attributes, e.opts...); err != nil {
+		return err
+	}
+	return printKey(e, "generated", *key, attributes)
+}
+
+func runDestroyKey(e *env, args []string) error {
+	flags := newFlagSet("destroy-key", "-key name")
+	key := flags.String("key", "", "name of the key")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "key", *key); err != nil {
+		return err
+	}
+	if err := e.client.PsaDestroyKey(*key, e.opts...); err != nil {
+		return err
+	}
+	return e.print(map[string]string{"destroyed_key": *key}, func(w io.Writer) {
+		fmt.Fprintf(w, "destroyed key %v\n", *key)
+	})
+}
+
+func runImportKey(e *env, args []string) error {
+	flags := newFlagSet("import-key", "-key name [-in file] (-preset kind [-bits n] [-hash alg] [-export] | -attributes json)")
+	key := flags.String("key", "", "name of the key")
+	in := flags.String("in", "-", "file holding the key, in PEM or the raw format parsec expects")
+	af := newAttributeFlags(flags)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "key", *key); err != nil {
+		return err
+	}
+	attributes, err := af.keyAttributes()
+	if err != nil {
+		return err
+	}
+	data, err := e.readInput(*in)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN ")) {
+		if data, err = parsec.KeyDataFromPEM(data, attributes.KeyType, attributes.KeyBits); err != nil {
+			return err
+		}
+	}
+	if err = e.client.PsaImportKey(*key, attributes, data, e.opts...); err != nil {
+		return err
+	}
+	return printKey(e, "imported", *key, attributes)
+}
+
+func runExportPublicKey(e *env, args []string) error {
+	flags := newFlagSet("export-public-key", "-key name [-out file]")
+	key := flags.String("key", "", "name of the key")
+	out := flags.String("out", "", "file to write the PEM public key to (default stdout)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if err := requireFlag(flags, "key", *key); err != nil {
+		return err
+	}
+	k, err := e.client.OpenKey(*key, e.opts...)
+	if err != nil {
+		return err
+	}
+	data, err := k.ExportPublic()
+	if err != nil {
+		return err
+	}
+	pub, err := gocrypto.PublicKey(k.Attributes(), data)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	if *out != "" {
+		return os.WriteFile(*out, pemData, 0o644) //nolint:gosec // public keys are not secret
+	}
+	result := struct {
+		PEM  string `json:"pem"`
+		Data []byte `json:"data"`
+	}{string(pemData), data}
+	return e.print(result, func(w io.Writer) {
+		fmt.Fprint(w, result.PEM)
+	})
+}
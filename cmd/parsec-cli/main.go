@@ -0,0 +1,213 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Command parsec-cli manages keys held by the parsec service and carries out cryptographic operations with them.
+//
+// Usage:
+//
+//	parsec-cli [global flags] <command> [flags]
+//
+// The parsec service is located using -endpoint, or the PARSEC_SERVICE_ENDPOINT environment variable if it is not
+// given.  If -app is given the direct authenticator is used with that application name, otherwise the
+// authenticator is chosen with -auth, or automatically.  With -json, each command writes its result to stdout as a
+// single JSON value, with binary data encoded in base64.
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/connection"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+// command is a parsec-cli subcommand.  Offline commands do not use the parsec service, so are run without a client.
+type command struct {
+	summary string
+	offline bool
+	run     func(e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"ping":                {summary: "show the wire protocol version of the parsec service", run: runPing},
+	"list-providers":      {summary: "list the providers of the parsec service", run: runListProviders},
+	"list-opcodes":        {summary: "list the operations supported by a provider", run: runListOpcodes},
+	"list-authenticators": {summary: "list the authenticators of the parsec service", run: runListAuthenticators},
+	"list-keys":           {summary: "list the keys of the application", run: runListKeys},
+	"list-clients":        {summary: "list the applications with keys (admin only)", run: runListClients},
+	"delete-client":       {summary: "delete an application and its keys (admin only)", run: runDeleteClient},
+	"generate-key":        {summary: "generate a key", run: runGenerateKey},
+	"destroy-key":         {summary: "destroy a key", run: runDestroyKey},
+	"import-key":          {summary: "import a key from a PEM or raw file", run: runImportKey},
+	"export-public-key":   {summary: "export the public part of a key as PEM", run: runExportPublicKey},
+	"sign":                {summary: "sign a message", run: runSign},
+	"verify":              {summary: "verify the signature of a message", run: runVerify},
+	"encrypt":             {summary: "encrypt a message", run: runEncrypt},
+	"decrypt":             {summary: "decrypt a message", run: runDecrypt},
+	"mac":                 {summary: "compute or verify the MAC of a message", run: runMac},
+	"hash":                {summary: "compute the hash of a message", run: runHash},
+	"random":              {summary: "generate random bytes", run: runRandom},
+	"provision":           {summary: "create and delete keys to match a manifest", run: runProvision},
+	"self-test":           {summary: "check the provider's results against known answers", run: runSelfTest},
+	"probe":               {summary: "show which key types and algorithms each provider supports", run: runProbe},
+	"decode":              {summary: "decode a captured wire protocol message", offline: true, run: runDecode},
+}
+
+// env holds the client and output settings shared by all commands.
+type env struct {
+	client *parsec.BasicClient
+	opts   []parsec.CallOption
+	json   bool
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// print writes value to stdout as JSON if -json was given, otherwise it calls text to write it for people.
+func (e *env) print(value interface{}, text func(w io.Writer)) error {
+	if !e.json {
+		text(e.stdout)
+		return nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.stdout, "%s\n", data)
+	return err
+}
+
+// printData writes binary data to the file out if it is given, otherwise to stdout in base64, or as a JSON object
+// with the data in field.
+func (e *env) printData(field string, data []byte, out string) error {
+	if out != "" {
+		return os.WriteFile(out, data, 0o600)
+	}
+	return e.print(map[string][]byte{field: data}, func(w io.Writer) {
+		fmt.Fprintln(w, base64.StdEncoding.EncodeToString(data))
+	})
+}
+
+// readInput returns the contents of the file name, or stdin if name is "-".
+func (e *env) readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(e.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %v [global flags] <command> [flags]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-20v %v\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(out, "\nGlobal flags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\nRun '%v <command> -h' for the flags of a command.\n", os.Args[0])
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	endpoint := flag.String("endpoint", "", "parsec service endpoint, such as unix:/run/parsec/parsec.sock")
+	provider := flag.String("provider", "", "provider to use, by name or id, instead of the first one available")
+	authenticator := flag.String("auth", "", "authenticator to use: none, direct or unix-peer (default automatic)")
+	app := flag.String("app", "", "application name for direct authentication")
+	timeout := flag.Duration("timeout", 0, "timeout for each operation")
+	jsonOutput := flag.Bool("json", false, "write results as JSON")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		return 2
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", flag.Arg(0))
+		usage()
+		return 2
+	}
+
+	e := &env{json: *jsonOutput, stdin: os.Stdin, stdout: os.Stdout}
+	if !cmd.offline {
+		config, opts, err := clientConfig(*endpoint, *provider, *authenticator, *app, *timeout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 2
+		}
+		if e.client, err = parsec.CreateConfiguredClient(config); err != nil {
+			fmt.Fprintf(os.Stderr, "could not connect to parsec service: %v\n", err)
+			return 1
+		}
+		defer e.client.Close()
+		e.opts = opts
+	}
+	if err := cmd.run(e, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", flag.Arg(0), err)
+		return 1
+	}
+	return 0
+}
+
+// clientConfig creates the client configuration and call options for the global flags.
+func clientConfig(endpoint, provider, authenticator, app string, timeout time.Duration) (*parsec.ClientConfig, []parsec.CallOption, error) {
+	config := parsec.NewClientConfig()
+	if endpoint != "" {
+		conn, err := connection.NewConnection(endpoint)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.Connection(conn)
+	}
+	var opts []parsec.CallOption
+	if provider != "" {
+		p, err := parseProvider(provider)
+		if err != nil {
+			return nil, nil, err
+		}
+		config.Provider(p)
+	}
+	if app != "" && authenticator == "" {
+		authenticator = "direct"
+	}
+	switch authenticator {
+	case "":
+	case "none":
+		config.Authenticator(parsec.NewNoAuthAuthenticator())
+	case "direct":
+		if app == "" {
+			return nil, nil, fmt.Errorf("-app must be given for direct authentication")
+		}
+		config.Authenticator(parsec.NewDirectAuthenticator(app))
+	case "unix-peer":
+		config.Authenticator(parsec.NewUnixPeerAuthenticator())
+	default:
+		return nil, nil, fmt.Errorf("unknown authenticator %q", authenticator)
+	}
+	if timeout != 0 {
+		opts = append(opts, parsec.WithTimeout(timeout))
+	}
+	return config, opts, nil
+}
+
+// parseProvider parses a provider name, such as "tpm", or id.
+func parseProvider(s string) (parsec.ProviderID, error) {
+	if id, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return parsec.ProviderID(id), nil
+	}
+	return parsec.ParseProviderID(s)
+}
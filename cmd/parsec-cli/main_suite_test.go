@@ -0,0 +1,16 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestParsecCli(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "parsec-cli suite")
+}
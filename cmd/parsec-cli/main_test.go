@@ -0,0 +1,198 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listproviders"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/ping"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestEnv returns an env using the MBed provider of a new fake parsec service, so that each test starts without
+// keys.
+func newTestEnv() *env {
+	service := parsectest.NewProvider().Service().
+		Handle(requests.OpPing, func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+			return &ping.Result{WireProtocolVersionMaj: 1}, requests.StatusSuccess
+		}).
+		Handle(requests.OpListProviders, func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+			return &listproviders.Result{Providers: []*listproviders.ProviderInfo{
+				{Id: uint32(requests.ProviderMBed), Vendor: "Arm", Description: "Mbed Crypto provider", VersionMin: 1},
+			}}, requests.StatusSuccess
+		})
+	client, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+		Provider(parsec.ProviderMBed).
+		Authenticator(parsec.NewDirectAuthenticator("parsec-cli")).
+		Connection(service))
+	Expect(err).NotTo(HaveOccurred())
+	return &env{client: client}
+}
+
+// run runs the command args[0] with the rest of args and stdin as its input, returning what it wrote to stdout.
+func (e *env) run(stdin string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	e.stdin, e.stdout = strings.NewReader(stdin), &stdout
+	err := commands[args[0]].run(e, args[1:])
+	return stdout.String(), err
+}
+
+// generate generates the key name from a preset.
+func (e *env) generate(name, preset string) {
+	_, err := e.run("", "generate-key", "-key", name, "-preset", preset)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func ecdsaPEM(curve elliptic.Curve) string {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// invocation is a command run by a test, after generating an ECDSA key named ecdsa if withKey is set.
+type invocation struct {
+	withKey bool
+	args    []string
+	json    bool
+	stdin   string
+}
+
+var _ = Describe("parsec-cli", func() {
+	var e *env
+
+	BeforeEach(func() {
+		e = newTestEnv()
+	})
+	AfterEach(func() {
+		Expect(e.client.Close()).To(Succeed())
+	})
+
+	run := func(c invocation) (string, error) {
+		if c.withKey {
+			e.generate("ecdsa", "ecdsa")
+		}
+		e.json = c.json
+		return e.run(c.stdin, c.args...)
+	}
+
+	DescribeTable("Should print the result of commands",
+		func(c invocation, want string) {
+			out, err := run(c)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring(want))
+		},
+		Entry("ping", invocation{args: []string{"ping"}}, "wire protocol version 1.0\n"),
+		Entry("ping as JSON", invocation{args: []string{"ping"}, json: true}, `"wire_protocol_version_maj": 1`),
+		Entry("list-providers", invocation{args: []string{"list-providers"}}, "MBed  0.1.0    Arm     Mbed Crypto provider"),
+		Entry("list-opcodes", invocation{args: []string{"list-opcodes"}}, "PsaSignMessage"),
+		Entry("generate-key", invocation{args: []string{"generate-key", "-key", "ecdsa", "-preset", "ecdsa"}},
+			"generated ecc_key_pair(SECP_R1) key ecdsa with PSA_ALG_ECDSA(PSA_ALG_SHA_256)\n"),
+		Entry("list-keys", invocation{withKey: true, args: []string{"list-keys"}}, "ecdsa"),
+		Entry("export-public-key", invocation{withKey: true, args: []string{"export-public-key", "-key", "ecdsa"}},
+			"-----BEGIN PUBLIC KEY-----"),
+		Entry("hash", invocation{args: []string{"hash"}, stdin: "abc"}, "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=\n"),
+		Entry("hash as JSON", invocation{args: []string{"hash", "-alg", "sha_256"}, json: true, stdin: "abc"},
+			`"hash": "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0="`),
+		Entry("random", invocation{args: []string{"random", "-size", "16"}, json: true}, `"random": "`),
+		Entry("destroy-key", invocation{withKey: true, args: []string{"destroy-key", "-key", "ecdsa"}}, "destroyed key ecdsa\n"),
+	)
+
+	DescribeTable("Should reject invalid commands",
+		func(c invocation, wantErr string) {
+			out, err := run(c)
+			Expect(err).To(MatchError(ContainSubstring(wantErr)), "output %q", out)
+		},
+		Entry("generate-key of an existing key", invocation{withKey: true, args: []string{"generate-key", "-key", "ecdsa", "-preset", "ecdsa"}},
+			"already exists"),
+		Entry("generate-key without -key", invocation{args: []string{"generate-key", "-preset", "ecdsa"}}, "-key must be given"),
+		Entry("generate-key with an unknown preset", invocation{args: []string{"generate-key", "-key", "k", "-preset", "dsa"}},
+			"-preset must be one of"),
+		Entry("generate-key with -attributes and -bits", invocation{args: []string{"generate-key", "-key", "k", "-attributes", "{}", "-bits", "256"}},
+			"-attributes cannot be used with"),
+		Entry("export-public-key of a missing key", invocation{args: []string{"export-public-key", "-key", "missing"}}, "not found"),
+		Entry("sign with an algorithm the key does not permit",
+			invocation{withKey: true, args: []string{"sign", "-key", "ecdsa", "-alg", "PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)"}, stdin: "message"},
+			"does not permit"),
+		Entry("verify without -signature", invocation{withKey: true, args: []string{"verify", "-key", "ecdsa"}, stdin: "message"},
+			"-signature must be given"),
+		Entry("verify with a bad -signature", invocation{withKey: true, args: []string{"verify", "-key", "ecdsa", "-signature", "!"}, stdin: "message"},
+			"-signature: illegal base64"),
+		Entry("hash with a cipher", invocation{args: []string{"hash", "-alg", "PSA_ALG_GCM"}}, "is not a hash algorithm"),
+		Entry("destroy-key of a missing key", invocation{args: []string{"destroy-key", "-key", "ecdsa"}}, "not"),
+	)
+
+	Context("Importing keys", func() {
+		It("Should import a PEM private key", func() {
+			out, err := e.run(ecdsaPEM(elliptic.P224()), "import-key", "-key", "p224", "-preset", "ecdsa", "-bits", "224")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("imported ecc_key_pair(SECP_R1) key p224"))
+			out, err = e.run("", "list-keys")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring("p224"))
+		})
+		It("Should reject a key of the wrong type", func() {
+			_, err := e.run(ecdsaPEM(elliptic.P256()), "import-key", "-key", "rsa", "-preset", "rsa-sign")
+			Expect(err).To(MatchError(ContainSubstring("expected an RSA private key")))
+		})
+	})
+
+	// message is the size of a SHA-256 hash, so it can also be signed as one
+	message := "0123456789abcdef0123456789abcdef"
+	signature := func(r map[string][]byte) []string { return []string{"-signature", encode(r["signature"])} }
+
+	DescribeTable("Should check the results of commands",
+		// The key is generated from preset, then create makes the result, which check is given with the flags created
+		// by flags.
+		func(preset string, create, check []string, flags func(result map[string][]byte) []string, want string) {
+			e.generate("key", preset)
+			e.json = true
+			out, err := e.run(message, append(create, "-key", "key")...)
+			Expect(err).NotTo(HaveOccurred())
+			result := map[string][]byte{}
+			Expect(json.Unmarshal([]byte(out), &result)).To(Succeed())
+			// Decryption takes the ciphertext as its input rather than the message
+			input := message
+			if ciphertext, ok := result["ciphertext"]; ok {
+				Expect(result["nonce"]).To(HaveLen(parsec.AeadNonceLength))
+				input = string(ciphertext)
+			}
+			args := append(append(check, "-key", "key"), flags(result)...)
+			out, err = e.run(input, args...)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(ContainSubstring(want))
+			_, err = e.run("tampered"+input[8:], args...)
+			Expect(err).To(HaveOccurred(), "accepted tampered input")
+		},
+		Entry("ECDSA", "ecdsa", []string{"sign"}, []string{"verify"}, signature, `"verified": true`),
+		Entry("ECDSA hash", "ecdsa", []string{"sign", "-prehashed"}, []string{"verify", "-prehashed"}, signature, `"verified": true`),
+		Entry("RSA-PSS", "rsa-pss", []string{"sign", "-alg", "PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)"}, []string{"verify"}, signature,
+			`"verified": true`),
+		Entry("HMAC", "hmac", []string{"mac"}, []string{"mac"},
+			func(r map[string][]byte) []string { return []string{"-verify", encode(r["mac"])} }, `"verified": true`),
+		Entry("AES-GCM", "aes-gcm", []string{"encrypt", "-ad", "aGVhZGVy"}, []string{"decrypt", "-ad", "aGVhZGVy"},
+			func(r map[string][]byte) []string { return []string{"-nonce", encode(r["nonce"])} },
+			`"plaintext": "`+encode([]byte(message))),
+	)
+})
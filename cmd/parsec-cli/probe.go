@@ -0,0 +1,37 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/probe"
+)
+
+func runProbe(e *env, args []string) error {
+	flags := newFlagSet("probe", "[-providers mbed,tpm]")
+	providerList := flags.String("providers", "", "comma separated providers to probe, by name or id (default all providers with crypto)")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	var providers []parsec.ProviderID
+	if *providerList != "" {
+		for _, name := range strings.Split(*providerList, ",") {
+			p, err := parseProvider(strings.TrimSpace(name))
+			if err != nil {
+				return err
+			}
+			providers = append(providers, p)
+		}
+	}
+	m, err := probe.Probe(e.client, providers, probe.Catalogue(), e.opts...)
+	if err != nil {
+		return err
+	}
+	return e.print(m, func(w io.Writer) {
+		_ = m.WriteText(w)
+	})
+}
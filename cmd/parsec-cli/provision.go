@@ -0,0 +1,86 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/provision"
+)
+
+// runProvision implements "parsec-cli provision plan|apply -f manifest".
+func runProvision(e *env, args []string) error {
+	flags := flag.NewFlagSet("provision", flag.ExitOnError)
+	manifestFile := flags.String("f", "", "manifest file, in YAML or JSON")
+	prune := flags.Bool("prune", false, "delete keys not in the manifest from the providers it uses")
+	replace := flags.Bool("replace", false, "destroy and recreate keys whose attributes differ from the manifest")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: parsec-cli provision plan|apply -f manifest [-prune] [-replace]\n")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return fmt.Errorf("plan or apply must be given")
+	}
+	action := args[0]
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if action != "plan" && action != "apply" {
+		flags.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+	if *manifestFile == "" {
+		flags.Usage()
+		return fmt.Errorf("a manifest must be given with -f")
+	}
+
+	m, err := provision.Load(*manifestFile)
+	if err != nil {
+		return err
+	}
+	plan, err := provision.NewPlan(e.client, m, provision.Options{Prune: *prune, Replace: *replace})
+	if err != nil {
+		return err
+	}
+	if err = printPlan(e, plan); err != nil {
+		return err
+	}
+	if action == "plan" {
+		if len(plan.Conflicts()) > 0 {
+			return fmt.Errorf("plan has conflicts; use -replace to recreate the keys")
+		}
+		return nil
+	}
+	if !plan.HasChanges() {
+		fmt.Fprintln(os.Stderr, "no changes")
+		return nil
+	}
+	return plan.Apply(e.client)
+}
+
+type changeResult struct {
+	Action      string   `json:"action"`
+	Name        string   `json:"name"`
+	Provider    string   `json:"provider"`
+	Differences []string `json:"differences,omitempty"`
+}
+
+func printPlan(e *env, plan *provision.Plan) error {
+	results := make([]changeResult, 0, len(plan.Changes))
+	for _, c := range plan.Changes {
+		results = append(results, changeResult{
+			Action:      c.Action.String(),
+			Name:        c.Name,
+			Provider:    c.Provider.String(),
+			Differences: c.Differences,
+		})
+	}
+	return e.print(results, func(w io.Writer) {
+		fmt.Fprint(w, plan)
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/selftest"
+)
+
+func runSelfTest(e *env, args []string) error {
+	if err := parseFlags(newFlagSet("self-test", ""), args); err != nil {
+		return err
+	}
+	report := selftest.Run(e.client, e.client.GetImplicitProvider(), e.opts...)
+	if err := e.print(report, func(w io.Writer) {
+		_ = report.WriteText(w)
+	}); err != nil {
+		return err
+	}
+	failed := 0
+	for _, res := range report.Results {
+		if res.Status == selftest.Fail {
+			failed++
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("%d algorithms failed", failed)
+	}
+	return nil
+}
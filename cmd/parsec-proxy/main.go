@@ -0,0 +1,118 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Command parsec-proxy sits between parsec clients and the parsec service, logging each request and response.
+//
+// Usage:
+//
+//	parsec-proxy -listen /tmp/parsec-proxy.sock [-endpoint unix:/run/parsec/parsec.sock] [-log-secrets] [-json]
+//
+// Point clients at the proxy by setting PARSEC_SERVICE_ENDPOINT=unix:/tmp/parsec-proxy.sock.  Requests and
+// responses are logged to stderr with their header fields, body and the time the service took to respond.  Key
+// material, plaintexts and authentication data are redacted unless -log-secrets is given.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/proxy"
+)
+
+const defaultEndpoint = "unix:/run/parsec/parsec.sock"
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	listen := flag.String("listen", "", "path of the unix socket to listen on")
+	endpoint := flag.String("endpoint", "", "parsec service endpoint (default $PARSEC_SERVICE_ENDPOINT or "+defaultEndpoint+")")
+	logSecrets := flag.Bool("log-secrets", false, "log key material, plaintexts and authentication data")
+	timeout := flag.Duration("timeout", 0, "time allowed for the parsec service to respond (default no limit)")
+	jsonOutput := flag.Bool("json", false, "write the log as JSON")
+	flag.Parse()
+	if *listen == "" || flag.NArg() != 0 {
+		flag.Usage()
+		return 2
+	}
+	path, err := endpointPath(*endpoint)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	var handler slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+	if *jsonOutput {
+		handler = slog.NewJSONHandler(os.Stderr, nil)
+	}
+	p := &proxy.Proxy{
+		Endpoint:   path,
+		Logger:     slog.New(handler),
+		LogSecrets: *logSecrets,
+		Timeout:    *timeout,
+	}
+	if err = removeStaleSocket(*listen); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	l, err := net.Listen("unix", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		l.Close()
+	}()
+
+	p.Logger.Info("forwarding", "listen", *listen, "endpoint", path)
+	if err = p.Serve(l); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// endpointPath returns the socket path of the parsec service endpoint, which is found in the same way as the
+// client does if not given.
+func endpointPath(endpoint string) (string, error) {
+	if endpoint == "" {
+		endpoint = os.Getenv("PARSEC_SERVICE_ENDPOINT")
+	}
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "unix" {
+		return "", fmt.Errorf("unsupported endpoint scheme %q", u.Scheme)
+	}
+	return u.Path, nil
+}
+
+// removeStaleSocket removes the socket left at path by an earlier run, refusing to remove anything else.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%v exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
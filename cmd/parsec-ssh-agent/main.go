@@ -0,0 +1,204 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Command parsec-ssh-agent is an SSH agent whose keys are held by the parsec service, for example in a TPM.
+//
+// Usage:
+//
+//	parsec-ssh-agent -socket /tmp/parsec-ssh-agent.sock [-endpoint unix:/run/parsec/parsec.sock] [-provider tpm]
+//	parsec-ssh-agent -generate name [-type ecdsa-256] [-provider tpm]
+//
+// The agent offers every RSA and ECDSA key the application can sign with.  Point SSH at it by setting
+// SSH_AUTH_SOCK to the socket, as printed when the agent starts.  Keys added with ssh-add are imported into parsec,
+// and -generate creates a key in parsec and prints its public key in the authorized_keys format.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/connection"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/sshagent"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// keyTypes are the keys -generate can create.  RSA keys may be used with any hash, so for all the RSA signature
+// formats.
+var keyTypes = map[string]func() *parsec.KeyAttributes{
+	"ecdsa-256": func() *parsec.KeyAttributes { return parsec.DefaultKeyAttribute().EcdsaSigningKey() },
+	"ecdsa-384": func() *parsec.KeyAttributes {
+		return parsec.DefaultKeyAttribute().EcdsaSigningKey(parsec.WithKeyBits(384))
+	},
+	"ecdsa-521": func() *parsec.KeyAttributes {
+		return parsec.DefaultKeyAttribute().EcdsaSigningKey(parsec.WithKeyBits(521))
+	},
+	"rsa-2048": func() *parsec.KeyAttributes { return rsaKey(2048) },
+	"rsa-3072": func() *parsec.KeyAttributes { return rsaKey(3072) },
+	"rsa-4096": func() *parsec.KeyAttributes { return rsaKey(4096) },
+}
+
+func rsaKey(bits uint32) *parsec.KeyAttributes {
+	attributes := parsec.DefaultKeyAttribute().SigningKey(parsec.WithKeyBits(bits))
+	attributes.KeyPolicy.KeyAlgorithm = algorithm.NewAsymmetricSignature().RsaPkcs1V15SignAny()
+	return attributes
+}
+
+func keyTypeNames() string {
+	names := make([]string, 0, len(keyTypes))
+	for name := range keyTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	socket := flag.String("socket", "", "path of the unix socket to serve the agent on")
+	endpoint := flag.String("endpoint", "", "parsec service endpoint, such as unix:/run/parsec/parsec.sock")
+	providerName := flag.String("provider", "", "provider to create keys in, by name or id (default chosen by the client)")
+	authenticator := flag.String("auth", "", "authenticator to use: none, direct or unix-peer (default chosen by the service)")
+	app := flag.String("app", "", "application name for direct authentication")
+	generate := flag.String("generate", "", "create a key with this name, after the "+sshagent.KeyPrefix+" prefix, print its public key and exit")
+	keyType := flag.String("type", "ecdsa-256", "type of key created by -generate: "+keyTypeNames())
+	flag.Parse()
+	if (*socket == "") == (*generate == "") || flag.NArg() != 0 {
+		flag.Usage()
+		return 2
+	}
+
+	config, err := clientConfig(*endpoint, *providerName, *authenticator, *app)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	client, err := parsec.CreateConfiguredClient(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer client.Close()
+	a := sshagent.New(client)
+
+	if *generate != "" {
+		attributes, ok := keyTypes[*keyType]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown key type %q, expected one of %v\n", *keyType, keyTypeNames())
+			return 2
+		}
+		pub, err := a.Generate(*generate, attributes())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Printf("%s %s\n", strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub))), sshagent.KeyPrefix+*generate)
+		return 0
+	}
+
+	if err = serve(a, *socket); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serve serves the agent on a unix socket at path, which only the user can connect to, until interrupted.
+func serve(a *sshagent.Agent, path string) error {
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	if err = os.Chmod(path, 0o600); err != nil {
+		l.Close()
+		return err
+	}
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		l.Close()
+	}()
+
+	fmt.Printf("SSH_AUTH_SOCK=%v; export SSH_AUTH_SOCK;\n", path)
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = agent.ServeAgent(a, conn)
+		}()
+	}
+}
+
+func clientConfig(endpoint, providerName, authenticator, app string) (*parsec.ClientConfig, error) {
+	config := parsec.NewClientConfig()
+	if endpoint != "" {
+		conn, err := connection.NewConnection(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		config.Connection(conn)
+	}
+	if providerName != "" {
+		provider, err := parsec.ParseProviderID(providerName)
+		if err != nil {
+			return nil, err
+		}
+		config.Provider(provider)
+	}
+	if app != "" && authenticator == "" {
+		authenticator = "direct"
+	}
+	switch authenticator {
+	case "":
+	case "none":
+		config.Authenticator(parsec.NewNoAuthAuthenticator())
+	case "direct":
+		if app == "" {
+			return nil, fmt.Errorf("-app must be given for direct authentication")
+		}
+		config.Authenticator(parsec.NewDirectAuthenticator(app))
+	case "unix-peer":
+		config.Authenticator(parsec.NewUnixPeerAuthenticator())
+	default:
+		return nil, fmt.Errorf("unknown authenticator %q", authenticator)
+	}
+	return config, nil
+}
+
+// removeStaleSocket removes the socket left at path by an earlier run, refusing to remove anything else.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%v exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
@@ -28,6 +28,23 @@ func (a AuthenticationType) IsValid() bool {
 	return a <= AuthJwtSvid
 }
 
+func (a AuthenticationType) String() string {
+	switch a {
+	case AuthNoAuth:
+		return "NoAuth"
+	case AuthDirect:
+		return "Direct"
+	case AuthJwt:
+		return "Jwt"
+	case AuthUnixPeerCredentials:
+		return "UnixPeerCredentials"
+	case AuthJwtSvid:
+		return "JwtSvid"
+	default:
+		return "Unknown"
+	}
+}
+
 func NewAuthenticationTypeFromU32(t uint32) (AuthenticationType, error) {
 	if t > uint32(AuthJwtSvid) {
 		return AuthNoAuth, fmt.Errorf("cannot convert value %v to AuthenticationType", t)
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
 const defaultUnixSocketAddress = "unix:/run/parsec/parsec.sock"
@@ -23,9 +24,17 @@ type Connection interface {
 	io.ReadWriteCloser
 }
 
+// Cloner is implemented by connections that can make further, independent connections to the same service.
+// A client whose connection is a Cloner gives each operation its own connection, so that operations on a shared
+// client run concurrently.
+type Cloner interface {
+	// Clone returns an unopened connection to the same service.
+	Clone() Connection
+}
+
 // type to manage unix socket connection
 type unixConnection struct {
-	rwc  io.ReadWriteCloser
+	rwc  net.Conn
 	path string
 }
 
@@ -58,6 +67,19 @@ func (conn *unixConnection) Close() error {
 	return nil
 }
 
+// SetDeadline sets the read and write deadline of the open unix socket
+func (conn *unixConnection) SetDeadline(t time.Time) error {
+	if conn.rwc == nil {
+		return fmt.Errorf("setting deadline on closed connection")
+	}
+	return conn.rwc.SetDeadline(t)
+}
+
+// Clone returns an unopened connection to the same unix socket
+func (conn *unixConnection) Clone() Connection {
+	return &unixConnection{path: conn.path}
+}
+
 // Opens the unix socket ready for read/write
 func (conn *unixConnection) Open() error {
 	rwc, err := net.Dial("unix", conn.path)
@@ -72,8 +94,7 @@ func (conn *unixConnection) Open() error {
 // NewDefaultConnection opens the default connection to the parsec service.
 // This returns a Connection.  If the PARSEC_SERVICE_ENDPOINT environment
 // variable is set, then this will be used to determine how to connect to the
-// parsec service.  This must be a valid URL, and currently only urls of the form
-// unix:/path are supported.
+// parsec service, as by NewConnection.
 // if the PARSEC_SERVICE_ENDPOINT environment variable is not set, then the default of
 // unix:/run/parsec/parsec.sock will be used
 // Connection implementations are not guaranteed to be thread safe, so should not be used
@@ -83,8 +104,14 @@ func NewDefaultConnection() (Connection, error) {
 	if addressRawURL == "" {
 		addressRawURL = defaultUnixSocketAddress
 	}
+	return NewConnection(addressRawURL)
+}
 
-	sockURL, err := url.Parse(addressRawURL)
+// NewConnection returns a connection to the parsec service at endpoint, which is given in the same way as the
+// PARSEC_SERVICE_ENDPOINT environment variable.  This must be a valid URL, and currently only urls of the form
+// unix:/path are supported.
+func NewConnection(endpoint string) (Connection, error) {
+	sockURL, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, err
 	}
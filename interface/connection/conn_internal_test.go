@@ -137,6 +137,25 @@ var _ = Describe("Connection Tests", func() {
 		})
 
 	})
+	Context("Given endpoint", func() {
+		BeforeEach(func() {
+			os.Setenv("PARSEC_SERVICE_ENDPOINT", "unix:/tmp/parsec-env.sock")
+		})
+		AfterEach(func() {
+			os.Setenv("PARSEC_SERVICE_ENDPOINT", "")
+		})
+		It("Should use the endpoint rather than the environment", func() {
+			c, err := NewConnection("unix:/tmp/parsec-given.sock")
+			Expect(err).NotTo(HaveOccurred())
+			uc, ok := c.(*unixConnection)
+			Expect(ok).To(BeTrue())
+			Expect(uc.path).To(Equal("/tmp/parsec-given.sock"))
+		})
+		It("Should fail with an unsupported scheme", func() {
+			_, err := NewConnection("http://google.com")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
 
 func TestRequests(t *testing.T) {
@@ -0,0 +1,56 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package operations
+
+import (
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"google.golang.org/protobuf/proto"
+)
+
+// Operation describes a single request to the parsec service as it passes through the interceptor chain.
+// Interceptors may modify any of the fields before passing the operation on to the next handler.
+type Operation struct {
+	OpCode        requests.OpCode
+	Provider      requests.ProviderID
+	Authenticator auth.Authenticator
+	// Request is the protobuf operation message to send
+	Request proto.Message
+	// Response is the protobuf result message that will be populated from the service response
+	Response proto.Message
+	// RequestSize and ResponseSize are the number of bytes written to and read from the connection.  These are
+	// populated when the operation is sent to the service, so are only available to an interceptor after calling next.
+	RequestSize  int
+	ResponseSize int
+}
+
+// AuthType returns the type of the authenticator used for this operation.
+func (op *Operation) AuthType() auth.AuthenticationType {
+	if op.Authenticator == nil {
+		return auth.AuthNoAuth
+	}
+	return op.Authenticator.GetType()
+}
+
+// Invoker carries out an operation, populating op.Response, and returns any error.
+type Invoker func(op *Operation) error
+
+// Interceptor wraps an operation.  An interceptor may decorate the call by inspecting or modifying op before
+// calling next and inspecting op.Response and the returned error afterwards.  An interceptor can short-circuit
+// the call by not calling next, in which case it is responsible for populating op.Response or returning an error.
+type Interceptor func(op *Operation, next Invoker) error
+
+// chainInterceptors builds a single invoker from the interceptors and the final invoker.  The first interceptor
+// in the list is the outermost, so will be called first.
+func chainInterceptors(interceptors []Interceptor, final Invoker) Invoker {
+	invoker := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor := interceptors[i]
+		next := invoker
+		invoker = func(op *Operation) error {
+			return interceptor(op, next)
+		}
+	}
+	return invoker
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package operations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MarshalJSON encodes a protobuf message as compact JSON using the proto field names and enum value names.
+// Unlike protojson, whose output is deliberately unstable, the result is the same for equal messages so can be
+// stored and compared.
+func MarshalJSON(m proto.Message) ([]byte, error) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	compact := &bytes.Buffer{}
+	if err = json.Compact(compact, data); err != nil {
+		return nil, err
+	}
+	return compact.Bytes(), nil
+}
+
+// UnmarshalJSON decodes JSON produced by MarshalJSON into m.  Unknown fields are rejected, as are enum values that
+// are not defined or are the NONE placeholder.
+func UnmarshalJSON(data []byte, m proto.Message) error {
+	if err := protojson.Unmarshal(data, m); err != nil {
+		return err
+	}
+	return ValidateEnums(m)
+}
+
+// ValidateEnums returns an error if any enum field in m holds a value that is not defined, or the NONE value that
+// the parsec operations reserve as a placeholder which should not be used.  Enum fields are checked whether or not
+// they are set, since an unset enum field reads as its zero value.
+func ValidateEnums(m proto.Message) error {
+	return validateEnums(m.ProtoReflect())
+}
+
+func validateEnums(m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.ContainingOneof() != nil && m.WhichOneof(fd.ContainingOneof()) != fd {
+			continue
+		}
+		var err error
+		switch {
+		case fd.IsMap():
+		case fd.IsList():
+			list := m.Get(fd).List()
+			for j := 0; j < list.Len() && err == nil; j++ {
+				err = validateEnumValue(fd, list.Get(j))
+			}
+		case fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind:
+			if m.Has(fd) {
+				err = validateEnums(m.Get(fd).Message())
+			}
+		default:
+			err = validateEnumValue(fd, m.Get(fd))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateEnumValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	switch fd.Kind() { //nolint:exhaustive // only enums and messages can hold enums
+	case protoreflect.EnumKind:
+		ev := fd.Enum().Values().ByNumber(v.Enum())
+		if ev == nil {
+			return fmt.Errorf("%v: undefined value %d", fd.FullName(), v.Enum())
+		}
+		if strings.HasSuffix(string(ev.Name()), "NONE") {
+			return fmt.Errorf("%v: %v must not be used", fd.FullName(), ev.Name())
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return validateEnums(v.Message())
+	}
+	return nil
+}
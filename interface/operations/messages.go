@@ -0,0 +1,93 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package operations
+
+import (
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/deleteclient"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listauthenticators"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listclients"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listkeys"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listproviders"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/ping"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeaddecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeadencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaasymmetricdecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaasymmetricencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psacipherdecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psacipherencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psadestroykey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaexportkey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaexportpublickey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneraterandom"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psahashcompare"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psahashcompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaimportkey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psamaccompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psamacverify"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psarawkeyagreement"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignmessage"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifyhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifymessage"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"google.golang.org/protobuf/proto"
+)
+
+// messageTypes holds an empty operation and result message for each opcode, used as prototypes to create new
+// messages.
+var messageTypes = map[requests.OpCode]struct {
+	operation proto.Message
+	result    proto.Message
+}{
+	requests.OpPing:                 {&ping.Operation{}, &ping.Result{}},
+	requests.OpPsaGenerateKey:       {&psageneratekey.Operation{}, &psageneratekey.Result{}},
+	requests.OpPsaDestroyKey:        {&psadestroykey.Operation{}, &psadestroykey.Result{}},
+	requests.OpPsaSignHash:          {&psasignhash.Operation{}, &psasignhash.Result{}},
+	requests.OpPsaVerifyHash:        {&psaverifyhash.Operation{}, &psaverifyhash.Result{}},
+	requests.OpPsaImportKey:         {&psaimportkey.Operation{}, &psaimportkey.Result{}},
+	requests.OpPsaExportPublicKey:   {&psaexportpublickey.Operation{}, &psaexportpublickey.Result{}},
+	requests.OpListProviders:        {&listproviders.Operation{}, &listproviders.Result{}},
+	requests.OpListOpcodes:          {&listopcodes.Operation{}, &listopcodes.Result{}},
+	requests.OpPsaAsymmetricEncrypt: {&psaasymmetricencrypt.Operation{}, &psaasymmetricencrypt.Result{}},
+	requests.OpPsaAsymmetricDecrypt: {&psaasymmetricdecrypt.Operation{}, &psaasymmetricdecrypt.Result{}},
+	requests.OpPsaExportKey:         {&psaexportkey.Operation{}, &psaexportkey.Result{}},
+	requests.OpPsaGenerateRandom:    {&psageneraterandom.Operation{}, &psageneraterandom.Result{}},
+	requests.OpListAuthenticators:   {&listauthenticators.Operation{}, &listauthenticators.Result{}},
+	requests.OpPsaHashCompute:       {&psahashcompute.Operation{}, &psahashcompute.Result{}},
+	requests.OpPsaHashCompare:       {&psahashcompare.Operation{}, &psahashcompare.Result{}},
+	requests.OpPsaAeadEncrypt:       {&psaaeadencrypt.Operation{}, &psaaeadencrypt.Result{}},
+	requests.OpPsaAeadDecrypt:       {&psaaeaddecrypt.Operation{}, &psaaeaddecrypt.Result{}},
+	requests.OpPsaRawKeyAgreement:   {&psarawkeyagreement.Operation{}, &psarawkeyagreement.Result{}},
+	requests.OpPsaCipherEncrypt:     {&psacipherencrypt.Operation{}, &psacipherencrypt.Result{}},
+	requests.OpPsaCipherDecrypt:     {&psacipherdecrypt.Operation{}, &psacipherdecrypt.Result{}},
+	requests.OpPsaMacCompute:        {&psamaccompute.Operation{}, &psamaccompute.Result{}},
+	requests.OpPsaMacVerify:         {&psamacverify.Operation{}, &psamacverify.Result{}},
+	requests.OpPsaSignMessage:       {&psasignmessage.Operation{}, &psasignmessage.Result{}},
+	requests.OpPsaVerifyMessage:     {&psaverifymessage.Operation{}, &psaverifymessage.Result{}},
+	requests.OpListKeys:             {&listkeys.Operation{}, &listkeys.Result{}},
+	requests.OpListClients:          {&listclients.Operation{}, &listclients.Result{}},
+	requests.OpDeleteClient:         {&deleteclient.Operation{}, &deleteclient.Result{}},
+}
+
+// NewOperationMessage returns an empty operation message, the body of a request, for the opcode.  It returns nil
+// if the opcode is not known.
+func NewOperationMessage(op requests.OpCode) proto.Message {
+	t, ok := messageTypes[op]
+	if !ok {
+		return nil
+	}
+	return t.operation.ProtoReflect().New().Interface()
+}
+
+// NewResultMessage returns an empty result message, the body of a response, for the opcode.  It returns nil if
+// the opcode is not known.
+func NewResultMessage(op requests.OpCode) proto.Message {
+	t, ok := messageTypes[op]
+	if !ok {
+		return nil
+	}
+	return t.result.ProtoReflect().New().Interface()
+}
@@ -5,6 +5,9 @@ package operations
 
 import (
 	"bytes"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/parallaxsecond/parsec-client-go/interface/auth"
 	connection "github.com/parallaxsecond/parsec-client-go/interface/connection"
@@ -41,33 +44,69 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-// Client is a Parsec client representing a connection and set of API implementations
+// Client is a Parsec client representing a connection and set of API implementations.  A Client may be shared
+// between goroutines.  If its connection is a connection.Cloner, as the default connection is, each operation uses
+// its own connection so operations run concurrently; otherwise operations are sent one at a time.
 type Client struct {
-	conn connection.Connection
+	conn         connection.Connection
+	interceptors []Interceptor
+	// lock is held by the operation using conn, when conn cannot be cloned.  It is a channel so that waiting for it
+	// can time out.
+	lock     chan struct{}
+	timeout  time.Duration
+	deadline time.Time
 }
 
-// InitClient initializes a Parsec client
-func InitClient() (*Client, error) {
+// deadliner is implemented by connections that support I/O deadlines.
+type deadliner interface {
+	SetDeadline(t time.Time) error
+}
+
+// InitClient initializes a Parsec client.  Any interceptors supplied are called, in order, around every operation.
+func InitClient(interceptors ...Interceptor) (*Client, error) {
 	conn, err := connection.NewDefaultConnection()
 	if err != nil {
 		return nil, err
 	}
 	client := &Client{
-		conn: conn,
+		conn:         conn,
+		interceptors: interceptors,
+		lock:         make(chan struct{}, 1),
 	}
 
 	return client, nil
 }
 
-// InitClient initializes a Parsec client
-func InitClientFromConnection(conn connection.Connection) (*Client, error) {
+// InitClientFromConnection initializes a Parsec client using the supplied connection.  Any interceptors supplied
+// are called, in order, around every operation.
+func InitClientFromConnection(conn connection.Connection, interceptors ...Interceptor) (*Client, error) {
 	client := &Client{
-		conn: conn,
+		conn:         conn,
+		interceptors: interceptors,
+		lock:         make(chan struct{}, 1),
 	}
 
 	return client, nil
 }
 
+// WithTimeout returns a copy of the client, sharing its connection, which fails operations that take longer than
+// timeout.  The timeout includes time spent waiting for other operations on the shared connection to complete, if
+// the connection cannot be cloned.  Once the operation is sent, it is only enforced for connections supporting
+// SetDeadline, such as the default unix socket connection.
+// A timeout of zero means no timeout.
+func (c Client) WithTimeout(timeout time.Duration) *Client {
+	c.timeout = timeout
+	return &c
+}
+
+// WithDeadline returns a copy of the client, sharing its connection, which fails operations that have not completed
+// by deadline.  It is enforced in the same way as WithTimeout, and if both are given the earlier limit applies.  A
+// zero deadline means no deadline.
+func (c Client) WithDeadline(deadline time.Time) *Client {
+	c.deadline = deadline
+	return &c
+}
+
 func (c *Client) Close() error {
 	// Just in case
 	return c.conn.Close()
@@ -389,7 +428,7 @@ func (c Client) PsaMACVerify(provider requests.ProviderID, authenticator auth.Au
 	}
 	resp := &psamacverify.Result{}
 
-	return c.operation(provider, authenticator, requests.OpPsaMacCompute, req, resp)
+	return c.operation(provider, authenticator, requests.OpPsaMacVerify, req, resp)
 }
 
 func (c Client) PsaRawKeyAgreement(provider requests.ProviderID, authenticator auth.Authenticator, alg *psaalgorithm.Algorithm_KeyAgreement_Raw, privateKey string, peerKey []byte) ([]byte, error) {
@@ -440,13 +479,43 @@ func (c Client) PsaAsymmetricEncrypt(provider requests.ProviderID, authenticator
 }
 
 func (c Client) operation(provider requests.ProviderID, authenticator auth.Authenticator, op requests.OpCode, request, response proto.Message) error {
-	err := c.conn.Open()
+	operation := &Operation{
+		OpCode:        op,
+		Provider:      provider,
+		Authenticator: authenticator,
+		Request:       request,
+		Response:      response,
+	}
+	return chainInterceptors(c.interceptors, c.invoke)(operation)
+}
+
+// invoke sends the operation to the parsec service and parses the response.  This is the final handler in the interceptor chain.
+func (c Client) invoke(op *Operation) error {
+	deadline := c.deadline
+	if c.timeout > 0 {
+		if d := time.Now().Add(c.timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	conn, release, err := c.connect(deadline)
 	if err != nil {
 		return err
 	}
-	defer c.conn.Close()
+	defer release()
 
-	r, err := requests.NewRequest(op, request, authenticator, provider)
+	err = conn.Open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if d, ok := conn.(deadliner); ok && !deadline.IsZero() {
+		err = d.SetDeadline(deadline)
+		if err != nil {
+			return err
+		}
+	}
+
+	r, err := requests.NewRequest(op.OpCode, op.Request, op.Authenticator, op.Provider)
 	if err != nil {
 		return err
 	}
@@ -456,16 +525,38 @@ func (c Client) operation(provider requests.ProviderID, authenticator auth.Authe
 	}
 	// TODO ensure that we continue writing whole buffer afer a short write
 	// https://github.com/parallaxsecond/parsec-client-go/issues/23
-	_, err = c.conn.Write(b.Bytes())
+	op.RequestSize, err = conn.Write(b.Bytes())
 	if err != nil {
 		return err
 	}
 
 	rcvBuf := new(bytes.Buffer)
-	_, err = rcvBuf.ReadFrom(c.conn)
+	received, err := rcvBuf.ReadFrom(conn)
+	op.ResponseSize = int(received)
 	if err != nil {
 		return err
 	}
 
-	return requests.ParseResponse(op, rcvBuf, response)
+	return requests.ParseResponse(op.OpCode, rcvBuf, op.Response)
+}
+
+// connect returns the connection to use for an operation, and a function to call once the operation is complete.
+// Connections that can be cloned give each operation its own connection.  Otherwise the operation waits for the
+// shared connection, until deadline if it is not zero.
+func (c Client) connect(deadline time.Time) (connection.Connection, func(), error) {
+	if cloner, ok := c.conn.(connection.Cloner); ok {
+		return cloner.Clone(), func() {}, nil
+	}
+	if deadline.IsZero() {
+		c.lock <- struct{}{}
+	} else {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		select {
+		case c.lock <- struct{}{}:
+		case <-timer.C:
+			return nil, nil, fmt.Errorf("waiting for the connection to the parsec service: %w", os.ErrDeadlineExceeded)
+		}
+	}
+	return c.conn, func() { <-c.lock }, nil
 }
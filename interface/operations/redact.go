@@ -0,0 +1,114 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package operations
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// sensitiveFieldNames are the names of operation fields that carry key material, secrets or plaintext.
+var sensitiveFieldNames = map[protoreflect.Name]bool{
+	"plaintext":     true,
+	"data":          true,
+	"shared_secret": true,
+	"random_bytes":  true,
+	"input":         true,
+	"message":       true,
+}
+
+// nonSensitiveFields are exceptions to sensitiveFieldNames.
+var nonSensitiveFields = map[protoreflect.FullName]bool{
+	"psa_export_public_key.Result.data": true,
+}
+
+// IsSensitiveField returns true if the field may hold key material, secrets or plaintext, and so should not be
+// written to logs.
+func IsSensitiveField(fd protoreflect.FieldDescriptor) bool {
+	return fd.Kind() == protoreflect.BytesKind && sensitiveFieldNames[fd.Name()] && !nonSensitiveFields[fd.FullName()]
+}
+
+// Redacted wraps a protobuf message so that it can be logged with slog.  Sensitive fields, as identified by
+// IsSensitiveField, are replaced by their length.  Other bytes fields are hex encoded.
+type Redacted struct {
+	Message proto.Message
+}
+
+// LogValue implements slog.LogValuer.
+func (r Redacted) LogValue() slog.Value {
+	return logValue(r.Message, true)
+}
+
+// Unredacted wraps a protobuf message so that it can be logged with slog in the same form as Redacted, but with
+// sensitive fields included.  It is only for debugging, where the secrets logged are known to be disposable.
+type Unredacted struct {
+	Message proto.Message
+}
+
+// LogValue implements slog.LogValuer.
+func (u Unredacted) LogValue() slog.Value {
+	return logValue(u.Message, false)
+}
+
+func logValue(m proto.Message, redact bool) slog.Value {
+	if m == nil || reflect.ValueOf(m).IsNil() {
+		return slog.StringValue("<nil>")
+	}
+	return messageLogValue(m.ProtoReflect(), redact)
+}
+
+func messageLogValue(m protoreflect.Message, redact bool) slog.Value {
+	fields := m.Descriptor().Fields()
+	attrs := make([]slog.Attr, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		attrs = append(attrs, fieldLogAttr(fd, m.Get(fd), redact))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func fieldLogAttr(fd protoreflect.FieldDescriptor, v protoreflect.Value, redact bool) slog.Attr {
+	name := string(fd.Name())
+	switch {
+	case fd.IsList():
+		list := v.List()
+		attrs := make([]slog.Attr, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			attrs[i] = slog.Attr{Key: strconv.Itoa(i), Value: singularLogValue(fd, list.Get(i), redact)}
+		}
+		return slog.Attr{Key: name, Value: slog.GroupValue(attrs...)}
+	case fd.IsMap():
+		return slog.String(name, fmt.Sprint(v.Interface()))
+	default:
+		return slog.Attr{Key: name, Value: singularLogValue(fd, v, redact)}
+	}
+}
+
+func singularLogValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, redact bool) slog.Value {
+	switch fd.Kind() { //nolint:exhaustive // all other kinds are scalars handled by default
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageLogValue(v.Message(), redact)
+	case protoreflect.BytesKind:
+		if redact && IsSensitiveField(fd) {
+			return slog.StringValue(fmt.Sprintf("[REDACTED %d bytes]", len(v.Bytes())))
+		}
+		return slog.StringValue(hex.EncodeToString(v.Bytes()))
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return slog.StringValue(string(ev.Name()))
+		}
+		return slog.Int64Value(int64(v.Enum()))
+	default:
+		return slog.AnyValue(v.Interface())
+	}
+}
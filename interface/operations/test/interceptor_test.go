@@ -0,0 +1,168 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package operations_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/ping"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+)
+
+var pingResp = []byte{
+	0x10, 0xa7, 0xc0, 0x5e, // magic
+	0x1e, 0x00, // header size
+	0x01, 0x00, // verMaj(8), verMin(8)
+	0x00, 0x00, // flags(16)
+	0x00,                                           // provider
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // session(64)
+	0x00,                   // content type
+	0x00,                   // accept type
+	0x00,                   // auth type
+	0x04, 0x00, 0x00, 0x00, // bodylen(32)
+	0x00, 0x00, // auth len
+	0x01, 0x00, 0x00, 0x00, //   opcode(32)
+	0x00, 0x00, // status
+	0x00, 0x00, // reserved
+	0x08, 0x01, 0x10, 0x00} //  body
+
+// cannedConnection records the request written to it and replies with a fixed response.
+type cannedConnection struct {
+	response []byte
+	written  *bytes.Buffer
+	reader   io.Reader
+	writes   int
+}
+
+func newCannedConnection(response []byte) *cannedConnection {
+	return &cannedConnection{response: response, written: &bytes.Buffer{}}
+}
+
+func (c *cannedConnection) Open() error {
+	c.reader = bytes.NewReader(c.response)
+	return nil
+}
+
+func (c *cannedConnection) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+func (c *cannedConnection) Write(p []byte) (int, error) {
+	c.writes++
+	return c.written.Write(p)
+}
+
+func (c *cannedConnection) Close() error {
+	return nil
+}
+
+var _ = Describe("interceptors", func() {
+	var (
+		conn          *cannedConnection
+		authenticator auth.Authenticator
+	)
+	BeforeEach(func() {
+		conn = newCannedConnection(pingResp)
+		authenticator = auth.NewDirectAuthenticator("testapp")
+	})
+
+	Context("no interceptors", func() {
+		It("Should send the request", func() {
+			c, err := operations.InitClientFromConnection(conn)
+			Expect(err).NotTo(HaveOccurred())
+			maj, min, err := c.Ping(requests.ProviderCore, authenticator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(maj).To(Equal(uint8(1)))
+			Expect(min).To(Equal(uint8(0)))
+			Expect(conn.writes).To(Equal(1))
+		})
+	})
+
+	Context("decorating interceptors", func() {
+		It("Should call interceptors in order with the operation details", func() {
+			var calls []string
+			recorder := func(name string) operations.Interceptor {
+				return func(op *operations.Operation, next operations.Invoker) error {
+					Expect(op.OpCode).To(Equal(requests.OpPing))
+					Expect(op.Provider).To(Equal(requests.ProviderCore))
+					Expect(op.AuthType()).To(Equal(auth.AuthDirect))
+					Expect(op.Request).To(BeAssignableToTypeOf(&ping.Operation{}))
+					calls = append(calls, name+" before")
+					err := next(op)
+					resp, ok := op.Response.(*ping.Result)
+					Expect(ok).To(BeTrue())
+					Expect(resp.WireProtocolVersionMaj).To(Equal(uint32(1)))
+					calls = append(calls, name+" after")
+					return err
+				}
+			}
+			c, err := operations.InitClientFromConnection(conn, recorder("a"), recorder("b"))
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = c.Ping(requests.ProviderCore, authenticator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(calls).To(Equal([]string{"a before", "b before", "b after", "a after"}))
+		})
+		It("Should send modifications made by an interceptor", func() {
+			switchAuth := func(op *operations.Operation, next operations.Invoker) error {
+				op.Authenticator = auth.NewNoAuthAuthenticator()
+				return next(op)
+			}
+			c, err := operations.InitClientFromConnection(conn, switchAuth)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = c.Ping(requests.ProviderCore, authenticator)
+			Expect(err).NotTo(HaveOccurred())
+			// auth type is at offset 21 of the header, auth length at offset 26
+			Expect(conn.written.Bytes()[21]).To(Equal(byte(auth.AuthNoAuth)))
+			Expect(conn.written.Bytes()[26]).To(Equal(byte(0)))
+		})
+		It("Should pass errors from the service back through the chain", func() {
+			badResp := append([]byte{}, pingResp...)
+			badResp[32] = byte(requests.StatusAuthenticationError)
+			conn = newCannedConnection(badResp)
+			var seen error
+			observer := func(op *operations.Operation, next operations.Invoker) error {
+				seen = next(op)
+				return seen
+			}
+			c, err := operations.InitClientFromConnection(conn, observer)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = c.Ping(requests.ProviderCore, authenticator)
+			Expect(err).To(HaveOccurred())
+			Expect(seen).To(Equal(err))
+		})
+	})
+
+	Context("short circuiting interceptors", func() {
+		It("Should return the interceptor response without calling the service", func() {
+			cached := func(op *operations.Operation, next operations.Invoker) error {
+				resp, ok := op.Response.(*ping.Result)
+				Expect(ok).To(BeTrue())
+				resp.WireProtocolVersionMaj = 7
+				return nil
+			}
+			c, err := operations.InitClientFromConnection(conn, cached)
+			Expect(err).NotTo(HaveOccurred())
+			maj, _, err := c.Ping(requests.ProviderCore, authenticator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(maj).To(Equal(uint8(7)))
+			Expect(conn.writes).To(Equal(0))
+		})
+		It("Should return the interceptor error without calling the service", func() {
+			deny := func(op *operations.Operation, next operations.Invoker) error {
+				return fmt.Errorf("denied by policy")
+			}
+			c, err := operations.InitClientFromConnection(conn, deny)
+			Expect(err).NotTo(HaveOccurred())
+			_, _, err = c.Ping(requests.ProviderCore, authenticator)
+			Expect(err).To(MatchError("denied by policy"))
+			Expect(conn.writes).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,34 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package operations_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+)
+
+var _ = Describe("message types", func() {
+	It("Should create the messages for an opcode", func() {
+		Expect(operations.NewOperationMessage(requests.OpPsaSignHash)).To(BeAssignableToTypeOf(&psasignhash.Operation{}))
+		Expect(operations.NewResultMessage(requests.OpPsaSignHash)).To(BeAssignableToTypeOf(&psasignhash.Result{}))
+	})
+	It("Should have messages for every opcode", func() {
+		for op := requests.OpPing; op <= requests.OpDeleteClient; op++ {
+			Expect(operations.NewOperationMessage(op)).NotTo(BeNil(), op.String())
+			Expect(operations.NewResultMessage(op)).NotTo(BeNil(), op.String())
+		}
+	})
+	It("Should return nil for unknown opcodes", func() {
+		Expect(operations.NewOperationMessage(requests.OpCode(0xffff))).To(BeNil())
+		Expect(operations.NewResultMessage(requests.OpCode(0xffff))).To(BeNil())
+	})
+	It("Should create a new message each time", func() {
+		m := operations.NewOperationMessage(requests.OpPsaSignHash).(*psasignhash.Operation)
+		m.KeyName = "changed"
+		Expect(operations.NewOperationMessage(requests.OpPsaSignHash).(*psasignhash.Operation).KeyName).To(BeEmpty())
+	})
+})
@@ -0,0 +1,57 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package operations_test
+
+import (
+	"bytes"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaexportpublickey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaimportkey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = Describe("redaction", func() {
+	var logged *bytes.Buffer
+	logMessage := func(m proto.Message) string {
+		logged = &bytes.Buffer{}
+		slog.New(slog.NewTextHandler(logged, nil)).Info("msg", "body", operations.Redacted{Message: m})
+		return logged.String()
+	}
+
+	It("Should redact key material", func() {
+		out := logMessage(&psaimportkey.Operation{
+			KeyName: "mykey",
+			Data:    []byte{0xde, 0xad, 0xbe, 0xef},
+			Attributes: &psakeyattributes.KeyAttributes{
+				KeyBits: 256,
+			},
+		})
+		Expect(out).To(ContainSubstring("body.key_name=mykey"))
+		Expect(out).To(ContainSubstring("body.attributes.key_bits=256"))
+		Expect(out).To(ContainSubstring(`body.data="[REDACTED 4 bytes]"`))
+		Expect(out).NotTo(ContainSubstring("deadbeef"))
+	})
+	It("Should not redact public keys", func() {
+		out := logMessage(&psaexportpublickey.Result{Data: []byte{0xde, 0xad, 0xbe, 0xef}})
+		Expect(out).To(ContainSubstring("body.data=deadbeef"))
+	})
+	It("Should include key material when unredacted", func() {
+		logged = &bytes.Buffer{}
+		slog.New(slog.NewTextHandler(logged, nil)).Info("msg", "body", operations.Unredacted{Message: &psaimportkey.Operation{
+			KeyName: "mykey",
+			Data:    []byte{0xde, 0xad, 0xbe, 0xef},
+		}})
+		Expect(logged.String()).To(ContainSubstring("body.key_name=mykey"))
+		Expect(logged.String()).To(ContainSubstring("body.data=deadbeef"))
+	})
+	It("Should cope with nil messages", func() {
+		var result *psaexportpublickey.Result
+		Expect(logMessage(result)).To(ContainSubstring("body=<nil>"))
+	})
+})
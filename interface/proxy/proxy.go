@@ -0,0 +1,200 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package proxy forwards connections from parsec clients to the parsec service, logging each request and response
+// as it passes.  It is intended for debugging problems between applications and the service.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/interface/wiredecode"
+)
+
+// Proxy forwards each connection it accepts to the parsec service.  As with the parsec service, each connection
+// carries a single request and response.
+type Proxy struct {
+	// Endpoint is the path of the parsec service socket.
+	Endpoint string
+	// Logger receives the decoded requests and responses at info level, and any failures.  If nil, slog.Default()
+	// is used.
+	Logger *slog.Logger
+	// LogSecrets logs key material, plaintexts and authentication data, which are otherwise redacted.
+	LogSecrets bool
+	// Timeout, if not zero, limits the time allowed for the parsec service to respond.
+	Timeout time.Duration
+	// RequestTimeout limits the time allowed for a client to send its request.  If zero, DefaultRequestTimeout is
+	// used.
+	RequestTimeout time.Duration
+	// BodyLenLimit is the largest request body forwarded to the parsec service.  A request with a larger body is
+	// answered with requests.StatusBodySizeExceedsLimit, as the service would.  If zero,
+	// requests.DefaultBodyLenLimit is used.
+	BodyLenLimit uint32
+
+	conns atomic.Uint64
+}
+
+// DefaultRequestTimeout is the time allowed for a client to send its request when Proxy.RequestTimeout is zero.
+const DefaultRequestTimeout = 10 * time.Second
+
+// Serve forwards connections accepted from l until l is closed.
+func (p *Proxy) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go p.handle(conn, p.conns.Add(1))
+	}
+}
+
+func (p *Proxy) logger() *slog.Logger {
+	if p.Logger == nil {
+		return slog.Default()
+	}
+	return p.Logger
+}
+
+// handle forwards the request read from client and the response to it, logging both.
+func (p *Proxy) handle(client net.Conn, id uint64) {
+	defer client.Close()
+	ctx := context.Background()
+	logger := p.logger().With(slog.Uint64("conn", id))
+
+	timeout := p.RequestTimeout
+	if timeout == 0 {
+		timeout = DefaultRequestTimeout
+	}
+	if err := client.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "could not set request deadline", slog.String("error", err.Error()))
+		return
+	}
+	limit := p.BodyLenLimit
+	if limit == 0 {
+		limit = requests.DefaultBodyLenLimit
+	}
+	req, err := requests.ReadMessage(client, limit)
+	if errors.Is(err, requests.ErrBodySizeExceedsLimit) {
+		logger.LogAttrs(ctx, slog.LevelError, "refused request", slog.String("error", err.Error()))
+		if _, err = client.Write(statusResponse(req, requests.StatusBodySizeExceedsLimit)); err != nil {
+			logger.LogAttrs(ctx, slog.LevelError, "could not write response", slog.String("error", err.Error()))
+			return
+		}
+		// Closing the connection with the request unread would reset it before the client reads the response, so
+		// the rest of the request is discarded until the deadline.
+		h, _ := requests.DecodeWireHeader(req)
+		_, _ = io.CopyN(io.Discard, client, int64(h.BodyLen)+int64(h.AuthLen))
+		return
+	}
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			logger.LogAttrs(ctx, slog.LevelError, "could not read request", slog.String("error", err.Error()))
+		}
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "parsec request", p.messageAttrs(wiredecode.Decode(req, wiredecode.Request), len(req))...)
+
+	start := time.Now()
+	resp, err := p.forward(req)
+	duration := time.Since(start)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "could not forward request", slog.String("error", err.Error()),
+			slog.Duration("duration", duration))
+		return
+	}
+	attrs := p.messageAttrs(wiredecode.Decode(resp, wiredecode.Response), len(resp))
+	attrs = append(attrs, slog.Duration("duration", duration))
+	logger.LogAttrs(ctx, slog.LevelInfo, "parsec response", attrs...)
+
+	if _, err = client.Write(resp); err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "could not write response", slog.String("error", err.Error()))
+	}
+}
+
+// forward sends the request to the parsec service and returns the response, which is everything the service
+// writes before closing the connection.
+func (p *Proxy) forward(req []byte) ([]byte, error) {
+	conn, err := net.Dial("unix", p.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if p.Timeout != 0 {
+		if err = conn.SetDeadline(time.Now().Add(p.Timeout)); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = conn.Write(req); err != nil {
+		return nil, err
+	}
+	resp, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		return nil, fmt.Errorf("parsec service closed the connection without responding")
+	}
+	return resp, nil
+}
+
+// statusResponse returns a response with no body and the given status to the request with wire header hdr.
+func statusResponse(hdr []byte, status requests.StatusCode) []byte {
+	h, _ := requests.DecodeWireHeader(hdr)
+	h.AcceptType, h.AuthType, h.BodyLen, h.AuthLen, h.Status = 0, 0, 0, 0, status
+	resp := &bytes.Buffer{}
+	// WireHeader holds only fixed size fields, in wire order, so writing it cannot fail
+	_ = binary.Write(resp, binary.LittleEndian, h)
+	return resp.Bytes()
+}
+
+// messageAttrs returns the attributes logged for a message of size bytes.
+func (p *Proxy) messageAttrs(m *wiredecode.Message, size int) []slog.Attr {
+	h := m.Header
+	attrs := []slog.Attr{
+		slog.String("opcode", h.OpCode.String()),
+		slog.String("provider", h.Provider.String()),
+		slog.Int("bytes", size),
+	}
+	if m.Direction == wiredecode.Request {
+		attrs = append(attrs, slog.String("authenticator", h.AuthType.String()))
+		switch {
+		case p.LogSecrets && h.AuthType == auth.AuthDirect:
+			attrs = append(attrs, slog.String("auth", string(m.Auth)))
+		case p.LogSecrets:
+			attrs = append(attrs, slog.String("auth", hex.EncodeToString(m.Auth)))
+		default:
+			attrs = append(attrs, slog.String("auth", fmt.Sprintf("[REDACTED %d bytes]", len(m.Auth))))
+		}
+	} else {
+		attrs = append(attrs, slog.String("status", h.Status.String()))
+	}
+	if p.LogSecrets {
+		attrs = append(attrs, slog.Any("body", operations.Unredacted{Message: m.Body}))
+	} else {
+		attrs = append(attrs, slog.Any("body", operations.Redacted{Message: m.Body}))
+	}
+	if len(m.Errors) != 0 {
+		problems := make([]string, len(m.Errors))
+		for i, e := range m.Errors {
+			problems[i] = e.String()
+		}
+		attrs = append(attrs, slog.Any("problems", problems))
+	}
+	return attrs
+}
@@ -0,0 +1,221 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaimportkey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/proxy"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"google.golang.org/protobuf/proto"
+)
+
+// syncBuffer is a buffer that can be written to by the proxy while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records returns the JSON log records written so far, leaving out those for the ListOpcodes operations the client
+// uses to check the provider's capabilities.
+func (b *syncBuffer) records() []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		Expect(json.Unmarshal([]byte(line), &record)).To(Succeed())
+		if record["opcode"] != "ListOpcodes" {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+var _ = Describe("logging proxy", func() {
+	signAlg := algorithm.NewAsymmetricSignature().RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256).GetAsymmetricSignature()
+	var dir string
+	var serviceListener, proxyListener net.Listener
+	var logged *syncBuffer
+	var logSecrets bool
+	var requestTimeout time.Duration
+	var bodyLenLimit uint32
+	var client *parsec.BasicClient
+
+	BeforeEach(func() {
+		logSecrets = false
+		requestTimeout = 0
+		bodyLenLimit = 0
+	})
+	JustBeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "parsec-proxy")
+		Expect(err).NotTo(HaveOccurred())
+		service := parsectest.NewService().
+			Handle(requests.OpPsaSignHash, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &psasignhash.Result{Signature: []byte{0xaa, 0xbb}}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaImportKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &psaimportkey.Result{}, requests.StatusSuccess
+			})
+		serviceListener, err = net.Listen("unix", filepath.Join(dir, "parsec.sock"))
+		Expect(err).NotTo(HaveOccurred())
+		go func(l net.Listener) {
+			_ = service.Serve(l)
+		}(serviceListener)
+
+		logged = &syncBuffer{}
+		p := &proxy.Proxy{
+			Endpoint:       filepath.Join(dir, "parsec.sock"),
+			Logger:         slog.New(slog.NewJSONHandler(logged, nil)),
+			LogSecrets:     logSecrets,
+			RequestTimeout: requestTimeout,
+			BodyLenLimit:   bodyLenLimit,
+		}
+		proxyListener, err = net.Listen("unix", filepath.Join(dir, "proxy.sock"))
+		Expect(err).NotTo(HaveOccurred())
+		go func(l net.Listener) {
+			_ = p.Serve(l)
+		}(proxyListener)
+
+		Expect(os.Setenv("PARSEC_SERVICE_ENDPOINT", "unix:"+filepath.Join(dir, "proxy.sock"))).To(Succeed())
+		client, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderTPM).
+			Authenticator(parsec.NewDirectAuthenticator("myapp")))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		Expect(client.Close()).To(Succeed())
+		Expect(proxyListener.Close()).To(Succeed())
+		_ = serviceListener.Close()
+		Expect(os.Unsetenv("PARSEC_SERVICE_ENDPOINT")).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should forward requests and log them with timing", func() {
+		signature, err := client.PsaSignHash("mykey", []byte{1, 2, 3, 4}, signAlg)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature).To(Equal([]byte{0xaa, 0xbb}))
+
+		records := logged.records()
+		Expect(records).To(HaveLen(2))
+		Expect(records[0]["msg"]).To(Equal("parsec request"))
+		Expect(records[0]["opcode"]).To(Equal("PsaSignHash"))
+		Expect(records[0]["provider"]).To(Equal("TPM"))
+		Expect(records[0]["authenticator"]).To(Equal("Direct"))
+		Expect(records[0]["auth"]).To(Equal("[REDACTED 5 bytes]"))
+		Expect(records[0]["body"]).To(HaveKeyWithValue("key_name", "mykey"))
+		Expect(records[0]["body"]).To(HaveKeyWithValue("hash", "01020304"))
+		Expect(records[1]["msg"]).To(Equal("parsec response"))
+		Expect(records[1]["status"]).To(Equal("Success"))
+		Expect(records[1]["body"]).To(HaveKeyWithValue("signature", "aabb"))
+		Expect(records[1]).To(HaveKey("duration"))
+		Expect(records[1]["conn"]).To(Equal(records[0]["conn"]))
+	})
+	It("Should redact key material", func() {
+		Expect(client.PsaImportKey("mykey", parsec.DefaultKeyAttribute().AesGcmKey(), []byte{0xde, 0xad, 0xbe, 0xef})).To(Succeed())
+		records := logged.records()
+		Expect(records[0]["body"]).To(HaveKeyWithValue("data", "[REDACTED 4 bytes]"))
+	})
+	Context("With secrets logged", func() {
+		BeforeEach(func() {
+			logSecrets = true
+		})
+		It("Should log key material and authentication", func() {
+			Expect(client.PsaImportKey("mykey", parsec.DefaultKeyAttribute().AesGcmKey(), []byte{0xde, 0xad, 0xbe, 0xef})).To(Succeed())
+			records := logged.records()
+			Expect(records[0]["body"]).To(HaveKeyWithValue("data", "deadbeef"))
+			Expect(records[0]["auth"]).To(Equal("myapp"))
+		})
+	})
+	It("Should log the status of failed operations", func() {
+		err := client.PsaDestroyKey("mykey")
+		Expect(err).To(HaveOccurred())
+		records := logged.records()
+		Expect(records[1]["status"]).To(Equal("OpcodeDoesNotExist"))
+	})
+	It("Should log when the parsec service is unavailable", func() {
+		Expect(serviceListener.Close()).To(Succeed())
+		_, err := client.PsaSignHash("mykey", []byte{1, 2, 3, 4}, signAlg, parsec.WithTimeout(time.Second))
+		Expect(err).To(HaveOccurred())
+		Expect(logged.records()).To(ContainElement(HaveKeyWithValue("msg", "could not forward request")))
+	})
+	It("Should refuse a request whose body exceeds the limit without reading it", func() {
+		conn, err := net.Dial("unix", filepath.Join(dir, "proxy.sock"))
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		// Only the header is sent, claiming a body of nearly 4GiB
+		hdr := &requests.WireHeader{
+			MagicNumber: 0x5EC0A710, HeaderSize: 30, VersionMajor: 1, Provider: requests.ProviderTPM,
+			BodyLen: 0xffffffff, OpCode: requests.OpPsaSignHash,
+		}
+		Expect(binary.Write(conn, binary.LittleEndian, hdr)).To(Succeed())
+		Expect(conn.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+		resp := make([]byte, requests.WireHeaderSize)
+		_, err = io.ReadFull(conn, resp)
+		Expect(err).NotTo(HaveOccurred())
+		h, errs := requests.DecodeWireHeader(resp)
+		Expect(errs).To(BeEmpty())
+		Expect(h.Status).To(Equal(requests.StatusBodySizeExceedsLimit))
+		Expect(h.OpCode).To(Equal(requests.OpPsaSignHash))
+		Expect(h.BodyLen).To(BeZero())
+		Expect(logged.records()).To(ContainElement(HaveKeyWithValue("msg", "refused request")))
+	})
+	Context("With a body length limit", func() {
+		BeforeEach(func() {
+			bodyLenLimit = 32
+		})
+		It("Should answer oversized requests as the parsec service would", func() {
+			err := client.PsaImportKey("mykey", parsec.DefaultKeyAttribute().AesGcmKey(), make([]byte, 64))
+			Expect(err).To(Equal(&requests.StatusError{Code: requests.StatusBodySizeExceedsLimit}))
+			_, err = client.PsaSignHash("mykey", []byte{1, 2, 3, 4}, signAlg)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+	Context("With a request timeout", func() {
+		BeforeEach(func() {
+			requestTimeout = 50 * time.Millisecond
+		})
+		It("Should close connections from clients that do not send a request", func() {
+			conn, err := net.Dial("unix", filepath.Join(dir, "proxy.sock"))
+			Expect(err).NotTo(HaveOccurred())
+			defer conn.Close()
+			_, err = conn.Write([]byte{0x10, 0xa7})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conn.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+			start := time.Now()
+			resp, err := io.ReadAll(conn)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp).To(BeEmpty())
+			Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+			Eventually(logged.records).Should(ContainElement(HaveKeyWithValue("msg", "could not read request")))
+		})
+	})
+})
@@ -0,0 +1,144 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+)
+
+// WireHeader holds the fields of a wire header as they were read, whether or not they are valid.
+type WireHeader struct {
+	MagicNumber  uint32
+	HeaderSize   uint16
+	VersionMajor uint8
+	VersionMinor uint8
+	Flags        uint16
+	Provider     ProviderID
+	Session      uint64
+	ContentType  uint8
+	AcceptType   uint8
+	AuthType     auth.AuthenticationType
+	BodyLen      uint32
+	AuthLen      uint16
+	OpCode       OpCode
+	Status       StatusCode
+	Reserved1    uint8
+	Reserved2    uint8
+}
+
+// FieldError describes a problem with one field of a wire header.
+type FieldError struct {
+	// Field is the name of the field, as used in WireHeader.
+	Field string
+	Msg   string
+}
+
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Msg
+}
+
+// headerDecoder reads the fields of a wire header in order, recording a FieldError for each field that is missing
+// from the data.
+type headerDecoder struct {
+	data   []byte
+	offset int
+	errs   []FieldError
+}
+
+func (d *headerDecoder) next(field string, size int) []byte {
+	if d.offset+size > len(d.data) {
+		d.errs = append(d.errs, FieldError{Field: field, Msg: fmt.Sprintf("missing, data ends at byte %d", len(d.data))})
+		d.offset += size
+		return make([]byte, size)
+	}
+	b := d.data[d.offset : d.offset+size]
+	d.offset += size
+	return b
+}
+
+func (d *headerDecoder) check(field string, valid bool, format string, args ...interface{}) {
+	if valid || d.offset > len(d.data) {
+		return
+	}
+	d.errs = append(d.errs, FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+// DecodeWireHeader reads the wire header at the start of data, for debugging captured traffic.  Unlike the parsing
+// done for responses, every field is read and checked, so the FieldErrors list each field that is invalid or
+// missing from data.  Fields that are missing are left as zero.
+func DecodeWireHeader(data []byte) (*WireHeader, []FieldError) {
+	d := &headerDecoder{data: data}
+	h := &WireHeader{}
+	h.MagicNumber = binary.LittleEndian.Uint32(d.next("MagicNumber", buffBytes32Bit))
+	d.check("MagicNumber", h.MagicNumber == magicNumber, "is %#x, should be %#x", h.MagicNumber, magicNumber)
+	h.HeaderSize = binary.LittleEndian.Uint16(d.next("HeaderSize", buffBytes16Bit))
+	d.check("HeaderSize", h.HeaderSize == wireHeaderSizeValue, "is %d, should be %d", h.HeaderSize, wireHeaderSizeValue)
+	h.VersionMajor = d.next("VersionMajor", buffBytes8Bit)[0]
+	h.VersionMinor = d.next("VersionMinor", buffBytes8Bit)[0]
+	d.check("VersionMajor", isSupportedWireHeaderVersion(versionMajorType(h.VersionMajor), versionMinorType(h.VersionMinor)),
+		"unsupported version %d.%d", h.VersionMajor, h.VersionMinor)
+	h.Flags = binary.LittleEndian.Uint16(d.next("Flags", buffBytes16Bit))
+	d.check("Flags", flagsType(h.Flags).isValid(), "is %#x, should be zero", h.Flags)
+	h.Provider = ProviderID(d.next("Provider", buffBytes8Bit)[0])
+	d.check("Provider", h.Provider.IsValid(), "unknown provider %d", h.Provider)
+	h.Session = binary.LittleEndian.Uint64(d.next("Session", buffBytes64Bit))
+	h.ContentType = d.next("ContentType", buffBytes8Bit)[0]
+	d.check("ContentType", contentType(h.ContentType).isValid(), "unknown content type %d", h.ContentType)
+	h.AcceptType = d.next("AcceptType", buffBytes8Bit)[0]
+	d.check("AcceptType", acceptType(h.AcceptType).isValid(), "unknown accept type %d", h.AcceptType)
+	h.AuthType = auth.AuthenticationType(d.next("AuthType", buffBytes8Bit)[0])
+	d.check("AuthType", h.AuthType.IsValid(), "unknown auth type %d", h.AuthType)
+	h.BodyLen = binary.LittleEndian.Uint32(d.next("BodyLen", buffBytes32Bit))
+	h.AuthLen = binary.LittleEndian.Uint16(d.next("AuthLen", buffBytes16Bit))
+	h.OpCode = OpCode(binary.LittleEndian.Uint32(d.next("OpCode", buffBytes32Bit)))
+	d.check("OpCode", h.OpCode.IsValid(), "unknown opcode %d", h.OpCode)
+	h.Status = StatusCode(binary.LittleEndian.Uint16(d.next("Status", buffBytes16Bit)))
+	d.check("Status", h.Status.IsValid(), "unknown status %d", h.Status)
+	h.Reserved1 = d.next("Reserved1", buffBytes8Bit)[0]
+	d.check("Reserved1", h.Reserved1 == 0, "is %#x, should be zero", h.Reserved1)
+	h.Reserved2 = d.next("Reserved2", buffBytes8Bit)[0]
+	d.check("Reserved2", h.Reserved2 == 0, "is %#x, should be zero", h.Reserved2)
+	return h, d.errs
+}
+
+// DefaultBodyLenLimit is the largest request body accepted by the parsec service unless it is configured otherwise.
+const DefaultBodyLenLimit = 1 << 20
+
+// ErrBodySizeExceedsLimit is returned by ReadMessage when the body length in a wire header is above the limit.
+var ErrBodySizeExceedsLimit = errors.New("body size exceeds limit")
+
+// ReadMessage reads a single request or response from r, using the lengths in its wire header to find the end of
+// the message.  Only the magic number and the body length are checked, so that invalid messages can still be passed
+// on or decoded.  A body longer than bodyLenLimit is refused without being read, returning the header and an error
+// wrapping ErrBodySizeExceedsLimit.
+func ReadMessage(r io.Reader, bodyLenLimit uint32) ([]byte, error) {
+	hdr := make([]byte, WireHeaderSize)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	h, _ := DecodeWireHeader(hdr)
+	if h.MagicNumber != magicNumber {
+		return nil, fmt.Errorf("invalid magic number %#x", h.MagicNumber)
+	}
+	if h.BodyLen > bodyLenLimit {
+		return hdr, fmt.Errorf("%w: %d byte body, limit %d", ErrBodySizeExceedsLimit, h.BodyLen, bodyLenLimit)
+	}
+	// The buffer grows as data arrives, rather than being sized from the header, so a sender cannot make us
+	// allocate more than it sends.
+	msg := bytes.NewBuffer(hdr)
+	n := int64(h.BodyLen) + int64(h.AuthLen)
+	if _, err := io.CopyN(msg, r, n); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("reading %d byte body and %d byte auth: %w", h.BodyLen, h.AuthLen, err)
+	}
+	return msg.Bytes(), nil
+}
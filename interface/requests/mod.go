@@ -41,3 +41,69 @@ const (
 func (o OpCode) IsValid() bool {
 	return o <= OpDeleteClient
 }
+
+// String returns the name of the operation, e.g. PsaSignHash.
+//
+//nolint:gocyclo
+func (o OpCode) String() string {
+	switch o {
+	case OpPing:
+		return "Ping"
+	case OpPsaGenerateKey:
+		return "PsaGenerateKey"
+	case OpPsaDestroyKey:
+		return "PsaDestroyKey"
+	case OpPsaSignHash:
+		return "PsaSignHash"
+	case OpPsaVerifyHash:
+		return "PsaVerifyHash"
+	case OpPsaImportKey:
+		return "PsaImportKey"
+	case OpPsaExportPublicKey:
+		return "PsaExportPublicKey"
+	case OpListProviders:
+		return "ListProviders"
+	case OpListOpcodes:
+		return "ListOpcodes"
+	case OpPsaAsymmetricEncrypt:
+		return "PsaAsymmetricEncrypt"
+	case OpPsaAsymmetricDecrypt:
+		return "PsaAsymmetricDecrypt"
+	case OpPsaExportKey:
+		return "PsaExportKey"
+	case OpPsaGenerateRandom:
+		return "PsaGenerateRandom"
+	case OpListAuthenticators:
+		return "ListAuthenticators"
+	case OpPsaHashCompute:
+		return "PsaHashCompute"
+	case OpPsaHashCompare:
+		return "PsaHashCompare"
+	case OpPsaAeadEncrypt:
+		return "PsaAeadEncrypt"
+	case OpPsaAeadDecrypt:
+		return "PsaAeadDecrypt"
+	case OpPsaRawKeyAgreement:
+		return "PsaRawKeyAgreement"
+	case OpPsaCipherEncrypt:
+		return "PsaCipherEncrypt"
+	case OpPsaCipherDecrypt:
+		return "PsaCipherDecrypt"
+	case OpPsaMacCompute:
+		return "PsaMacCompute"
+	case OpPsaMacVerify:
+		return "PsaMacVerify"
+	case OpPsaSignMessage:
+		return "PsaSignMessage"
+	case OpPsaVerifyMessage:
+		return "PsaVerifyMessage"
+	case OpListKeys:
+		return "ListKeys"
+	case OpListClients:
+		return "ListClients"
+	case OpDeleteClient:
+		return "DeleteClient"
+	default:
+		return "Unknown"
+	}
+}
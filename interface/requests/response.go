@@ -72,6 +72,100 @@ func (code StatusCode) IsValid() bool {
 	return (code >= StatusSuccess && code <= StatusAdminOperation) || (code >= StatusPsaErrorGenericError && code <= StatusPsaErrorDataCorrupt)
 }
 
+// String returns the name of the status code, as used in the parsec status code documentation.
+//
+//nolint:gocyclo
+func (code StatusCode) String() string {
+	switch code {
+	case StatusSuccess:
+		return "Success"
+	case StatusWrongProviderID:
+		return "WrongProviderID"
+	case StatusContentTypeNotSupported:
+		return "ContentTypeNotSupported"
+	case StatusAcceptTypeNotSupported:
+		return "AcceptTypeNotSupported"
+	case StatusWireProtocolVersionNotSupported:
+		return "WireProtocolVersionNotSupported"
+	case StatusProviderNotRegistered:
+		return "ProviderNotRegistered"
+	case StatusProviderDoesNotExist:
+		return "ProviderDoesNotExist"
+	case StatusDeserializingBodyFailed:
+		return "DeserializingBodyFailed"
+	case StatusSerializingBodyFailed:
+		return "SerializingBodyFailed"
+	case StatusOpcodeDoesNotExist:
+		return "OpcodeDoesNotExist"
+	case StatusResponseTooLarge:
+		return "ResponseTooLarge"
+	case StatusAuthenticationError:
+		return "AuthenticationError"
+	case StatusAuthenticatorDoesNotExist:
+		return "AuthenticatorDoesNotExist"
+	case StatusAuthenticatorNotRegistered:
+		return "AuthenticatorNotRegistered"
+	case StatusKeyInfoManagerError:
+		return "KeyInfoManagerError"
+	case StatusConnectionError:
+		return "ConnectionError"
+	case StatusInvalidEncoding:
+		return "InvalidEncoding"
+	case StatusInvalidHeader:
+		return "InvalidHeader"
+	case StatusWrongProviderUUID:
+		return "WrongProviderUUID"
+	case StatusNotAuthenticated:
+		return "NotAuthenticated"
+	case StatusBodySizeExceedsLimit:
+		return "BodySizeExceedsLimit"
+	case StatusAdminOperation:
+		return "AdminOperation"
+	case StatusPsaErrorGenericError:
+		return "PsaErrorGenericError"
+	case StatusPsaErrorNotPermitted:
+		return "PsaErrorNotPermitted"
+	case StatusPsaErrorNotSupported:
+		return "PsaErrorNotSupported"
+	case StatusPsaErrorInvalidArgument:
+		return "PsaErrorInvalidArgument"
+	case StatusPsaErrorInvalidHandle:
+		return "PsaErrorInvalidHandle"
+	case StatusPsaErrorBadState:
+		return "PsaErrorBadState"
+	case StatusPsaErrorBufferTooSmall:
+		return "PsaErrorBufferTooSmall"
+	case StatusPsaErrorAlreadyExists:
+		return "PsaErrorAlreadyExists"
+	case StatusPsaErrorDoesNotExist:
+		return "PsaErrorDoesNotExist"
+	case StatusPsaErrorInsufficientMemory:
+		return "PsaErrorInsufficientMemory"
+	case StatusPsaErrorInsufficientStorage:
+		return "PsaErrorInsufficientStorage"
+	case StatusPsaErrorInssuficientData:
+		return "PsaErrorInssuficientData"
+	case StatusPsaErrorCommunicationFailure:
+		return "PsaErrorCommunicationFailure"
+	case StatusPsaErrorStorageFailure:
+		return "PsaErrorStorageFailure"
+	case StatusPsaErrorHardwareFailure:
+		return "PsaErrorHardwareFailure"
+	case StatusPsaErrorInsufficientEntropy:
+		return "PsaErrorInsufficientEntropy"
+	case StatusPsaErrorInvalidSignature:
+		return "PsaErrorInvalidSignature"
+	case StatusPsaErrorInvalidPadding:
+		return "PsaErrorInvalidPadding"
+	case StatusPsaErrorCorruptionDetected:
+		return "PsaErrorCorruptionDetected"
+	case StatusPsaErrorDataCorrupt:
+		return "PsaErrorDataCorrupt"
+	default:
+		return "Unknown"
+	}
+}
+
 // ParseResponse returns a response if it successfully unmarshals the given byte buffer
 func ParseResponse(expectedOpCode OpCode, buf *bytes.Buffer, responseProtoBuf proto.Message) error {
 	if buf == nil {
@@ -111,96 +205,126 @@ func ParseResponse(expectedOpCode OpCode, buf *bytes.Buffer, responseProtoBuf pr
 	return wireHeader.Status.ToErr()
 }
 
-// ToErr returns nil if the response code is a success, or an appropriate error otherwise.
+// ToErr returns nil if the response code is a success, or a *StatusError otherwise.
+func (code StatusCode) ToErr() error {
+	if code == StatusSuccess {
+		return nil
+	}
+	return &StatusError{Code: code}
+}
+
+// message returns the description of the status code used as the error message.
 //
 //nolint:gocyclo
-func (code StatusCode) ToErr() error {
+func (code StatusCode) message() string {
 	switch code {
 	case StatusSuccess:
-		return nil
+		return "success"
 	case StatusWrongProviderID:
-		return errors.Errorf("wrong provider id")
+		return "wrong provider id"
 	case StatusContentTypeNotSupported:
-		return errors.Errorf("content type not supported")
+		return "content type not supported"
 	case StatusAcceptTypeNotSupported:
-		return errors.Errorf("accept type not supported")
+		return "accept type not supported"
 	case StatusWireProtocolVersionNotSupported:
-		return errors.Errorf("requested version is not supported by the backend")
+		return "requested version is not supported by the backend"
 	case StatusProviderNotRegistered:
-		return errors.Errorf("provider not registered")
+		return "provider not registered"
 	case StatusProviderDoesNotExist:
-		return errors.Errorf("provider does not exist")
+		return "provider does not exist"
 	case StatusDeserializingBodyFailed:
-		return errors.Errorf("deserializing body failed")
+		return "deserializing body failed"
 	case StatusSerializingBodyFailed:
-		return errors.Errorf("serializing body failed")
+		return "serializing body failed"
 	case StatusOpcodeDoesNotExist:
-		return errors.Errorf("opcode does not exist")
+		return "opcode does not exist"
 	case StatusResponseTooLarge:
-		return errors.Errorf("response too large")
+		return "response too large"
 	case StatusAuthenticationError:
-		return errors.Errorf("authentication error")
+		return "authentication error"
 	case StatusAuthenticatorDoesNotExist:
-		return errors.Errorf("authentication does not exist")
+		return "authentication does not exist"
 	case StatusAuthenticatorNotRegistered:
-		return errors.Errorf("authentication not registered")
+		return "authentication not registered"
 	case StatusKeyInfoManagerError:
-		return errors.Errorf("internal error in the Key Info Manager")
+		return "internal error in the Key Info Manager"
 	case StatusConnectionError:
-		return errors.Errorf("generic input/output error")
+		return "generic input/output error"
 	case StatusInvalidEncoding:
-		return errors.Errorf("invalid value for this data type")
+		return "invalid value for this data type"
 	case StatusInvalidHeader:
-		return errors.Errorf("constant fields in header are invalid")
+		return "constant fields in header are invalid"
 	case StatusWrongProviderUUID:
-		return errors.Errorf("the UUID vector needs to only contain 16 bytes")
+		return "the UUID vector needs to only contain 16 bytes"
 	case StatusNotAuthenticated:
-		return errors.Errorf("request did not provide a required authentication")
+		return "request did not provide a required authentication"
 	case StatusBodySizeExceedsLimit:
-		return errors.Errorf("request length specified in the header is above defined limit")
+		return "request length specified in the header is above defined limit"
 	case StatusAdminOperation:
-		return errors.Errorf("the operation requires admin privilege")
+		return "the operation requires admin privilege"
 
 	case StatusPsaErrorGenericError:
-		return errors.Errorf("generic error")
+		return "generic error"
 	case StatusPsaErrorNotPermitted:
-		return errors.Errorf("not permitted")
+		return "not permitted"
 	case StatusPsaErrorNotSupported:
-		return errors.Errorf("not supported")
+		return "not supported"
 	case StatusPsaErrorInvalidArgument:
-		return errors.Errorf("invalid argument")
+		return "invalid argument"
 	case StatusPsaErrorInvalidHandle:
-		return errors.Errorf("invalid handle")
+		return "invalid handle"
 	case StatusPsaErrorBadState:
-		return errors.Errorf("bad state")
+		return "bad state"
 	case StatusPsaErrorBufferTooSmall:
-		return errors.Errorf("buffer too small")
+		return "buffer too small"
 	case StatusPsaErrorAlreadyExists:
-		return errors.Errorf("already exists")
+		return "already exists"
 	case StatusPsaErrorDoesNotExist:
-		return errors.Errorf("does not exist")
+		return "does not exist"
 	case StatusPsaErrorInsufficientMemory:
-		return errors.Errorf("insufficient memory")
+		return "insufficient memory"
 	case StatusPsaErrorInsufficientStorage:
-		return errors.Errorf("insufficient storage")
+		return "insufficient storage"
 	case StatusPsaErrorInssuficientData:
-		return errors.Errorf("insufficient data")
+		return "insufficient data"
 	case StatusPsaErrorCommunicationFailure:
-		return errors.Errorf("communications failure")
+		return "communications failure"
 	case StatusPsaErrorStorageFailure:
-		return errors.Errorf("storage failure")
+		return "storage failure"
 	case StatusPsaErrorHardwareFailure:
-		return errors.Errorf("hardware failure")
+		return "hardware failure"
 	case StatusPsaErrorInsufficientEntropy:
-		return errors.Errorf("insufficient entropy")
+		return "insufficient entropy"
 	case StatusPsaErrorInvalidSignature:
-		return errors.Errorf("invalid signature")
+		return "invalid signature"
 	case StatusPsaErrorInvalidPadding:
-		return errors.Errorf("invalid padding")
+		return "invalid padding"
 	case StatusPsaErrorCorruptionDetected:
-		return errors.Errorf("tampering detected")
+		return "tampering detected"
 	case StatusPsaErrorDataCorrupt:
-		return errors.Errorf("stored data has been corrupted")
+		return "stored data has been corrupted"
+	}
+	return "unknown error code"
+}
+
+// StatusError is the error returned when the parsec service responds with a status other than StatusSuccess.
+type StatusError struct {
+	Code StatusCode
+}
+
+func (e *StatusError) Error() string {
+	return e.Code.message()
+}
+
+// StatusCodeFromError returns the response status code carried by err.  If err is nil, StatusSuccess is returned.
+// The boolean return is false if err was not caused by a response status code, e.g. a connection error.
+func StatusCodeFromError(err error) (StatusCode, bool) {
+	if err == nil {
+		return StatusSuccess, true
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code, true
 	}
-	return errors.Errorf("unknown error code")
+	return StatusSuccess, false
 }
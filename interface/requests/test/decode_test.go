@@ -0,0 +1,37 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package requests_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+)
+
+var _ = Describe("ReadMessage", func() {
+	It("Should read a message up to the end of its body", func() {
+		r := bytes.NewReader(append(append([]byte{}, expectedPingResp...), 0xff))
+		msg, err := requests.ReadMessage(r, requests.DefaultBodyLenLimit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg).To(Equal(expectedPingResp))
+		Expect(r.Len()).To(Equal(1))
+	})
+	It("Should refuse a body above the limit without reading it", func() {
+		r := bytes.NewReader(expectedPingResp)
+		msg, err := requests.ReadMessage(r, 1)
+		Expect(errors.Is(err, requests.ErrBodySizeExceedsLimit)).To(BeTrue())
+		Expect(msg).To(Equal(expectedPingResp[:requests.WireHeaderSize]))
+		Expect(r.Len()).To(Equal(2))
+	})
+	It("Should report a truncated body", func() {
+		r := bytes.NewReader(expectedPingResp[:len(expectedPingResp)-1])
+		_, err := requests.ReadMessage(r, requests.DefaultBodyLenLimit)
+		Expect(errors.Is(err, io.ErrUnexpectedEOF)).To(BeTrue())
+	})
+})
@@ -5,6 +5,7 @@ package requests_test
 
 import (
 	"bytes"
+	"fmt"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -210,4 +211,18 @@ var _ = Describe("response", func() {
 		})
 	})
 
+	Describe("Status errors", func() {
+		It("Should carry the status code", func() {
+			err := requests.StatusPsaErrorNotPermitted.ToErr()
+			Expect(err).To(MatchError("not permitted"))
+			code, ok := requests.StatusCodeFromError(fmt.Errorf("sign failed: %w", err))
+			Expect(ok).To(BeTrue())
+			Expect(code).To(Equal(requests.StatusPsaErrorNotPermitted))
+			Expect(code.String()).To(Equal("PsaErrorNotPermitted"))
+		})
+		It("Should not find a status code in other errors", func() {
+			_, ok := requests.StatusCodeFromError(fmt.Errorf("connection refused"))
+			Expect(ok).To(BeFalse())
+		})
+	})
 })
@@ -97,7 +97,7 @@ func (r *wireHeader) checkForRequest() error {
 		return fmt.Errorf("invalid accept type %v", r.acceptType)
 	}
 	if !r.authType.IsValid() {
-		return fmt.Errorf("invaliid auth type %v", r.authType)
+		return fmt.Errorf("invaliid auth type %d", r.authType)
 	}
 	return nil
 }
@@ -148,13 +148,13 @@ func parseWireHeaderFromBuf(buf *bytes.Buffer) (*wireHeader, error) {
 	r.acceptType = acceptType(buf.Next(buffBytes8Bit)[0]) // This should only be set in requests so we must not check value
 	r.authType = auth.AuthenticationType(buf.Next(buffBytes8Bit)[0])
 	if !r.authType.IsValid() {
-		return nil, fmt.Errorf("invalid auth type %v", r.authType)
+		return nil, fmt.Errorf("invalid auth type %d", r.authType)
 	}
 	r.bodyLen = binary.LittleEndian.Uint32(buf.Next(buffBytes32Bit))
 	r.authLen = binary.LittleEndian.Uint16(buf.Next(buffBytes16Bit))
 	r.opCode = OpCode(binary.LittleEndian.Uint32(buf.Next(buffBytes32Bit)))
 	if !r.opCode.IsValid() {
-		return nil, fmt.Errorf("invalid opcode %v", r.opCode)
+		return nil, fmt.Errorf("invalid opcode %d", r.opCode)
 	}
 	r.Status = StatusCode(binary.LittleEndian.Uint16(buf.Next(buffBytes16Bit)))
 	if !r.Status.IsValid() {
@@ -0,0 +1,175 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package wiredecode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+)
+
+// errorsFor returns the messages of the errors for any of fields.
+func (m *Message) errorsFor(fields ...string) []string {
+	var msgs []string
+	for _, e := range m.Errors {
+		for _, field := range fields {
+			if e.Field == field {
+				msgs = append(msgs, e.Msg)
+			}
+		}
+	}
+	return msgs
+}
+
+// WriteText writes the message for people to read, one field per line, with any problem with a field flagged
+// next to it.
+func (m *Message) WriteText(w io.Writer) error {
+	h := m.Header
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	line := func(name, value string, fields ...string) {
+		fmt.Fprintf(table, "%v:\t%v", name, value)
+		for _, msg := range m.errorsFor(fields...) {
+			fmt.Fprintf(table, "\t<- %v", msg)
+		}
+		fmt.Fprintln(table)
+	}
+	direction := m.Direction.String()
+	if m.Guessed {
+		direction += " (guessed)"
+	}
+	line("magic", fmt.Sprintf("%#08x", h.MagicNumber), "MagicNumber")
+	line("header size", fmt.Sprintf("%d", h.HeaderSize), "HeaderSize")
+	line("version", fmt.Sprintf("%d.%d", h.VersionMajor, h.VersionMinor), "VersionMajor")
+	line("flags", fmt.Sprintf("%#04x", h.Flags), "Flags")
+	line("provider", fmt.Sprintf("%d (%v)", uint8(h.Provider), h.Provider), "Provider")
+	line("session", fmt.Sprintf("%d", h.Session), "Session")
+	line("content type", fmt.Sprintf("%d", h.ContentType), "ContentType")
+	line("accept type", fmt.Sprintf("%d", h.AcceptType), "AcceptType")
+	line("auth type", fmt.Sprintf("%d (%v)", uint8(h.AuthType), h.AuthType), "AuthType")
+	line("body length", fmt.Sprintf("%d", h.BodyLen), "BodyLen")
+	line("auth length", fmt.Sprintf("%d", h.AuthLen), "AuthLen")
+	line("opcode", fmt.Sprintf("%d (%v)", uint32(h.OpCode), h.OpCode), "OpCode")
+	line("status", fmt.Sprintf("%d (%v)", uint16(h.Status), h.Status), "Status")
+	line("reserved", fmt.Sprintf("%d %d", h.Reserved1, h.Reserved2), "Reserved1", "Reserved2")
+	line("direction", direction)
+	body, err := m.bodyJSON()
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		line("body", string(body), "Body")
+	} else {
+		line("body", fmt.Sprintf("%x", m.RawBody), "Body")
+	}
+	line("auth", fmt.Sprintf("%x", m.Auth), "Auth")
+	if m.Trailing != nil {
+		line("trailing", fmt.Sprintf("%x", m.Trailing), "Trailing")
+	}
+	return table.Flush()
+}
+
+func (m *Message) bodyJSON() (json.RawMessage, error) {
+	if m.Body == nil {
+		return nil, nil
+	}
+	return operations.MarshalJSON(m.Body)
+}
+
+type jsonFieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"msg"`
+}
+
+type jsonHeader struct {
+	MagicNumber  uint32 `json:"magic_number"`
+	HeaderSize   uint16 `json:"header_size"`
+	VersionMajor uint8  `json:"version_major"`
+	VersionMinor uint8  `json:"version_minor"`
+	Flags        uint16 `json:"flags"`
+	Provider     uint8  `json:"provider"`
+	ProviderName string `json:"provider_name"`
+	Session      uint64 `json:"session"`
+	ContentType  uint8  `json:"content_type"`
+	AcceptType   uint8  `json:"accept_type"`
+	AuthType     uint8  `json:"auth_type"`
+	AuthTypeName string `json:"auth_type_name"`
+	BodyLen      uint32 `json:"body_len"`
+	AuthLen      uint16 `json:"auth_len"`
+	OpCode       uint32 `json:"opcode"`
+	OpCodeName   string `json:"opcode_name"`
+	Status       uint16 `json:"status"`
+	StatusName   string `json:"status_name"`
+	Reserved1    uint8  `json:"reserved1"`
+	Reserved2    uint8  `json:"reserved2"`
+}
+
+func newJSONHeader(h *requests.WireHeader) jsonHeader {
+	return jsonHeader{
+		MagicNumber:  h.MagicNumber,
+		HeaderSize:   h.HeaderSize,
+		VersionMajor: h.VersionMajor,
+		VersionMinor: h.VersionMinor,
+		Flags:        h.Flags,
+		Provider:     uint8(h.Provider),
+		ProviderName: h.Provider.String(),
+		Session:      h.Session,
+		ContentType:  h.ContentType,
+		AcceptType:   h.AcceptType,
+		AuthType:     uint8(h.AuthType),
+		AuthTypeName: h.AuthType.String(),
+		BodyLen:      h.BodyLen,
+		AuthLen:      h.AuthLen,
+		OpCode:       uint32(h.OpCode),
+		OpCodeName:   h.OpCode.String(),
+		Status:       uint16(h.Status),
+		StatusName:   h.Status.String(),
+		Reserved1:    h.Reserved1,
+		Reserved2:    h.Reserved2,
+	}
+}
+
+// MarshalJSON encodes the message as JSON, with the body encoded by operations.MarshalJSON and binary data in hex.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	body, err := m.bodyJSON()
+	if err != nil {
+		return nil, err
+	}
+	errs := make([]jsonFieldError, len(m.Errors))
+	for i, e := range m.Errors {
+		errs[i] = jsonFieldError{Field: e.Field, Msg: e.Msg}
+	}
+	return json.Marshal(struct {
+		Header    jsonHeader       `json:"header"`
+		Direction string           `json:"direction"`
+		Guessed   bool             `json:"guessed"`
+		BodyType  string           `json:"body_type,omitempty"`
+		Body      json.RawMessage  `json:"body,omitempty"`
+		RawBody   string           `json:"raw_body"`
+		Auth      string           `json:"auth"`
+		Trailing  string           `json:"trailing,omitempty"`
+		Errors    []jsonFieldError `json:"errors"`
+	}{
+		Header:    newJSONHeader(m.Header),
+		Direction: m.Direction.String(),
+		Guessed:   m.Guessed,
+		BodyType:  m.bodyType(),
+		Body:      body,
+		RawBody:   hex.EncodeToString(m.RawBody),
+		Auth:      hex.EncodeToString(m.Auth),
+		Trailing:  hex.EncodeToString(m.Trailing),
+		Errors:    errs,
+	})
+}
+
+func (m *Message) bodyType() string {
+	if m.Body == nil {
+		return ""
+	}
+	return string(m.Body.ProtoReflect().Descriptor().FullName())
+}
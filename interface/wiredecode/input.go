@@ -0,0 +1,55 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package wiredecode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// magicPrefix is the magic number as it appears at the start of a message.
+var magicPrefix = binary.LittleEndian.AppendUint32(nil, 0x5EC0A710)
+
+// ParseInput converts captured data to the bytes of a message.  The data may be the raw bytes, such as those
+// extracted from a packet capture, or hex or base64 text.  Hex may be split into bytes by whitespace or colons
+// and each byte may have a 0x prefix.  Data that is neither hex nor base64 is returned unchanged.
+func ParseInput(data []byte) []byte {
+	if bytes.HasPrefix(data, magicPrefix) {
+		return data
+	}
+	text := string(bytes.TrimSpace(data))
+	if decoded, ok := parseHex(text); ok {
+		return decoded
+	}
+	if decoded, ok := parseBase64(text); ok {
+		return decoded
+	}
+	return data
+}
+
+func parseHex(text string) ([]byte, bool) {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ':'
+	})
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(strings.TrimPrefix(strings.TrimPrefix(f, "0x"), "0X"))
+	}
+	decoded, err := hex.DecodeString(b.String())
+	return decoded, err == nil && len(decoded) != 0
+}
+
+func parseBase64(text string) ([]byte, bool) {
+	text = strings.Join(strings.Fields(text), "")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(text); err == nil && len(decoded) != 0 {
+			return decoded, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package wiredecode_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/interface/wiredecode"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"google.golang.org/protobuf/proto"
+)
+
+func signHashRequest() []byte {
+	req, err := requests.NewRequest(requests.OpPsaSignHash, &psasignhash.Operation{
+		KeyName: "mykey",
+		Hash:    []byte{1, 2, 3, 4},
+	}, auth.NewDirectAuthenticator("app"), requests.ProviderTPM)
+	Expect(err).NotTo(HaveOccurred())
+	buf, err := req.Pack()
+	Expect(err).NotTo(HaveOccurred())
+	return buf.Bytes()
+}
+
+func signHashResponse(status requests.StatusCode) []byte {
+	resp, err := parsectest.Respond(&parsectest.Request{OpCode: requests.OpPsaSignHash, Provider: requests.ProviderTPM},
+		func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+			return &psasignhash.Result{Signature: []byte{0xaa, 0xbb}}, status
+		})
+	Expect(err).NotTo(HaveOccurred())
+	return resp
+}
+
+func fields(m *wiredecode.Message) []string {
+	var names []string
+	for _, e := range m.Errors {
+		names = append(names, e.Field)
+	}
+	return names
+}
+
+var _ = Describe("wire decoding", func() {
+	It("Should decode a request", func() {
+		m := wiredecode.Decode(signHashRequest(), wiredecode.Auto)
+		Expect(m.Errors).To(BeEmpty())
+		Expect(m.Header.MagicNumber).To(Equal(uint32(0x5EC0A710)))
+		Expect(m.Header.HeaderSize).To(Equal(uint16(30)))
+		Expect(m.Header.Provider).To(Equal(requests.ProviderTPM))
+		Expect(m.Header.AuthType).To(Equal(auth.AuthDirect))
+		Expect(m.Header.OpCode).To(Equal(requests.OpPsaSignHash))
+		Expect(m.Direction).To(Equal(wiredecode.Request))
+		Expect(m.Guessed).To(BeTrue())
+		Expect(proto.Equal(m.Body, &psasignhash.Operation{KeyName: "mykey", Hash: []byte{1, 2, 3, 4}})).To(BeTrue())
+		Expect(m.Auth).To(Equal([]byte("app")))
+	})
+	It("Should decode a response", func() {
+		m := wiredecode.Decode(signHashResponse(requests.StatusSuccess), wiredecode.Auto)
+		Expect(m.Errors).To(BeEmpty())
+		Expect(m.Direction).To(Equal(wiredecode.Response))
+		Expect(proto.Equal(m.Body, &psasignhash.Result{Signature: []byte{0xaa, 0xbb}})).To(BeTrue())
+	})
+	It("Should treat an error status as a response", func() {
+		m := wiredecode.Decode(signHashResponse(requests.StatusPsaErrorNotPermitted), wiredecode.Auto)
+		Expect(m.Errors).To(BeEmpty())
+		Expect(m.Direction).To(Equal(wiredecode.Response))
+		Expect(m.Header.Status).To(Equal(requests.StatusPsaErrorNotPermitted))
+	})
+	It("Should use the direction given", func() {
+		m := wiredecode.Decode(signHashResponse(requests.StatusSuccess), wiredecode.Request)
+		Expect(m.Direction).To(Equal(wiredecode.Request))
+		Expect(m.Guessed).To(BeFalse())
+		Expect(fields(m)).To(Equal([]string{"Body"}))
+	})
+	It("Should flag every invalid header field", func() {
+		data := signHashRequest()
+		data[0] = 0
+		data[6] = 2    // version major
+		data[10] = 9   // provider
+		data[20] = 1   // accept type
+		data[21] = 200 // auth type
+		data[28] = 0xff
+		data[34] = 1 // reserved1
+		m := wiredecode.Decode(data, wiredecode.Auto)
+		Expect(fields(m)).To(Equal([]string{"MagicNumber", "VersionMajor", "Provider", "AcceptType", "AuthType", "OpCode",
+			"Reserved1", "Body"}))
+		Expect(m.Errors[2].String()).To(Equal("Provider: unknown provider 9"))
+		Expect(m.Header.Provider).To(Equal(requests.ProviderID(9)))
+		Expect(m.Body).To(BeNil())
+	})
+	It("Should flag missing fields of a truncated header", func() {
+		m := wiredecode.Decode(signHashRequest()[:24], wiredecode.Auto)
+		Expect(fields(m)).To(Equal([]string{"BodyLen", "AuthLen", "OpCode", "Status", "Reserved1", "Reserved2"}))
+		Expect(m.Errors[0].Msg).To(Equal("missing, data ends at byte 24"))
+		Expect(m.Header.Provider).To(Equal(requests.ProviderTPM))
+	})
+	It("Should flag a truncated body", func() {
+		data := signHashRequest()
+		m := wiredecode.Decode(data[:len(data)-8], wiredecode.Auto)
+		Expect(fields(m)).To(Equal([]string{"BodyLen", "AuthLen"}))
+		Expect(m.Body).To(BeNil())
+	})
+	It("Should flag trailing data", func() {
+		m := wiredecode.Decode(append(signHashRequest(), 0, 0), wiredecode.Auto)
+		Expect(fields(m)).To(Equal([]string{"Trailing"}))
+		Expect(m.Trailing).To(Equal([]byte{0, 0}))
+		Expect(m.Body).NotTo(BeNil())
+	})
+	It("Should write the fields as text, flagging problems", func() {
+		data := signHashRequest()
+		data[10] = 9
+		out := &bytes.Buffer{}
+		Expect(wiredecode.Decode(data, wiredecode.Auto).WriteText(out)).To(Succeed())
+		Expect(out.String()).To(MatchRegexp(`provider:\s+9 \(Unknown\)\s+<- unknown provider 9\n`))
+		Expect(out.String()).To(MatchRegexp(`opcode:\s+4 \(PsaSignHash\)\n`))
+		Expect(out.String()).To(MatchRegexp(`direction:\s+request \(guessed\)\n`))
+		Expect(out.String()).To(ContainSubstring(`{"key_name":"mykey","hash":"AQIDBA=="}`))
+	})
+	It("Should encode as JSON", func() {
+		data, err := json.Marshal(wiredecode.Decode(signHashRequest(), wiredecode.Auto))
+		Expect(err).NotTo(HaveOccurred())
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(decoded["body_type"]).To(Equal("psa_sign_hash.Operation"))
+		Expect(decoded["body"]).To(Equal(map[string]interface{}{"key_name": "mykey", "hash": "AQIDBA=="}))
+		Expect(decoded["header"]).To(HaveKeyWithValue("opcode_name", "PsaSignHash"))
+		Expect(decoded["auth"]).To(Equal(hex.EncodeToString([]byte("app"))))
+	})
+})
+
+var _ = Describe("input parsing", func() {
+	data := signHashRequest()
+	hexString := hex.EncodeToString(data)
+	var colonSeparated []string
+	for i := 0; i < len(hexString); i += 2 {
+		colonSeparated = append(colonSeparated, hexString[i:i+2])
+	}
+	It("Should accept raw bytes", func() {
+		Expect(wiredecode.ParseInput(data)).To(Equal(data))
+	})
+	It("Should accept hex", func() {
+		Expect(wiredecode.ParseInput([]byte(hexString + "\n"))).To(Equal(data))
+		Expect(wiredecode.ParseInput([]byte(strings.Join(colonSeparated, ":")))).To(Equal(data))
+		Expect(wiredecode.ParseInput([]byte("0x" + strings.Join(colonSeparated, " 0x")))).To(Equal(data))
+	})
+	It("Should accept base64", func() {
+		Expect(wiredecode.ParseInput([]byte(base64.StdEncoding.EncodeToString(data)))).To(Equal(data))
+		Expect(wiredecode.ParseInput([]byte(base64.RawURLEncoding.EncodeToString(data)))).To(Equal(data))
+	})
+	It("Should return other data unchanged", func() {
+		Expect(wiredecode.ParseInput([]byte("not a message!"))).To(Equal([]byte("not a message!")))
+	})
+})
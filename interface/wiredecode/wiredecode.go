@@ -0,0 +1,180 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package wiredecode decodes captured parsec wire protocol messages for debugging.  The header fields are decoded
+// and checked individually, and the body is decoded as the operation or result message for the opcode.
+package wiredecode
+
+import (
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Direction is whether a message is a request to the parsec service or a response from it.  The header does not
+// say, but it decides the type of the body.
+type Direction int
+
+const (
+	// Auto guesses the direction from the header and body.
+	Auto Direction = iota
+	Request
+	Response
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Auto:
+		return "auto"
+	case Request:
+		return "request"
+	case Response:
+		return "response"
+	default:
+		return "unknown"
+	}
+}
+
+// Message is a decoded wire protocol message.
+type Message struct {
+	Header *requests.WireHeader
+	// Direction is the direction the message was decoded as.  Guessed is true if it was not given to Decode.
+	Direction Direction
+	Guessed   bool
+	// RawBody is the body as it was read, which may be shorter than Header.BodyLen if the data was truncated.
+	RawBody []byte
+	// Body is the decoded body, or nil if it could not be decoded.
+	Body proto.Message
+	Auth []byte
+	// Trailing holds any data following the message.
+	Trailing []byte
+	// Errors lists the problems found, with the header field they relate to.  Problems with the body, auth and
+	// trailing data use the fields Body, Auth and Trailing.
+	Errors []requests.FieldError
+}
+
+func (m *Message) addError(field, format string, args ...interface{}) {
+	m.Errors = append(m.Errors, requests.FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Decode decodes the message at the start of data.  It never fails: any problems found are recorded in the
+// message's Errors and the rest of the message is decoded as far as possible.
+func Decode(data []byte, dir Direction) *Message {
+	m := &Message{Direction: dir}
+	var errs []requests.FieldError
+	m.Header, errs = requests.DecodeWireHeader(data)
+	m.Errors = append(m.Errors, errs...)
+	if len(data) < int(requests.WireHeaderSize) {
+		// The missing header fields have been reported, and there is nothing more to decode
+		m.guessDirection(nil)
+		return m
+	}
+	rest := data[requests.WireHeaderSize:]
+
+	bodyLen := int(m.Header.BodyLen)
+	if bodyLen > len(rest) {
+		m.addError("BodyLen", "is %d, but only %d bytes follow the header", bodyLen, len(rest))
+		bodyLen = len(rest)
+	}
+	m.RawBody, rest = rest[:bodyLen], rest[bodyLen:]
+	authLen := int(m.Header.AuthLen)
+	if authLen > len(rest) {
+		m.addError("AuthLen", "is %d, but only %d bytes follow the body", authLen, len(rest))
+		authLen = len(rest)
+	}
+	m.Auth, rest = rest[:authLen], rest[authLen:]
+	if len(rest) > 0 {
+		m.Trailing = rest
+		m.addError("Trailing", "%d bytes follow the message", len(rest))
+	}
+
+	if bodyLen < int(m.Header.BodyLen) {
+		m.guessDirection(nil)
+		return m
+	}
+	m.decodeBody()
+	return m
+}
+
+// decodeBody decodes the body as the operation or result message for the opcode, depending on the direction.
+func (m *Message) decodeBody() {
+	if operations.NewOperationMessage(m.Header.OpCode) == nil {
+		m.addError("Body", "not decoded as the opcode is unknown")
+		m.guessDirection(nil)
+		return
+	}
+	operation, operationErr := unmarshal(m.RawBody, operations.NewOperationMessage(m.Header.OpCode))
+	result, resultErr := unmarshal(m.RawBody, operations.NewResultMessage(m.Header.OpCode))
+	m.guessDirection(func() bool {
+		// Prefer whichever message the body fits, or the request if both fit equally well
+		return operationErr != nil && resultErr == nil
+	})
+	body, err := operation, operationErr
+	if m.Direction == Response {
+		body, err = result, resultErr
+	}
+	if err != nil {
+		m.addError("Body", "%v", err)
+	}
+	m.Body = body
+}
+
+// guessDirection sets the direction if it was not given.  Only responses have an error status and only requests
+// carry authentication.  When neither settles it, isResponse, if given, checks which message the body fits.
+func (m *Message) guessDirection(isResponse func() bool) {
+	if m.Direction != Auto {
+		return
+	}
+	m.Guessed = true
+	switch {
+	case m.Header.Status != requests.StatusSuccess:
+		m.Direction = Response
+	case m.Header.AuthLen != 0:
+		m.Direction = Request
+	case isResponse != nil && isResponse():
+		m.Direction = Response
+	default:
+		m.Direction = Request
+	}
+}
+
+// unmarshal decodes data into msg, returning an error if it is not valid protobuf or has fields msg does not
+// define.  The message is returned as far as it was decoded, or nil if nothing could be decoded.
+func unmarshal(data []byte, msg proto.Message) (proto.Message, error) {
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("not a valid %v: %w", msg.ProtoReflect().Descriptor().FullName(), err)
+	}
+	if hasUnknownFields(msg.ProtoReflect()) {
+		return msg, fmt.Errorf("has fields not defined by %v", msg.ProtoReflect().Descriptor().FullName())
+	}
+	if err := operations.ValidateEnums(msg); err != nil {
+		return msg, err
+	}
+	return msg, nil
+}
+
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) != 0 {
+		return true
+	}
+	unknown := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			for i := 0; i < v.List().Len() && !unknown; i++ {
+				unknown = hasUnknownFields(v.List().Get(i).Message())
+			}
+		case fd.IsMap():
+		default:
+			unknown = hasUnknownFields(v.Message())
+		}
+		return !unknown
+	})
+	return unknown
+}
@@ -0,0 +1,606 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsectest
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listkeys"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeaddecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeadencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaalgorithm"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psacipherdecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psacipherencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psadestroykey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaexportpublickey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneraterandom"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psahashcompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaimportkey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psamaccompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psamacverify"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignmessage"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifyhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifymessage"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm/gocrypto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Decode returns a handler that decodes the request body into a new operation of type T and passes it to h, so
+// that h only handles well formed requests.
+func Decode[T proto.Message](h func(provider requests.ProviderID, op T) (proto.Message, requests.StatusCode)) Handler {
+	return func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+		var zero T
+		op, _ := zero.ProtoReflect().New().Interface().(T)
+		if err := proto.Unmarshal(body, op); err != nil {
+			return nil, requests.StatusDeserializingBodyFailed
+		}
+		return h(provider, op)
+	}
+}
+
+// rsaKeys caches the RSA key pairs made by providers by size, as generating them is slow.
+var rsaKeys = struct {
+	mu   sync.Mutex
+	keys map[uint32]*rsa.PrivateKey
+}{keys: map[uint32]*rsa.PrivateKey{}}
+
+func rsaKey(bits uint32) (*rsa.PrivateKey, error) {
+	rsaKeys.mu.Lock()
+	defer rsaKeys.mu.Unlock()
+	if k, ok := rsaKeys.keys[bits]; ok {
+		return k, nil
+	}
+	k, err := rsa.GenerateKey(rand.Reader, int(bits))
+	if err != nil {
+		return nil, err
+	}
+	rsaKeys.keys[bits] = k
+	return k, nil
+}
+
+type keyID struct {
+	provider requests.ProviderID
+	name     string
+}
+
+type providerKey struct {
+	attributes *psakeyattributes.KeyAttributes
+	// secret is the key of symmetric keys
+	secret []byte
+	// private is set for key pairs, and public for key pairs and public keys
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// Provider is a fake of the keys and cryptographic operations of parsec providers, implemented with Go's crypto
+// library.  Keys are held separately for each provider, and are used only as their policy permits.
+//
+// It supports RSA keys with PKCS#1 v1.5 and PSS signatures, ECDSA keys on the NIST curves, HMAC, and AES keys with
+// CTR, CBC without padding and GCM.  Other algorithms are answered with requests.StatusPsaErrorNotSupported.  RSA
+// key pairs of the same size are the same key, as generating them is slow.
+type Provider struct {
+	// PSSSaltLength is the salt length in bytes of the RSA-PSS signatures made.  If zero, the salt is the length of
+	// the hash, as PSA requires.
+	PSSSaltLength int
+
+	mu   sync.Mutex
+	keys map[keyID]*providerKey
+}
+
+// NewProvider returns a Provider without keys.
+func NewProvider() *Provider {
+	return &Provider{keys: map[keyID]*providerKey{}}
+}
+
+// KeyNames returns the names of the keys in every provider, sorted.
+func (p *Provider) KeyNames() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	names := make([]string, 0, len(p.keys))
+	for id := range p.keys {
+		names = append(names, id.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *Provider) add(provider requests.ProviderID, name string, k *providerKey) requests.StatusCode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := keyID{provider, name}
+	if _, ok := p.keys[id]; ok {
+		return requests.StatusPsaErrorAlreadyExists
+	}
+	p.keys[id] = k
+	return requests.StatusSuccess
+}
+
+// use returns the key called name in provider if its usage flags permit the use.
+func (p *Provider) use(provider requests.ProviderID, name string, permits func(*psakeyattributes.UsageFlags) bool) (*providerKey, requests.StatusCode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	k, ok := p.keys[keyID{provider, name}]
+	if !ok {
+		return nil, requests.StatusPsaErrorDoesNotExist
+	}
+	if !permits(k.attributes.GetKeyPolicy().GetKeyUsageFlags()) {
+		return nil, requests.StatusPsaErrorNotPermitted
+	}
+	return k, requests.StatusSuccess
+}
+
+// Service returns a Service answering the key management and cryptographic operations with the provider.  It lists
+// the opcodes of those operations, and further handlers can be registered to replace them.
+func (p *Provider) Service() *Service {
+	s := NewService()
+	handlers := map[requests.OpCode]Handler{
+		requests.OpListKeys:           p.listKeys,
+		requests.OpPsaGenerateKey:     Decode(p.generateKey),
+		requests.OpPsaImportKey:       Decode(p.importKey),
+		requests.OpPsaDestroyKey:      Decode(p.destroyKey),
+		requests.OpPsaExportPublicKey: Decode(p.exportPublicKey),
+		requests.OpPsaGenerateRandom:  Decode(generateRandom),
+		requests.OpPsaHashCompute:     Decode(hashCompute),
+		requests.OpPsaSignHash:        Decode(p.signHash),
+		requests.OpPsaSignMessage:     Decode(p.signMessage),
+		requests.OpPsaVerifyHash:      Decode(p.verifyHash),
+		requests.OpPsaVerifyMessage:   Decode(p.verifyMessage),
+		requests.OpPsaMacCompute:      Decode(p.macCompute),
+		requests.OpPsaMacVerify:       Decode(p.macVerify),
+		requests.OpPsaCipherEncrypt:   Decode(p.cipherEncrypt),
+		requests.OpPsaCipherDecrypt:   Decode(p.cipherDecrypt),
+		requests.OpPsaAeadEncrypt:     Decode(p.aeadEncrypt),
+		requests.OpPsaAeadDecrypt:     Decode(p.aeadDecrypt),
+	}
+	opcodes := make([]uint32, 0, len(handlers))
+	for op, h := range handlers {
+		s.Handle(op, h)
+		opcodes = append(opcodes, uint32(op))
+	}
+	sort.Slice(opcodes, func(i, j int) bool { return opcodes[i] < opcodes[j] })
+	s.Handle(requests.OpListOpcodes, func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+		return &listopcodes.Result{Opcodes: opcodes}, requests.StatusSuccess
+	})
+	return s
+}
+
+func (p *Provider) listKeys(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := &listkeys.Result{}
+	for id, k := range p.keys {
+		result.Keys = append(result.Keys, &listkeys.KeyInfo{ProviderId: uint32(id.provider), Name: id.name, Attributes: k.attributes})
+	}
+	sort.Slice(result.Keys, func(i, j int) bool { return result.Keys[i].Name < result.Keys[j].Name })
+	return result, requests.StatusSuccess
+}
+
+func curve(bits uint32) elliptic.Curve {
+	switch bits {
+	case 224:
+		return elliptic.P224()
+	case 256:
+		return elliptic.P256()
+	case 384:
+		return elliptic.P384()
+	case 521:
+		return elliptic.P521()
+	}
+	return nil
+}
+
+func (p *Provider) generateKey(provider requests.ProviderID, op *psageneratekey.Operation) (proto.Message, requests.StatusCode) {
+	attributes := op.Attributes
+	k := &providerKey{attributes: attributes}
+	switch {
+	case attributes.GetKeyType().GetRsaKeyPair() != nil:
+		priv, err := rsaKey(attributes.KeyBits)
+		if err != nil {
+			return nil, requests.StatusPsaErrorInvalidArgument
+		}
+		k.private, k.public = priv, priv.Public()
+	case attributes.GetKeyType().GetEccKeyPair() != nil:
+		c := curve(attributes.KeyBits)
+		if c == nil {
+			return nil, requests.StatusPsaErrorNotSupported
+		}
+		priv, err := ecdsa.GenerateKey(c, rand.Reader)
+		if err != nil {
+			return nil, requests.StatusPsaErrorGenericError
+		}
+		k.private, k.public = priv, priv.Public()
+	case attributes.GetKeyBits()%8 == 0 && attributes.GetKeyBits() > 0:
+		k.secret = make([]byte, attributes.KeyBits/8)
+		if _, err := rand.Read(k.secret); err != nil {
+			return nil, requests.StatusPsaErrorGenericError
+		}
+	default:
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	return &psageneratekey.Result{}, p.add(provider, op.KeyName, k)
+}
+
+func (p *Provider) importKey(provider requests.ProviderID, op *psaimportkey.Operation) (proto.Message, requests.StatusCode) {
+	attributes := op.Attributes
+	k := &providerKey{attributes: attributes}
+	keyType := attributes.GetKeyType()
+	switch {
+	case keyType.GetRsaKeyPair() != nil:
+		priv, err := x509.ParsePKCS1PrivateKey(op.Data)
+		if err != nil {
+			return nil, requests.StatusPsaErrorInvalidArgument
+		}
+		k.private, k.public = priv, priv.Public()
+	case keyType.GetRsaPublicKey() != nil:
+		pub, err := x509.ParsePKCS1PublicKey(op.Data)
+		if err != nil {
+			return nil, requests.StatusPsaErrorInvalidArgument
+		}
+		k.public = pub
+	case keyType.GetEccKeyPair() != nil:
+		c := curve(attributes.KeyBits)
+		if c == nil {
+			return nil, requests.StatusPsaErrorNotSupported
+		}
+		priv := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(op.Data)}
+		priv.Curve = c
+		priv.X, priv.Y = c.ScalarBaseMult(op.Data) //nolint:staticcheck // no other way to build the key in Go 1.21
+		k.private, k.public = priv, priv.Public()
+	case keyType.GetEccPublicKey() != nil:
+		c := curve(attributes.KeyBits)
+		if c == nil {
+			return nil, requests.StatusPsaErrorNotSupported
+		}
+		x, y := elliptic.Unmarshal(c, op.Data) //nolint:staticcheck // no other way to build the key in Go 1.21
+		if x == nil {
+			return nil, requests.StatusPsaErrorInvalidArgument
+		}
+		k.public = &ecdsa.PublicKey{Curve: c, X: x, Y: y}
+	default:
+		k.secret = op.Data
+	}
+	return &psaimportkey.Result{}, p.add(provider, op.KeyName, k)
+}
+
+func (p *Provider) destroyKey(provider requests.ProviderID, op *psadestroykey.Operation) (proto.Message, requests.StatusCode) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := keyID{provider, op.KeyName}
+	if _, ok := p.keys[id]; !ok {
+		return nil, requests.StatusPsaErrorDoesNotExist
+	}
+	delete(p.keys, id)
+	return &psadestroykey.Result{}, requests.StatusSuccess
+}
+
+func (p *Provider) exportPublicKey(provider requests.ProviderID, op *psaexportpublickey.Operation) (proto.Message, requests.StatusCode) {
+	k, status := p.use(provider, op.KeyName, func(*psakeyattributes.UsageFlags) bool { return true })
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return &psaexportpublickey.Result{Data: x509.MarshalPKCS1PublicKey(pub)}, requests.StatusSuccess
+	case *ecdsa.PublicKey:
+		return &psaexportpublickey.Result{Data: elliptic.Marshal(pub.Curve, pub.X, pub.Y)}, requests.StatusSuccess //nolint:staticcheck
+	}
+	return nil, requests.StatusPsaErrorInvalidArgument
+}
+
+func generateRandom(_ requests.ProviderID, op *psageneraterandom.Operation) (proto.Message, requests.StatusCode) {
+	random := make([]byte, op.Size)
+	if _, err := rand.Read(random); err != nil {
+		return nil, requests.StatusPsaErrorGenericError
+	}
+	return &psageneraterandom.Result{RandomBytes: random}, requests.StatusSuccess
+}
+
+func hashFor(alg psaalgorithm.Algorithm_Hash) crypto.Hash {
+	h, err := gocrypto.ToCryptoHash(algorithm.HashAlgorithmType(alg))
+	if err != nil || !h.Available() {
+		return 0
+	}
+	return h
+}
+
+func hashCompute(_ requests.ProviderID, op *psahashcompute.Operation) (proto.Message, requests.StatusCode) {
+	h := hashFor(op.Alg)
+	if h == 0 {
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	digest := h.New()
+	digest.Write(op.Input)
+	return &psahashcompute.Result{Hash: digest.Sum(nil)}, requests.StatusSuccess
+}
+
+// signHash returns the hash of a signature algorithm, or 0 if it permits any hash or signs a hash of any algorithm.
+func signHash(alg *psaalgorithm.Algorithm_AsymmetricSignature) crypto.Hash {
+	var signHash *psaalgorithm.Algorithm_AsymmetricSignature_SignHash
+	switch {
+	case alg.GetRsaPkcs1V15Sign() != nil:
+		signHash = alg.GetRsaPkcs1V15Sign().GetHashAlg()
+	case alg.GetRsaPss() != nil:
+		signHash = alg.GetRsaPss().GetHashAlg()
+	case alg.GetEcdsa() != nil:
+		signHash = alg.GetEcdsa().GetHashAlg()
+	case alg.GetDeterministicEcdsa() != nil:
+		signHash = alg.GetDeterministicEcdsa().GetHashAlg()
+	}
+	if signHash == nil || signHash.GetAny() != nil {
+		return 0
+	}
+	return hashFor(signHash.GetSpecific())
+}
+
+// hashForSize returns the hash whose hashes are size bytes, for signature algorithms permitting any hash.
+func hashForSize(size int) crypto.Hash {
+	for _, h := range []crypto.Hash{crypto.SHA1, crypto.SHA224, crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		if h.Size() == size {
+			return h
+		}
+	}
+	return 0
+}
+
+// sign signs hash with the algorithm.  ECDSA signatures are the concatenated r and s, as made by parsec.
+func (p *Provider) sign(k *providerKey, alg *psaalgorithm.Algorithm_AsymmetricSignature, hash []byte) ([]byte, requests.StatusCode) {
+	h := signHash(alg)
+	if h == 0 {
+		h = hashForSize(len(hash))
+	}
+	switch priv := k.private.(type) {
+	case *rsa.PrivateKey:
+		var sig []byte
+		var err error
+		switch {
+		case alg.GetRsaPss() != nil:
+			saltLength := p.PSSSaltLength
+			if saltLength == 0 {
+				saltLength = rsa.PSSSaltLengthEqualsHash
+			}
+			sig, err = rsa.SignPSS(rand.Reader, priv, h, hash, &rsa.PSSOptions{SaltLength: saltLength})
+		case alg.GetRsaPkcs1V15Sign() != nil:
+			sig, err = rsa.SignPKCS1v15(rand.Reader, priv, h, hash)
+		default:
+			return nil, requests.StatusPsaErrorNotPermitted
+		}
+		if err != nil {
+			return nil, requests.StatusPsaErrorInvalidArgument
+		}
+		return sig, requests.StatusSuccess
+	case *ecdsa.PrivateKey:
+		if alg.GetEcdsa() == nil && alg.GetEcdsaAny() == nil && alg.GetDeterministicEcdsa() == nil {
+			return nil, requests.StatusPsaErrorNotPermitted
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, hash)
+		if err != nil {
+			return nil, requests.StatusPsaErrorGenericError
+		}
+		size := (priv.Curve.Params().BitSize + 7) / 8
+		return append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...), requests.StatusSuccess
+	}
+	return nil, requests.StatusPsaErrorNotPermitted
+}
+
+func (p *Provider) signHash(provider requests.ProviderID, op *psasignhash.Operation) (proto.Message, requests.StatusCode) {
+	k, status := p.use(provider, op.KeyName, (*psakeyattributes.UsageFlags).GetSignHash)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	sig, status := p.sign(k, op.Alg, op.Hash)
+	return &psasignhash.Result{Signature: sig}, status
+}
+
+func (p *Provider) signMessage(provider requests.ProviderID, op *psasignmessage.Operation) (proto.Message, requests.StatusCode) {
+	k, status := p.use(provider, op.KeyName, (*psakeyattributes.UsageFlags).GetSignMessage)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	h := signHash(op.Alg)
+	if h == 0 {
+		return nil, requests.StatusPsaErrorInvalidArgument
+	}
+	digest := h.New()
+	digest.Write(op.Message)
+	sig, status := p.sign(k, op.Alg, digest.Sum(nil))
+	return &psasignmessage.Result{Signature: sig}, status
+}
+
+// verify verifies the signature of hash with the algorithm.
+func verify(k *providerKey, alg *psaalgorithm.Algorithm_AsymmetricSignature, hash, signature []byte) requests.StatusCode {
+	h := signHash(alg)
+	if h == 0 {
+		h = hashForSize(len(hash))
+	}
+	valid := false
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		if alg.GetRsaPss() != nil {
+			valid = rsa.VerifyPSS(pub, h, hash, signature, nil) == nil
+		} else {
+			valid = rsa.VerifyPKCS1v15(pub, h, hash, signature) == nil
+		}
+	case *ecdsa.PublicKey:
+		n := len(signature) / 2
+		valid = ecdsa.Verify(pub, hash, new(big.Int).SetBytes(signature[:n]), new(big.Int).SetBytes(signature[n:]))
+	}
+	if !valid {
+		return requests.StatusPsaErrorInvalidSignature
+	}
+	return requests.StatusSuccess
+}
+
+func (p *Provider) verifyHash(provider requests.ProviderID, op *psaverifyhash.Operation) (proto.Message, requests.StatusCode) {
+	k, status := p.use(provider, op.KeyName, (*psakeyattributes.UsageFlags).GetVerifyHash)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	return &psaverifyhash.Result{}, verify(k, op.Alg, op.Hash, op.Signature)
+}
+
+func (p *Provider) verifyMessage(provider requests.ProviderID, op *psaverifymessage.Operation) (proto.Message, requests.StatusCode) {
+	k, status := p.use(provider, op.KeyName, (*psakeyattributes.UsageFlags).GetVerifyMessage)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	h := signHash(op.Alg)
+	if h == 0 {
+		return nil, requests.StatusPsaErrorInvalidArgument
+	}
+	digest := h.New()
+	digest.Write(op.Message)
+	return &psaverifymessage.Result{}, verify(k, op.Alg, digest.Sum(nil), op.Signature)
+}
+
+func (p *Provider) mac(provider requests.ProviderID, name string, alg *psaalgorithm.Algorithm_Mac, permits func(*psakeyattributes.UsageFlags) bool, input []byte) ([]byte, requests.StatusCode) {
+	k, status := p.use(provider, name, permits)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	hmacAlg := alg.GetFullLength().GetHmac()
+	if hmacAlg == nil || k.secret == nil {
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	h := hashFor(hmacAlg.HashAlg)
+	if h == 0 {
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	m := hmac.New(h.New, k.secret)
+	m.Write(input)
+	return m.Sum(nil), requests.StatusSuccess
+}
+
+func (p *Provider) macCompute(provider requests.ProviderID, op *psamaccompute.Operation) (proto.Message, requests.StatusCode) {
+	mac, status := p.mac(provider, op.KeyName, op.Alg, (*psakeyattributes.UsageFlags).GetSignMessage, op.Input)
+	return &psamaccompute.Result{Mac: mac}, status
+}
+
+func (p *Provider) macVerify(provider requests.ProviderID, op *psamacverify.Operation) (proto.Message, requests.StatusCode) {
+	mac, status := p.mac(provider, op.KeyName, op.Alg, (*psakeyattributes.UsageFlags).GetVerifyMessage, op.Input)
+	if status == requests.StatusSuccess && !hmac.Equal(mac, op.Mac) {
+		status = requests.StatusPsaErrorInvalidSignature
+	}
+	return &psamacverify.Result{}, status
+}
+
+// block returns the AES block cipher of the key called name in provider, if its usage flags permit the use.
+func (p *Provider) block(provider requests.ProviderID, name string, permits func(*psakeyattributes.UsageFlags) bool) (cipher.Block, requests.StatusCode) {
+	k, status := p.use(provider, name, permits)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	if k.attributes.GetKeyType().GetAes() == nil {
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	block, err := aes.NewCipher(k.secret)
+	if err != nil {
+		return nil, requests.StatusPsaErrorInvalidArgument
+	}
+	return block, requests.StatusSuccess
+}
+
+func (p *Provider) cipherEncrypt(provider requests.ProviderID, op *psacipherencrypt.Operation) (proto.Message, requests.StatusCode) {
+	block, status := p.block(provider, op.KeyName, (*psakeyattributes.UsageFlags).GetEncrypt)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	iv := make([]byte, block.BlockSize(), block.BlockSize()+len(op.Plaintext))
+	if _, err := rand.Read(iv); err != nil {
+		return nil, requests.StatusPsaErrorGenericError
+	}
+	out := make([]byte, len(op.Plaintext))
+	switch op.Alg {
+	case psaalgorithm.Algorithm_CTR:
+		cipher.NewCTR(block, iv).XORKeyStream(out, op.Plaintext)
+	case psaalgorithm.Algorithm_CBC_NO_PADDING:
+		if len(op.Plaintext)%block.BlockSize() != 0 {
+			return nil, requests.StatusPsaErrorInvalidArgument
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, op.Plaintext)
+	default:
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	return &psacipherencrypt.Result{Ciphertext: append(iv, out...)}, requests.StatusSuccess
+}
+
+func (p *Provider) cipherDecrypt(provider requests.ProviderID, op *psacipherdecrypt.Operation) (proto.Message, requests.StatusCode) {
+	block, status := p.block(provider, op.KeyName, (*psakeyattributes.UsageFlags).GetDecrypt)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	if len(op.Ciphertext) < block.BlockSize() {
+		return nil, requests.StatusPsaErrorInvalidArgument
+	}
+	iv, in := op.Ciphertext[:block.BlockSize()], op.Ciphertext[block.BlockSize():]
+	out := make([]byte, len(in))
+	switch op.Alg {
+	case psaalgorithm.Algorithm_CTR:
+		cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	case psaalgorithm.Algorithm_CBC_NO_PADDING:
+		if len(in)%block.BlockSize() != 0 {
+			return nil, requests.StatusPsaErrorInvalidArgument
+		}
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, in)
+	default:
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	return &psacipherdecrypt.Result{Plaintext: out}, requests.StatusSuccess
+}
+
+// gcm returns AES-GCM with the key called name in provider, if its usage flags permit the use and the nonce is the
+// right size.
+func (p *Provider) gcm(provider requests.ProviderID, name string, alg *psaalgorithm.Algorithm_Aead, permits func(*psakeyattributes.UsageFlags) bool, nonce []byte) (cipher.AEAD, requests.StatusCode) {
+	if alg.GetAeadWithDefaultLengthTag() != psaalgorithm.Algorithm_Aead_GCM {
+		return nil, requests.StatusPsaErrorNotSupported
+	}
+	block, status := p.block(provider, name, permits)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, requests.StatusPsaErrorInvalidArgument
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, requests.StatusPsaErrorInvalidArgument
+	}
+	return gcm, requests.StatusSuccess
+}
+
+func (p *Provider) aeadEncrypt(provider requests.ProviderID, op *psaaeadencrypt.Operation) (proto.Message, requests.StatusCode) {
+	gcm, status := p.gcm(provider, op.KeyName, op.Alg, (*psakeyattributes.UsageFlags).GetEncrypt, op.Nonce)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	return &psaaeadencrypt.Result{Ciphertext: gcm.Seal(nil, op.Nonce, op.Plaintext, op.AdditionalData)}, requests.StatusSuccess
+}
+
+func (p *Provider) aeadDecrypt(provider requests.ProviderID, op *psaaeaddecrypt.Operation) (proto.Message, requests.StatusCode) {
+	gcm, status := p.gcm(provider, op.KeyName, op.Alg, (*psakeyattributes.UsageFlags).GetDecrypt, op.Nonce)
+	if status != requests.StatusSuccess {
+		return nil, status
+	}
+	plaintext, err := gcm.Open(nil, op.Nonce, op.Ciphertext, op.AdditionalData)
+	if err != nil {
+		return nil, requests.StatusPsaErrorInvalidSignature
+	}
+	return &psaaeaddecrypt.Result{Plaintext: plaintext}, requests.StatusSuccess
+}
@@ -0,0 +1,253 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package parsectest provides a fake parsec service for the tests in this repository.  It is internal so that it is
+// not part of the client's API.  The fake implements connection.Connection, so it can be given to a client using
+// ClientConfig.Connection, and answers each request using a handler registered for its opcode.  It can also serve a
+// unix socket, for testing code that connects to the parsec service itself.  Provider supplies handlers that keep
+// keys and perform operations with Go's crypto library, for tests needing a working provider rather than canned
+// answers.
+package parsectest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/interface/connection"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	wireMagic        = 0x5EC0A710
+	wireHeaderLength = 30
+)
+
+// Handler answers the request body for a single opcode, returning the result message and response status.
+// The result is ignored unless the status is requests.StatusSuccess.
+type Handler func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode)
+
+// Request records a request received by the Service.
+type Request struct {
+	OpCode   requests.OpCode
+	Provider requests.ProviderID
+	AuthType auth.AuthenticationType
+	Auth     []byte
+	Body     []byte
+}
+
+// Service is a fake parsec service.  Requests for opcodes without a handler are answered with
+// requests.StatusOpcodeDoesNotExist, as the parsec service would.  It is safe for concurrent use.
+type Service struct {
+	mu       sync.Mutex
+	handlers map[requests.OpCode]Handler
+	received []Request
+	response *bytes.Reader
+}
+
+// NewService returns a Service with no handlers.
+func NewService() *Service {
+	return &Service{
+		handlers: make(map[requests.OpCode]Handler),
+		response: bytes.NewReader(nil),
+	}
+}
+
+// Handle registers h to answer requests for op, returning the service so calls can be chained.
+func (s *Service) Handle(op requests.OpCode, h Handler) *Service {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[op] = h
+	return s
+}
+
+// Handler returns the handler registered for op, or nil if there is none, so that a test can wrap it.
+func (s *Service) Handler(op requests.OpCode) Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handlers[op]
+}
+
+// Received returns the requests received so far.
+func (s *Service) Received() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request{}, s.received...)
+}
+
+// Count returns the number of requests received for op.
+func (s *Service) Count(op requests.OpCode) int {
+	n := 0
+	for _, r := range s.Received() {
+		if r.OpCode == op {
+			n++
+		}
+	}
+	return n
+}
+
+// Open implements connection.Connection.
+func (s *Service) Open() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.response = bytes.NewReader(nil)
+	return nil
+}
+
+// Read returns the response to the last request written.
+func (s *Service) Read(p []byte) (n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.response.Read(p)
+}
+
+// Write accepts a complete request, which is answered by the handler for its opcode.
+func (s *Service) Write(p []byte) (n int, err error) {
+	resp, err := s.answer(p)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	s.response = bytes.NewReader(resp)
+	s.mu.Unlock()
+	return len(p), nil
+}
+
+// answer records the request p and returns the packed response.
+func (s *Service) answer(p []byte) ([]byte, error) {
+	req, err := parseRequest(p)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.received = append(s.received, *req)
+	h := s.handlers[req.OpCode]
+	s.mu.Unlock()
+	return Respond(req, h)
+}
+
+// Serve answers requests on the connections accepted from l until l is closed, so that the Service can stand in
+// for the parsec service socket.  As with the parsec service, each connection carries a single request, and is
+// closed once the response has been written.
+func (s *Service) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Service) serveConn(conn net.Conn) {
+	defer conn.Close()
+	p, err := requests.ReadMessage(conn, requests.DefaultBodyLenLimit)
+	if err != nil {
+		return
+	}
+	resp, err := s.answer(p)
+	if err != nil {
+		return
+	}
+	_, _ = conn.Write(resp)
+}
+
+// Close implements connection.Connection.
+func (s *Service) Close() error {
+	return nil
+}
+
+// Clone implements connection.Cloner, so that a client using the Service sends operations concurrently, as it
+// would to the parsec service.
+func (s *Service) Clone() connection.Connection {
+	return &serviceConn{service: s, response: bytes.NewReader(nil)}
+}
+
+// serviceConn is a connection to a Service carrying a single operation.
+type serviceConn struct {
+	service  *Service
+	response *bytes.Reader
+}
+
+func (c *serviceConn) Open() error {
+	c.response = bytes.NewReader(nil)
+	return nil
+}
+
+func (c *serviceConn) Read(p []byte) (n int, err error) {
+	return c.response.Read(p)
+}
+
+func (c *serviceConn) Write(p []byte) (n int, err error) {
+	resp, err := c.service.answer(p)
+	if err != nil {
+		return 0, err
+	}
+	c.response = bytes.NewReader(resp)
+	return len(p), nil
+}
+
+func (c *serviceConn) Close() error {
+	return nil
+}
+
+func parseRequest(p []byte) (*Request, error) {
+	const headerSize = int(requests.WireHeaderSize)
+	if len(p) < headerSize {
+		return nil, fmt.Errorf("request too short: %d bytes", len(p))
+	}
+	if magic := binary.LittleEndian.Uint32(p[0:4]); magic != wireMagic {
+		return nil, fmt.Errorf("invalid magic number %#x", magic)
+	}
+	bodyLen := int(binary.LittleEndian.Uint32(p[22:26]))
+	authLen := int(binary.LittleEndian.Uint16(p[26:28]))
+	if len(p) < headerSize+bodyLen+authLen {
+		return nil, fmt.Errorf("request truncated: %d bytes, expected %d", len(p), headerSize+bodyLen+authLen)
+	}
+	body := p[headerSize : headerSize+bodyLen]
+	return &Request{
+		OpCode:   requests.OpCode(binary.LittleEndian.Uint32(p[28:32])),
+		Provider: requests.ProviderID(p[10]),
+		AuthType: auth.AuthenticationType(p[21]),
+		Auth:     append([]byte{}, p[headerSize+bodyLen:headerSize+bodyLen+authLen]...),
+		Body:     append([]byte{}, body...),
+	}, nil
+}
+
+// Respond calls h to answer req, returning the packed response.  If h is nil, the response has status
+// requests.StatusOpcodeDoesNotExist.
+func Respond(req *Request, h Handler) ([]byte, error) {
+	status := requests.StatusOpcodeDoesNotExist
+	var body []byte
+	if h != nil {
+		var result proto.Message
+		result, status = h(req.Provider, req.Body)
+		if status == requests.StatusSuccess {
+			var err error
+			body, err = proto.Marshal(result)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	resp := &bytes.Buffer{}
+	hdr := []interface{}{
+		uint32(wireMagic), uint16(wireHeaderLength), uint8(1), uint8(0), uint16(0), uint8(req.Provider), uint64(0),
+		uint8(0), uint8(0), uint8(0), uint32(len(body)), uint16(0), uint32(req.OpCode), uint16(status), uint8(0), uint8(0),
+	}
+	for _, field := range hdr {
+		if err := binary.Write(resp, binary.LittleEndian, field); err != nil {
+			return nil, err
+		}
+	}
+	resp.Write(body)
+	return resp.Bytes(), nil
+}
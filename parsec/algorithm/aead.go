@@ -34,19 +34,21 @@ func (a *aeadFactory) AeadShortenedTag(algType AeadAlgorithmType, tagLength uint
 	return &Algorithm{
 		variant: &AeadAlgorithm{
 			variant: &AeadAlgorithmShortenedTag{
-				AeadAlg: algType,
+				AeadAlg:   algType,
+				TagLength: tagLength,
 			},
 		},
 	}
 }
 
+// AeadAlgorithmType is an AEAD algorithm, with the values used on the wire.
 type AeadAlgorithmType uint32
 
 const (
-	AeadAlgorithmNODEFAULTTAG     AeadAlgorithmType = 0
-	AeadAlgorithmCCM              AeadAlgorithmType = 1
-	AeadAlgorithmGCM              AeadAlgorithmType = 2
-	AeadAlgorithmChacha20Poly1305 AeadAlgorithmType = 4
+	AeadAlgorithmNODEFAULTTAG     = AeadAlgorithmType(psaalgorithm.Algorithm_Aead_AEAD_WITH_DEFAULT_LENGTH_TAG_NONE)
+	AeadAlgorithmCCM              = AeadAlgorithmType(psaalgorithm.Algorithm_Aead_CCM)
+	AeadAlgorithmGCM              = AeadAlgorithmType(psaalgorithm.Algorithm_Aead_GCM)
+	AeadAlgorithmChacha20Poly1305 = AeadAlgorithmType(psaalgorithm.Algorithm_Aead_CHACHA20_POLY1305)
 )
 
 type AeadAlgorithm struct {
@@ -72,6 +72,27 @@ func (a *Algorithm) GetHash() *HashAlgorithm {
 	return nil
 }
 
+func (a *Algorithm) GetMac() *MacAlgorithm {
+	if sub, ok := a.variant.(*MacAlgorithm); ok {
+		return sub
+	}
+	return nil
+}
+
+func (a *Algorithm) GetKeyAgreement() *KeyAgreement {
+	if sub, ok := a.variant.(*KeyAgreement); ok {
+		return sub
+	}
+	return nil
+}
+
+func (a *Algorithm) GetKeyDerivation() *KeyDerivation {
+	if sub, ok := a.variant.(*KeyDerivation); ok {
+		return sub
+	}
+	return nil
+}
+
 func NewAlgorithmFromWireInterface(op interface{}) (*Algorithm, error) {
 	var algvar algorithmVariant
 	var err error
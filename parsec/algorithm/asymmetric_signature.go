@@ -18,6 +18,7 @@ type AsymmetricSignatureFactory interface {
 	RsaPssAny() *Algorithm
 	Ecdsa(hashAlg HashAlgorithmType) *Algorithm
 	EcdsaAny() *Algorithm
+	EcdsaAnyHash() *Algorithm
 	DeterministicEcdsa(hashAlg HashAlgorithmType) *Algorithm
 	DeterministicEcdsaAny() *Algorithm
 }
@@ -109,6 +110,20 @@ func (a *asymmetricSignatureFactory) EcdsaAny() *Algorithm {
 	}
 }
 
+// EcdsaAnyHash returns ECDSA with a wildcard hash, for use in a key policy permitting ECDSA with any hash.  It differs
+// from EcdsaAny, which signs a hash without knowing its algorithm.
+func (a *asymmetricSignatureFactory) EcdsaAnyHash() *Algorithm {
+	return &Algorithm{
+		variant: &AsymmetricSignatureAlgorithm{
+			variant: &AsymmetricSignatureEcdsa{
+				SignHash: &AsymmetricSignatureSignHash{
+					variant: &AsymmetricSignatureSignHashAny{},
+				},
+			},
+		},
+	}
+}
+
 func (a *asymmetricSignatureFactory) DeterministicEcdsa(hashAlg HashAlgorithmType) *Algorithm {
 	return &Algorithm{
 		variant: &AsymmetricSignatureAlgorithm{
@@ -196,6 +211,15 @@ func (a *AsymmetricSignatureAlgorithm) GetDeterministicEcdsa() *AsymmetricSignat
 	return nil
 }
 
+// HashAlg returns the hash used by a hash-and-sign algorithm, or HashAlgorithmTypeNONE if the algorithm signs raw
+// data or accepts any hash.
+func (a *AsymmetricSignatureAlgorithm) HashAlg() HashAlgorithmType {
+	if signHash := a.signHash(); signHash != nil {
+		return signHash.GetSpecific()
+	}
+	return HashAlgorithmTypeNONE
+}
+
 type AsymmetricSignatureRsaPkcs1V15Sign struct {
 	SignHash *AsymmetricSignatureSignHash
 }
@@ -0,0 +1,77 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gocrypto
+
+import (
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+// FromEllipticCurve returns the parsec ECC family and key size in bits for c.
+func FromEllipticCurve(c elliptic.Curve) (parsec.EccFamily, uint32, error) {
+	switch c {
+	case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return parsec.KeyTypeSECPR1, uint32(c.Params().BitSize), nil
+	}
+	name := "<nil>"
+	if c != nil {
+		name = c.Params().Name
+	}
+	return parsec.KeyTypeECCFAMILYNONE, 0, fmt.Errorf("%w: elliptic curve %v", ErrUnsupported, name)
+}
+
+// ToEllipticCurve returns the elliptic curve for a parsec ECC key of the given family and size.
+func ToEllipticCurve(family parsec.EccFamily, bits uint32) (elliptic.Curve, error) {
+	if family == parsec.KeyTypeSECPR1 {
+		switch bits {
+		case 224:
+			return elliptic.P224(), nil
+		case 256:
+			return elliptic.P256(), nil
+		case 384:
+			return elliptic.P384(), nil
+		case 521:
+			return elliptic.P521(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %v bit curve in ECC family %v", ErrUnsupported, bits, familyName(family))
+}
+
+// FromECDHCurve returns the parsec ECC family and key size in bits for c.
+func FromECDHCurve(c ecdh.Curve) (parsec.EccFamily, uint32, error) {
+	switch c {
+	case ecdh.P256():
+		return parsec.KeyTypeSECPR1, 256, nil
+	case ecdh.P384():
+		return parsec.KeyTypeSECPR1, 384, nil
+	case ecdh.P521():
+		return parsec.KeyTypeSECPR1, 521, nil
+	case ecdh.X25519():
+		return parsec.KeyTypeMONTGOMERY, 255, nil
+	}
+	return parsec.KeyTypeECCFAMILYNONE, 0, fmt.Errorf("%w: ecdh curve %v", ErrUnsupported, c)
+}
+
+// ToECDHCurve returns the ecdh curve for a parsec ECC key of the given family and size.
+func ToECDHCurve(family parsec.EccFamily, bits uint32) (ecdh.Curve, error) {
+	switch {
+	case family == parsec.KeyTypeSECPR1 && bits == 256:
+		return ecdh.P256(), nil
+	case family == parsec.KeyTypeSECPR1 && bits == 384:
+		return ecdh.P384(), nil
+	case family == parsec.KeyTypeSECPR1 && bits == 521:
+		return ecdh.P521(), nil
+	case family == parsec.KeyTypeMONTGOMERY && bits == 255:
+		return ecdh.X25519(), nil
+	}
+	return nil, fmt.Errorf("%w: %v bit ecdh curve in ECC family %v", ErrUnsupported, bits, familyName(family))
+}
+
+func familyName(family parsec.EccFamily) string {
+	return psakeyattributes.KeyType_EccFamily(family).String()
+}
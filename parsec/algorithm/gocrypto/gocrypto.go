@@ -0,0 +1,12 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gocrypto maps between the identifiers used by the Go standard crypto packages and the parsec algorithm
+// and key types, so that keys held by parsec can be used with crypto.Signer, x509 and tls.
+package gocrypto
+
+import "errors"
+
+// ErrUnsupported is returned, wrapped with the details, when an identifier has no equivalent on the other side of a
+// mapping.  Test for it using errors.Is.
+var ErrUnsupported = errors.New("no equivalent algorithm")
@@ -0,0 +1,48 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gocrypto
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// hashes maps every parsec hash algorithm to its crypto.Hash.  MD2 is not in the table as Go has no equivalent.
+var hashes = map[algorithm.HashAlgorithmType]crypto.Hash{
+	algorithm.HashAlgorithmTypeMD4:        crypto.MD4,
+	algorithm.HashAlgorithmTypeMD5:        crypto.MD5,
+	algorithm.HashAlgorithmTypeRIPEMD160:  crypto.RIPEMD160,
+	algorithm.HashAlgorithmTypeSHA1:       crypto.SHA1,
+	algorithm.HashAlgorithmTypeSHA224:     crypto.SHA224,
+	algorithm.HashAlgorithmTypeSHA256:     crypto.SHA256,
+	algorithm.HashAlgorithmTypeSHA384:     crypto.SHA384,
+	algorithm.HashAlgorithmTypeSHA512:     crypto.SHA512,
+	algorithm.HashAlgorithmTypeSHA512_224: crypto.SHA512_224,
+	algorithm.HashAlgorithmTypeSHA512_256: crypto.SHA512_256,
+	algorithm.HashAlgorithmTypeSHA3_224:   crypto.SHA3_224,
+	algorithm.HashAlgorithmTypeSHA3_256:   crypto.SHA3_256,
+	algorithm.HashAlgorithmTypeSHA3_384:   crypto.SHA3_384,
+	algorithm.HashAlgorithmTypeSHA3_512:   crypto.SHA3_512,
+}
+
+// FromCryptoHash returns the parsec hash algorithm for h.
+func FromCryptoHash(h crypto.Hash) (algorithm.HashAlgorithmType, error) {
+	for alg, ch := range hashes {
+		if ch == h {
+			return alg, nil
+		}
+	}
+	return algorithm.HashAlgorithmTypeNONE, fmt.Errorf("%w: crypto.Hash %v", ErrUnsupported, h)
+}
+
+// ToCryptoHash returns the crypto.Hash for the parsec hash algorithm h.  Note that the returned hash may still need
+// its implementation to be linked in, by importing the package that provides it, before it is available.
+func ToCryptoHash(h algorithm.HashAlgorithmType) (crypto.Hash, error) {
+	if ch, ok := hashes[h]; ok {
+		return ch, nil
+	}
+	return 0, fmt.Errorf("%w: hash algorithm %v", ErrUnsupported, h)
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gocrypto
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+// PublicKey parses a public key in the format returned by PsaExportPublicKey for a key with attributes: PKCS#1 DER
+// for RSA keys and the uncompressed point for ECC keys.  The result is an *rsa.PublicKey, an *ecdsa.PublicKey for
+// the SECP R1 curves or an *ecdh.PublicKey for X25519.  If attributes.KeyBits is 0, the size of an ECC key is taken
+// from data.
+func PublicKey(attributes *parsec.KeyAttributes, data []byte) (crypto.PublicKey, error) {
+	if attributes == nil || attributes.KeyType == nil {
+		return nil, fmt.Errorf("key type must be set")
+	}
+	wire, ok := attributes.KeyType.ToWireInterface().(*psakeyattributes.KeyType)
+	if !ok || wire == nil || wire.Variant == nil {
+		return nil, fmt.Errorf("key type has no variant")
+	}
+	var family psakeyattributes.KeyType_EccFamily
+	switch kt := wire.Variant.(type) {
+	case *psakeyattributes.KeyType_RsaKeyPair_, *psakeyattributes.KeyType_RsaPublicKey_:
+		return x509.ParsePKCS1PublicKey(data)
+	case *psakeyattributes.KeyType_EccKeyPair_:
+		family = kt.EccKeyPair.CurveFamily
+	case *psakeyattributes.KeyType_EccPublicKey_:
+		family = kt.EccPublicKey.CurveFamily
+	default:
+		m := wire.ProtoReflect()
+		return nil, fmt.Errorf("%w: public key for key type %v", ErrUnsupported, m.WhichOneof(m.Descriptor().Oneofs().Get(0)).Name())
+	}
+
+	bits := attributes.KeyBits
+	if family == psakeyattributes.KeyType_MONTGOMERY {
+		if bits != 0 && bits != 255 {
+			return nil, fmt.Errorf("%w: %v bit Montgomery curve", ErrUnsupported, bits)
+		}
+		return ecdh.X25519().NewPublicKey(data)
+	}
+	if bits == 0 && len(data) > 0 {
+		// An uncompressed point is 0x04 followed by the two coordinates
+		bits = uint32((len(data)-1)/2) * 8
+		if bits == 528 {
+			bits = 521
+		}
+	}
+	curve, err := ToEllipticCurve(parsec.EccFamily(family), bits)
+	if err != nil {
+		return nil, err
+	}
+	x, y := elliptic.Unmarshal(curve, data) //nolint:staticcheck // crypto/ecdh does not support P-224
+	if x == nil {
+		return nil, fmt.Errorf("invalid %v public key", curve.Params().Name)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gocrypto_test
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm/gocrypto"
+)
+
+func TestHashes(t *testing.T) {
+	for h := algorithm.HashAlgorithmTypeMD2; h <= algorithm.HashAlgorithmTypeSHA3_512; h++ {
+		ch, err := gocrypto.ToCryptoHash(h)
+		if h == algorithm.HashAlgorithmTypeMD2 {
+			if !errors.Is(err, gocrypto.ErrUnsupported) {
+				t.Errorf("expected MD2 to be unsupported, got %v, %v", ch, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", h, err)
+			continue
+		}
+		if ch.Size() != h.Size() {
+			t.Errorf("%v maps to %v with size %v, expected %v", h, ch, ch.Size(), h.Size())
+		}
+		back, err := gocrypto.FromCryptoHash(ch)
+		if err != nil || back != h {
+			t.Errorf("%v maps back to %v, %v", ch, back, err)
+		}
+	}
+	for _, ch := range []crypto.Hash{crypto.MD5SHA1, crypto.BLAKE2b_256, 0} {
+		if _, err := gocrypto.FromCryptoHash(ch); !errors.Is(err, gocrypto.ErrUnsupported) {
+			t.Errorf("expected %v to be unsupported, got %v", ch, err)
+		}
+	}
+}
+
+func TestX509SignatureAlgorithms(t *testing.T) {
+	sig := algorithm.NewAsymmetricSignature()
+	tests := map[x509.SignatureAlgorithm]*algorithm.Algorithm{
+		x509.MD2WithRSA:       sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeMD2),
+		x509.MD5WithRSA:       sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeMD5),
+		x509.SHA1WithRSA:      sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA1),
+		x509.SHA256WithRSA:    sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256),
+		x509.SHA384WithRSA:    sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA384),
+		x509.SHA512WithRSA:    sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA512),
+		x509.SHA256WithRSAPSS: sig.RsaPss(algorithm.HashAlgorithmTypeSHA256),
+		x509.SHA384WithRSAPSS: sig.RsaPss(algorithm.HashAlgorithmTypeSHA384),
+		x509.SHA512WithRSAPSS: sig.RsaPss(algorithm.HashAlgorithmTypeSHA512),
+		x509.ECDSAWithSHA1:    sig.Ecdsa(algorithm.HashAlgorithmTypeSHA1),
+		x509.ECDSAWithSHA256:  sig.Ecdsa(algorithm.HashAlgorithmTypeSHA256),
+		x509.ECDSAWithSHA384:  sig.Ecdsa(algorithm.HashAlgorithmTypeSHA384),
+		x509.ECDSAWithSHA512:  sig.Ecdsa(algorithm.HashAlgorithmTypeSHA512),
+	}
+	for s, want := range tests {
+		alg, err := gocrypto.FromX509SignatureAlgorithm(s)
+		if err != nil || !reflect.DeepEqual(alg, want) {
+			t.Errorf("%v: got %v, %v, expected %v", s, alg, err, want)
+		}
+		back, err := gocrypto.ToX509SignatureAlgorithm(want)
+		if err != nil || back != s {
+			t.Errorf("%v: got %v, %v, expected %v", want, back, err, s)
+		}
+	}
+	if s, err := gocrypto.ToX509SignatureAlgorithm(sig.DeterministicEcdsa(algorithm.HashAlgorithmTypeSHA384)); err != nil || s != x509.ECDSAWithSHA384 {
+		t.Errorf("deterministic ECDSA: got %v, %v", s, err)
+	}
+	for _, s := range []x509.SignatureAlgorithm{x509.DSAWithSHA256, x509.PureEd25519, x509.UnknownSignatureAlgorithm} {
+		if _, err := gocrypto.FromX509SignatureAlgorithm(s); !errors.Is(err, gocrypto.ErrUnsupported) {
+			t.Errorf("expected %v to be unsupported, got %v", s, err)
+		}
+	}
+	for _, alg := range []*algorithm.Algorithm{
+		sig.RsaPssAny(), sig.EcdsaAny(), sig.RsaPkcs1V15SignRaw(), sig.RsaPss(algorithm.HashAlgorithmTypeSHA3_256),
+		algorithm.NewAead().Aead(algorithm.AeadAlgorithmGCM), nil,
+	} {
+		if _, err := gocrypto.ToX509SignatureAlgorithm(alg); !errors.Is(err, gocrypto.ErrUnsupported) {
+			t.Errorf("expected %v to be unsupported, got %v", alg, err)
+		}
+	}
+}
+
+func TestTLSSignatureSchemes(t *testing.T) {
+	sig := algorithm.NewAsymmetricSignature()
+	tests := map[tls.SignatureScheme]*algorithm.Algorithm{
+		tls.PKCS1WithSHA1:          sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA1),
+		tls.PKCS1WithSHA256:        sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256),
+		tls.PKCS1WithSHA384:        sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA384),
+		tls.PKCS1WithSHA512:        sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA512),
+		tls.PSSWithSHA256:          sig.RsaPss(algorithm.HashAlgorithmTypeSHA256),
+		tls.PSSWithSHA384:          sig.RsaPss(algorithm.HashAlgorithmTypeSHA384),
+		tls.PSSWithSHA512:          sig.RsaPss(algorithm.HashAlgorithmTypeSHA512),
+		tls.ECDSAWithSHA1:          sig.Ecdsa(algorithm.HashAlgorithmTypeSHA1),
+		tls.ECDSAWithP256AndSHA256: sig.Ecdsa(algorithm.HashAlgorithmTypeSHA256),
+		tls.ECDSAWithP384AndSHA384: sig.Ecdsa(algorithm.HashAlgorithmTypeSHA384),
+		tls.ECDSAWithP521AndSHA512: sig.Ecdsa(algorithm.HashAlgorithmTypeSHA512),
+	}
+	for s, want := range tests {
+		alg, err := gocrypto.FromTLSSignatureScheme(s)
+		if err != nil || !reflect.DeepEqual(alg, want) {
+			t.Errorf("%v: got %v, %v, expected %v", s, alg, err, want)
+		}
+		back, err := gocrypto.ToTLSSignatureScheme(want)
+		if err != nil || back != s {
+			t.Errorf("%v: got %v, %v, expected %v", want, back, err, s)
+		}
+	}
+	if _, err := gocrypto.FromTLSSignatureScheme(tls.Ed25519); !errors.Is(err, gocrypto.ErrUnsupported) {
+		t.Errorf("expected Ed25519 to be unsupported, got %v", err)
+	}
+	if _, err := gocrypto.ToTLSSignatureScheme(sig.RsaPss(algorithm.HashAlgorithmTypeSHA1)); !errors.Is(err, gocrypto.ErrUnsupported) {
+		t.Errorf("expected PSS with SHA-1 to be unsupported, got %v", err)
+	}
+}
+
+func TestEllipticCurves(t *testing.T) {
+	for _, c := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		family, bits, err := gocrypto.FromEllipticCurve(c)
+		if err != nil || family != parsec.KeyTypeSECPR1 || int(bits) != c.Params().BitSize {
+			t.Errorf("%v: got %v, %v, %v", c.Params().Name, family, bits, err)
+			continue
+		}
+		back, err := gocrypto.ToEllipticCurve(family, bits)
+		if err != nil || back != c {
+			t.Errorf("%v: got %v, %v", c.Params().Name, back, err)
+		}
+	}
+	if _, _, err := gocrypto.FromEllipticCurve(nil); !errors.Is(err, gocrypto.ErrUnsupported) {
+		t.Errorf("expected nil curve to be unsupported, got %v", err)
+	}
+	if _, err := gocrypto.ToEllipticCurve(parsec.KeyTypeSECPR1, 192); !errors.Is(err, gocrypto.ErrUnsupported) {
+		t.Errorf("expected secp192r1 to be unsupported, got %v", err)
+	}
+	if _, err := gocrypto.ToEllipticCurve(parsec.KeyTypeSECPK1, 256); !errors.Is(err, gocrypto.ErrUnsupported) {
+		t.Errorf("expected secp256k1 to be unsupported, got %v", err)
+	}
+}
+
+func TestECDHCurves(t *testing.T) {
+	for _, c := range []ecdh.Curve{ecdh.P256(), ecdh.P384(), ecdh.P521(), ecdh.X25519()} {
+		family, bits, err := gocrypto.FromECDHCurve(c)
+		if err != nil {
+			t.Errorf("%v: %v", c, err)
+			continue
+		}
+		back, err := gocrypto.ToECDHCurve(family, bits)
+		if err != nil || back != c {
+			t.Errorf("%v: got %v, %v", c, back, err)
+		}
+	}
+	if family, bits, _ := gocrypto.FromECDHCurve(ecdh.X25519()); family != parsec.KeyTypeMONTGOMERY || bits != 255 {
+		t.Errorf("X25519: got %v, %v", family, bits)
+	}
+	if _, err := gocrypto.ToECDHCurve(parsec.KeyTypeMONTGOMERY, 448); !errors.Is(err, gocrypto.ErrUnsupported) {
+		t.Errorf("expected X448 to be unsupported, got %v", err)
+	}
+}
+
+func TestPublicKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kt := parsec.NewKeyType()
+	pub, err := gocrypto.PublicKey(&parsec.KeyAttributes{KeyType: kt.RsaKeyPair(), KeyBits: 1024}, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey))
+	if err != nil || !rsaKey.PublicKey.Equal(pub) {
+		t.Errorf("RSA public key: got %v, %v", pub, err)
+	}
+
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		ecKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := elliptic.Marshal(curve, ecKey.X, ecKey.Y) //nolint:staticcheck // the format parsec exports
+		for _, bits := range []uint32{uint32(curve.Params().BitSize), 0} {
+			pub, err = gocrypto.PublicKey(&parsec.KeyAttributes{KeyType: kt.EccPublicKey(parsec.KeyTypeSECPR1), KeyBits: bits}, data)
+			if err != nil || !ecKey.PublicKey.Equal(pub) {
+				t.Errorf("%v public key with %v bits: got %v, %v", curve.Params().Name, bits, pub, err)
+			}
+		}
+	}
+
+	x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err = gocrypto.PublicKey(&parsec.KeyAttributes{KeyType: kt.EccKeyPair(parsec.KeyTypeMONTGOMERY), KeyBits: 255}, x25519.PublicKey().Bytes())
+	if err != nil || !x25519.PublicKey().Equal(pub) {
+		t.Errorf("X25519 public key: got %v, %v", pub, err)
+	}
+
+	if _, err = gocrypto.PublicKey(&parsec.KeyAttributes{KeyType: kt.Aes(), KeyBits: 128}, []byte{1}); !errors.Is(err, gocrypto.ErrUnsupported) {
+		t.Errorf("expected AES public key to be unsupported, got %v", err)
+	}
+	if _, err = gocrypto.PublicKey(&parsec.KeyAttributes{KeyType: kt.EccPublicKey(parsec.KeyTypeSECPR1), KeyBits: 256}, []byte{4, 1, 2}); err == nil {
+		t.Error("expected invalid point to fail")
+	}
+}
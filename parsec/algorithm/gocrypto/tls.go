@@ -0,0 +1,47 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gocrypto
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+var tlsSignatureSchemes = map[tls.SignatureScheme]hashAndSign{
+	tls.PKCS1WithSHA1:          {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA1},
+	tls.PKCS1WithSHA256:        {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA256},
+	tls.PKCS1WithSHA384:        {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA384},
+	tls.PKCS1WithSHA512:        {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA512},
+	tls.PSSWithSHA256:          {schemePss, algorithm.HashAlgorithmTypeSHA256},
+	tls.PSSWithSHA384:          {schemePss, algorithm.HashAlgorithmTypeSHA384},
+	tls.PSSWithSHA512:          {schemePss, algorithm.HashAlgorithmTypeSHA512},
+	tls.ECDSAWithSHA1:          {schemeEcdsa, algorithm.HashAlgorithmTypeSHA1},
+	tls.ECDSAWithP256AndSHA256: {schemeEcdsa, algorithm.HashAlgorithmTypeSHA256},
+	tls.ECDSAWithP384AndSHA384: {schemeEcdsa, algorithm.HashAlgorithmTypeSHA384},
+	tls.ECDSAWithP521AndSHA512: {schemeEcdsa, algorithm.HashAlgorithmTypeSHA512},
+}
+
+// FromTLSSignatureScheme returns the parsec signature algorithm for s.  Ed25519 is not supported by parsec.
+func FromTLSSignatureScheme(s tls.SignatureScheme) (*algorithm.Algorithm, error) {
+	if sig, ok := tlsSignatureSchemes[s]; ok {
+		return sig.scheme.algorithm(sig.hash), nil
+	}
+	return nil, fmt.Errorf("%w: tls signature scheme %v", ErrUnsupported, s)
+}
+
+// ToTLSSignatureScheme returns the tls signature scheme for alg.  The ECDSA schemes name a curve, which TLS 1.3
+// requires the key to use but TLS 1.2 does not, so the caller should check that the key's curve matches before
+// offering the scheme for TLS 1.3.  Deterministic ECDSA maps to the ECDSA scheme with the same hash.
+func ToTLSSignatureScheme(alg *algorithm.Algorithm) (tls.SignatureScheme, error) {
+	if scheme, hash, ok := signature(alg); ok {
+		for s, sig := range tlsSignatureSchemes {
+			if sig.scheme == scheme && sig.hash == hash {
+				return s, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: %v in tls", ErrUnsupported, alg)
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package gocrypto
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// hashAndSign identifies a hash-and-sign signature algorithm with a specific hash.
+type hashAndSign struct {
+	scheme signatureScheme
+	hash   algorithm.HashAlgorithmType
+}
+
+type signatureScheme int
+
+const (
+	schemePkcs1V15 signatureScheme = iota
+	schemePss
+	schemeEcdsa
+)
+
+var x509Signatures = map[x509.SignatureAlgorithm]hashAndSign{
+	x509.MD2WithRSA:       {schemePkcs1V15, algorithm.HashAlgorithmTypeMD2},
+	x509.MD5WithRSA:       {schemePkcs1V15, algorithm.HashAlgorithmTypeMD5},
+	x509.SHA1WithRSA:      {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA1},
+	x509.SHA256WithRSA:    {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA256},
+	x509.SHA384WithRSA:    {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA384},
+	x509.SHA512WithRSA:    {schemePkcs1V15, algorithm.HashAlgorithmTypeSHA512},
+	x509.SHA256WithRSAPSS: {schemePss, algorithm.HashAlgorithmTypeSHA256},
+	x509.SHA384WithRSAPSS: {schemePss, algorithm.HashAlgorithmTypeSHA384},
+	x509.SHA512WithRSAPSS: {schemePss, algorithm.HashAlgorithmTypeSHA512},
+	x509.ECDSAWithSHA1:    {schemeEcdsa, algorithm.HashAlgorithmTypeSHA1},
+	x509.ECDSAWithSHA256:  {schemeEcdsa, algorithm.HashAlgorithmTypeSHA256},
+	x509.ECDSAWithSHA384:  {schemeEcdsa, algorithm.HashAlgorithmTypeSHA384},
+	x509.ECDSAWithSHA512:  {schemeEcdsa, algorithm.HashAlgorithmTypeSHA512},
+}
+
+func (s signatureScheme) algorithm(hash algorithm.HashAlgorithmType) *algorithm.Algorithm {
+	sig := algorithm.NewAsymmetricSignature()
+	switch s {
+	case schemePkcs1V15:
+		return sig.RsaPkcs1V15Sign(hash)
+	case schemePss:
+		return sig.RsaPss(hash)
+	default:
+		return sig.Ecdsa(hash)
+	}
+}
+
+// signature returns the scheme and hash of a hash-and-sign algorithm with a specific hash.  Deterministic ECDSA is
+// reported as ECDSA, as the signatures are verified in the same way.
+func signature(alg *algorithm.Algorithm) (signatureScheme, algorithm.HashAlgorithmType, bool) {
+	if alg == nil || alg.GetAsymmetricSignature() == nil {
+		return 0, algorithm.HashAlgorithmTypeNONE, false
+	}
+	sig := alg.GetAsymmetricSignature()
+	var scheme signatureScheme
+	var signHash *algorithm.AsymmetricSignatureSignHash
+	switch {
+	case sig.GetRsaPkcs1V15Sign() != nil:
+		scheme, signHash = schemePkcs1V15, sig.GetRsaPkcs1V15Sign().SignHash
+	case sig.GetRsaPss() != nil:
+		scheme, signHash = schemePss, sig.GetRsaPss().SignHash
+	case sig.GetEcdsa() != nil:
+		scheme, signHash = schemeEcdsa, sig.GetEcdsa().SignHash
+	case sig.GetDeterministicEcdsa() != nil:
+		scheme, signHash = schemeEcdsa, sig.GetDeterministicEcdsa().SignHash
+	default:
+		return 0, algorithm.HashAlgorithmTypeNONE, false
+	}
+	if signHash == nil || signHash.GetAny() != nil {
+		return 0, algorithm.HashAlgorithmTypeNONE, false
+	}
+	return scheme, signHash.GetSpecific(), true
+}
+
+// FromX509SignatureAlgorithm returns the parsec signature algorithm for s.  DSA and Ed25519 signatures are not
+// supported by parsec.
+func FromX509SignatureAlgorithm(s x509.SignatureAlgorithm) (*algorithm.Algorithm, error) {
+	if sig, ok := x509Signatures[s]; ok {
+		return sig.scheme.algorithm(sig.hash), nil
+	}
+	return nil, fmt.Errorf("%w: x509 signature algorithm %v", ErrUnsupported, s)
+}
+
+// ToX509SignatureAlgorithm returns the x509 signature algorithm for alg.  Deterministic ECDSA maps to the ECDSA
+// signature algorithm with the same hash, as certificates do not distinguish them.  Algorithms with a wildcard hash
+// have no x509 equivalent.
+func ToX509SignatureAlgorithm(alg *algorithm.Algorithm) (x509.SignatureAlgorithm, error) {
+	if scheme, hash, ok := signature(alg); ok {
+		for s, sig := range x509Signatures {
+			if sig.scheme == scheme && sig.hash == hash {
+				return s, nil
+			}
+		}
+	}
+	return x509.UnknownSignatureAlgorithm, fmt.Errorf("%w: %v in x509", ErrUnsupported, alg)
+}
@@ -73,6 +73,25 @@ func (a HashAlgorithmType) String() string {
 	return ""
 }
 
+// Size returns the length in bytes of the digest produced by the hash algorithm, or 0 if it is not known.
+func (a HashAlgorithmType) Size() int {
+	switch a {
+	case HashAlgorithmTypeMD2, HashAlgorithmTypeMD4, HashAlgorithmTypeMD5:
+		return 16
+	case HashAlgorithmTypeRIPEMD160, HashAlgorithmTypeSHA1:
+		return 20
+	case HashAlgorithmTypeSHA224, HashAlgorithmTypeSHA512_224, HashAlgorithmTypeSHA3_224:
+		return 28
+	case HashAlgorithmTypeSHA256, HashAlgorithmTypeSHA512_256, HashAlgorithmTypeSHA3_256:
+		return 32
+	case HashAlgorithmTypeSHA384, HashAlgorithmTypeSHA3_384:
+		return 48
+	case HashAlgorithmTypeSHA512, HashAlgorithmTypeSHA3_512:
+		return 64
+	}
+	return 0
+}
+
 type HashAlgorithm struct {
 	HashAlg HashAlgorithmType
 }
@@ -91,7 +110,7 @@ func (a HashAlgorithm) String() string {
 
 func (a HashAlgorithm) isAlgorithmVariant() {}
 func (a *HashAlgorithm) ToWireInterface() interface{} {
-	return psaalgorithm.Algorithm{
+	return &psaalgorithm.Algorithm{
 		Variant: &psaalgorithm.Algorithm_Hash_{
 			// We've defined HashAlg to be same as protoc interface so we can cast safely
 			Hash: psaalgorithm.Algorithm_Hash(a.HashAlg),
@@ -99,6 +118,11 @@ func (a *HashAlgorithm) ToWireInterface() interface{} {
 	}
 }
 
-func newHashFromWire(a psaalgorithm.Algorithm_Hash) (algorithmVariant, error) {
-	return nil, fmt.Errorf("not implemented")
+func newHashFromWire(a psaalgorithm.Algorithm_Hash) (*HashAlgorithm, error) {
+	if _, ok := psaalgorithm.Algorithm_Hash_name[int32(a)]; !ok {
+		return nil, fmt.Errorf("unknown hash algorithm %v", int32(a))
+	}
+	return &HashAlgorithm{
+		HashAlg: HashAlgorithmType(a),
+	}, nil
 }
@@ -90,7 +90,7 @@ func (f *macFactory) CMACTruncated(macLength uint32) *Algorithm {
 		variant: &MacAlgorithm{
 			variant: &MacTruncated{
 				MacAlg: &MacFullLength{
-					variant: &MacFullLengthCbcMac{},
+					variant: &MacFullLengthCmac{},
 				},
 				MacLength: macLength,
 			},
@@ -111,6 +111,22 @@ func (a *MacAlgorithm) ToWireInterface() interface{} {
 	return a.variant.ToWireInterface()
 }
 
+// GetFullLength returns the algorithm if it is a full length MAC, or nil.
+func (a *MacAlgorithm) GetFullLength() *MacFullLength {
+	if alg, ok := a.variant.(*MacFullLength); ok {
+		return alg
+	}
+	return nil
+}
+
+// GetTruncated returns the algorithm if it is a truncated MAC, or nil.
+func (a *MacAlgorithm) GetTruncated() *MacTruncated {
+	if alg, ok := a.variant.(*MacTruncated); ok {
+		return alg
+	}
+	return nil
+}
+
 type macAlgorithmVariant interface {
 	toWire
 	isMacAlgorithmVariant()
@@ -136,6 +152,43 @@ func (a *MacFullLength) ToWireInterface() interface{} {
 	return a.variant.ToWireInterface()
 }
 
+// GetHmac returns the algorithm if it is HMAC, or nil.
+func (a *MacFullLength) GetHmac() *MacFullLengthHmac {
+	if alg, ok := a.variant.(*MacFullLengthHmac); ok {
+		return alg
+	}
+	return nil
+}
+
+// GetCbcMac returns the algorithm if it is CBC-MAC, or nil.
+func (a *MacFullLength) GetCbcMac() *MacFullLengthCbcMac {
+	if alg, ok := a.variant.(*MacFullLengthCbcMac); ok {
+		return alg
+	}
+	return nil
+}
+
+// GetCmac returns the algorithm if it is CMAC, or nil.
+func (a *MacFullLength) GetCmac() *MacFullLengthCmac {
+	if alg, ok := a.variant.(*MacFullLengthCmac); ok {
+		return alg
+	}
+	return nil
+}
+
+// Size returns the length in bytes of the full length MAC, or 0 if it is not known.
+func (a *MacFullLength) Size() int {
+	switch mac := a.variant.(type) {
+	case *MacFullLengthHmac:
+		return mac.HashAlg.Size()
+	case *MacFullLengthCbcMac, *MacFullLengthCmac:
+		// CBC-MAC and CMAC are the block size of the cipher, which is 16 for all the block ciphers parsec supports
+		// apart from DES.
+		return 16
+	}
+	return 0
+}
+
 type MacTruncated struct {
 	MacAlg    *MacFullLength
 	MacLength uint32
@@ -173,7 +226,7 @@ func (a *MacFullLengthHmac) toWireInterfaceSpecific() *psaalgorithm.Algorithm_Ma
 	}
 }
 func (a *MacFullLengthHmac) ToWireInterface() interface{} {
-	return psaalgorithm.Algorithm{
+	return &psaalgorithm.Algorithm{
 		Variant: &psaalgorithm.Algorithm_Mac_{
 			Mac: &psaalgorithm.Algorithm_Mac{
 				Variant: &psaalgorithm.Algorithm_Mac_FullLength_{
@@ -195,7 +248,7 @@ func (a *MacFullLengthCbcMac) toWireInterfaceSpecific() *psaalgorithm.Algorithm_
 	}
 }
 func (a *MacFullLengthCbcMac) ToWireInterface() interface{} {
-	return psaalgorithm.Algorithm{
+	return &psaalgorithm.Algorithm{
 		Variant: &psaalgorithm.Algorithm_Mac_{
 			Mac: &psaalgorithm.Algorithm_Mac{
 				Variant: &psaalgorithm.Algorithm_Mac_FullLength_{
@@ -217,7 +270,7 @@ func (a *MacFullLengthCmac) toWireInterfaceSpecific() *psaalgorithm.Algorithm_Ma
 	}
 }
 func (a *MacFullLengthCmac) ToWireInterface() interface{} {
-	return psaalgorithm.Algorithm{
+	return &psaalgorithm.Algorithm{
 		Variant: &psaalgorithm.Algorithm_Mac_{
 			Mac: &psaalgorithm.Algorithm_Mac{
 				Variant: &psaalgorithm.Algorithm_Mac_FullLength_{
@@ -0,0 +1,76 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package algorithm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaalgorithm"
+	"google.golang.org/protobuf/proto"
+)
+
+// MarshalJSON encodes the algorithm as the JSON form of its wire representation, using the field and enum names of
+// the parsec operations, for example {"asymmetric_signature":{"ecdsa":{"hash_alg":{"specific":"SHA_256"}}}}.
+// The encoding is stable, so may be stored and compared.
+func (a *Algorithm) MarshalJSON() ([]byte, error) {
+	wire, err := a.toWireAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+	return operations.MarshalJSON(wire)
+}
+
+// UnmarshalJSON decodes an algorithm encoded by MarshalJSON, returning an error if it is not a valid algorithm.
+func (a *Algorithm) UnmarshalJSON(data []byte) error {
+	wire := &psaalgorithm.Algorithm{}
+	if err := operations.UnmarshalJSON(data, wire); err != nil {
+		return fmt.Errorf("could not decode algorithm: %w", err)
+	}
+	alg, err := NewAlgorithmFromWireInterface(wire)
+	if err != nil {
+		return err
+	}
+	// Check nothing was lost in the conversion, for example a field from another variant.
+	roundTrip, err := alg.toWireAlgorithm()
+	if err != nil {
+		return err
+	}
+	if !proto.Equal(wire, roundTrip) {
+		return fmt.Errorf("invalid algorithm %s", data)
+	}
+	*a = *alg
+	return nil
+}
+
+// MarshalText encodes the algorithm in the same way as MarshalJSON.
+func (a *Algorithm) MarshalText() ([]byte, error) {
+	return a.MarshalJSON()
+}
+
+// UnmarshalText decodes an algorithm encoded by MarshalText, or named as in the PSA specification, as accepted by
+// Parse.
+func (a *Algorithm) UnmarshalText(text []byte) error {
+	if s := strings.TrimSpace(string(text)); !strings.HasPrefix(s, "{") {
+		alg, err := Parse(s)
+		if err != nil {
+			return err
+		}
+		*a = *alg
+		return nil
+	}
+	return a.UnmarshalJSON(text)
+}
+
+func (a *Algorithm) toWireAlgorithm() (*psaalgorithm.Algorithm, error) {
+	if a == nil || a.variant == nil {
+		return nil, fmt.Errorf("algorithm has no variant")
+	}
+	wire, ok := a.ToWireInterface().(*psaalgorithm.Algorithm)
+	if !ok || wire == nil {
+		return nil, fmt.Errorf("no wire representation for algorithm")
+	}
+	return wire, nil
+}
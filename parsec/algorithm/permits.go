@@ -0,0 +1,160 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package algorithm
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// aeadDefaultTagLength is the tag length in bytes of all the AEAD algorithms parsec supports when used with their
+// default length tag.
+const aeadDefaultTagLength = 16
+
+// Permits returns true if a key whose policy algorithm is policy may be used with the requested algorithm, following
+// the PSA Crypto API policy rules:
+//
+// - A hash-and-sign policy with a wildcard hash, such as RsaPssAny, permits the same signature scheme with any hash.
+// - ECDSA and deterministic ECDSA are different algorithms, so one does not permit the other.  Use PermitsVerification
+// when the key will only be used to verify signatures, as the two produce signatures that verify in the same way.
+// - A shortened AEAD tag or truncated MAC length in the policy is a minimum, permitting the same algorithm with that
+// tag length or longer, up to and including the default length.
+//
+// Any other requested algorithm must be identical to the policy.  A nil policy or requested algorithm permits nothing.
+func Permits(policy, requested *Algorithm) bool {
+	return permits(policy, requested, false)
+}
+
+// PermitsVerification is like Permits, but for a key that will only be used to verify signatures, so also allows
+// ECDSA to be used with a deterministic ECDSA policy and the other way round.
+func PermitsVerification(policy, requested *Algorithm) bool {
+	return permits(policy, requested, true)
+}
+
+func permits(policy, requested *Algorithm, verify bool) bool {
+	if policy == nil || requested == nil || policy.variant == nil || requested.variant == nil {
+		return false
+	}
+	if p, r := policy.GetAsymmetricSignature(), requested.GetAsymmetricSignature(); p != nil && r != nil {
+		return signaturePermits(p, r, verify)
+	}
+	if p, r := policy.GetAead(), requested.GetAead(); p != nil && r != nil {
+		return aeadPermits(p, r)
+	}
+	if p, r := policy.GetMac(), requested.GetMac(); p != nil && r != nil {
+		return macPermits(p, r)
+	}
+	return equal(policy, requested)
+}
+
+func signaturePermits(policy, requested *AsymmetricSignatureAlgorithm, verify bool) bool {
+	if policy.variant == nil || requested.variant == nil {
+		return false
+	}
+	policyScheme, requestedScheme := reflect.TypeOf(policy.variant), reflect.TypeOf(requested.variant)
+	if verify {
+		policyScheme, requestedScheme = verificationScheme(policyScheme), verificationScheme(requestedScheme)
+	}
+	if policyScheme != requestedScheme {
+		return false
+	}
+	policyHash, requestedHash := policy.signHash(), requested.signHash()
+	if policyHash == nil || requestedHash == nil {
+		// RsaPkcs1V15SignRaw and EcdsaAny have no hash, so only permit themselves
+		return policyHash == nil && requestedHash == nil
+	}
+	if policyHash.GetAny() != nil {
+		return true
+	}
+	return requestedHash.GetAny() == nil && policyHash.GetSpecific() == requestedHash.GetSpecific()
+}
+
+// verificationScheme maps deterministic ECDSA to ECDSA, as signatures from both are verified in the same way.
+func verificationScheme(scheme reflect.Type) reflect.Type {
+	if scheme == reflect.TypeOf(&AsymmetricSignatureDeterministicEcdsa{}) {
+		return reflect.TypeOf(&AsymmetricSignatureEcdsa{})
+	}
+	return scheme
+}
+
+// signHash returns the hash used by a hash-and-sign algorithm, or nil if the algorithm does not specify one.
+func (a *AsymmetricSignatureAlgorithm) signHash() *AsymmetricSignatureSignHash {
+	switch alg := a.variant.(type) {
+	case *AsymmetricSignatureRsaPkcs1V15Sign:
+		return alg.SignHash
+	case *AsymmetricSignatureRsaPss:
+		return alg.SignHash
+	case *AsymmetricSignatureEcdsa:
+		return alg.SignHash
+	case *AsymmetricSignatureDeterministicEcdsa:
+		return alg.SignHash
+	}
+	return nil
+}
+
+func aeadPermits(policy, requested *AeadAlgorithm) bool {
+	policyAlg, policyTag := policy.tag()
+	requestedAlg, requestedTag := requested.tag()
+	if policyAlg != requestedAlg || policyTag == 0 {
+		return false
+	}
+	return requestedTag >= policyTag && requestedTag <= aeadDefaultTagLength
+}
+
+// tag returns the AEAD algorithm and its tag length in bytes.
+func (a *AeadAlgorithm) tag() (AeadAlgorithmType, uint32) {
+	switch alg := a.variant.(type) {
+	case *AeadAlgorithmDefaultLengthTag:
+		return alg.AeadAlg, aeadDefaultTagLength
+	case *AeadAlgorithmShortenedTag:
+		return alg.AeadAlg, alg.TagLength
+	}
+	return AeadAlgorithmNODEFAULTTAG, 0
+}
+
+func macPermits(policy, requested *MacAlgorithm) bool {
+	policyAlg, policyLength := policy.length()
+	requestedAlg, requestedLength := requested.length()
+	if policyAlg == nil || requestedAlg == nil || !equal(&Algorithm{variant: &MacAlgorithm{variant: policyAlg}},
+		&Algorithm{variant: &MacAlgorithm{variant: requestedAlg}}) {
+		return false
+	}
+	full := uint32(policyAlg.Size())
+	if full == 0 {
+		// Without knowing the full length of the MAC, the lengths can only be compared exactly
+		return policyLength == requestedLength
+	}
+	if policyLength == 0 {
+		policyLength = full
+	}
+	if requestedLength == 0 {
+		requestedLength = full
+	}
+	return requestedLength >= policyLength && requestedLength <= full
+}
+
+// length returns the full length MAC algorithm and the length in bytes it is truncated to, which is 0 if it is not
+// truncated.
+func (a *MacAlgorithm) length() (*MacFullLength, uint32) {
+	switch alg := a.variant.(type) {
+	case *MacFullLength:
+		return alg, 0
+	case *MacTruncated:
+		return alg.MacAlg, alg.MacLength
+	}
+	return nil, 0
+}
+
+func equal(a, b *Algorithm) bool {
+	wireA, err := a.toWireAlgorithm()
+	if err != nil {
+		return false
+	}
+	wireB, err := b.toWireAlgorithm()
+	if err != nil {
+		return false
+	}
+	return proto.Equal(wireA, wireB)
+}
@@ -0,0 +1,472 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package algorithm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaalgorithm"
+)
+
+// Algorithms are named as in the PSA Crypto API specification, for example PSA_ALG_SHA_256, PSA_ALG_ECDSA_ANY,
+// PSA_ALG_RSA_PSS(PSA_ALG_SHA_256) or PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12).  Signature algorithms that
+// permit any hash take PSA_ALG_ANY_HASH as their hash.
+
+const psaPrefix = "PSA_ALG_"
+
+const (
+	psaAnyHash          = "PSA_ALG_ANY_HASH"
+	psaNone             = "PSA_ALG_NONE"
+	psaHmac             = "PSA_ALG_HMAC"
+	psaCbcMac           = "PSA_ALG_CBC_MAC"
+	psaCmac             = "PSA_ALG_CMAC"
+	psaTruncatedMac     = "PSA_ALG_TRUNCATED_MAC"
+	psaShortenedTag     = "PSA_ALG_AEAD_WITH_SHORTENED_TAG"
+	psaRsaPkcs1V15Sign  = "PSA_ALG_RSA_PKCS1V15_SIGN"
+	psaRsaPkcs1V15Raw   = "PSA_ALG_RSA_PKCS1V15_SIGN_RAW"
+	psaRsaPss           = "PSA_ALG_RSA_PSS"
+	psaEcdsa            = "PSA_ALG_ECDSA"
+	psaEcdsaAny         = "PSA_ALG_ECDSA_ANY"
+	psaDetEcdsa         = "PSA_ALG_DETERMINISTIC_ECDSA"
+	psaRsaPkcs1V15Crypt = "PSA_ALG_RSA_PKCS1V15_CRYPT"
+	psaRsaOaep          = "PSA_ALG_RSA_OAEP"
+	psaHkdf             = "PSA_ALG_HKDF"
+	psaTLS12Prf         = "PSA_ALG_TLS12_PRF"
+	psaTLS12PskToMs     = "PSA_ALG_TLS12_PSK_TO_MS"
+	psaKeyAgreement     = "PSA_ALG_KEY_AGREEMENT"
+)
+
+// String returns the PSA specification name of the algorithm, which Parse accepts.  An algorithm without a variant
+// is PSA_ALG_NONE.
+//
+//nolint:gocyclo
+func (a *Algorithm) String() string {
+	if a == nil || a.variant == nil {
+		return psaNone
+	}
+	switch v := a.variant.(type) {
+	case *HashAlgorithm:
+		return hashName(v.HashAlg)
+	case *Cipher:
+		return enumName(psaalgorithm.Algorithm_Cipher_name, int32(v.Mode))
+	case *AeadAlgorithm:
+		switch aead := v.variant.(type) {
+		case *AeadAlgorithmDefaultLengthTag:
+			return aeadName(aead.AeadAlg)
+		case *AeadAlgorithmShortenedTag:
+			return fmt.Sprintf("%v(%v, %d)", psaShortenedTag, aeadName(aead.AeadAlg), aead.TagLength)
+		}
+	case *MacAlgorithm:
+		switch mac := v.variant.(type) {
+		case *MacFullLength:
+			return macName(mac)
+		case *MacTruncated:
+			return fmt.Sprintf("%v(%v, %d)", psaTruncatedMac, macName(mac.MacAlg), mac.MacLength)
+		}
+	case *AsymmetricSignatureAlgorithm:
+		switch sig := v.variant.(type) {
+		case *AsymmetricSignatureRsaPkcs1V15Sign:
+			return fmt.Sprintf("%v(%v)", psaRsaPkcs1V15Sign, signHashName(sig.SignHash))
+		case *AsymmetricSignatureRsaPkcs1V15SignRaw:
+			return psaRsaPkcs1V15Raw
+		case *AsymmetricSignatureRsaPss:
+			return fmt.Sprintf("%v(%v)", psaRsaPss, signHashName(sig.SignHash))
+		case *AsymmetricSignatureEcdsa:
+			return fmt.Sprintf("%v(%v)", psaEcdsa, signHashName(sig.SignHash))
+		case *AsymmetricSignatureEcdsaAny:
+			return psaEcdsaAny
+		case *AsymmetricSignatureDeterministicEcdsa:
+			return fmt.Sprintf("%v(%v)", psaDetEcdsa, signHashName(sig.SignHash))
+		}
+	case *AsymmetricEncryptionAlgorithm:
+		switch enc := v.variant.(type) {
+		case *AsymmetricEncryptionRsaPkcs1V15Crypt:
+			return psaRsaPkcs1V15Crypt
+		case *AsymmetricEncryptionRsaOaep:
+			return fmt.Sprintf("%v(%v)", psaRsaOaep, hashName(enc.HashAlg))
+		}
+	case *KeyDerivation:
+		return keyDerivationName(v)
+	case *KeyAgreement:
+		switch ka := v.variant.(type) {
+		case *KeyAgreementRaw:
+			return keyAgreementName(ka.RawAlg)
+		case *KeyAgreementWithKeyDerivation:
+			return fmt.Sprintf("%v(%v, %v)", psaKeyAgreement, keyAgreementName(ka.KaAlg), keyDerivationName(ka.DerivationAlg))
+		}
+	}
+	return fmt.Sprintf("<unknown algorithm %T>", a.variant)
+}
+
+// String returns the PSA specification name of the signature algorithm, such as PSA_ALG_ECDSA(PSA_ALG_SHA_256).
+func (a *AsymmetricSignatureAlgorithm) String() string {
+	if a == nil {
+		return psaNone
+	}
+	return (&Algorithm{variant: a}).String()
+}
+
+// enumName returns the PSA name for a wire enum value, or a placeholder naming the value if it is not defined.
+func enumName(names map[int32]string, value int32) string {
+	if name, ok := names[value]; ok {
+		return psaPrefix + name
+	}
+	return fmt.Sprintf("<unknown %d>", value)
+}
+
+func hashName(h HashAlgorithmType) string {
+	return enumName(psaalgorithm.Algorithm_Hash_name, int32(h))
+}
+
+func aeadName(a AeadAlgorithmType) string {
+	return enumName(psaalgorithm.Algorithm_Aead_AeadWithDefaultLengthTag_name, int32(a))
+}
+
+func keyAgreementName(k KeyAgreementRawType) string {
+	return enumName(psaalgorithm.Algorithm_KeyAgreement_Raw_name, int32(k))
+}
+
+func signHashName(h *AsymmetricSignatureSignHash) string {
+	if h == nil {
+		return "<missing hash>"
+	}
+	if h.GetAny() != nil {
+		return psaAnyHash
+	}
+	return hashName(h.GetSpecific())
+}
+
+func macName(m *MacFullLength) string {
+	if m == nil {
+		return "<missing mac>"
+	}
+	switch mac := m.variant.(type) {
+	case *MacFullLengthHmac:
+		return fmt.Sprintf("%v(%v)", psaHmac, hashName(mac.HashAlg))
+	case *MacFullLengthCbcMac:
+		return psaCbcMac
+	case *MacFullLengthCmac:
+		return psaCmac
+	}
+	return fmt.Sprintf("<unknown mac %T>", m.variant)
+}
+
+func keyDerivationName(k *KeyDerivation) string {
+	if k == nil {
+		return "<missing key derivation>"
+	}
+	switch kdf := k.variant.(type) {
+	case *KeyDerivationHkdf:
+		return fmt.Sprintf("%v(%v)", psaHkdf, hashName(kdf.HashAlg))
+	case *KeyDerivationTLS12Prf:
+		return fmt.Sprintf("%v(%v)", psaTLS12Prf, hashName(kdf.HashAlg))
+	case *KeyDerivationTLS12PskToMs:
+		return fmt.Sprintf("%v(%v)", psaTLS12PskToMs, hashName(kdf.HashAlg))
+	}
+	return fmt.Sprintf("<unknown key derivation %T>", k.variant)
+}
+
+// ParseError describes where and why Parse failed.
+type ParseError struct {
+	// Input is the string being parsed.
+	Input string
+	// Offset is the byte offset in Input at which the error was found.
+	Offset int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid algorithm %q at offset %d: %v", e.Input, e.Offset, e.Msg)
+}
+
+// Parse returns the algorithm with the given PSA specification name, as returned by Algorithm.String.  Names are
+// matched case-insensitively and whitespace is allowed around arguments.  Errors are returned as *ParseError.
+func Parse(s string) (*Algorithm, error) {
+	p := &psaParser{input: s}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf(p.pos, "unexpected %q after algorithm", p.input[p.pos:])
+	}
+	return p.algorithm(expr)
+}
+
+// psaExpr is a parsed PSA name, with optional arguments, or a number.
+type psaExpr struct {
+	offset   int
+	name     string
+	args     []*psaExpr
+	isNumber bool
+	number   uint32
+}
+
+type psaParser struct {
+	input string
+	pos   int
+}
+
+func (p *psaParser) errorf(offset int, format string, args ...interface{}) error {
+	return &ParseError{Input: p.input, Offset: offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *psaParser) skipSpace() {
+	for p.pos < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
+
+func (p *psaParser) parseExpr() (*psaExpr, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && isNameChar(p.input[p.pos]) {
+		p.pos++
+	}
+	token := p.input[start:p.pos]
+	if token == "" {
+		if p.pos == len(p.input) {
+			return nil, p.errorf(p.pos, "expected algorithm name, got end of input")
+		}
+		return nil, p.errorf(p.pos, "expected algorithm name, got %q", p.input[p.pos])
+	}
+	if token[0] >= '0' && token[0] <= '9' {
+		n, err := strconv.ParseUint(token, 10, 32)
+		if err != nil {
+			return nil, p.errorf(start, "invalid number %v", token)
+		}
+		return &psaExpr{offset: start, isNumber: true, number: uint32(n)}, nil
+	}
+	expr := &psaExpr{offset: start, name: strings.ToUpper(token)}
+	p.skipSpace()
+	if p.pos == len(p.input) || p.input[p.pos] != '(' {
+		return expr, nil
+	}
+	p.pos++
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		expr.args = append(expr.args, arg)
+		p.skipSpace()
+		if p.pos == len(p.input) {
+			return nil, p.errorf(p.pos, "expected ',' or ')', got end of input")
+		}
+		switch p.input[p.pos] {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return expr, nil
+		default:
+			return nil, p.errorf(p.pos, "expected ',' or ')', got %q", p.input[p.pos])
+		}
+	}
+}
+
+// psaFunctions are the algorithms taking arguments, with the kind of each argument.
+var psaFunctions = map[string][]string{
+	psaHmac:            {"hash"},
+	psaTruncatedMac:    {"mac", "length"},
+	psaShortenedTag:    {"aead", "length"},
+	psaRsaPkcs1V15Sign: {"signhash"},
+	psaRsaPss:          {"signhash"},
+	psaEcdsa:           {"signhash"},
+	psaDetEcdsa:        {"signhash"},
+	psaRsaOaep:         {"hash"},
+	psaHkdf:            {"hash"},
+	psaTLS12Prf:        {"hash"},
+	psaTLS12PskToMs:    {"hash"},
+	psaKeyAgreement:    {"keyagreement", "keyderivation"},
+}
+
+var argDescriptions = map[string]string{
+	"hash":          "a hash algorithm",
+	"signhash":      "a hash algorithm or " + psaAnyHash,
+	"mac":           "a full length MAC algorithm",
+	"aead":          "an AEAD algorithm with default length tag",
+	"length":        "a length",
+	"keyagreement":  "a raw key agreement algorithm",
+	"keyderivation": "a key derivation algorithm",
+}
+
+// psaArgs holds the evaluated arguments of a function.
+type psaArgs struct {
+	hash    HashAlgorithmType
+	anyHash bool
+	mac     *MacFullLength
+	aead    AeadAlgorithmType
+	length  uint32
+	ka      KeyAgreementRawType
+	kdf     *KeyDerivation
+}
+
+// algorithm evaluates a parsed expression.
+//
+//nolint:gocyclo
+func (p *psaParser) algorithm(e *psaExpr) (*Algorithm, error) {
+	if e.isNumber {
+		return nil, p.errorf(e.offset, "expected algorithm name, got number %d", e.number)
+	}
+	kinds, isFunction := psaFunctions[e.name]
+	if !isFunction {
+		if len(e.args) > 0 {
+			if _, err := p.constant(e); err != nil {
+				return nil, err
+			}
+			return nil, p.errorf(e.offset, "%v takes no arguments", e.name)
+		}
+		return p.constant(e)
+	}
+	if len(e.args) == 0 {
+		return nil, p.errorf(e.offset, "%v requires arguments", e.name)
+	}
+	if len(e.args) != len(kinds) {
+		return nil, p.errorf(e.offset, "%v takes %d argument(s), got %d", e.name, len(kinds), len(e.args))
+	}
+	args := &psaArgs{}
+	for i, kind := range kinds {
+		if err := p.argument(e.name, e.args[i], kind, args); err != nil {
+			return nil, err
+		}
+	}
+
+	sig := NewAsymmetricSignature()
+	withSignHash := func(specific func(HashAlgorithmType) *Algorithm, anyHash func() *Algorithm) *Algorithm {
+		if args.anyHash {
+			return anyHash()
+		}
+		return specific(args.hash)
+	}
+	switch e.name {
+	case psaHmac:
+		return NewMAC().HMAC(args.hash), nil
+	case psaTruncatedMac:
+		return &Algorithm{variant: &MacAlgorithm{variant: &MacTruncated{MacAlg: args.mac, MacLength: args.length}}}, nil
+	case psaShortenedTag:
+		return NewAead().AeadShortenedTag(args.aead, args.length), nil
+	case psaRsaPkcs1V15Sign:
+		return withSignHash(sig.RsaPkcs1V15Sign, sig.RsaPkcs1V15SignAny), nil
+	case psaRsaPss:
+		return withSignHash(sig.RsaPss, sig.RsaPssAny), nil
+	case psaEcdsa:
+		return withSignHash(sig.Ecdsa, sig.EcdsaAnyHash), nil
+	case psaDetEcdsa:
+		return withSignHash(sig.DeterministicEcdsa, sig.DeterministicEcdsaAny), nil
+	case psaRsaOaep:
+		return NewAsymmetricEncryption().RsaOaep(args.hash), nil
+	case psaHkdf:
+		return NewKeyDerivation().Hkdf(args.hash), nil
+	case psaTLS12Prf:
+		return NewKeyDerivation().TLS12PRF(args.hash), nil
+	case psaTLS12PskToMs:
+		return NewKeyDerivation().TLS12PSKToMs(args.hash), nil
+	case psaKeyAgreement:
+		return &Algorithm{variant: &KeyAgreement{variant: &KeyAgreementWithKeyDerivation{KaAlg: args.ka, DerivationAlg: args.kdf}}}, nil
+	}
+	return nil, p.errorf(e.offset, "unknown algorithm %v", e.name)
+}
+
+// argument evaluates the argument of function fn, which must be of the given kind, storing it in args.
+func (p *psaParser) argument(fn string, e *psaExpr, kind string, args *psaArgs) error {
+	wrongKind := func(got string) error {
+		return p.errorf(e.offset, "%v: expected %v, got %v", fn, argDescriptions[kind], got)
+	}
+	if kind == "length" {
+		if !e.isNumber {
+			return wrongKind(e.name)
+		}
+		if e.number == 0 {
+			return p.errorf(e.offset, "%v: length must not be 0", fn)
+		}
+		args.length = e.number
+		return nil
+	}
+	if e.isNumber {
+		return wrongKind(fmt.Sprintf("number %d", e.number))
+	}
+	if kind == "signhash" && e.name == psaAnyHash && len(e.args) == 0 {
+		args.anyHash = true
+		return nil
+	}
+	alg, err := p.algorithm(e)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "hash", "signhash":
+		if h := alg.GetHash(); h != nil {
+			args.hash = h.HashAlg
+			return nil
+		}
+	case "mac":
+		if m := alg.GetMac(); m != nil {
+			if full, ok := m.variant.(*MacFullLength); ok {
+				args.mac = full
+				return nil
+			}
+		}
+	case "aead":
+		if a := alg.GetAead(); a != nil && a.GetAeadDefaultLengthTag() != nil {
+			args.aead = a.GetAeadDefaultLengthTag().AeadAlg
+			return nil
+		}
+	case "keyagreement":
+		if ka := alg.GetKeyAgreement(); ka != nil && ka.GetRaw() != nil {
+			args.ka = ka.GetRaw().RawAlg
+			return nil
+		}
+	case "keyderivation":
+		if kdf := alg.GetKeyDerivation(); kdf != nil {
+			args.kdf = kdf
+			return nil
+		}
+	}
+	return wrongKind(alg.String())
+}
+
+// constant evaluates an algorithm name that takes no arguments.
+func (p *psaParser) constant(e *psaExpr) (*Algorithm, error) {
+	switch e.name {
+	case psaCbcMac:
+		return NewMAC().CBCMAC(), nil
+	case psaCmac:
+		return NewMAC().CMAC(), nil
+	case psaRsaPkcs1V15Raw:
+		return NewAsymmetricSignature().RsaPkcs1V15SignRaw(), nil
+	case psaEcdsaAny:
+		return NewAsymmetricSignature().EcdsaAny(), nil
+	case psaRsaPkcs1V15Crypt:
+		return NewAsymmetricEncryption().RsaPkcs1V15Crypt(), nil
+	case psaAnyHash:
+		return nil, p.errorf(e.offset, "%v is only valid as the hash of a signature algorithm", psaAnyHash)
+	case psaNone:
+		return nil, p.errorf(e.offset, "%v does not name an algorithm", psaNone)
+	}
+	name := strings.TrimPrefix(e.name, psaPrefix)
+	if !strings.HasPrefix(e.name, psaPrefix) || strings.HasSuffix(name, "NONE") {
+		return nil, p.errorf(e.offset, "unknown algorithm %v", e.name)
+	}
+	if v, ok := psaalgorithm.Algorithm_Hash_value[name]; ok {
+		return NewHashAlgorithm(HashAlgorithmType(v)), nil
+	}
+	if v, ok := psaalgorithm.Algorithm_Cipher_value[name]; ok {
+		return NewCipher(CipherModeType(v)), nil
+	}
+	if v, ok := psaalgorithm.Algorithm_Aead_AeadWithDefaultLengthTag_value[name]; ok {
+		return NewAead().Aead(AeadAlgorithmType(v)), nil
+	}
+	if v, ok := psaalgorithm.Algorithm_KeyAgreement_Raw_value[name]; ok {
+		return &Algorithm{variant: &KeyAgreement{variant: &KeyAgreementRaw{RawAlg: KeyAgreementRawType(v)}}}, nil
+	}
+	return nil, p.errorf(e.offset, "unknown algorithm %v", e.name)
+}
@@ -4,8 +4,10 @@
 package algorithm_test
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaalgorithm"
 	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
 )
 
@@ -42,3 +44,122 @@ func TestAlgorithmSubclasses(t *testing.T) {
 		}
 	}
 }
+
+func TestMacWireRoundTrip(t *testing.T) {
+	wire := algorithm.NewMAC().HMAC(algorithm.HashAlgorithmTypeSHA256).ToWireInterface()
+	alg, err := algorithm.NewAlgorithmFromWireInterface(wire)
+	if err != nil {
+		t.Fatalf("could not convert mac from wire: %v", err)
+	}
+	if alg.GetMac() == nil {
+		t.Fatal("Expected mac algorithm")
+	}
+	if alg.GetCipher() != nil || alg.GetKeyAgreement() != nil || alg.GetKeyDerivation() != nil {
+		t.Error("Expected only to get non nil subtype for mac")
+	}
+}
+
+func TestAeadWireValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		alg       *algorithm.Algorithm
+		want      psaalgorithm.Algorithm_Aead_AeadWithDefaultLengthTag
+		tagLength uint32
+	}{
+		{"ccm", algorithm.NewAead().Aead(algorithm.AeadAlgorithmCCM), psaalgorithm.Algorithm_Aead_CCM, 0},
+		{"gcm", algorithm.NewAead().Aead(algorithm.AeadAlgorithmGCM), psaalgorithm.Algorithm_Aead_GCM, 0},
+		{"chacha20 poly1305", algorithm.NewAead().Aead(algorithm.AeadAlgorithmChacha20Poly1305), psaalgorithm.Algorithm_Aead_CHACHA20_POLY1305, 0},
+		{"gcm shortened tag", algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmGCM, 12), psaalgorithm.Algorithm_Aead_GCM, 12},
+		{"chacha20 poly1305 shortened tag", algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmChacha20Poly1305, 8), psaalgorithm.Algorithm_Aead_CHACHA20_POLY1305, 8},
+	}
+	for _, test := range tests {
+		wire := test.alg.ToWireInterface().(*psaalgorithm.Algorithm).GetAead()
+		if test.tagLength == 0 {
+			if got := wire.GetAeadWithDefaultLengthTag(); got != test.want {
+				t.Errorf("%v: sent as %v, want %v", test.name, got, test.want)
+			}
+		} else {
+			got := wire.GetAeadWithShortenedTag()
+			if got.GetAeadAlg() != test.want || got.GetTagLength() != test.tagLength {
+				t.Errorf("%v: sent as %v with %d byte tag, want %v with %d byte tag", test.name, got.GetAeadAlg(), got.GetTagLength(), test.want, test.tagLength)
+			}
+		}
+		back, err := algorithm.NewAlgorithmFromWireInterface(test.alg.ToWireInterface())
+		if err != nil {
+			t.Errorf("%v: could not convert from wire: %v", test.name, err)
+		} else if !reflect.DeepEqual(back, test.alg) {
+			t.Errorf("%v: got %v back from wire, want %v", test.name, back, test.alg)
+		}
+	}
+}
+
+func TestMacWireValues(t *testing.T) {
+	mac := algorithm.NewMAC()
+	tests := []struct {
+		name   string
+		alg    *algorithm.Algorithm
+		want   string
+		length uint32
+	}{
+		{"hmac", mac.HMAC(algorithm.HashAlgorithmTypeSHA256), "hmac", 0},
+		{"cbc mac", mac.CBCMAC(), "cbc_mac", 0},
+		{"cmac", mac.CMAC(), "cmac", 0},
+		{"truncated hmac", mac.HMACTruncated(algorithm.HashAlgorithmTypeSHA256, 16), "hmac", 16},
+		{"truncated cbc mac", mac.CBCMACTruncated(8), "cbc_mac", 8},
+		{"truncated cmac", mac.CMACTruncated(8), "cmac", 8},
+	}
+	for _, test := range tests {
+		wire := test.alg.ToWireInterface().(*psaalgorithm.Algorithm).GetMac()
+		full := wire.GetFullLength()
+		if test.length != 0 {
+			full = wire.GetTruncated().GetMacAlg()
+			if got := wire.GetTruncated().GetMacLength(); got != test.length {
+				t.Errorf("%v: sent with a %d byte MAC, want %d", test.name, got, test.length)
+			}
+		}
+		got := "none"
+		switch {
+		case full.GetHmac() != nil:
+			got = "hmac"
+		case full.GetCbcMac() != nil:
+			got = "cbc_mac"
+		case full.GetCmac() != nil:
+			got = "cmac"
+		}
+		if got != test.want {
+			t.Errorf("%v: sent as %v, want %v", test.name, got, test.want)
+		}
+		back, err := algorithm.NewAlgorithmFromWireInterface(test.alg.ToWireInterface())
+		if err != nil {
+			t.Errorf("%v: could not convert from wire: %v", test.name, err)
+		} else if !reflect.DeepEqual(back, test.alg) {
+			t.Errorf("%v: got %v back from wire, want %v", test.name, back, test.alg)
+		}
+	}
+}
+
+func TestAeadFromWire(t *testing.T) {
+	wire := &psaalgorithm.Algorithm{
+		Variant: &psaalgorithm.Algorithm_Aead_{
+			Aead: &psaalgorithm.Algorithm_Aead{
+				Variant: &psaalgorithm.Algorithm_Aead_AeadWithShortenedTag_{
+					AeadWithShortenedTag: &psaalgorithm.Algorithm_Aead_AeadWithShortenedTag{
+						AeadAlg:   psaalgorithm.Algorithm_Aead_CHACHA20_POLY1305,
+						TagLength: 8,
+					},
+				},
+			},
+		},
+	}
+	alg, err := algorithm.NewAlgorithmFromWireInterface(wire)
+	if err != nil {
+		t.Fatalf("Could not convert from wire: %v", err)
+	}
+	shortened := alg.GetAead().GetAeadShortenedTag()
+	if shortened == nil {
+		t.Fatal("Expected aead with shortened tag")
+	}
+	if shortened.AeadAlg != algorithm.AeadAlgorithmChacha20Poly1305 || shortened.TagLength != 8 {
+		t.Errorf("Expected AeadAlgorithmChacha20Poly1305 with 8 byte tag but got %v with %d byte tag", shortened.AeadAlg, shortened.TagLength)
+	}
+}
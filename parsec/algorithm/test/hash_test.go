@@ -39,3 +39,29 @@ func TestHashString(t *testing.T) {
 		t.Fatal("Incorrect string value for hash algorithm")
 	}
 }
+
+func TestHashWireRoundTrip(t *testing.T) {
+	wire := algorithm.NewHashAlgorithm(algorithm.HashAlgorithmTypeSHA384).ToWireInterface()
+	alg, err := algorithm.NewAlgorithmFromWireInterface(wire)
+	if err != nil {
+		t.Fatalf("could not convert hash from wire: %v", err)
+	}
+	if alg.GetHash() == nil || alg.GetHash().HashAlg != algorithm.HashAlgorithmTypeSHA384 {
+		t.Fatalf("Expected SHA_384 hash algorithm, got %v", alg.GetHash())
+	}
+}
+
+func TestSignatureHashAlg(t *testing.T) {
+	for name, want := range map[string]algorithm.HashAlgorithmType{
+		"PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_SHA_256)":   algorithm.HashAlgorithmTypeSHA256,
+		"PSA_ALG_RSA_PSS(PSA_ALG_SHA_512)":             algorithm.HashAlgorithmTypeSHA512,
+		"PSA_ALG_DETERMINISTIC_ECDSA(PSA_ALG_SHA_384)": algorithm.HashAlgorithmTypeSHA384,
+		"PSA_ALG_RSA_PSS(PSA_ALG_ANY_HASH)":            algorithm.HashAlgorithmTypeNONE,
+		"PSA_ALG_RSA_PKCS1V15_SIGN_RAW":                algorithm.HashAlgorithmTypeNONE,
+		"PSA_ALG_ECDSA_ANY":                            algorithm.HashAlgorithmTypeNONE,
+	} {
+		if got := mustParse(t, name).GetAsymmetricSignature().HashAlg(); got != want {
+			t.Errorf("%v: expected hash %v, got %v", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package algorithm_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// factoryAlgorithms returns an algorithm built by every factory method, keyed by a description.
+func factoryAlgorithms() map[string]*algorithm.Algorithm {
+	sig := algorithm.NewAsymmetricSignature()
+	enc := algorithm.NewAsymmetricEncryption()
+	aead := algorithm.NewAead()
+	mac := algorithm.NewMAC()
+	kdf := algorithm.NewKeyDerivation()
+	ka := algorithm.NewKeyAgreement()
+	hkdf := kdf.Hkdf(algorithm.HashAlgorithmTypeSHA256).GetKeyDerivation()
+	prf := kdf.TLS12PRF(algorithm.HashAlgorithmTypeSHA384).GetKeyDerivation()
+	return map[string]*algorithm.Algorithm{
+		"hash":                    algorithm.NewHashAlgorithm(algorithm.HashAlgorithmTypeSHA3_512),
+		"cipher":                  algorithm.NewCipher(algorithm.CipherModeCBCPKCS7),
+		"stream cipher":           algorithm.NewCipher(algorithm.CipherModeSTREAMCIPHER),
+		"rsa pkcs1v15 sign":       sig.RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256),
+		"rsa pkcs1v15 sign any":   sig.RsaPkcs1V15SignAny(),
+		"rsa pkcs1v15 sign raw":   sig.RsaPkcs1V15SignRaw(),
+		"rsa pss":                 sig.RsaPss(algorithm.HashAlgorithmTypeSHA512),
+		"rsa pss any":             sig.RsaPssAny(),
+		"ecdsa":                   sig.Ecdsa(algorithm.HashAlgorithmTypeSHA256),
+		"ecdsa any":               sig.EcdsaAny(),
+		"deterministic ecdsa":     sig.DeterministicEcdsa(algorithm.HashAlgorithmTypeSHA384),
+		"deterministic ecdsa any": sig.DeterministicEcdsaAny(),
+		"rsa pkcs1v15 crypt":      enc.RsaPkcs1V15Crypt(),
+		"rsa oaep":                enc.RsaOaep(algorithm.HashAlgorithmTypeSHA1),
+		"ccm":                     aead.Aead(algorithm.AeadAlgorithmCCM),
+		"gcm":                     aead.Aead(algorithm.AeadAlgorithmGCM),
+		"chacha20 poly1305":       aead.Aead(algorithm.AeadAlgorithmChacha20Poly1305),
+		"gcm shortened tag":       aead.AeadShortenedTag(algorithm.AeadAlgorithmGCM, 12),
+		"ccm shortened tag":       aead.AeadShortenedTag(algorithm.AeadAlgorithmCCM, 8),
+		"hmac":                    mac.HMAC(algorithm.HashAlgorithmTypeSHA256),
+		"hmac truncated":          mac.HMACTruncated(algorithm.HashAlgorithmTypeSHA512, 32),
+		"cbc mac":                 mac.CBCMAC(),
+		"cbc mac truncated":       mac.CBCMACTruncated(8),
+		"cmac":                    mac.CMAC(),
+		"cmac truncated":          mac.CMACTruncated(10),
+		"hkdf":                    kdf.Hkdf(algorithm.HashAlgorithmTypeSHA256),
+		"tls12 prf":               kdf.TLS12PRF(algorithm.HashAlgorithmTypeSHA384),
+		"tls12 psk to ms":         kdf.TLS12PSKToMs(algorithm.HashAlgorithmTypeSHA256),
+		"raw ffdh":                ka.RawFFDH(),
+		"raw ecdh":                ka.RawECDH(),
+		"ffdh with prf":           ka.FFDH(prf),
+		"ecdh with hkdf":          ka.ECDH(hkdf),
+	}
+}
+
+func TestAlgorithmJSONRoundTrip(t *testing.T) {
+	for name, alg := range factoryAlgorithms() {
+		data, err := json.Marshal(alg)
+		if err != nil {
+			t.Errorf("%v: could not marshal: %v", name, err)
+			continue
+		}
+		again, err := json.Marshal(alg)
+		if err != nil || string(again) != string(data) {
+			t.Errorf("%v: encoding not stable: %s then %s", name, data, again)
+		}
+		decoded := &algorithm.Algorithm{}
+		if err = json.Unmarshal(data, decoded); err != nil {
+			t.Errorf("%v: could not unmarshal %s: %v", name, data, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, alg) {
+			t.Errorf("%v: %s decoded to a different algorithm", name, data)
+		}
+	}
+}
+
+func TestAlgorithmTextRoundTrip(t *testing.T) {
+	for name, alg := range factoryAlgorithms() {
+		text, err := alg.MarshalText()
+		if err != nil {
+			t.Errorf("%v: could not marshal: %v", name, err)
+			continue
+		}
+		decoded := &algorithm.Algorithm{}
+		if err = decoded.UnmarshalText(text); err != nil {
+			t.Errorf("%v: could not unmarshal %s: %v", name, text, err)
+			continue
+		}
+		if !reflect.DeepEqual(decoded, alg) {
+			t.Errorf("%v: %s decoded to a different algorithm", name, text)
+		}
+	}
+}
+
+func TestAlgorithmJSONEncoding(t *testing.T) {
+	data, err := json.Marshal(algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmGCM, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"aead":{"aead_with_shortened_tag":{"aead_alg":"GCM","tag_length":12}}}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestAlgorithmUnmarshalInvalid(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		err  string
+	}{
+		"not json":       {`ecdsa`, "could not decode algorithm"},
+		"unknown field":  {`{"asymmetric_signature":{"ecdsa":{"hash_alg":{"specific":"SHA_256"}},"colour":1}}`, "colour"},
+		"unknown hash":   {`{"hash":"SHA_999"}`, "SHA_999"},
+		"undefined enum": {`{"hash":99}`, "undefined value 99"},
+		"none hash":      {`{"asymmetric_signature":{"ecdsa":{"hash_alg":{"specific":"HASH_NONE"}}}}`, "HASH_NONE must not be used"},
+		"unset enum":     {`{"aead":{"aead_with_shortened_tag":{"tag_length":12}}}`, "must not be used"},
+		"no variant":     {`{}`, "unknown algorithm"},
+	}
+	for name, tt := range tests {
+		err := (&algorithm.Algorithm{}).UnmarshalJSON([]byte(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%v: got error %v, want error containing %q", name, err, tt.err)
+		}
+	}
+}
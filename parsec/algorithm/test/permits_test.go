@@ -0,0 +1,96 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package algorithm_test
+
+import (
+	"testing"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+func mustParse(t *testing.T, s string) *algorithm.Algorithm {
+	t.Helper()
+	alg, err := algorithm.Parse(s)
+	if err != nil {
+		t.Fatalf("could not parse %v: %v", s, err)
+	}
+	return alg
+}
+
+func TestPermits(t *testing.T) {
+	tests := []struct {
+		policy    string
+		requested string
+		permits   bool
+		verify    bool
+	}{
+		{"PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_ANY_HASH)", "PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_SHA_256)", true, true},
+		{"PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_ANY_HASH)", "PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_ANY_HASH)", true, true},
+		{"PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_ANY_HASH)", "PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)", false, false},
+		{"PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_ANY_HASH)", "PSA_ALG_RSA_PKCS1V15_SIGN_RAW", false, false},
+		{"PSA_ALG_RSA_PKCS1V15_SIGN_RAW", "PSA_ALG_RSA_PKCS1V15_SIGN_RAW", true, true},
+		{"PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)", "PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)", true, true},
+		{"PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)", "PSA_ALG_RSA_PSS(PSA_ALG_SHA_384)", false, false},
+		{"PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)", "PSA_ALG_RSA_PSS(PSA_ALG_ANY_HASH)", false, false},
+		{"PSA_ALG_DETERMINISTIC_ECDSA(PSA_ALG_ANY_HASH)", "PSA_ALG_DETERMINISTIC_ECDSA(PSA_ALG_SHA_512)", true, true},
+		{"PSA_ALG_ECDSA(PSA_ALG_SHA_256)", "PSA_ALG_DETERMINISTIC_ECDSA(PSA_ALG_SHA_256)", false, true},
+		{"PSA_ALG_DETERMINISTIC_ECDSA(PSA_ALG_SHA_256)", "PSA_ALG_ECDSA(PSA_ALG_SHA_256)", false, true},
+		{"PSA_ALG_DETERMINISTIC_ECDSA(PSA_ALG_ANY_HASH)", "PSA_ALG_ECDSA(PSA_ALG_SHA_384)", false, true},
+		{"PSA_ALG_ECDSA(PSA_ALG_SHA_256)", "PSA_ALG_DETERMINISTIC_ECDSA(PSA_ALG_SHA_384)", false, false},
+		{"PSA_ALG_ECDSA_ANY", "PSA_ALG_ECDSA(PSA_ALG_SHA_256)", false, false},
+		{"PSA_ALG_ECDSA_ANY", "PSA_ALG_ECDSA_ANY", true, true},
+		{"PSA_ALG_GCM", "PSA_ALG_GCM", true, true},
+		{"PSA_ALG_GCM", "PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 16)", true, true},
+		{"PSA_ALG_GCM", "PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)", false, false},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)", "PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 14)", true, true},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)", "PSA_ALG_GCM", true, true},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)", "PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 8)", false, false},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)", "PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 20)", false, false},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)", "PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_CCM, 12)", false, false},
+		{"PSA_ALG_HMAC(PSA_ALG_SHA_256)", "PSA_ALG_HMAC(PSA_ALG_SHA_256)", true, true},
+		{"PSA_ALG_HMAC(PSA_ALG_SHA_256)", "PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 16)", false, false},
+		{"PSA_ALG_HMAC(PSA_ALG_SHA_256)", "PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 32)", true, true},
+		{"PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 16)", "PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 20)", true, true},
+		{"PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 16)", "PSA_ALG_HMAC(PSA_ALG_SHA_256)", true, true},
+		{"PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 16)", "PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 10)", false, false},
+		{"PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 16)", "PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_512), 16)", false, false},
+		{"PSA_ALG_TRUNCATED_MAC(PSA_ALG_CMAC, 8)", "PSA_ALG_CMAC", true, true},
+		{"PSA_ALG_TRUNCATED_MAC(PSA_ALG_CMAC, 8)", "PSA_ALG_TRUNCATED_MAC(PSA_ALG_CBC_MAC, 8)", false, false},
+		{"PSA_ALG_CBC_NO_PADDING", "PSA_ALG_CBC_NO_PADDING", true, true},
+		{"PSA_ALG_CBC_NO_PADDING", "PSA_ALG_CBC_PKCS7", false, false},
+		{"PSA_ALG_RSA_OAEP(PSA_ALG_SHA_256)", "PSA_ALG_RSA_OAEP(PSA_ALG_SHA_256)", true, true},
+		{"PSA_ALG_RSA_OAEP(PSA_ALG_SHA_256)", "PSA_ALG_RSA_OAEP(PSA_ALG_SHA_1)", false, false},
+		{"PSA_ALG_ECDH", "PSA_ALG_KEY_AGREEMENT(PSA_ALG_ECDH, PSA_ALG_HKDF(PSA_ALG_SHA_256))", false, false},
+	}
+	for _, test := range tests {
+		policy, requested := mustParse(t, test.policy), mustParse(t, test.requested)
+		if got := algorithm.Permits(policy, requested); got != test.permits {
+			t.Errorf("Permits(%v, %v) = %v, want %v", test.policy, test.requested, got, test.permits)
+		}
+		if got := algorithm.PermitsVerification(policy, requested); got != test.verify {
+			t.Errorf("PermitsVerification(%v, %v) = %v, want %v", test.policy, test.requested, got, test.verify)
+		}
+	}
+}
+
+func TestPermitsNone(t *testing.T) {
+	alg := algorithm.NewCipher(algorithm.CipherModeCTR)
+	if algorithm.Permits(nil, alg) {
+		t.Error("nil policy should not permit an algorithm")
+	}
+	if algorithm.Permits(alg, nil) {
+		t.Error("policy should not permit a nil algorithm")
+	}
+	if algorithm.Permits(&algorithm.Algorithm{}, &algorithm.Algorithm{}) {
+		t.Error("empty policy should not permit an empty algorithm")
+	}
+}
+
+func TestPermitsFactoryAlgorithms(t *testing.T) {
+	for name, alg := range factoryAlgorithms() {
+		if !algorithm.Permits(alg, alg) {
+			t.Errorf("%v: %v does not permit itself", name, alg)
+		}
+	}
+}
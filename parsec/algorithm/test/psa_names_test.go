@@ -0,0 +1,117 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package algorithm_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+func TestPSANameRoundTrip(t *testing.T) {
+	for name, alg := range factoryAlgorithms() {
+		s := alg.String()
+		parsed, err := algorithm.Parse(s)
+		if err != nil {
+			t.Errorf("%v: could not parse %v: %v", name, s, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, alg) {
+			t.Errorf("%v: %v parsed to %v", name, s, parsed)
+		}
+	}
+}
+
+func TestPSANames(t *testing.T) {
+	kdf := algorithm.NewKeyDerivation().Hkdf(algorithm.HashAlgorithmTypeSHA256).GetKeyDerivation()
+	tests := map[string]*algorithm.Algorithm{
+		"PSA_ALG_SHA_256":                                                    algorithm.NewHashAlgorithm(algorithm.HashAlgorithmTypeSHA256),
+		"PSA_ALG_CBC_PKCS7":                                                  algorithm.NewCipher(algorithm.CipherModeCBCPKCS7),
+		"PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)":                                   algorithm.NewAsymmetricSignature().RsaPss(algorithm.HashAlgorithmTypeSHA256),
+		"PSA_ALG_RSA_PSS(PSA_ALG_ANY_HASH)":                                  algorithm.NewAsymmetricSignature().RsaPssAny(),
+		"PSA_ALG_ECDSA_ANY":                                                  algorithm.NewAsymmetricSignature().EcdsaAny(),
+		"PSA_ALG_ECDSA(PSA_ALG_ANY_HASH)":                                    algorithm.NewAsymmetricSignature().EcdsaAnyHash(),
+		"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)":                   algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmGCM, 12),
+		"PSA_ALG_CHACHA20_POLY1305":                                          algorithm.NewAead().Aead(algorithm.AeadAlgorithmChacha20Poly1305),
+		"PSA_ALG_TRUNCATED_MAC(PSA_ALG_HMAC(PSA_ALG_SHA_256), 16)":           algorithm.NewMAC().HMACTruncated(algorithm.HashAlgorithmTypeSHA256, 16),
+		"PSA_ALG_KEY_AGREEMENT(PSA_ALG_ECDH, PSA_ALG_HKDF(PSA_ALG_SHA_256))": algorithm.NewKeyAgreement().ECDH(kdf),
+	}
+	for name, alg := range tests {
+		if s := alg.String(); s != name {
+			t.Errorf("got name %v, want %v", s, name)
+		}
+		parsed, err := algorithm.Parse(name)
+		if err != nil {
+			t.Errorf("could not parse %v: %v", name, err)
+		} else if !reflect.DeepEqual(parsed, alg) {
+			t.Errorf("%v parsed to %v", name, parsed)
+		}
+	}
+}
+
+func TestPSANameParseLeniency(t *testing.T) {
+	want := algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmCCM, 8)
+	alg, err := algorithm.Parse(" psa_alg_aead_with_shortened_tag( PSA_ALG_CCM ,8 ) ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(alg, want) {
+		t.Errorf("got %v, want %v", alg, want)
+	}
+}
+
+func TestPSANameParseErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+		msg    string
+	}{
+		{"", 0, "expected algorithm name, got end of input"},
+		{"PSA_ALG_SHA_999", 0, "unknown algorithm PSA_ALG_SHA_999"},
+		{"SHA_256", 0, "unknown algorithm SHA_256"},
+		{"PSA_ALG_HASH_NONE", 0, "unknown algorithm PSA_ALG_HASH_NONE"},
+		{"PSA_ALG_NONE", 0, "PSA_ALG_NONE does not name an algorithm"},
+		{"PSA_ALG_RSA_PSS", 0, "PSA_ALG_RSA_PSS requires arguments"},
+		{"PSA_ALG_RSA_PSS(PSA_ALG_GCM)", 16, "PSA_ALG_RSA_PSS: expected a hash algorithm or PSA_ALG_ANY_HASH, got PSA_ALG_GCM"},
+		{"PSA_ALG_RSA_PSS(PSA_ALG_SHA_256, PSA_ALG_SHA_1)", 0, "PSA_ALG_RSA_PSS takes 1 argument(s), got 2"},
+		{"PSA_ALG_RSA_PSS(PSA_ALG_SHA_256", 31, "expected ',' or ')', got end of input"},
+		{"PSA_ALG_RSA_PSS(PSA_ALG_SHA_256))", 32, `unexpected ")" after algorithm`},
+		{"PSA_ALG_ECDSA_ANY(PSA_ALG_SHA_256)", 0, "PSA_ALG_ECDSA_ANY takes no arguments"},
+		{"PSA_ALG_ANY_HASH", 0, "PSA_ALG_ANY_HASH is only valid as the hash of a signature algorithm"},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, PSA_ALG_CCM)", 45, "expected a length, got PSA_ALG_CCM"},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 0)", 45, "length must not be 0"},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 99999999999)", 45, "invalid number 99999999999"},
+		{"PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 8), 4)", 32,
+			"expected an AEAD algorithm with default length tag"},
+		{"PSA_ALG_TRUNCATED_MAC(PSA_ALG_SHA_256, 16)", 22, "expected a full length MAC algorithm, got PSA_ALG_SHA_256"},
+		{"PSA_ALG_KEY_AGREEMENT(PSA_ALG_HKDF(PSA_ALG_SHA_256), PSA_ALG_ECDH)", 22, "expected a raw key agreement algorithm"},
+		{"PSA_ALG_HMAC(PSA_ALG_SHA_999)", 13, "unknown algorithm PSA_ALG_SHA_999"},
+		{"PSA_ALG_HMAC(12)", 13, "expected a hash algorithm, got number 12"},
+		{"PSA_ALG_HMAC[PSA_ALG_SHA_256]", 12, `unexpected "[PSA_ALG_SHA_256]" after algorithm`},
+	}
+	for _, tt := range tests {
+		_, err := algorithm.Parse(tt.input)
+		var parseErr *algorithm.ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%q: got error %v, want *ParseError", tt.input, err)
+			continue
+		}
+		if parseErr.Offset != tt.offset || !strings.Contains(parseErr.Msg, tt.msg) {
+			t.Errorf("%q: got %v, want offset %d and message containing %q", tt.input, err, tt.offset, tt.msg)
+		}
+	}
+}
+
+func TestAlgorithmUnmarshalTextPSAName(t *testing.T) {
+	alg := &algorithm.Algorithm{}
+	if err := alg.UnmarshalText([]byte("PSA_ALG_ECDSA(PSA_ALG_SHA_384)")); err != nil {
+		t.Fatal(err)
+	}
+	if want := algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA384); !reflect.DeepEqual(alg, want) {
+		t.Errorf("got %v, want %v", alg, want)
+	}
+}
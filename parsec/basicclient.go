@@ -6,6 +6,7 @@ package parsec
 import (
 	"fmt"
 	"reflect"
+	"sync/atomic"
 
 	"github.com/parallaxsecond/parsec-client-go/interface/auth"
 	"github.com/parallaxsecond/parsec-client-go/interface/operations"
@@ -13,11 +14,17 @@ import (
 	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
 )
 
+// AeadNonceLength is the nonce length of GCM and ChaCha20-Poly1305.  It is not a length for every AEAD algorithm:
+// CCM nonces are between 7 and 13 bytes.
+const AeadNonceLength = 12
+
 // BasicClient is a Parsec client representing a connection and set of API implementations
 type BasicClient struct {
-	opclient         *operations.Client
-	auth             Authenticator
-	implicitProvider ProviderID
+	opclient *operations.Client
+	auth     Authenticator
+	// implicitProvider holds a ProviderID.  It may be changed while operations are in progress.
+	implicitProvider atomic.Uint32
+	capabilities     *capabilityCache
 }
 
 // CreateNakedClient creates a Parsec client, setting implicit provider to ProviderCore and
@@ -27,16 +34,17 @@ func CreateNakedClient() (*BasicClient, error) {
 	if err != nil {
 		return nil, err
 	}
-	bc := BasicClient{
-		opclient:         opclient,
-		implicitProvider: ProviderCore,
-		auth:             NewNoAuthAuthenticator(),
+	bc := &BasicClient{
+		opclient:     opclient,
+		auth:         NewNoAuthAuthenticator(),
+		capabilities: newCapabilityCache(0),
 	}
-	return &bc, nil
+	return bc, nil
 }
 
 // CreateConfiguredClient initializes a Parsec client
-// This will autoselect the first provider returned by the parsec service.  It will also attempt to
+// This will autoselect the first provider returned by the parsec service that meets the preferences and
+// requirements in the config.  It will also attempt to
 // select the first available authenticator it can configure.  The config can either be a *ClientConfig or a string.
 // If it is a string, then this is used as an application name if the default authenticator is the Direct Authenticator - it will be ignored otherwise.
 // If nil is passed, then the client will try and find the first supported authenticator that requires no configuration.
@@ -55,27 +63,32 @@ func CreateConfiguredClient(config interface{}) (*BasicClient, error) {
 		}
 	}
 
+	interceptors := clientConfig.interceptors
+	if clientConfig.logger != nil {
+		// Logging is innermost so that it records the operation as sent after any other interceptors have run.
+		interceptors = append(append([]operations.Interceptor{}, interceptors...), newLoggingInterceptor(clientConfig.logger))
+	}
 	var opclient *operations.Client
 	var err error
 	if clientConfig.connection == nil {
-		opclient, err = operations.InitClient()
+		opclient, err = operations.InitClient(interceptors...)
 	} else {
-		opclient, err = operations.InitClientFromConnection(clientConfig.connection)
+		opclient, err = operations.InitClientFromConnection(clientConfig.connection, interceptors...)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	bc := BasicClient{
-		opclient:         opclient,
-		implicitProvider: ProviderCore,
-		auth:             NewNoAuthAuthenticator(),
+	bc := &BasicClient{
+		opclient:     opclient,
+		auth:         NewNoAuthAuthenticator(),
+		capabilities: newCapabilityCache(clientConfig.capabilityRetry),
 	}
 
 	if clientConfig.defaultProvider != nil {
-		bc.implicitProvider = *clientConfig.defaultProvider
+		bc.SetImplicitProvider(*clientConfig.defaultProvider)
 	} else {
-		err = bc.selectDefaultProvider()
+		err = bc.selectDefaultProvider(&clientConfig.selection)
 		if err != nil {
 			return nil, err
 		}
@@ -90,7 +103,7 @@ func CreateConfiguredClient(config interface{}) (*BasicClient, error) {
 		}
 	}
 
-	return &bc, nil
+	return bc, nil
 }
 
 // Close the client and any underlying connections
@@ -98,27 +111,15 @@ func (c *BasicClient) Close() error {
 	return c.opclient.Close()
 }
 
-// SetImplicitProvider sets the provider to use for non-core operations
+// SetImplicitProvider sets the provider to use for non-core operations.  It is safe to call while operations are
+// in progress on other goroutines, but to use a different provider for a single call use WithProvider instead.
 func (c *BasicClient) SetImplicitProvider(provider ProviderID) {
-	c.implicitProvider = provider
+	c.implicitProvider.Store(uint32(provider))
 }
 
 // GetImplicitProvider returns the provider used for non-core operations
 func (c *BasicClient) GetImplicitProvider() ProviderID {
-	return c.implicitProvider
-}
-
-// selectDefaultProvider will request the list of providers from the parsec service and then select the first one.
-func (c *BasicClient) selectDefaultProvider() error {
-	c.implicitProvider = ProviderCore // We know this one is always present.
-	availableProviders, err := c.ListProviders()
-	if err != nil {
-		return err
-	}
-	if len(availableProviders) > 0 {
-		c.implicitProvider = availableProviders[0].ID
-	}
-	return nil
+	return ProviderID(c.implicitProvider.Load())
 }
 
 // selectDefaultAuthenticator will request the list of authenticators from the parsec service and will then select
@@ -157,13 +158,15 @@ func (c *BasicClient) GetAuthenticatorType() AuthenticatorType {
 }
 
 // Ping server and return wire protocol major and minor version number
-func (c BasicClient) Ping() (uint8, uint8, error) { //nolint:gocritic
-	return c.opclient.Ping(requests.ProviderCore, c.auth.toNativeAuthenticator())
+func (c *BasicClient) Ping(opts ...CallOption) (uint8, uint8, error) { //nolint:gocritic
+	o := c.callOptions(opts)
+	return o.opclient.Ping(requests.ProviderCore, o.nativeAuth())
 }
 
 // ListProviders returns a list of the providers supported by the server.
-func (c BasicClient) ListProviders() ([]*ProviderInfo, error) {
-	nativeProv, err := c.opclient.ListProviders(requests.ProviderCore, c.auth.toNativeAuthenticator())
+func (c *BasicClient) ListProviders(opts ...CallOption) ([]*ProviderInfo, error) {
+	o := c.callOptions(opts)
+	nativeProv, err := o.opclient.ListProviders(requests.ProviderCore, o.nativeAuth())
 	if err != nil {
 		return nil, err
 	}
@@ -175,23 +178,27 @@ func (c BasicClient) ListProviders() ([]*ProviderInfo, error) {
 }
 
 // ListOpcodes list the opcodes for a provider
-func (c BasicClient) ListOpcodes(providerID ProviderID) ([]uint32, error) {
-	return c.opclient.ListOpcodes(requests.ProviderCore, c.auth.toNativeAuthenticator(), uint32(providerID))
+func (c *BasicClient) ListOpcodes(providerID ProviderID, opts ...CallOption) ([]uint32, error) {
+	o := c.callOptions(opts)
+	return o.opclient.ListOpcodes(requests.ProviderCore, o.nativeAuth(), uint32(providerID))
 }
 
 // ListClients lists the clients.  Requires admin privileges
-func (c BasicClient) ListClients() ([]string, error) {
-	return c.opclient.ListClients(requests.ProviderID(ProviderCore), c.auth.toNativeAuthenticator())
+func (c *BasicClient) ListClients(opts ...CallOption) ([]string, error) {
+	o := c.callOptions(opts)
+	return o.opclient.ListClients(requests.ProviderCore, o.nativeAuth())
 }
 
 // Delete a client.  Requires admin privileges
-func (c BasicClient) DeleteClient(client string) error {
-	return c.opclient.DeleteClient(requests.ProviderID(ProviderCore), c.auth.toNativeAuthenticator(), client)
+func (c *BasicClient) DeleteClient(client string, opts ...CallOption) error {
+	o := c.callOptions(opts)
+	return o.opclient.DeleteClient(requests.ProviderCore, o.nativeAuth(), client)
 }
 
 // ListKeys obtain keys stored for current application
-func (c BasicClient) ListKeys() ([]*KeyInfo, error) {
-	retkeys, err := c.opclient.ListKeys(requests.ProviderCore, c.auth.toNativeAuthenticator())
+func (c *BasicClient) ListKeys(opts ...CallOption) ([]*KeyInfo, error) {
+	o := c.callOptions(opts)
+	retkeys, err := o.opclient.ListKeys(requests.ProviderCore, o.nativeAuth())
 	if err != nil {
 		return nil, err
 	}
@@ -207,8 +214,9 @@ func (c BasicClient) ListKeys() ([]*KeyInfo, error) {
 }
 
 // ListAuthenticators obtain authenticators supported by server
-func (c BasicClient) ListAuthenticators() ([]*AuthenticatorInfo, error) {
-	retauths, err := c.opclient.ListAuthenticators(requests.ProviderCore, c.auth.toNativeAuthenticator())
+func (c *BasicClient) ListAuthenticators(opts ...CallOption) ([]*AuthenticatorInfo, error) {
+	o := c.callOptions(opts)
+	retauths, err := o.opclient.ListAuthenticators(requests.ProviderCore, o.nativeAuth())
 	if err != nil {
 		return nil, err
 	}
@@ -223,225 +231,284 @@ func (c BasicClient) ListAuthenticators() ([]*AuthenticatorInfo, error) {
 	return auths, nil
 }
 
-// PsaGenerateKey create key named name with attributes
-func (c BasicClient) PsaGenerateKey(name string, attributes *KeyAttributes) error {
-	if !c.implicitProvider.HasCrypto() {
-		return fmt.Errorf("provider does not support crypto operation")
+// PsaGenerateKey create key named name with attributes.  The attributes are checked with KeyAttributes.Validate
+// before the request is sent.
+func (c *BasicClient) PsaGenerateKey(name string, attributes *KeyAttributes, opts ...CallOption) error {
+	if err := attributes.validateForGenerate(); err != nil {
+		return err
 	}
-
 	ka, err := attributes.toWireInterface()
-
 	if err != nil {
 		return err
 	}
-	fmt.Printf("keyattributes: %+v\n", ka)
-	return c.opclient.PsaGenerateKey(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), name, ka)
+	o := c.callOptions(opts)
+	err = c.checkCryptoOperation(OpPsaGenerateKey, o)
+	if err != nil {
+		return err
+	}
+	return o.opclient.PsaGenerateKey(o.nativeProvider(), o.nativeAuth(), name, ka)
 }
 
 // PsaDestroyKey destroys a key with given name
-func (c BasicClient) PsaDestroyKey(name string) error {
-	if !c.implicitProvider.HasCrypto() {
-		return fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaDestroyKey(name string, opts ...CallOption) error {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaDestroyKey, o); err != nil {
+		return err
 	}
-	return c.opclient.PsaDestroyKey(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), name)
+	return o.opclient.PsaDestroyKey(o.nativeProvider(), o.nativeAuth(), name)
 }
 
 // PsaHashCompute calculates a hash of a message using specified algorithm
-func (c BasicClient) PsaHashCompute(message []byte, alg algorithm.HashAlgorithmType) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaHashCompute(message []byte, alg algorithm.HashAlgorithmType, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaHashCompute, o); err != nil {
+		return nil, err
 	}
-	return c.opclient.PsaHashCompute(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), message, hashAlgToWire(alg))
+	return o.opclient.PsaHashCompute(o.nativeProvider(), o.nativeAuth(), message, hashAlgToWire(alg))
 }
 
-// PsaSignMessage signs message using signingKey and algorithm, returning the signature.
-func (c BasicClient) PsaSignMessage(signingKey string, message []byte, alg *algorithm.AsymmetricSignatureAlgorithm) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+// PsaSignMessage signs message using signingKey and algorithm, returning the signature.  ECDSA signatures are r
+// followed by s unless WithDERSignature is given.
+func (c *BasicClient) PsaSignMessage(signingKey string, message []byte, alg *algorithm.AsymmetricSignatureAlgorithm, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaSignMessage, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algAsymmetricSigToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaSignMessage(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), signingKey, message, opalg)
+	curve, err := c.derSignatureCurve(signingKey, alg, o, opts)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := o.opclient.PsaSignMessage(o.nativeProvider(), o.nativeAuth(), signingKey, message, opalg)
+	if err != nil {
+		return nil, err
+	}
+	return curve.toDER(signature)
 }
 
-// PsaSignHash signs hash using signingKey and algorithm, returning the signature.
-func (c BasicClient) PsaSignHash(signingKey string, hash []byte, alg *algorithm.AsymmetricSignatureAlgorithm) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+// PsaSignHash signs hash using signingKey and algorithm, returning the signature.  ECDSA signatures are r followed
+// by s unless WithDERSignature is given.
+func (c *BasicClient) PsaSignHash(signingKey string, hash []byte, alg *algorithm.AsymmetricSignatureAlgorithm, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaSignHash, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algAsymmetricSigToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaSignHash(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), signingKey, hash, opalg)
+	curve, err := c.derSignatureCurve(signingKey, alg, o, opts)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := o.opclient.PsaSignHash(o.nativeProvider(), o.nativeAuth(), signingKey, hash, opalg)
+	if err != nil {
+		return nil, err
+	}
+	return curve.toDER(signature)
 }
 
 // PsaVerifyMessage verify a signature  of message with verifyingKey using signature algorithm alg.
-func (c BasicClient) PsaVerifyMessage(verifyingKey string, message, signature []byte, alg *algorithm.AsymmetricSignatureAlgorithm) error {
-	if !c.implicitProvider.HasCrypto() {
-		return fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaVerifyMessage(verifyingKey string, message, signature []byte, alg *algorithm.AsymmetricSignatureAlgorithm, opts ...CallOption) error {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaVerifyMessage, o); err != nil {
+		return err
 	}
 	opalg, err := algAsymmetricSigToWire(alg)
 	if err != nil {
 		return err
 	}
-	return c.opclient.PsaVerifyMessage(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), verifyingKey, message, signature, opalg)
+	curve, err := c.derSignatureCurve(verifyingKey, alg, o, opts)
+	if err != nil {
+		return err
+	}
+	if signature, err = curve.fromDER(signature); err != nil {
+		return err
+	}
+	return o.opclient.PsaVerifyMessage(o.nativeProvider(), o.nativeAuth(), verifyingKey, message, signature, opalg)
 }
 
 // PsaVerifyHash verify a signature  of hash with verifyingKey using signature algorithm alg.
-func (c BasicClient) PsaVerifyHash(verifyingKey string, hash, signature []byte, alg *algorithm.AsymmetricSignatureAlgorithm) error {
-	if !c.implicitProvider.HasCrypto() {
-		return fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaVerifyHash(verifyingKey string, hash, signature []byte, alg *algorithm.AsymmetricSignatureAlgorithm, opts ...CallOption) error {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaVerifyHash, o); err != nil {
+		return err
 	}
 	opalg, err := algAsymmetricSigToWire(alg)
 	if err != nil {
 		return err
 	}
-	return c.opclient.PsaVerifyHash(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), verifyingKey, hash, signature, opalg)
+	curve, err := c.derSignatureCurve(verifyingKey, alg, o, opts)
+	if err != nil {
+		return err
+	}
+	if signature, err = curve.fromDER(signature); err != nil {
+		return err
+	}
+	return o.opclient.PsaVerifyHash(o.nativeProvider(), o.nativeAuth(), verifyingKey, hash, signature, opalg)
 }
 
 // PsaCipherEncrypt carries out symmetric encryption on plaintext using defined key/algorithm, returning ciphertext
-func (c BasicClient) PsaCipherEncrypt(keyName string, alg *algorithm.Cipher, plaintext []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaCipherEncrypt(keyName string, alg *algorithm.Cipher, plaintext []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaCipherEncrypt, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algCipherAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaCipherEncrypt(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, plaintext)
+	return o.opclient.PsaCipherEncrypt(o.nativeProvider(), o.nativeAuth(), keyName, opalg, plaintext)
 }
 
 // PsaCipherDecrypt decrypts symmetrically encrypted ciphertext using defined key/algorithm, returning plaintext
-func (c BasicClient) PsaCipherDecrypt(keyName string, alg *algorithm.Cipher, ciphertext []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaCipherDecrypt(keyName string, alg *algorithm.Cipher, ciphertext []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaCipherDecrypt, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algCipherAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaCipherDecrypt(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, ciphertext)
+	return o.opclient.PsaCipherDecrypt(o.nativeProvider(), o.nativeAuth(), keyName, opalg, ciphertext)
 }
 
 // PsaAeadDecrypt decrypts Aead encrypted cipher text and validates authenticates over nonce, additionalData and plaintext.  Returns plaintext
-func (c BasicClient) PsaAeadDecrypt(keyName string, alg *algorithm.AeadAlgorithm, nonce, additionalData, ciphertext []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaAeadDecrypt(keyName string, alg *algorithm.AeadAlgorithm, nonce, additionalData, ciphertext []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaAeadDecrypt, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algAeadAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaAeadDecrypt(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, nonce, additionalData, ciphertext)
+	return o.opclient.PsaAeadDecrypt(o.nativeProvider(), o.nativeAuth(), keyName, opalg, nonce, additionalData, ciphertext)
 }
 
 // PsaAeadEncrypt encrypts plaintext and provides authentication protection to plaintext, nonce and additionalData, returns ciphertext
-func (c BasicClient) PsaAeadEncrypt(keyName string, alg *algorithm.AeadAlgorithm, nonce, additionalData, plaintext []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaAeadEncrypt(keyName string, alg *algorithm.AeadAlgorithm, nonce, additionalData, plaintext []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaAeadEncrypt, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algAeadAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaAeadEncrypt(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, nonce, additionalData, plaintext)
+	return o.opclient.PsaAeadEncrypt(o.nativeProvider(), o.nativeAuth(), keyName, opalg, nonce, additionalData, plaintext)
 }
 
 // PsaExportKey exports the key, if it is exportable.
-func (c BasicClient) PsaExportKey(keyName string) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaExportKey(keyName string, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaExportKey, o); err != nil {
+		return nil, err
 	}
-	return c.opclient.PsaExportKey(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName)
+	return o.opclient.PsaExportKey(o.nativeProvider(), o.nativeAuth(), keyName)
 }
 
-// PsaImportKey imports a key and gives it the specified attributes
-func (c BasicClient) PsaImportKey(keyName string, attributes *KeyAttributes, data []byte) error {
-	if !c.implicitProvider.HasCrypto() {
-		return fmt.Errorf("provider does not support crypto operation")
+// PsaImportKey imports a key and gives it the specified attributes.  The attributes are checked with
+// KeyAttributes.Validate before the request is sent.
+func (c *BasicClient) PsaImportKey(keyName string, attributes *KeyAttributes, data []byte, opts ...CallOption) error {
+	if err := attributes.Validate(); err != nil {
+		return err
 	}
 	opattrs, err := attributes.toWireInterface()
 	if err != nil {
 		return err
 	}
-	return c.opclient.PsaImportKey(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opattrs, data)
+	o := c.callOptions(opts)
+	err = c.checkCryptoOperation(OpPsaImportKey, o)
+	if err != nil {
+		return err
+	}
+	return o.opclient.PsaImportKey(o.nativeProvider(), o.nativeAuth(), keyName, opattrs, data)
 }
 
 // PsaExportPublicKey exports a public key.
-func (c BasicClient) PsaExportPublicKey(keyName string) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaExportPublicKey(keyName string, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaExportPublicKey, o); err != nil {
+		return nil, err
 	}
-	return c.opclient.PsaExportPublicKey(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName)
+	return o.opclient.PsaExportPublicKey(o.nativeProvider(), o.nativeAuth(), keyName)
 }
 
 // PsaGenerateRandom generates size bytes of random data
-func (c BasicClient) PsaGenerateRandom(size uint64) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaGenerateRandom(size uint64, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaGenerateRandom, o); err != nil {
+		return nil, err
 	}
-	return c.opclient.PsaGenerateRandom(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), size)
+	return o.opclient.PsaGenerateRandom(o.nativeProvider(), o.nativeAuth(), size)
 }
 
 // PsaMACCompute computes a mac over the input, using defined key, using the defined algorithm.  Returns the mac.
-func (c BasicClient) PsaMACCompute(keyName string, alg *algorithm.MacAlgorithm, input []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaMACCompute(keyName string, alg *algorithm.MacAlgorithm, input []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaMacCompute, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algMacAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaMACCompute(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, input)
+	return o.opclient.PsaMACCompute(o.nativeProvider(), o.nativeAuth(), keyName, opalg, input)
 }
 
 // PsaMACVerify verifies the supplied mac matches the input, for the defined key and algorithm.
-func (c BasicClient) PsaMACVerify(keyName string, alg *algorithm.MacAlgorithm, input, mac []byte) error {
-	if !c.implicitProvider.HasCrypto() {
-		return fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaMACVerify(keyName string, alg *algorithm.MacAlgorithm, input, mac []byte, opts ...CallOption) error {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaMacVerify, o); err != nil {
+		return err
 	}
 	opalg, err := algMacAlgToWire(alg)
 	if err != nil {
 		return err
 	}
-	return c.opclient.PsaMACVerify(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, input, mac)
+	return o.opclient.PsaMACVerify(o.nativeProvider(), o.nativeAuth(), keyName, opalg, input, mac)
 }
 
 // PsaRawKeyAgreement creates a key agreement using specified algorithm and keys.
-func (c BasicClient) PsaRawKeyAgreement(alg *algorithm.KeyAgreementRaw, privateKey string, peerKey []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaRawKeyAgreement(alg *algorithm.KeyAgreementRaw, privateKey string, peerKey []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaRawKeyAgreement, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algKeyAgreementRawAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaRawKeyAgreement(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), opalg.GetRaw().Enum(), privateKey, peerKey)
+	return o.opclient.PsaRawKeyAgreement(o.nativeProvider(), o.nativeAuth(), opalg.GetRaw().Enum(), privateKey, peerKey)
 }
 
 // PsaAsymmetricDecrypt decrypt ciphertext using specified key and asymmetric algorithm.  Returns plaintext.
-func (c BasicClient) PsaAsymmetricDecrypt(keyName string, alg *algorithm.AsymmetricEncryptionAlgorithm, salt, ciphertext []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaAsymmetricDecrypt(keyName string, alg *algorithm.AsymmetricEncryptionAlgorithm, salt, ciphertext []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaAsymmetricDecrypt, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algAsymmetricEncryptionAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaAsymmetricDecrypt(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, salt, ciphertext)
+	return o.opclient.PsaAsymmetricDecrypt(o.nativeProvider(), o.nativeAuth(), keyName, opalg, salt, ciphertext)
 }
 
 // PsaAsymmetricEncrypt encrypt plaintext using specified asymmetric key and algorithm.  Returns ciphertext.
-func (c BasicClient) PsaAsymmetricEncrypt(keyName string, alg *algorithm.AsymmetricEncryptionAlgorithm, salt, plaintext []byte) ([]byte, error) {
-	if !c.implicitProvider.HasCrypto() {
-		return nil, fmt.Errorf("provider does not support crypto operation")
+func (c *BasicClient) PsaAsymmetricEncrypt(keyName string, alg *algorithm.AsymmetricEncryptionAlgorithm, salt, plaintext []byte, opts ...CallOption) ([]byte, error) {
+	o := c.callOptions(opts)
+	if err := c.checkCryptoOperation(OpPsaAsymmetricEncrypt, o); err != nil {
+		return nil, err
 	}
 	opalg, err := algAsymmetricEncryptionAlgToWire(alg)
 	if err != nil {
 		return nil, err
 	}
-	return c.opclient.PsaAsymmetricEncrypt(requests.ProviderID(c.implicitProvider), c.auth.toNativeAuthenticator(), keyName, opalg, salt, plaintext)
+	return o.opclient.PsaAsymmetricEncrypt(o.nativeProvider(), o.nativeAuth(), keyName, opalg, salt, plaintext)
 }
@@ -0,0 +1,148 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bench measures the performance of the parsec service by running workloads concurrently against each
+// provider, for sizing hardware and comparing providers.  Each operation is timed by a metrics interceptor, and the
+// report gives the throughput, latency percentiles and error rate of each operation.
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/metrics"
+)
+
+// Config describes a benchmark.
+type Config struct {
+	// ClientConfig is the configuration of the client shared by the workers.
+	ClientConfig *parsec.ClientConfig
+	// Providers are the providers to benchmark.  If empty, the provider the clients select is used.
+	Providers []parsec.ProviderID
+	// Workloads are run in turn for each provider.
+	Workloads []Workload
+	// Concurrency is the number of workers running each workload at once, 1 if zero.
+	Concurrency int
+	// Duration is how long each workload is run for.  If zero, each worker runs Iterations iterations.
+	Duration time.Duration
+	// Iterations, if not zero, is the number of iterations of a workload each worker runs.
+	Iterations int
+}
+
+// Worker is the state of one worker running a workload.
+type Worker struct {
+	// ID distinguishes the workers running a workload at once.
+	ID     int
+	Client *parsec.BasicClient
+	// Opts select the provider being benchmarked, and must be passed to every operation.
+	Opts []parsec.CallOption
+}
+
+// KeyName returns a name for a key used by the worker, unique to the worker and workload.
+func (w *Worker) KeyName(workload string) string {
+	return fmt.Sprintf("parsec-bench-%v-%d", workload, w.ID)
+}
+
+// Workload is an operation, or short sequence of operations, repeated by each worker.
+type Workload struct {
+	Name string
+	// Prepare is called for each worker before the workload is timed, for instance to create the keys the
+	// workload uses.  It returns the function run for each iteration and a function to clean up afterwards, which
+	// may be nil.
+	Prepare func(w *Worker) (run func() error, cleanup func() error, err error)
+}
+
+// Run runs the benchmark, returning the report.  Workloads that cannot be prepared for a provider, for instance
+// because the provider does not support the algorithm, are recorded in the report as skipped.  An error is only
+// returned if the clients cannot be created.
+func Run(config *Config) (*Report, error) {
+	if config.Duration == 0 && config.Iterations == 0 {
+		return nil, errors.New("a duration or number of iterations must be given")
+	}
+	concurrency := config.Concurrency
+	if concurrency == 0 {
+		concurrency = 1
+	}
+	collector := newCollector()
+	client, err := parsec.CreateConfiguredClient(config.ClientConfig.Interceptors(metrics.NewInterceptor(collector)))
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	providers := config.Providers
+	if len(providers) == 0 {
+		providers = []parsec.ProviderID{client.GetImplicitProvider()}
+	}
+	report := &Report{
+		Concurrency: concurrency,
+		Duration:    config.Duration,
+		Iterations:  config.Iterations,
+	}
+	for _, provider := range providers {
+		for _, workload := range config.Workloads {
+			workers := make([]*Worker, concurrency)
+			for i := range workers {
+				workers[i] = &Worker{ID: i, Client: client, Opts: []parsec.CallOption{parsec.WithProvider(provider)}}
+			}
+			results, err := runWorkload(config, collector, workload, workers)
+			if err != nil {
+				report.Skipped = append(report.Skipped, Skipped{Workload: workload.Name, Provider: provider.String(), Reason: err.Error()})
+				continue
+			}
+			report.Results = append(report.Results, results...)
+		}
+	}
+	return report, nil
+}
+
+// runWorkload prepares the workload for each worker and runs one iteration to warm up, then runs the workers at
+// once.  Only the operations run after the warm up are recorded.  The warm up also finds workloads the provider
+// does not support, whose first iteration fails.
+func runWorkload(config *Config, collector *collector, workload Workload, workers []*Worker) ([]Result, error) {
+	runs := make([]func() error, len(workers))
+	var cleanups []func() error
+	defer func() {
+		for _, cleanup := range cleanups {
+			_ = cleanup()
+		}
+	}()
+	for i, w := range workers {
+		run, cleanup, err := workload.Prepare(w)
+		if cleanup != nil {
+			cleanups = append(cleanups, cleanup)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = run(); err != nil {
+			return nil, err
+		}
+		runs[i] = run
+	}
+
+	var deadline time.Time
+	if config.Duration != 0 {
+		deadline = time.Now().Add(config.Duration)
+	}
+	collector.start()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, run := range runs {
+		wg.Add(1)
+		go func(run func() error) {
+			defer wg.Done()
+			for i := 0; config.Iterations == 0 || i < config.Iterations; i++ {
+				if !deadline.IsZero() && !time.Now().Before(deadline) {
+					return
+				}
+				_ = run()
+			}
+		}(run)
+	}
+	wg.Wait()
+	return collector.stop(workload.Name, time.Since(start)), nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/parsec/metrics"
+)
+
+// Report holds the results of a benchmark.
+type Report struct {
+	Concurrency int           `json:"concurrency"`
+	Duration    time.Duration `json:"duration_ns"`
+	Iterations  int           `json:"iterations"`
+	Results     []Result      `json:"results"`
+	Skipped     []Skipped     `json:"skipped"`
+}
+
+// Result holds the measurements of one operation sent by a workload to a provider.  Latencies are in seconds.
+type Result struct {
+	Workload  string `json:"workload"`
+	Provider  string `json:"provider"`
+	Operation string `json:"operation"`
+	Count     int    `json:"count"`
+	Errors    int    `json:"errors"`
+	// ErrorsByStatus counts the errors by status code name, see metrics.Observation.Status.
+	ErrorsByStatus map[string]int `json:"errors_by_status"`
+	ErrorRate      float64        `json:"error_rate"`
+	// Throughput is the number of operations completed per second, across all workers.
+	Throughput float64 `json:"throughput"`
+	P50        float64 `json:"p50_seconds"`
+	P95        float64 `json:"p95_seconds"`
+	P99        float64 `json:"p99_seconds"`
+	Max        float64 `json:"max_seconds"`
+}
+
+// Skipped records a workload that was not run for a provider.
+type Skipped struct {
+	Workload string `json:"workload"`
+	Provider string `json:"provider"`
+	Reason   string `json:"reason"`
+}
+
+// WriteText writes the report as a table for people to read.
+func (r *Report) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "WORKLOAD\tPROVIDER\tOPERATION\tCOUNT\tOPS/S\tP50\tP95\tP99\tMAX\tERRORS\t")
+	for _, res := range r.Results {
+		fmt.Fprintf(table, "%v\t%v\t%v\t%d\t%.1f\t%v\t%v\t%v\t%v\t%.2f%%\t\n", res.Workload, res.Provider, res.Operation,
+			res.Count, res.Throughput, seconds(res.P50), seconds(res.P95), seconds(res.P99), seconds(res.Max), 100*res.ErrorRate)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	for _, s := range r.Skipped {
+		fmt.Fprintf(w, "skipped %v on %v: %v\n", s.Workload, s.Provider, s.Reason)
+	}
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Microsecond)
+}
+
+type seriesKey struct {
+	opCode   requests.OpCode
+	provider requests.ProviderID
+}
+
+type series struct {
+	durations []time.Duration
+	errors    map[string]int
+}
+
+// collector is a metrics.Collector keeping every duration observed while recording, so that percentiles can be
+// calculated exactly.
+type collector struct {
+	mu        sync.Mutex
+	recording bool
+	series    map[seriesKey]*series
+}
+
+func newCollector() *collector {
+	return &collector{}
+}
+
+// Observe implements metrics.Collector.
+func (c *collector) Observe(o *metrics.Observation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.recording {
+		return
+	}
+	key := seriesKey{opCode: o.OpCode, provider: o.Provider}
+	s, ok := c.series[key]
+	if !ok {
+		s = &series{errors: make(map[string]int)}
+		c.series[key] = s
+	}
+	s.durations = append(s.durations, o.Duration)
+	if o.Err != nil {
+		s.errors[o.Status()]++
+	}
+}
+
+func (c *collector) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recording = true
+	c.series = make(map[seriesKey]*series)
+}
+
+// stop stops recording and returns the results for the workload, which ran for elapsed.
+func (c *collector) stop(workload string, elapsed time.Duration) []Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recording = false
+	keys := make([]seriesKey, 0, len(c.series))
+	for key := range c.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].provider != keys[j].provider {
+			return keys[i].provider < keys[j].provider
+		}
+		return keys[i].opCode < keys[j].opCode
+	})
+	results := make([]Result, 0, len(keys))
+	for _, key := range keys {
+		s := c.series[key]
+		sort.Slice(s.durations, func(i, j int) bool { return s.durations[i] < s.durations[j] })
+		res := Result{
+			Workload:       workload,
+			Provider:       key.provider.String(),
+			Operation:      key.opCode.String(),
+			Count:          len(s.durations),
+			ErrorsByStatus: s.errors,
+			Throughput:     float64(len(s.durations)) / elapsed.Seconds(),
+			P50:            percentile(s.durations, 50),
+			P95:            percentile(s.durations, 95),
+			P99:            percentile(s.durations, 99),
+			Max:            s.durations[len(s.durations)-1].Seconds(),
+		}
+		for _, n := range s.errors {
+			res.Errors += n
+		}
+		res.ErrorRate = float64(res.Errors) / float64(res.Count)
+		results = append(results, res)
+	}
+	c.series = nil
+	return results
+}
+
+// percentile returns the p'th percentile of the sorted durations in seconds, using the nearest rank.
+func percentile(sorted []time.Duration, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Seconds()
+}
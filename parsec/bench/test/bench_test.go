@@ -0,0 +1,194 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package bench_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeadencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psadestroykey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneraterandom"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psahashcompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifyhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/bench"
+	"google.golang.org/protobuf/proto"
+)
+
+func succeed(result proto.Message) parsectest.Handler {
+	return func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+		return result, requests.StatusSuccess
+	}
+}
+
+var _ = Describe("benchmark", func() {
+	var dir string
+	var listener net.Listener
+	var service *parsectest.Service
+	var config *bench.Config
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "parsec-bench")
+		Expect(err).NotTo(HaveOccurred())
+		// The providers list their opcodes, so that the client's capability checks are cached after the warm up
+		opcodes := []uint32{
+			uint32(requests.OpPsaGenerateKey), uint32(requests.OpPsaDestroyKey), uint32(requests.OpPsaSignHash),
+			uint32(requests.OpPsaVerifyHash), uint32(requests.OpPsaAeadEncrypt), uint32(requests.OpPsaHashCompute),
+			uint32(requests.OpPsaGenerateRandom),
+		}
+		service = parsectest.NewService().
+			Handle(requests.OpListOpcodes, succeed(&listopcodes.Result{Opcodes: opcodes})).
+			Handle(requests.OpPsaGenerateKey, succeed(&psageneratekey.Result{})).
+			Handle(requests.OpPsaDestroyKey, succeed(&psadestroykey.Result{})).
+			Handle(requests.OpPsaSignHash, succeed(&psasignhash.Result{Signature: []byte{0xaa}})).
+			Handle(requests.OpPsaVerifyHash, succeed(&psaverifyhash.Result{})).
+			Handle(requests.OpPsaAeadEncrypt, succeed(&psaaeadencrypt.Result{Ciphertext: []byte{0xbb}})).
+			Handle(requests.OpPsaHashCompute, succeed(&psahashcompute.Result{Hash: make([]byte, 32)})).
+			Handle(requests.OpPsaGenerateRandom, succeed(&psageneraterandom.Result{RandomBytes: make([]byte, 16)}))
+		listener, err = net.Listen("unix", filepath.Join(dir, "parsec.sock"))
+		Expect(err).NotTo(HaveOccurred())
+		go func(l net.Listener, s *parsectest.Service) {
+			_ = s.Serve(l)
+		}(listener, service)
+		Expect(os.Setenv("PARSEC_SERVICE_ENDPOINT", "unix:"+filepath.Join(dir, "parsec.sock"))).To(Succeed())
+
+		config = &bench.Config{
+			ClientConfig: parsec.NewClientConfig().
+				Provider(parsec.ProviderMBed).
+				Authenticator(parsec.NewDirectAuthenticator("bench")),
+			Concurrency: 2,
+			Iterations:  5,
+		}
+	})
+	AfterEach(func() {
+		Expect(listener.Close()).To(Succeed())
+		Expect(os.Unsetenv("PARSEC_SERVICE_ENDPOINT")).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should require a duration or number of iterations", func() {
+		config.Iterations = 0
+		_, err := bench.Run(config)
+		Expect(err).To(HaveOccurred())
+	})
+	It("Should measure each operation of a workload", func() {
+		config.Workloads = []bench.Workload{bench.SignVerify(parsec.DefaultKeyAttribute().SigningKey())}
+		report, err := bench.Run(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Skipped).To(BeEmpty())
+		Expect(report.Results).To(HaveLen(2))
+		for i, op := range []string{"PsaSignHash", "PsaVerifyHash"} {
+			res := report.Results[i]
+			Expect(res.Workload).To(Equal("sign-verify"))
+			Expect(res.Provider).To(Equal("MBed"))
+			Expect(res.Operation).To(Equal(op))
+			Expect(res.Count).To(Equal(10))
+			Expect(res.Errors).To(BeZero())
+			Expect(res.Throughput).To(BeNumerically(">", 0))
+			Expect(res.P50).To(BeNumerically("<=", res.P95))
+			Expect(res.P95).To(BeNumerically("<=", res.P99))
+			Expect(res.P99).To(BeNumerically("<=", res.Max))
+		}
+		// Each worker creates its key once, and destroys it afterwards
+		Expect(service.Count(requests.OpPsaGenerateKey)).To(Equal(2))
+		Expect(service.Count(requests.OpPsaDestroyKey)).To(Equal(4))
+		Expect(service.Count(requests.OpPsaSignHash)).To(Equal(12))
+	})
+	It("Should sign hashes of the size for the algorithm", func() {
+		config.Workloads = []bench.Workload{bench.SignVerify(parsec.DefaultKeyAttribute().SigningKey(parsec.WithHash(algorithm.HashAlgorithmTypeSHA384)))}
+		_, err := bench.Run(config)
+		Expect(err).NotTo(HaveOccurred())
+		for _, req := range service.Received() {
+			if req.OpCode == requests.OpPsaSignHash {
+				op := &psasignhash.Operation{}
+				Expect(proto.Unmarshal(req.Body, op)).To(Succeed())
+				Expect(op.Hash).To(HaveLen(48))
+			}
+		}
+	})
+	It("Should run the other workloads", func() {
+		config.Workloads = []bench.Workload{
+			bench.AeadEncrypt(64),
+			bench.Hash(algorithm.HashAlgorithmTypeSHA256, 1024),
+			bench.Random(16),
+			bench.KeyChurn(parsec.DefaultKeyAttribute().AesGcmKey()),
+		}
+		report, err := bench.Run(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Skipped).To(BeEmpty())
+		var names []string
+		for _, res := range report.Results {
+			names = append(names, res.Workload+" "+res.Operation)
+		}
+		Expect(names).To(Equal([]string{
+			"aead-encrypt-64 PsaAeadEncrypt",
+			"hash-SHA_256-1024 PsaHashCompute",
+			"random-16 PsaGenerateRandom",
+			"key-churn PsaGenerateKey",
+			"key-churn PsaDestroyKey",
+		}))
+	})
+	It("Should count errors by status", func() {
+		service.Handle(requests.OpPsaGenerateRandom, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+			if service.Count(requests.OpPsaGenerateRandom)%2 == 0 {
+				return nil, requests.StatusPsaErrorInsufficientEntropy
+			}
+			return &psageneraterandom.Result{RandomBytes: make([]byte, 16)}, requests.StatusSuccess
+		})
+		config.Concurrency = 1
+		config.Iterations = 4
+		config.Workloads = []bench.Workload{bench.Random(16)}
+		report, err := bench.Run(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Results).To(HaveLen(1))
+		Expect(report.Results[0].Count).To(Equal(4))
+		Expect(report.Results[0].Errors).To(Equal(2))
+		Expect(report.Results[0].ErrorRate).To(Equal(0.5))
+		Expect(report.Results[0].ErrorsByStatus).To(Equal(map[string]int{"PsaErrorInsufficientEntropy": 2}))
+	})
+	It("Should skip workloads the provider does not support", func() {
+		config.Workloads = []bench.Workload{bench.Random(16), bench.SignVerify(parsec.DefaultKeyAttribute().AesGcmKey())}
+		config.Providers = []parsec.ProviderID{parsec.ProviderMBed, parsec.ProviderTPM}
+		service.Handle(requests.OpPsaGenerateRandom, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+			if provider == requests.ProviderTPM {
+				return nil, requests.StatusPsaErrorNotSupported
+			}
+			return &psageneraterandom.Result{RandomBytes: make([]byte, 16)}, requests.StatusSuccess
+		})
+		report, err := bench.Run(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Results).To(HaveLen(1))
+		Expect(report.Results[0].Provider).To(Equal("MBed"))
+		Expect(report.Skipped).To(HaveLen(3))
+		Expect(report.Skipped[1].Workload).To(Equal("random-16"))
+		Expect(report.Skipped[1].Provider).To(Equal("TPM"))
+	})
+	It("Should write the report as text and JSON", func() {
+		config.Workloads = []bench.Workload{bench.Random(16)}
+		report, err := bench.Run(config)
+		Expect(err).NotTo(HaveOccurred())
+		var text bytes.Buffer
+		Expect(report.WriteText(&text)).To(Succeed())
+		Expect(text.String()).To(ContainSubstring("P99"))
+		Expect(text.String()).To(ContainSubstring("PsaGenerateRandom"))
+		encoded, err := json.Marshal(report)
+		Expect(err).NotTo(HaveOccurred())
+		decoded := map[string]interface{}{}
+		Expect(json.Unmarshal(encoded, &decoded)).To(Succeed())
+		Expect(decoded["results"]).To(ContainElement(HaveKeyWithValue("operation", "PsaGenerateRandom")))
+	})
+})
@@ -0,0 +1,141 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package bench
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strconv"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+func randomBytes(size int) ([]byte, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// generateKey creates the key for a workload, destroying any key of the same name left by an earlier run.
+func generateKey(w *Worker, workload string, attributes *parsec.KeyAttributes) (*parsec.Key, func() error, error) {
+	name := w.KeyName(workload)
+	_ = w.Client.PsaDestroyKey(name, w.Opts...)
+	k, err := w.Client.GenerateKey(name, attributes, w.Opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, func() error { return k.Destroy() }, nil
+}
+
+// SignVerify signs a hash with a key with the attributes, which must allow signing and verifying hashes, then
+// verifies the signature.
+func SignVerify(attributes *parsec.KeyAttributes) Workload {
+	const name = "sign-verify"
+	return Workload{
+		Name: name,
+		Prepare: func(w *Worker) (func() error, func() error, error) {
+			sig := attributes.KeyPolicy.KeyAlgorithm.GetAsymmetricSignature()
+			if sig == nil || sig.HashAlg() == algorithm.HashAlgorithmTypeNONE {
+				return nil, nil, fmt.Errorf("%v is not a hash-and-sign algorithm", attributes.KeyPolicy.KeyAlgorithm)
+			}
+			hash, err := randomBytes(sig.HashAlg().Size())
+			if err != nil {
+				return nil, nil, err
+			}
+			k, cleanup, err := generateKey(w, name, attributes)
+			if err != nil {
+				return nil, nil, err
+			}
+			run := func() error {
+				signature, err := k.SignHash(hash)
+				if err != nil {
+					return err
+				}
+				return k.VerifyHash(hash, signature)
+			}
+			return run, cleanup, nil
+		},
+	}
+}
+
+// AeadEncrypt encrypts a message of size bytes with an AES-GCM key, using a new nonce each time.
+func AeadEncrypt(size int) Workload {
+	name := "aead-encrypt-" + strconv.Itoa(size)
+	return Workload{
+		Name: name,
+		Prepare: func(w *Worker) (func() error, func() error, error) {
+			plaintext, err := randomBytes(size)
+			if err != nil {
+				return nil, nil, err
+			}
+			k, cleanup, err := generateKey(w, name, parsec.DefaultKeyAttribute().AesGcmKey())
+			if err != nil {
+				return nil, nil, err
+			}
+			run := func() error {
+				nonce, err := randomBytes(parsec.AeadNonceLength)
+				if err != nil {
+					return err
+				}
+				_, err = k.AeadEncrypt(nonce, nil, plaintext)
+				return err
+			}
+			return run, cleanup, nil
+		},
+	}
+}
+
+// Hash computes the hash of a message of size bytes.
+func Hash(alg algorithm.HashAlgorithmType, size int) Workload {
+	return Workload{
+		Name: fmt.Sprintf("hash-%v-%d", alg, size),
+		Prepare: func(w *Worker) (func() error, func() error, error) {
+			message, err := randomBytes(size)
+			if err != nil {
+				return nil, nil, err
+			}
+			run := func() error {
+				_, err := w.Client.PsaHashCompute(message, alg, w.Opts...)
+				return err
+			}
+			return run, nil, nil
+		},
+	}
+}
+
+// Random generates size random bytes.
+func Random(size int) Workload {
+	return Workload{
+		Name: "random-" + strconv.Itoa(size),
+		Prepare: func(w *Worker) (func() error, func() error, error) {
+			run := func() error {
+				_, err := w.Client.PsaGenerateRandom(uint64(size), w.Opts...)
+				return err
+			}
+			return run, nil, nil
+		},
+	}
+}
+
+// KeyChurn generates a key with the attributes then destroys it.
+func KeyChurn(attributes *parsec.KeyAttributes) Workload {
+	const name = "key-churn"
+	return Workload{
+		Name: name,
+		Prepare: func(w *Worker) (func() error, func() error, error) {
+			keyName := w.KeyName(name)
+			_ = w.Client.PsaDestroyKey(keyName, w.Opts...)
+			run := func() error {
+				if err := w.Client.PsaGenerateKey(keyName, attributes, w.Opts...); err != nil {
+					return err
+				}
+				return w.Client.PsaDestroyKey(keyName, w.Opts...)
+			}
+			return run, nil, nil
+		},
+	}
+}
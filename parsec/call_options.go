@@ -0,0 +1,125 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// CallOption overrides the client's configuration for a single call.  Options are applied in order, so later
+// options take precedence.
+type CallOption func(*callOptions)
+
+// WithProvider sends the operation to provider rather than the client's implicit provider.  Core operations,
+// such as ListProviders, are always sent to the core provider.
+func WithProvider(provider ProviderID) CallOption {
+	return func(o *callOptions) {
+		o.provider = provider
+	}
+}
+
+// WithAuthenticator authenticates the operation using authenticator rather than the client's authenticator.
+// This allows a single client to act on behalf of several applications.
+func WithAuthenticator(authenticator Authenticator) CallOption {
+	return func(o *callOptions) {
+		o.auth = authenticator
+	}
+}
+
+// WithTimeout fails the operation if it does not complete within timeout.  The timeout covers any requests made to
+// discover the provider's capabilities or look up the key as well as the operation itself, so together they never
+// take longer than timeout.
+func WithTimeout(timeout time.Duration) CallOption {
+	return func(o *callOptions) {
+		o.timeout = timeout
+	}
+}
+
+// withDeadline fails the operation if it has not completed by deadline.  It is used to give the requests made for
+// a call the time remaining from the call's timeout.  A zero deadline is ignored, and an earlier deadline from
+// WithTimeout still applies.
+func withDeadline(deadline time.Time) CallOption {
+	return func(o *callOptions) {
+		if !deadline.IsZero() && (o.deadline.IsZero() || deadline.Before(o.deadline)) {
+			o.deadline = deadline
+		}
+	}
+}
+
+// WithAlgorithm overrides the algorithm taken from the key's policy for operations on a Key.  It is ignored by
+// BasicClient methods, which take the algorithm as a parameter.
+func WithAlgorithm(alg *algorithm.Algorithm) CallOption {
+	return func(o *callOptions) {
+		o.algorithm = alg
+	}
+}
+
+// WithDERSignature makes PsaSignHash and PsaSignMessage return ECDSA signatures in the ASN.1 DER encoding used by
+// x509 and TLS, and PsaVerifyHash and PsaVerifyMessage accept them, rather than the PSA format of r followed by s.
+// The curve of the key is looked up with ListKeys unless the call is made through a Key.  Signatures made with
+// other algorithms are not changed.
+func WithDERSignature() CallOption {
+	return func(o *callOptions) {
+		o.derSignature = true
+	}
+}
+
+// withKeyAttributes gives the attributes of the key an operation uses, when they are already known.
+func withKeyAttributes(attributes *KeyAttributes) CallOption {
+	return func(o *callOptions) {
+		o.keyAttributes = attributes
+	}
+}
+
+type callOptions struct {
+	provider      ProviderID
+	auth          Authenticator
+	timeout       time.Duration
+	deadline      time.Time
+	algorithm     *algorithm.Algorithm
+	derSignature  bool
+	keyAttributes *KeyAttributes
+	opclient      *operations.Client
+}
+
+// callOptions resolves opts against the client's configuration.
+func (c *BasicClient) callOptions(opts []CallOption) *callOptions {
+	o := &callOptions{
+		provider: c.GetImplicitProvider(),
+		auth:     c.auth,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.timeout > 0 {
+		withDeadline(time.Now().Add(o.timeout))(o)
+	}
+	o.opclient = c.opclient
+	if !o.deadline.IsZero() {
+		o.opclient = c.opclient.WithDeadline(o.deadline)
+	}
+	return o
+}
+
+// within returns opts with the call's deadline added, for the other requests made by the call, so that they share
+// its timeout rather than each having their own.
+func (o *callOptions) within(opts []CallOption) []CallOption {
+	if o.deadline.IsZero() {
+		return opts
+	}
+	return append(opts[:len(opts):len(opts)], withDeadline(o.deadline))
+}
+
+func (o *callOptions) nativeProvider() requests.ProviderID {
+	return requests.ProviderID(o.provider)
+}
+
+func (o *callOptions) nativeAuth() auth.Authenticator {
+	return o.auth.toNativeAuthenticator()
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOperationNotSupported is returned, wrapped in an *OperationNotSupportedError, when the provider does not
+// support the requested operation.  Test for it using errors.Is.
+var ErrOperationNotSupported = errors.New("operation not supported by provider")
+
+// OperationNotSupportedError details the operation and provider when an operation is not supported.
+type OperationNotSupportedError struct {
+	Op       OpCode
+	Provider ProviderID
+}
+
+func (e *OperationNotSupportedError) Error() string {
+	return fmt.Sprintf("operation %v not supported by provider %v", e.Op, e.Provider)
+}
+
+// Is allows errors.Is(err, ErrOperationNotSupported) to match.
+func (e *OperationNotSupportedError) Is(target error) bool {
+	return target == ErrOperationNotSupported
+}
+
+// DefaultCapabilityRetryInterval is how long a failure to list the operations supported by a provider is remembered
+// before ListOpcodes is sent again, unless set with ClientConfig.CapabilityRetryInterval.
+const DefaultCapabilityRetryInterval = 30 * time.Second
+
+// capabilityFailure is a failure to list the operations supported by a provider.
+type capabilityFailure struct {
+	err   error
+	retry time.Time
+}
+
+// capabilityCache holds the opcodes supported by each provider, as returned by ListOpcodes, and the failures to
+// retrieve them until they are retried.  It is safe for concurrent use.
+type capabilityCache struct {
+	mu            sync.Mutex
+	providers     map[ProviderID]OpCodeSet
+	failures      map[ProviderID]capabilityFailure
+	retryInterval time.Duration
+}
+
+func newCapabilityCache(retryInterval time.Duration) *capabilityCache {
+	if retryInterval <= 0 {
+		retryInterval = DefaultCapabilityRetryInterval
+	}
+	return &capabilityCache{
+		providers:     make(map[ProviderID]OpCodeSet),
+		failures:      make(map[ProviderID]capabilityFailure),
+		retryInterval: retryInterval,
+	}
+}
+
+func (cc *capabilityCache) get(provider ProviderID) (OpCodeSet, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	ops, ok := cc.providers[provider]
+	return ops, ok
+}
+
+// failed returns the error retrieving the opcodes of provider if it should not be retried yet.
+func (cc *capabilityCache) failed(provider ProviderID) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	failure, ok := cc.failures[provider]
+	if !ok {
+		return nil
+	}
+	if !time.Now().Before(failure.retry) {
+		delete(cc.failures, provider)
+		return nil
+	}
+	return failure.err
+}
+
+func (cc *capabilityCache) set(provider ProviderID, ops OpCodeSet) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.providers[provider] = ops
+	delete(cc.failures, provider)
+}
+
+func (cc *capabilityCache) fail(provider ProviderID, err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.failures[provider] = capabilityFailure{err: err, retry: time.Now().Add(cc.retryInterval)}
+}
+
+func (cc *capabilityCache) clear() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.providers = make(map[ProviderID]OpCodeSet)
+	cc.failures = make(map[ProviderID]capabilityFailure)
+}
+
+// Capabilities returns the set of operations supported by provider.  The set is retrieved from the parsec service
+// using ListOpcodes the first time it is requested for each provider and cached thereafter.  If ListOpcodes fails,
+// the error is returned again without asking the service until the capability retry interval has passed.
+func (c *BasicClient) Capabilities(provider ProviderID, opts ...CallOption) (OpCodeSet, error) {
+	if ops, ok := c.capabilities.get(provider); ok {
+		return ops, nil
+	}
+	if err := c.capabilities.failed(provider); err != nil {
+		return nil, err
+	}
+	opcodes, err := c.ListOpcodes(provider, opts...)
+	if err != nil {
+		c.capabilities.fail(provider, err)
+		return nil, err
+	}
+	ops := newOpCodeSet(opcodes)
+	c.capabilities.set(provider, ops)
+	return ops, nil
+}
+
+// RefreshCapabilities discards the cached capabilities and failures to retrieve them, so they will be requested from
+// the parsec service again.
+func (c *BasicClient) RefreshCapabilities() {
+	c.capabilities.clear()
+}
+
+// Supports returns true if the implicit provider supports op.  If the supported operations could not be
+// retrieved from the parsec service, false is returned.
+func (c *BasicClient) Supports(op OpCode) bool {
+	ops, err := c.Capabilities(c.GetImplicitProvider())
+	if err != nil {
+		return false
+	}
+	return ops.Contains(op)
+}
+
+// checkCryptoOperation returns an error if the provider selected for the call cannot carry out op.  If the supported
+// operations cannot be retrieved from the parsec service, the operation is allowed so that the service can report
+// any error.  The failure is cached, so a service without ListOpcodes is not asked again for every operation.
+func (c *BasicClient) checkCryptoOperation(op OpCode, o *callOptions) error {
+	if !o.provider.HasCrypto() {
+		return fmt.Errorf("provider does not support crypto operation")
+	}
+	ops, err := c.Capabilities(o.provider, o.within([]CallOption{WithAuthenticator(o.auth)})...)
+	if err != nil {
+		return nil //nolint:nilerr // the service will report if the operation is not supported
+	}
+	if !ops.Contains(op) {
+		return &OperationNotSupportedError{Op: op, Provider: o.provider}
+	}
+	return nil
+}
@@ -3,8 +3,12 @@
 package parsec
 
 import (
+	"log/slog"
+	"time"
+
 	"github.com/parallaxsecond/parsec-client-go/interface/auth"
 	"github.com/parallaxsecond/parsec-client-go/interface/connection"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
 )
 
 // ClientConfig holds a configuration for the basic client to be passed to InitClient
@@ -15,6 +19,10 @@ type ClientConfig struct {
 	connection        connection.Connection
 	defaultProvider   *ProviderID
 	authenticator     Authenticator
+	interceptors      []operations.Interceptor
+	logger            *slog.Logger
+	selection         providerSelection
+	capabilityRetry   time.Duration
 }
 
 // NewClientConfig ceates a ClientConfig with defaults
@@ -58,3 +66,62 @@ func (config *ClientConfig) Authenticator(authenticator Authenticator) *ClientCo
 	config.authenticator = authenticator
 	return config
 }
+
+// Interceptors adds interceptors to be called around every operation the client sends to the parsec service,
+// including those made while configuring the client.  Interceptors are called in the order they are added,
+// the first being the outermost.
+func (config *ClientConfig) Interceptors(interceptors ...operations.Interceptor) *ClientConfig {
+	config.interceptors = append(config.interceptors, interceptors...)
+	return config
+}
+
+// Logger sets the logger used to trace operations sent to the parsec service.  Operations are logged at debug level,
+// with key material and plaintexts redacted.  If no logger is set, the client does not log.
+func (config *ClientConfig) Logger(logger *slog.Logger) *ClientConfig {
+	config.logger = logger
+	return config
+}
+
+// PreferProviders sets the order in which providers are considered when the client selects a provider.  Providers
+// offered by the parsec service but not in this list are considered afterwards, in the order the service lists them.
+// This has no effect if Provider is set.
+func (config *ClientConfig) PreferProviders(providers ...ProviderID) *ClientConfig {
+	config.selection.preferred = providers
+	return config
+}
+
+// RequireProviderVendor restricts provider selection to providers whose vendor matches vendor exactly.
+// This has no effect if Provider is set.
+func (config *ClientConfig) RequireProviderVendor(vendor string) *ClientConfig {
+	config.selection.vendor = &vendor
+	return config
+}
+
+// RequireProviderUUID restricts provider selection to the provider with the given UUID.
+// This has no effect if Provider is set.
+func (config *ClientConfig) RequireProviderUUID(uuid string) *ClientConfig {
+	config.selection.uuid = &uuid
+	return config
+}
+
+// RequireProviderMinVersion restricts provider selection to providers with at least the given version.
+// This has no effect if Provider is set.
+func (config *ClientConfig) RequireProviderMinVersion(major, minor, rev uint32) *ClientConfig {
+	config.selection.minVersion = &providerVersion{major: major, minor: minor, rev: rev}
+	return config
+}
+
+// RequireOperations restricts provider selection to providers supporting all of ops, as reported by ListOpcodes.
+// This has no effect if Provider is set.
+func (config *ClientConfig) RequireOperations(ops ...OpCode) *ClientConfig {
+	config.selection.operations = append(config.selection.operations, ops...)
+	return config
+}
+
+// CapabilityRetryInterval sets how long a failure to list the operations supported by a provider is remembered
+// before ListOpcodes is sent again.  Until then, operations are sent without checking they are supported.  The
+// default is DefaultCapabilityRetryInterval.
+func (config *ClientConfig) CapabilityRetryInterval(interval time.Duration) *ClientConfig {
+	config.capabilityRetry = interval
+	return config
+}
@@ -5,36 +5,167 @@ package parsec
 
 import "github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
 
+// DefaultKeyAttributeFactory creates the attributes for commonly used keys.  Each preset can be adjusted using
+// KeyAttributeOptions, for example DefaultKeyAttribute().AesGcmKey(WithKeyBits(128)) for an AES-128-GCM key.
 type DefaultKeyAttributeFactory interface {
-	SigningKey() *KeyAttributes
+	// SigningKey is an RSA 2048 bit key for PKCS#1 v1.5 signatures with SHA-256.
+	SigningKey(opts ...KeyAttributeOption) *KeyAttributes
+	// RsaPssSigningKey is an RSA 2048 bit key for PSS signatures with SHA-256.
+	RsaPssSigningKey(opts ...KeyAttributeOption) *KeyAttributes
+	// EcdsaSigningKey is a NIST P-256 key for ECDSA signatures with SHA-256.  With WithKeyBits(384) it is a P-384 key
+	// using SHA-384, and with WithKeyBits(521) a P-521 key using SHA-512.
+	EcdsaSigningKey(opts ...KeyAttributeOption) *KeyAttributes
+	// RsaOaepEncryptionKey is an RSA 2048 bit key for OAEP encryption with SHA-256.
+	RsaOaepEncryptionKey(opts ...KeyAttributeOption) *KeyAttributes
+	// AesGcmKey is an AES-256 key for GCM authenticated encryption.
+	AesGcmKey(opts ...KeyAttributeOption) *KeyAttributes
+	// Chacha20Poly1305Key is a ChaCha20 key for ChaCha20-Poly1305 authenticated encryption.
+	Chacha20Poly1305Key(opts ...KeyAttributeOption) *KeyAttributes
+	// HmacKey is a key for HMAC with SHA-256, the same size as the hash output.
+	HmacKey(opts ...KeyAttributeOption) *KeyAttributes
+	// CmacKey is an AES-256 key for CMAC.
+	CmacKey(opts ...KeyAttributeOption) *KeyAttributes
+	// EcdhKey is a NIST P-256 key for raw ECDH key agreement.
+	EcdhKey(opts ...KeyAttributeOption) *KeyAttributes
+	// X25519Key is a Curve25519 key for raw ECDH key agreement.
+	X25519Key(opts ...KeyAttributeOption) *KeyAttributes
+	// FfdhKey is a 2048 bit RFC 7919 Diffie-Hellman key for raw FFDH key agreement.
+	FfdhKey(opts ...KeyAttributeOption) *KeyAttributes
 }
 
-type defaultKeyAttributeFactory struct{}
+// KeyAttributeOption adjusts the attributes created by a DefaultKeyAttributeFactory preset.
+type KeyAttributeOption func(*keyAttributeOptions)
 
-func DefaultKeyAttribute() DefaultKeyAttributeFactory {
-	return &defaultKeyAttributeFactory{}
+// WithExport allows the key to be exported.
+func WithExport() KeyAttributeOption {
+	return func(o *keyAttributeOptions) {
+		o.export = true
+	}
+}
+
+// WithKeyBits sets the size of the key, replacing the preset's default.
+func WithKeyBits(bits uint32) KeyAttributeOption {
+	return func(o *keyAttributeOptions) {
+		o.bits = bits
+	}
+}
+
+// WithHash sets the hash used by the key's algorithm, replacing the preset's default.  It is ignored by presets
+// whose algorithm does not use a hash.
+func WithHash(hash algorithm.HashAlgorithmType) KeyAttributeOption {
+	return func(o *keyAttributeOptions) {
+		o.hash = hash
+	}
+}
+
+type keyAttributeOptions struct {
+	export bool
+	bits   uint32
+	hash   algorithm.HashAlgorithmType
 }
 
-func (f *defaultKeyAttributeFactory) SigningKey() *KeyAttributes {
-	const keyBits = 2048
-	const hashAlg = algorithm.HashAlgorithmTypeSHA256
+func newKeyAttributeOptions(bits uint32, hash algorithm.HashAlgorithmType, opts []KeyAttributeOption) *keyAttributeOptions {
+	o := &keyAttributeOptions{
+		bits: bits,
+		hash: hash,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// attributes creates the key attributes, allowing the usages given plus export if requested.
+func (o *keyAttributeOptions) attributes(keyType *KeyType, alg *algorithm.Algorithm, usage UsageFlags) *KeyAttributes {
+	usage.Export = o.export
 	return &KeyAttributes{
-		KeyBits: keyBits,
-		KeyType: NewKeyType().RsaKeyPair(),
+		KeyBits: o.bits,
+		KeyType: keyType,
 		KeyPolicy: &KeyPolicy{
-			KeyAlgorithm: algorithm.NewAsymmetricSignature().RsaPkcs1V15Sign(hashAlg),
-			KeyUsageFlags: &UsageFlags{
-				Cache:         false,
-				Copy:          false,
-				Decrypt:       false,
-				Derive:        false,
-				Encrypt:       false,
-				Export:        false,
-				SignHash:      true,
-				SignMessage:   true,
-				VerifyHash:    true,
-				VerifyMessage: true,
-			},
+			KeyAlgorithm:  alg,
+			KeyUsageFlags: &usage,
 		},
 	}
 }
+
+var (
+	signUsage      = UsageFlags{SignHash: true, SignMessage: true, VerifyHash: true, VerifyMessage: true}
+	encryptUsage   = UsageFlags{Encrypt: true, Decrypt: true}
+	macUsage       = UsageFlags{SignMessage: true, VerifyMessage: true}
+	agreementUsage = UsageFlags{Derive: true}
+)
+
+type defaultKeyAttributeFactory struct{}
+
+func DefaultKeyAttribute() DefaultKeyAttributeFactory {
+	return &defaultKeyAttributeFactory{}
+}
+
+func (f *defaultKeyAttributeFactory) SigningKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(2048, algorithm.HashAlgorithmTypeSHA256, opts)
+	return o.attributes(NewKeyType().RsaKeyPair(), algorithm.NewAsymmetricSignature().RsaPkcs1V15Sign(o.hash), signUsage)
+}
+
+func (f *defaultKeyAttributeFactory) RsaPssSigningKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(2048, algorithm.HashAlgorithmTypeSHA256, opts)
+	return o.attributes(NewKeyType().RsaKeyPair(), algorithm.NewAsymmetricSignature().RsaPss(o.hash), signUsage)
+}
+
+func (f *defaultKeyAttributeFactory) EcdsaSigningKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(256, algorithm.HashAlgorithmTypeNONE, opts)
+	if o.hash == algorithm.HashAlgorithmTypeNONE {
+		// Use the hash with the same strength as the curve
+		switch {
+		case o.bits > 384:
+			o.hash = algorithm.HashAlgorithmTypeSHA512
+		case o.bits > 256:
+			o.hash = algorithm.HashAlgorithmTypeSHA384
+		default:
+			o.hash = algorithm.HashAlgorithmTypeSHA256
+		}
+	}
+	return o.attributes(NewKeyType().EccKeyPair(KeyTypeSECPR1), algorithm.NewAsymmetricSignature().Ecdsa(o.hash), signUsage)
+}
+
+func (f *defaultKeyAttributeFactory) RsaOaepEncryptionKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(2048, algorithm.HashAlgorithmTypeSHA256, opts)
+	return o.attributes(NewKeyType().RsaKeyPair(), algorithm.NewAsymmetricEncryption().RsaOaep(o.hash), encryptUsage)
+}
+
+func (f *defaultKeyAttributeFactory) AesGcmKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(256, algorithm.HashAlgorithmTypeNONE, opts)
+	return o.attributes(NewKeyType().Aes(), algorithm.NewAead().Aead(algorithm.AeadAlgorithmGCM), encryptUsage)
+}
+
+func (f *defaultKeyAttributeFactory) Chacha20Poly1305Key(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(256, algorithm.HashAlgorithmTypeNONE, opts)
+	return o.attributes(NewKeyType().Chacha20(), algorithm.NewAead().Aead(algorithm.AeadAlgorithmChacha20Poly1305), encryptUsage)
+}
+
+func (f *defaultKeyAttributeFactory) HmacKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(0, algorithm.HashAlgorithmTypeSHA256, opts)
+	if o.bits == 0 {
+		o.bits = uint32(o.hash.Size() * 8)
+	}
+	return o.attributes(NewKeyType().Hmac(), algorithm.NewMAC().HMAC(o.hash), macUsage)
+}
+
+func (f *defaultKeyAttributeFactory) CmacKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(256, algorithm.HashAlgorithmTypeNONE, opts)
+	return o.attributes(NewKeyType().Aes(), algorithm.NewMAC().CMAC(), macUsage)
+}
+
+func (f *defaultKeyAttributeFactory) EcdhKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(256, algorithm.HashAlgorithmTypeNONE, opts)
+	return o.attributes(NewKeyType().EccKeyPair(KeyTypeSECPR1), algorithm.NewKeyAgreement().RawECDH(), agreementUsage)
+}
+
+func (f *defaultKeyAttributeFactory) X25519Key(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(255, algorithm.HashAlgorithmTypeNONE, opts)
+	return o.attributes(NewKeyType().EccKeyPair(KeyTypeMONTGOMERY), algorithm.NewKeyAgreement().RawECDH(), agreementUsage)
+}
+
+func (f *defaultKeyAttributeFactory) FfdhKey(opts ...KeyAttributeOption) *KeyAttributes {
+	o := newKeyAttributeOptions(2048, algorithm.HashAlgorithmTypeNONE, opts)
+	return o.attributes(NewKeyType().DhKeyPair(KeyTypeRFC7919), algorithm.NewKeyAgreement().RawFFDH(), agreementUsage)
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// ecdsaDERSignature is the ASN.1 form of an ECDSA signature used by x509, TLS and Go's crypto/ecdsa.
+type ecdsaDERSignature struct {
+	R, S *big.Int
+}
+
+// EcdsaSignatureSize returns the size in bytes of an ECDSA signature in the PSA format, r followed by s, each the
+// size of the curve, for a key on the curve of the given family and size in bits.
+func EcdsaSignatureSize(family EccFamily, bits uint32) (int, error) {
+	if family == KeyTypeMONTGOMERY {
+		return 0, fmt.Errorf("Montgomery curves cannot be used for ECDSA")
+	}
+	for _, b := range eccCurveBits[family] {
+		if b == bits {
+			return 2 * int((bits+7)/8), nil
+		}
+	}
+	return 0, fmt.Errorf("no %v bit curve in ECC family %v", bits, psakeyattributes.KeyType_EccFamily(family))
+}
+
+// EcdsaSignatureToDER converts an ECDSA signature in the PSA format, as returned by PsaSignHash and PsaSignMessage,
+// to the ASN.1 DER encoding used by x509 and TLS.  The family and bits are those of the signing key.
+func EcdsaSignatureToDER(family EccFamily, bits uint32, signature []byte) ([]byte, error) {
+	size, err := EcdsaSignatureSize(family, bits)
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) != size {
+		return nil, fmt.Errorf("%d byte ECDSA signature, expected %d bytes for a %v bit key", len(signature), size, bits)
+	}
+	return asn1.Marshal(ecdsaDERSignature{
+		R: new(big.Int).SetBytes(signature[:size/2]),
+		S: new(big.Int).SetBytes(signature[size/2:]),
+	})
+}
+
+// EcdsaSignatureFromDER converts an ECDSA signature in the ASN.1 DER encoding to the PSA format expected by
+// PsaVerifyHash and PsaVerifyMessage.  The family and bits are those of the verifying key.
+func EcdsaSignatureFromDER(family EccFamily, bits uint32, der []byte) ([]byte, error) {
+	size, err := EcdsaSignatureSize(family, bits)
+	if err != nil {
+		return nil, err
+	}
+	var sig ecdsaDERSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DER ECDSA signature: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("invalid DER ECDSA signature: %d bytes of trailing data", len(rest))
+	}
+	signature := make([]byte, size)
+	for i, v := range []*big.Int{sig.R, sig.S} {
+		if v.Sign() <= 0 || (v.BitLen()+7)/8 > size/2 {
+			return nil, fmt.Errorf("invalid DER ECDSA signature: value out of range for a %v bit key", bits)
+		}
+		v.FillBytes(signature[i*size/2 : (i+1)*size/2])
+	}
+	return signature, nil
+}
+
+// isEcdsa returns true for the ECDSA signature algorithms, whose signatures WithDERSignature converts.
+func isEcdsa(alg *algorithm.AsymmetricSignatureAlgorithm) bool {
+	return alg != nil && (alg.GetEcdsa() != nil || alg.GetEcdsaAny() != nil || alg.GetDeterministicEcdsa() != nil)
+}
+
+// ecdsaCurve is the curve of the key used for an ECDSA signature that WithDERSignature converts.  A nil
+// *ecdsaCurve leaves signatures unchanged.
+type ecdsaCurve struct {
+	family EccFamily
+	bits   uint32
+}
+
+// derSignatureCurve returns the curve of the key used for an ECDSA signature when WithDERSignature was given,
+// looking up the key's attributes if they are not already known.  It returns nil if the signature does not need
+// converting.  Signing looks up the curve before sending the operation, so that a signature is never made and then
+// lost because its key cannot be found.
+func (c *BasicClient) derSignatureCurve(keyName string, alg *algorithm.AsymmetricSignatureAlgorithm, o *callOptions, opts []CallOption) (*ecdsaCurve, error) {
+	if !o.derSignature || !isEcdsa(alg) {
+		return nil, nil
+	}
+	attributes := o.keyAttributes
+	if attributes == nil {
+		info, err := c.findKey(keyName, o.within(opts))
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			return nil, fmt.Errorf("%w: %q for provider %v", ErrKeyNotFound, keyName, o.provider)
+		}
+		attributes = info.Attributes
+	}
+	if attributes == nil || attributes.KeyType == nil || attributes.KeyType.eccFamily() == KeyTypeECCFAMILYNONE {
+		return nil, fmt.Errorf("key %q is not an ECC key", keyName)
+	}
+	return &ecdsaCurve{family: attributes.KeyType.eccFamily(), bits: attributes.KeyBits}, nil
+}
+
+// toDER converts a signature returned by the service to DER.
+func (curve *ecdsaCurve) toDER(signature []byte) ([]byte, error) {
+	if curve == nil {
+		return signature, nil
+	}
+	return EcdsaSignatureToDER(curve.family, curve.bits, signature)
+}
+
+// fromDER converts a signature to be verified from DER.
+func (curve *ecdsaCurve) fromDER(signature []byte) ([]byte, error) {
+	if curve == nil {
+		return signature, nil
+	}
+	return EcdsaSignatureFromDER(curve.family, curve.bits, signature)
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrKeyMismatch is returned, wrapped in a *KeyMismatchError, by EnsureKey when the key exists but its attributes
+// differ from those requested.  Test for it using errors.Is.
+var ErrKeyMismatch = errors.New("existing key does not match requested attributes")
+
+// KeyMismatchError lists the differences between an existing key and the attributes requested from EnsureKey.
+type KeyMismatchError struct {
+	Name        string
+	Differences []string
+}
+
+func (e *KeyMismatchError) Error() string {
+	return fmt.Sprintf("existing key %q does not match requested attributes: %v", e.Name, strings.Join(e.Differences, "; "))
+}
+
+// Is allows errors.Is(err, ErrKeyMismatch) to match.
+func (e *KeyMismatchError) Is(target error) bool {
+	return target == ErrKeyMismatch
+}
+
+// EnsureKey returns a handle to the key called name, generating it with attributes if it does not exist.  If the
+// key exists, its key type, size, usage flags and algorithm are compared with attributes and a *KeyMismatchError
+// is returned if they differ.  A KeyBits of zero in attributes matches any key size.
+// EnsureKey is safe to call from several processes at once: if another process creates the key first, the key
+// it created is checked instead.
+func (c *BasicClient) EnsureKey(name string, attributes *KeyAttributes, opts ...CallOption) (*Key, error) {
+	_, err := attributes.toWireInterface()
+	if err != nil {
+		return nil, err
+	}
+	// The lookups and generation share the timeout
+	opts = c.callOptions(opts).within(opts)
+	info, err := c.findKey(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		key, err := c.GenerateKey(name, attributes, opts...)
+		if code, _ := requests.StatusCodeFromError(err); code != requests.StatusPsaErrorAlreadyExists {
+			return key, err
+		}
+		// We lost a race with another client creating the key, so check the one they created.
+		info, err = c.findKey(name, opts)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			return nil, fmt.Errorf("key %q already exists but was not listed", name)
+		}
+	}
+	differences, err := info.Attributes.Differences(attributes)
+	if err != nil {
+		differences = []string{err.Error()}
+	}
+	if len(differences) > 0 {
+		return nil, &KeyMismatchError{Name: name, Differences: differences}
+	}
+	return c.newKey(name, info.Attributes, opts), nil
+}
+
+// Differences returns a description of each way in which the key type, size, usage flags and algorithm of ka differ
+// from want.  A KeyBits of zero in want matches any key size.  An error is returned if either attributes are
+// incomplete.
+func (ka *KeyAttributes) Differences(want *KeyAttributes) ([]string, error) {
+	haveWire, err := ka.toWireInterface()
+	if err != nil {
+		return nil, err
+	}
+	wantWire, err := want.toWireInterface()
+	if err != nil {
+		return nil, err
+	}
+	return compareKeyAttributes(haveWire, wantWire), nil
+}
+
+// compareKeyAttributes returns a description of each way in which have differs from want.
+func compareKeyAttributes(have, want *psakeyattributes.KeyAttributes) []string {
+	var differences []string
+	if !proto.Equal(have.GetKeyType(), want.GetKeyType()) {
+		differences = append(differences, fmt.Sprintf("key type is %v, want %v", have.GetKeyType(), want.GetKeyType()))
+	}
+	if want.GetKeyBits() != 0 && have.GetKeyBits() != want.GetKeyBits() {
+		differences = append(differences, fmt.Sprintf("key bits is %v, want %v", have.GetKeyBits(), want.GetKeyBits()))
+	}
+	haveFlags := have.GetKeyPolicy().GetKeyUsageFlags().ProtoReflect()
+	wantFlags := want.GetKeyPolicy().GetKeyUsageFlags().ProtoReflect()
+	fields := wantFlags.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if h, w := haveFlags.Get(fd).Bool(), wantFlags.Get(fd).Bool(); h != w {
+			differences = append(differences, fmt.Sprintf("usage flag %v is %v, want %v", fd.Name(), h, w))
+		}
+	}
+	if !proto.Equal(have.GetKeyPolicy().GetKeyAlgorithm(), want.GetKeyPolicy().GetKeyAlgorithm()) {
+		differences = append(differences, fmt.Sprintf("algorithm is %v, want %v",
+			have.GetKeyPolicy().GetKeyAlgorithm(), want.GetKeyPolicy().GetKeyAlgorithm()))
+	}
+	return differences
+}
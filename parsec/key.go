@@ -0,0 +1,319 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// ErrKeyNotFound is returned by OpenKey when the key does not exist for the provider and authenticator.
+var ErrKeyNotFound = errors.New("key not found")
+
+// ErrKeyPolicy is returned, wrapped with details, when an operation on a Key is not allowed by the key's policy.
+// The check is made by the client, before the operation is sent to the parsec service.
+var ErrKeyPolicy = errors.New("operation not permitted by key policy")
+
+// Key is a handle to a key held by the parsec service, returned by GenerateKey, ImportKey and OpenKey.  It binds
+// the key name to the provider and authenticator used to obtain it, and to the key's attributes.  Operations on the
+// key use the algorithm from the key's policy unless WithAlgorithm is given, and are checked against the policy's
+// usage flags before being sent.  Options passed to Key methods cannot change the provider or authenticator.
+type Key struct {
+	client     *BasicClient
+	name       string
+	provider   ProviderID
+	auth       Authenticator
+	attributes *KeyAttributes
+}
+
+// GenerateKey creates a key named name with attributes, returning a handle to it.
+func (c *BasicClient) GenerateKey(name string, attributes *KeyAttributes, opts ...CallOption) (*Key, error) {
+	err := c.PsaGenerateKey(name, attributes, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.newKey(name, attributes, opts), nil
+}
+
+// ImportKey imports data as a key named name with attributes, returning a handle to it.
+func (c *BasicClient) ImportKey(name string, attributes *KeyAttributes, data []byte, opts ...CallOption) (*Key, error) {
+	err := c.PsaImportKey(name, attributes, data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.newKey(name, attributes, opts), nil
+}
+
+// OpenKey returns a handle to an existing key, looking up its attributes with ListKeys.  If there is no key called
+// name for the provider, an error wrapping ErrKeyNotFound is returned.
+func (c *BasicClient) OpenKey(name string, opts ...CallOption) (*Key, error) {
+	info, err := c.findKey(name, opts)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		o := c.callOptions(opts)
+		return nil, fmt.Errorf("%w: %q for provider %v", ErrKeyNotFound, name, o.provider)
+	}
+	return c.newKey(name, info.Attributes, opts), nil
+}
+
+// findKey returns the KeyInfo for the key called name for the provider selected by opts, or nil if there is none.
+func (c *BasicClient) findKey(name string, opts []CallOption) (*KeyInfo, error) {
+	o := c.callOptions(opts)
+	keys, err := c.ListKeys(opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.Name == name && k.ProviderID == o.provider {
+			return k, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *BasicClient) newKey(name string, attributes *KeyAttributes, opts []CallOption) *Key {
+	o := c.callOptions(opts)
+	return &Key{
+		client:     c,
+		name:       name,
+		provider:   o.provider,
+		auth:       o.auth,
+		attributes: attributes,
+	}
+}
+
+// Name returns the name of the key.
+func (k *Key) Name() string {
+	return k.name
+}
+
+// Provider returns the provider holding the key.
+func (k *Key) Provider() ProviderID {
+	return k.provider
+}
+
+// Attributes returns the attributes of the key.
+func (k *Key) Attributes() *KeyAttributes {
+	return k.attributes
+}
+
+// options appends the key's provider, authenticator and attributes to opts, so they take precedence.
+func (k *Key) options(opts []CallOption) []CallOption {
+	return append(opts[:len(opts):len(opts)], WithProvider(k.provider), WithAuthenticator(k.auth), withKeyAttributes(k.attributes))
+}
+
+// algorithm returns the algorithm given by WithAlgorithm in opts, if the key's policy algorithm permits it as tested
+// by permits, or the algorithm from the key's policy.
+func (k *Key) algorithm(opts []CallOption, permits func(policy, requested *algorithm.Algorithm) bool) (*algorithm.Algorithm, error) {
+	if k.attributes == nil || k.attributes.KeyPolicy == nil || k.attributes.KeyPolicy.KeyAlgorithm == nil {
+		return nil, fmt.Errorf("%w: key %q has no policy algorithm", ErrKeyPolicy, k.name)
+	}
+	policy := k.attributes.KeyPolicy.KeyAlgorithm
+	if alg := k.client.callOptions(opts).algorithm; alg != nil {
+		if !permits(policy, alg) {
+			return nil, fmt.Errorf("%w: key %q policy %v does not permit %v", ErrKeyPolicy, k.name, policy, alg)
+		}
+		return alg, nil
+	}
+	return policy, nil
+}
+
+// checkUsage returns an error if the key's usage flags do not include usage, as tested by permitted.
+func (k *Key) checkUsage(usage string, permitted func(u *UsageFlags) bool) error {
+	if k.attributes == nil || k.attributes.KeyPolicy == nil || k.attributes.KeyPolicy.KeyUsageFlags == nil ||
+		!permitted(k.attributes.KeyPolicy.KeyUsageFlags) {
+		return fmt.Errorf("%w: key %q does not permit %v", ErrKeyPolicy, k.name, usage)
+	}
+	return nil
+}
+
+// signatureAlgorithm checks the key permits usage, returning the signature algorithm to use.  Verification may use
+// the algorithms allowed by algorithm.PermitsVerification.
+func (k *Key) signatureAlgorithm(usage string, permitted func(u *UsageFlags) bool, verify bool, opts []CallOption) (*algorithm.AsymmetricSignatureAlgorithm, error) {
+	if err := k.checkUsage(usage, permitted); err != nil {
+		return nil, err
+	}
+	permits := algorithm.Permits
+	if verify {
+		permits = algorithm.PermitsVerification
+	}
+	alg, err := k.algorithm(opts, permits)
+	if err != nil {
+		return nil, err
+	}
+	sig := alg.GetAsymmetricSignature()
+	if sig == nil {
+		return nil, fmt.Errorf("%w: key %q algorithm is not an asymmetric signature algorithm", ErrKeyPolicy, k.name)
+	}
+	return sig, nil
+}
+
+// Sign signs message, returning the signature.
+func (k *Key) Sign(message []byte, opts ...CallOption) ([]byte, error) {
+	alg, err := k.signatureAlgorithm("SignMessage", func(u *UsageFlags) bool { return u.SignMessage }, false, opts)
+	if err != nil {
+		return nil, err
+	}
+	return k.client.PsaSignMessage(k.name, message, alg, k.options(opts)...)
+}
+
+// SignHash signs a hash that has already been computed, returning the signature.
+func (k *Key) SignHash(hash []byte, opts ...CallOption) ([]byte, error) {
+	alg, err := k.signatureAlgorithm("SignHash", func(u *UsageFlags) bool { return u.SignHash }, false, opts)
+	if err != nil {
+		return nil, err
+	}
+	return k.client.PsaSignHash(k.name, hash, alg, k.options(opts)...)
+}
+
+// Verify verifies signature is a valid signature of message.
+func (k *Key) Verify(message, signature []byte, opts ...CallOption) error {
+	alg, err := k.signatureAlgorithm("VerifyMessage", func(u *UsageFlags) bool { return u.VerifyMessage }, true, opts)
+	if err != nil {
+		return err
+	}
+	return k.client.PsaVerifyMessage(k.name, message, signature, alg, k.options(opts)...)
+}
+
+// VerifyHash verifies signature is a valid signature of a hash that has already been computed.
+func (k *Key) VerifyHash(hash, signature []byte, opts ...CallOption) error {
+	alg, err := k.signatureAlgorithm("VerifyHash", func(u *UsageFlags) bool { return u.VerifyHash }, true, opts)
+	if err != nil {
+		return err
+	}
+	return k.client.PsaVerifyHash(k.name, hash, signature, alg, k.options(opts)...)
+}
+
+// Encrypt encrypts plaintext using an asymmetric encryption or cipher algorithm, returning the ciphertext.
+// Use AeadEncrypt for keys with an AEAD algorithm.
+func (k *Key) Encrypt(plaintext []byte, opts ...CallOption) ([]byte, error) {
+	if err := k.checkUsage("Encrypt", func(u *UsageFlags) bool { return u.Encrypt }); err != nil {
+		return nil, err
+	}
+	alg, err := k.algorithm(opts, algorithm.Permits)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case alg.GetAsymmetricEncryption() != nil:
+		return k.client.PsaAsymmetricEncrypt(k.name, alg.GetAsymmetricEncryption(), nil, plaintext, k.options(opts)...)
+	case alg.GetCipher() != nil:
+		return k.client.PsaCipherEncrypt(k.name, alg.GetCipher(), plaintext, k.options(opts)...)
+	case alg.GetAead() != nil:
+		return nil, fmt.Errorf("key %q has an AEAD algorithm, use AeadEncrypt", k.name)
+	default:
+		return nil, fmt.Errorf("%w: key %q algorithm is not an encryption algorithm", ErrKeyPolicy, k.name)
+	}
+}
+
+// Decrypt decrypts ciphertext using an asymmetric encryption or cipher algorithm, returning the plaintext.
+// Use AeadDecrypt for keys with an AEAD algorithm.
+func (k *Key) Decrypt(ciphertext []byte, opts ...CallOption) ([]byte, error) {
+	if err := k.checkUsage("Decrypt", func(u *UsageFlags) bool { return u.Decrypt }); err != nil {
+		return nil, err
+	}
+	alg, err := k.algorithm(opts, algorithm.Permits)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case alg.GetAsymmetricEncryption() != nil:
+		return k.client.PsaAsymmetricDecrypt(k.name, alg.GetAsymmetricEncryption(), nil, ciphertext, k.options(opts)...)
+	case alg.GetCipher() != nil:
+		return k.client.PsaCipherDecrypt(k.name, alg.GetCipher(), ciphertext, k.options(opts)...)
+	case alg.GetAead() != nil:
+		return nil, fmt.Errorf("key %q has an AEAD algorithm, use AeadDecrypt", k.name)
+	default:
+		return nil, fmt.Errorf("%w: key %q algorithm is not an encryption algorithm", ErrKeyPolicy, k.name)
+	}
+}
+
+func (k *Key) aeadAlgorithm(usage string, permitted func(u *UsageFlags) bool, opts []CallOption) (*algorithm.AeadAlgorithm, error) {
+	if err := k.checkUsage(usage, permitted); err != nil {
+		return nil, err
+	}
+	alg, err := k.algorithm(opts, algorithm.Permits)
+	if err != nil {
+		return nil, err
+	}
+	aead := alg.GetAead()
+	if aead == nil {
+		return nil, fmt.Errorf("%w: key %q algorithm is not an AEAD algorithm", ErrKeyPolicy, k.name)
+	}
+	return aead, nil
+}
+
+// AeadEncrypt encrypts and authenticates plaintext, also authenticating nonce and additionalData, returning
+// the ciphertext.
+func (k *Key) AeadEncrypt(nonce, additionalData, plaintext []byte, opts ...CallOption) ([]byte, error) {
+	alg, err := k.aeadAlgorithm("Encrypt", func(u *UsageFlags) bool { return u.Encrypt }, opts)
+	if err != nil {
+		return nil, err
+	}
+	return k.client.PsaAeadEncrypt(k.name, alg, nonce, additionalData, plaintext, k.options(opts)...)
+}
+
+// AeadDecrypt decrypts and authenticates ciphertext, nonce and additionalData, returning the plaintext.
+func (k *Key) AeadDecrypt(nonce, additionalData, ciphertext []byte, opts ...CallOption) ([]byte, error) {
+	alg, err := k.aeadAlgorithm("Decrypt", func(u *UsageFlags) bool { return u.Decrypt }, opts)
+	if err != nil {
+		return nil, err
+	}
+	return k.client.PsaAeadDecrypt(k.name, alg, nonce, additionalData, ciphertext, k.options(opts)...)
+}
+
+func (k *Key) macAlgorithm(usage string, permitted func(u *UsageFlags) bool, opts []CallOption) (*algorithm.MacAlgorithm, error) {
+	if err := k.checkUsage(usage, permitted); err != nil {
+		return nil, err
+	}
+	alg, err := k.algorithm(opts, algorithm.Permits)
+	if err != nil {
+		return nil, err
+	}
+	mac := alg.GetMac()
+	if mac == nil {
+		return nil, fmt.Errorf("%w: key %q algorithm is not a MAC algorithm", ErrKeyPolicy, k.name)
+	}
+	return mac, nil
+}
+
+// MAC computes the MAC of input.
+func (k *Key) MAC(input []byte, opts ...CallOption) ([]byte, error) {
+	alg, err := k.macAlgorithm("SignMessage", func(u *UsageFlags) bool { return u.SignMessage }, opts)
+	if err != nil {
+		return nil, err
+	}
+	return k.client.PsaMACCompute(k.name, alg, input, k.options(opts)...)
+}
+
+// VerifyMAC verifies mac is the MAC of input.
+func (k *Key) VerifyMAC(input, mac []byte, opts ...CallOption) error {
+	alg, err := k.macAlgorithm("VerifyMessage", func(u *UsageFlags) bool { return u.VerifyMessage }, opts)
+	if err != nil {
+		return err
+	}
+	return k.client.PsaMACVerify(k.name, alg, input, mac, k.options(opts)...)
+}
+
+// ExportPublic exports the public part of an asymmetric key.
+func (k *Key) ExportPublic(opts ...CallOption) ([]byte, error) {
+	return k.client.PsaExportPublicKey(k.name, k.options(opts)...)
+}
+
+// Export exports the key material, if the key's policy permits it.
+func (k *Key) Export(opts ...CallOption) ([]byte, error) {
+	if err := k.checkUsage("Export", func(u *UsageFlags) bool { return u.Export }); err != nil {
+		return nil, err
+	}
+	return k.client.PsaExportKey(k.name, k.options(opts)...)
+}
+
+// Destroy destroys the key.  The handle must not be used afterwards.
+func (k *Key) Destroy(opts ...CallOption) error {
+	return k.client.PsaDestroyKey(k.name, k.options(opts)...)
+}
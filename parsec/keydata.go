@@ -0,0 +1,169 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+)
+
+// KeyDataFromPEM converts the first PEM block in data to the format PsaImportKey expects for keyType:
+// PKCS#1 DER for RSA keys, the private value for ECC key pairs, the public value (the uncompressed point for the
+// NIST curves) for ECC public keys and the block contents for any other key type.  Private keys may be PKCS#8,
+// PKCS#1 or SEC 1 and public keys PKIX, PKCS#1 or a certificate.  ECC keys may be on the NIST curves or X25519.
+// An error is returned if an ECC key is not on a curve of the key type's family, or if an RSA or ECC key is not of
+// size bits.  If bits is 0, keys of any size are accepted.
+func KeyDataFromPEM(data []byte, keyType *KeyType, bits uint32) ([]byte, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	if keyType == nil {
+		return nil, fmt.Errorf("key type must be set")
+	}
+	switch variant := keyType.variant.(type) {
+	case *KeyTypeRsaKeyPair:
+		key, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("PEM key is %T, expected an RSA private key", key)
+		}
+		if err = checkRSABits(&rsaKey.PublicKey, bits); err != nil {
+			return nil, err
+		}
+		return x509.MarshalPKCS1PrivateKey(rsaKey), nil
+	case *KeyTypeRsaPublicKey:
+		key, err := parsePublicKey(block)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("PEM key is %T, expected an RSA public key", key)
+		}
+		if err = checkRSABits(rsaKey, bits); err != nil {
+			return nil, err
+		}
+		return x509.MarshalPKCS1PublicKey(rsaKey), nil
+	case *KeyTypeEccKeyPair:
+		key, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkCurve(key, variant.CurveFamily, bits); err != nil {
+			return nil, err
+		}
+		switch ecKey := key.(type) {
+		case *ecdsa.PrivateKey:
+			// The private value is padded to the size of the curve, which ecdsa.PrivateKey.ECDH cannot do for P-224
+			return ecKey.D.FillBytes(make([]byte, (ecKey.Curve.Params().BitSize+7)/8)), nil
+		case *ecdh.PrivateKey:
+			// X25519 keys are only found in PKCS#8
+			return ecKey.Bytes(), nil
+		default:
+			return nil, fmt.Errorf("PEM key is %T, expected an ECC private key", key)
+		}
+	case *KeyTypeEccPublicKey:
+		key, err := parsePublicKey(block)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkCurve(key, variant.CurveFamily, bits); err != nil {
+			return nil, err
+		}
+		switch ecKey := key.(type) {
+		case *ecdsa.PublicKey:
+			size := (ecKey.Curve.Params().BitSize + 7) / 8
+			point := make([]byte, 1+2*size)
+			point[0] = 4 // uncompressed
+			ecKey.X.FillBytes(point[1 : 1+size])
+			ecKey.Y.FillBytes(point[1+size:])
+			return point, nil
+		case *ecdh.PublicKey:
+			return ecKey.Bytes(), nil
+		default:
+			return nil, fmt.Errorf("PEM key is %T, expected an ECC public key", key)
+		}
+	default:
+		return block.Bytes, nil
+	}
+}
+
+func checkRSABits(key *rsa.PublicKey, bits uint32) error {
+	if size := uint32(key.N.BitLen()); bits != 0 && size != bits {
+		return fmt.Errorf("PEM key is a %d bit RSA key, expected %d bits", size, bits)
+	}
+	return nil
+}
+
+// checkCurve returns an error if key is an ECC key that is not on a curve of family with size bits.  Other keys are
+// left for the caller to reject.  The curves are those of gocrypto.FromEllipticCurve and FromECDHCurve, which cannot
+// be used here as gocrypto imports this package.
+func checkCurve(key interface{}, family EccFamily, bits uint32) error {
+	var keyFamily EccFamily
+	var keyBits uint32
+	var name string
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		keyFamily, keyBits, name = KeyTypeSECPR1, uint32(k.Curve.Params().BitSize), k.Curve.Params().Name
+	case *ecdsa.PublicKey:
+		keyFamily, keyBits, name = KeyTypeSECPR1, uint32(k.Curve.Params().BitSize), k.Curve.Params().Name
+	case *ecdh.PrivateKey:
+		return checkCurve(k.PublicKey(), family, bits)
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return fmt.Errorf("PEM key is on unsupported curve %v", k.Curve())
+		}
+		keyFamily, keyBits, name = KeyTypeMONTGOMERY, 255, "X25519"
+	default:
+		return nil
+	}
+	if keyFamily != family {
+		return fmt.Errorf("PEM key is on curve %v of family %v, expected family %v", name,
+			psakeyattributes.KeyType_EccFamily(keyFamily), psakeyattributes.KeyType_EccFamily(family))
+	}
+	if bits != 0 && keyBits != bits {
+		return fmt.Errorf("PEM key is on %d bit curve %v, expected %d bits", keyBits, name, bits)
+	}
+	return nil
+}
+
+func parsePrivateKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("PEM block type %q is not a private key", block.Type)
+	}
+}
+
+func parsePublicKey(block *pem.Block) (interface{}, error) {
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("PEM block type %q is not a public key", block.Type)
+	}
+}
@@ -45,27 +45,65 @@ func (u *UsageFlags) toNativeWireInterface() *psakeyattributes.UsageFlags {
 	}
 }
 
+func newUsageFlagsFromOp(u *psakeyattributes.UsageFlags) *UsageFlags {
+	return &UsageFlags{
+		Export:        u.GetExport(),
+		Copy:          u.GetCopy(),
+		Cache:         u.GetCache(),
+		Encrypt:       u.GetEncrypt(),
+		Decrypt:       u.GetDecrypt(),
+		SignMessage:   u.GetSignMessage(),
+		VerifyMessage: u.GetVerifyMessage(),
+		SignHash:      u.GetSignHash(),
+		VerifyHash:    u.GetVerifyHash(),
+		Derive:        u.GetDerive(),
+	}
+}
+
 type KeyPolicy struct {
 	KeyUsageFlags *UsageFlags
 	KeyAlgorithm  *algorithm.Algorithm
 }
 
-func (kp *KeyPolicy) toNativeWireInterface() *psakeyattributes.KeyPolicy {
+func (kp *KeyPolicy) toNativeWireInterface() (*psakeyattributes.KeyPolicy, error) {
+	if kp == nil || kp.KeyAlgorithm == nil {
+		return nil, fmt.Errorf("key policy must have an algorithm")
+	}
+	if kp.KeyUsageFlags == nil {
+		return nil, fmt.Errorf("key policy must have usage flags")
+	}
 	kaif := kp.KeyAlgorithm.ToWireInterface()
 	if kaif == nil {
-		fmt.Println("no wire alg from kp.KeyAlgorithm")
-		return nil
+		return nil, fmt.Errorf("no wire algorithm for key policy algorithm")
 	}
 	ka, ok := kaif.(*psaalgorithm.Algorithm)
 	if !ok {
-		fmt.Printf("kaif is wrong type, got %v\n", reflect.TypeOf(kaif))
-		return nil
+		return nil, fmt.Errorf("unexpected type for key policy wire algorithm, expecting *psaalgorithm.Algorithm, got %v", reflect.TypeOf(kaif))
 	}
 
 	return &psakeyattributes.KeyPolicy{
 		KeyAlgorithm:  ka,
 		KeyUsageFlags: kp.KeyUsageFlags.toNativeWireInterface(),
+	}, nil
+}
+
+func newKeyPolicyFromOp(kp *psakeyattributes.KeyPolicy) (*KeyPolicy, error) {
+	if kp == nil {
+		return nil, fmt.Errorf("key policy missing")
+	}
+	policy := &KeyPolicy{
+		KeyUsageFlags: newUsageFlagsFromOp(kp.KeyUsageFlags),
+	}
+	// Keys that permit no algorithm have a policy algorithm of None, which we represent as nil.
+	if kp.KeyAlgorithm.GetVariant() == nil || kp.KeyAlgorithm.GetNone() != nil {
+		return policy, nil
+	}
+	alg, err := algorithm.NewAlgorithmFromWireInterface(kp.KeyAlgorithm)
+	if err != nil {
+		return nil, err
 	}
+	policy.KeyAlgorithm = alg
+	return policy, nil
 }
 
 type KeyAttributes struct {
@@ -74,15 +112,29 @@ type KeyAttributes struct {
 	KeyPolicy *KeyPolicy
 }
 
-func newKeyAttributesFromOp(ka *psakeyattributes.KeyAttributes) (*KeyAttributes, error) { //nolint:unparam
+func newKeyAttributesFromOp(ka *psakeyattributes.KeyAttributes) (*KeyAttributes, error) {
+	if ka == nil {
+		return nil, fmt.Errorf("key attributes missing")
+	}
+	keytype, err := newKeyTypeFromWire(ka.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	keypolicy, err := newKeyPolicyFromOp(ka.KeyPolicy)
+	if err != nil {
+		return nil, err
+	}
 	return &KeyAttributes{
-		KeyBits: ka.KeyBits,
-		// KeyPolicy: ,
+		KeyType:   keytype,
+		KeyBits:   ka.KeyBits,
+		KeyPolicy: keypolicy,
 	}, nil
-	// TODO finish this
 }
 
 func (ka *KeyAttributes) toWireInterface() (*psakeyattributes.KeyAttributes, error) {
+	if ka == nil || ka.KeyType == nil {
+		return nil, fmt.Errorf("key attributes must have a key type")
+	}
 	keytypeif := ka.KeyType.ToWireInterface()
 	if keytypeif == nil {
 		return nil, fmt.Errorf("nil keytype returned for wire interface")
@@ -91,10 +143,14 @@ func (ka *KeyAttributes) toWireInterface() (*psakeyattributes.KeyAttributes, err
 	if !ok {
 		return nil, fmt.Errorf("incorrect type returned for keytype")
 	}
+	keypolicy, err := ka.KeyPolicy.toNativeWireInterface()
+	if err != nil {
+		return nil, err
+	}
 	return &psakeyattributes.KeyAttributes{
 		KeyBits:   ka.KeyBits,
 		KeyType:   keytype,
-		KeyPolicy: ka.KeyPolicy.toNativeWireInterface(),
+		KeyPolicy: keypolicy,
 	}, nil
 }
 
@@ -3,7 +3,12 @@
 
 package parsec
 
-import "github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+)
 
 type KeyTypeFactory interface {
 	RawData() *KeyType
@@ -113,6 +118,47 @@ func (k *KeyType) ToWireInterface() interface{} {
 	return k.variant.toWireInterface()
 }
 
+//nolint:gocyclo
+func newKeyTypeFromWire(kt *psakeyattributes.KeyType) (*KeyType, error) {
+	if kt == nil {
+		return nil, fmt.Errorf("key type missing")
+	}
+	var variant keyTypeVariant
+	switch v := kt.Variant.(type) {
+	case *psakeyattributes.KeyType_RawData_:
+		variant = &KeyTypeRawData{}
+	case *psakeyattributes.KeyType_Hmac_:
+		variant = &KeyTypeHmac{}
+	case *psakeyattributes.KeyType_Derive_:
+		variant = &KeyTypeDerive{}
+	case *psakeyattributes.KeyType_Aes_:
+		variant = &KeyTypeAes{}
+	case *psakeyattributes.KeyType_Des_:
+		variant = &KeyTypeDes{}
+	case *psakeyattributes.KeyType_Camellia_:
+		variant = &KeyTypeCamellia{}
+	case *psakeyattributes.KeyType_Arc4_:
+		variant = &KeyTypeArc4{}
+	case *psakeyattributes.KeyType_Chacha20_:
+		variant = &KeyTypeChacha20{}
+	case *psakeyattributes.KeyType_RsaPublicKey_:
+		variant = &KeyTypeRsaPublicKey{}
+	case *psakeyattributes.KeyType_RsaKeyPair_:
+		variant = &KeyTypeRsaKeyPair{}
+	case *psakeyattributes.KeyType_EccKeyPair_:
+		variant = &KeyTypeEccKeyPair{CurveFamily: EccFamily(v.EccKeyPair.GetCurveFamily())}
+	case *psakeyattributes.KeyType_EccPublicKey_:
+		variant = &KeyTypeEccPublicKey{CurveFamily: EccFamily(v.EccPublicKey.GetCurveFamily())}
+	case *psakeyattributes.KeyType_DhKeyPair_:
+		variant = &KeyTypeDhKeyPair{GroupFamily: DhFamily(v.DhKeyPair.GetGroupFamily())}
+	case *psakeyattributes.KeyType_DhPublicKey_:
+		variant = &KeyTypeDhPublicKey{GroupFamily: DhFamily(v.DhPublicKey.GetGroupFamily())}
+	default:
+		return nil, fmt.Errorf("unexpected key type %v", reflect.TypeOf(v))
+	}
+	return &KeyType{variant: variant}, nil
+}
+
 type EccFamily int32
 
 const (
@@ -243,7 +289,11 @@ func (k KeyTypeEccKeyPair) isKeyTypeVariant() {}
 
 func (k *KeyTypeEccKeyPair) toWireInterface() interface{} {
 	return &psakeyattributes.KeyType{
-		Variant: &psakeyattributes.KeyType_EccKeyPair_{},
+		Variant: &psakeyattributes.KeyType_EccKeyPair_{
+			EccKeyPair: &psakeyattributes.KeyType_EccKeyPair{
+				CurveFamily: psakeyattributes.KeyType_EccFamily(k.CurveFamily),
+			},
+		},
 	}
 }
 
@@ -255,7 +305,11 @@ func (k KeyTypeEccPublicKey) isKeyTypeVariant() {}
 
 func (k *KeyTypeEccPublicKey) toWireInterface() interface{} {
 	return &psakeyattributes.KeyType{
-		Variant: &psakeyattributes.KeyType_EccPublicKey_{},
+		Variant: &psakeyattributes.KeyType_EccPublicKey_{
+			EccPublicKey: &psakeyattributes.KeyType_EccPublicKey{
+				CurveFamily: psakeyattributes.KeyType_EccFamily(k.CurveFamily),
+			},
+		},
 	}
 }
 
@@ -267,7 +321,11 @@ func (k KeyTypeDhKeyPair) isKeyTypeVariant() {}
 
 func (k *KeyTypeDhKeyPair) toWireInterface() interface{} {
 	return &psakeyattributes.KeyType{
-		Variant: &psakeyattributes.KeyType_DhKeyPair_{},
+		Variant: &psakeyattributes.KeyType_DhKeyPair_{
+			DhKeyPair: &psakeyattributes.KeyType_DhKeyPair{
+				GroupFamily: psakeyattributes.KeyType_DhFamily(k.GroupFamily),
+			},
+		},
 	}
 }
 
@@ -277,6 +335,10 @@ func (k KeyTypeDhPublicKey) isKeyTypeVariant() {}
 
 func (k *KeyTypeDhPublicKey) toWireInterface() interface{} {
 	return &psakeyattributes.KeyType{
-		Variant: &psakeyattributes.KeyType_DhPublicKey_{},
+		Variant: &psakeyattributes.KeyType_DhPublicKey_{
+			DhPublicKey: &psakeyattributes.KeyType_DhPublicKey{
+				GroupFamily: psakeyattributes.KeyType_DhFamily(k.GroupFamily),
+			},
+		},
 	}
 }
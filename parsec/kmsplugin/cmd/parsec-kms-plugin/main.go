@@ -0,0 +1,181 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Command parsec-kms-plugin is a Kubernetes KMS v2 plugin that encrypts secrets at rest with keys held by the parsec
+// service, for example in a TPM or PKCS#11 token.
+//
+// Usage:
+//
+//	parsec-kms-plugin -socket /var/run/kmsplugin/parsec.sock [-key kube-kms] [-provider pkcs11]
+//	parsec-kms-plugin -rotate [-key kube-kms] [-provider pkcs11]
+//
+// The socket is given to the API server as the endpoint of a kms provider with apiVersion v2 in its
+// EncryptionConfiguration, as unix:///var/run/kmsplugin/parsec.sock.  Only the API server should be able to reach
+// the socket's directory.  -rotate creates the next version of the key and exits; running plugins encrypt with it
+// from their next status check.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/connection"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/kmsplugin"
+	"k8s.io/kms/pkg/service"
+)
+
+// keyTypes are the keys the plugin can create.
+var keyTypes = map[string]func() *parsec.KeyAttributes{
+	"aes-128-gcm":       func() *parsec.KeyAttributes { return parsec.DefaultKeyAttribute().AesGcmKey(parsec.WithKeyBits(128)) },
+	"aes-256-gcm":       func() *parsec.KeyAttributes { return parsec.DefaultKeyAttribute().AesGcmKey() },
+	"chacha20-poly1305": func() *parsec.KeyAttributes { return parsec.DefaultKeyAttribute().Chacha20Poly1305Key() },
+}
+
+func keyTypeNames() string {
+	names := make([]string, 0, len(keyTypes))
+	for name := range keyTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	socket := flag.String("socket", "", "path of the unix socket to serve the plugin on")
+	endpoint := flag.String("endpoint", "", "parsec service endpoint, such as unix:/run/parsec/parsec.sock")
+	providerName := flag.String("provider", "", "provider to create keys in, by name or id (default chosen by the client)")
+	authenticator := flag.String("auth", "", "authenticator to use: none, direct or unix-peer (default chosen by the service)")
+	app := flag.String("app", "", "application name for direct authentication")
+	keyName := flag.String("key", "kube-kms", "base name of the key, whose versions are named after it followed by -1, -2 and so on")
+	keyType := flag.String("type", "aes-256-gcm", "type of key created: "+keyTypeNames())
+	rotate := flag.Bool("rotate", false, "create the next version of the key, print its key ID and exit")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout for the API server to establish a connection")
+	flag.Parse()
+	if (*socket == "") == !*rotate || flag.NArg() != 0 {
+		flag.Usage()
+		return 2
+	}
+	attributes, ok := keyTypes[*keyType]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown key type %q, expected one of %v\n", *keyType, keyTypeNames())
+		return 2
+	}
+
+	config, err := clientConfig(*endpoint, *providerName, *authenticator, *app)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+	client, err := parsec.CreateConfiguredClient(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer client.Close()
+	plugin, err := kmsplugin.New(client, *keyName, attributes())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	if *rotate {
+		keyID, err := plugin.Rotate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Println(keyID)
+		return 0
+	}
+
+	if err = serve(plugin, *socket, *timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serve serves the plugin on a unix socket at path until interrupted.
+func serve(plugin *kmsplugin.Plugin, path string, timeout time.Duration) error {
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	server := service.NewGRPCService(path, timeout, plugin)
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		server.Shutdown()
+	}()
+	keyID, err := plugin.KeyID()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("serving KMS v2 plugin on %v with key %v\n", path, keyID)
+	// ListenAndServe returns nil once shut down
+	return server.ListenAndServe()
+}
+
+func clientConfig(endpoint, providerName, authenticator, app string) (*parsec.ClientConfig, error) {
+	config := parsec.NewClientConfig()
+	if endpoint != "" {
+		conn, err := connection.NewConnection(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		config.Connection(conn)
+	}
+	if providerName != "" {
+		provider, err := parsec.ParseProviderID(providerName)
+		if err != nil {
+			return nil, err
+		}
+		config.Provider(provider)
+	}
+	if app != "" && authenticator == "" {
+		authenticator = "direct"
+	}
+	switch authenticator {
+	case "":
+	case "none":
+		config.Authenticator(parsec.NewNoAuthAuthenticator())
+	case "direct":
+		if app == "" {
+			return nil, fmt.Errorf("-app must be given for direct authentication")
+		}
+		config.Authenticator(parsec.NewDirectAuthenticator(app))
+	case "unix-peer":
+		config.Authenticator(parsec.NewUnixPeerAuthenticator())
+	default:
+		return nil, fmt.Errorf("unknown authenticator %q", authenticator)
+	}
+	return config, nil
+}
+
+// removeStaleSocket removes the socket left at path by an earlier run, refusing to remove anything else.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%v exists and is not a socket", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,300 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kmsplugin is a Kubernetes KMS v2 plugin that encrypts with AEAD keys held by the parsec service, for
+// example in a TPM or PKCS#11 token, so that the keys protecting secrets at rest never leave it.  Serve it to the
+// API server with service.NewGRPCService from k8s.io/kms/pkg/service.
+//
+// The plugin's keys are named after a base name followed by '-' and a version number, such as kms-1.  The key with
+// the highest version encrypts, and every version remains usable to decrypt.  Rotate creates the next version;
+// Kubernetes sees the new key ID at its next Status call and re-encrypts with it, after which the old versions can
+// be destroyed.
+package kmsplugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"k8s.io/kms/pkg/service"
+)
+
+const (
+	// APIVersion is the KMS API version reported by Status.
+	APIVersion = "v2"
+	// KeyIDAnnotation is the annotation naming the key that encrypted a ciphertext.
+	KeyIDAnnotation = "key-id.kms.parsec.community"
+	// NonceSize is the size of the random nonce at the start of each ciphertext.
+	NonceSize = parsec.AeadNonceLength
+)
+
+// healthCheck is encrypted and decrypted by Status to check the current key works.
+var healthCheck = []byte("parsec kms plugin health check")
+
+// key is a version of the plugin's key.
+type key struct {
+	name     string
+	version  uint64
+	provider parsec.ProviderID
+	alg      *algorithm.AeadAlgorithm
+}
+
+// Plugin implements service.Service using parsec AEAD keys.
+type Plugin struct {
+	client     *parsec.BasicClient
+	name       string
+	attributes *parsec.KeyAttributes
+	opts       []parsec.CallOption
+
+	mu sync.Mutex
+	// keys are the versions of the key found by the last refresh, by name
+	keys    map[string]*key
+	current *key
+}
+
+var _ service.Service = (*Plugin)(nil)
+
+// New returns a plugin using the versions of the key with base name name, creating the first version with
+// attributes if there are none.  Nil attributes create AES-256-GCM keys.  The attributes must name an AEAD
+// algorithm and permit encryption and decryption.  The options are used for every call, so can select the
+// authenticator, and the provider new versions are created in.
+func New(client *parsec.BasicClient, name string, attributes *parsec.KeyAttributes, opts ...parsec.CallOption) (*Plugin, error) {
+	if name == "" {
+		return nil, fmt.Errorf("key name must be set")
+	}
+	if attributes == nil {
+		attributes = parsec.DefaultKeyAttribute().AesGcmKey()
+	}
+	if aeadAlgorithm(attributes) == nil {
+		return nil, fmt.Errorf("attributes do not describe an AEAD key that can encrypt and decrypt")
+	}
+	p := &Plugin{client: client, name: name, attributes: attributes, opts: opts}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	if p.current == nil {
+		if _, err := p.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// aeadAlgorithm returns the AEAD algorithm of a key with attributes, or nil if it cannot encrypt and decrypt with
+// one.
+func aeadAlgorithm(attributes *parsec.KeyAttributes) *algorithm.AeadAlgorithm {
+	if attributes == nil || attributes.KeyPolicy == nil || attributes.KeyPolicy.KeyAlgorithm == nil {
+		return nil
+	}
+	usage := attributes.KeyPolicy.KeyUsageFlags
+	if usage == nil || !usage.Encrypt || !usage.Decrypt {
+		return nil
+	}
+	return attributes.KeyPolicy.KeyAlgorithm.GetAead()
+}
+
+// version returns the version of the key called keyName, or false if it is not a version of the plugin's key.
+func (p *Plugin) version(keyName string) (uint64, bool) {
+	s, ok := strings.CutPrefix(keyName, p.name+"-")
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	// Versions with leading zeros would make two names for the same version
+	if err != nil || v == 0 || strconv.FormatUint(v, 10) != s {
+		return 0, false
+	}
+	return v, true
+}
+
+// refresh finds the versions of the key, so that versions created or destroyed by other clients are seen.  It must
+// be called with p.mu held.
+func (p *Plugin) refresh() error {
+	infos, err := p.client.ListKeys(p.opts...)
+	if err != nil {
+		return err
+	}
+	keys := map[string]*key{}
+	var current *key
+	for _, info := range infos {
+		v, ok := p.version(info.Name)
+		if !ok {
+			continue
+		}
+		alg := aeadAlgorithm(info.Attributes)
+		if alg == nil {
+			continue
+		}
+		if _, ok := keys[info.Name]; ok {
+			return fmt.Errorf("key %q exists in more than one provider", info.Name)
+		}
+		k := &key{name: info.Name, version: v, provider: info.ProviderID, alg: alg}
+		keys[info.Name] = k
+		if current == nil || v > current.version {
+			current = k
+		}
+	}
+	p.keys, p.current = keys, current
+	return nil
+}
+
+// Rotate creates the next version of the key and encrypts with it from now on, returning its key ID.  Plugins in
+// other processes using the same key switch to the new version at their next Status call.
+func (p *Plugin) Rotate() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.refresh(); err != nil {
+		return "", err
+	}
+	return p.rotate()
+}
+
+// rotate creates the version after the current one.  It must be called with p.mu held.
+func (p *Plugin) rotate() (string, error) {
+	var v uint64 = 1
+	if p.current != nil {
+		v = p.current.version + 1
+	}
+	name := p.name + "-" + strconv.FormatUint(v, 10)
+	generated, err := p.client.GenerateKey(name, p.attributes, p.opts...)
+	if err != nil {
+		return "", err
+	}
+	k := &key{name: name, version: v, provider: generated.Provider(), alg: aeadAlgorithm(p.attributes)}
+	p.keys[name] = k
+	p.current = k
+	return name, nil
+}
+
+// KeyID returns the ID of the key used to encrypt, which is its name.
+func (p *Plugin) KeyID() (string, error) {
+	k, err := p.currentKey()
+	if err != nil {
+		return "", err
+	}
+	return k.name, nil
+}
+
+// currentKey returns the version of the key used to encrypt.  There is none if every version was destroyed.
+func (p *Plugin) currentKey() (*key, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.current == nil {
+		return nil, fmt.Errorf("no versions of key %q found", p.name)
+	}
+	return p.current, nil
+}
+
+// keyOptions returns the options for an operation on k, which is sent to the provider holding it.
+func (p *Plugin) keyOptions(k *key) []parsec.CallOption {
+	return append(p.opts[:len(p.opts):len(p.opts)], parsec.WithProvider(k.provider))
+}
+
+// lookup returns the version of the key with ID keyID, refreshing the versions if it is not known.
+func (p *Plugin) lookup(keyID string) (*key, error) {
+	if _, ok := p.version(keyID); !ok {
+		return nil, fmt.Errorf("key ID %q is not a version of key %q", keyID, p.name)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.keys[keyID]; ok {
+		return k, nil
+	}
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	if k, ok := p.keys[keyID]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: %q", parsec.ErrKeyNotFound, keyID)
+}
+
+// Status finds any new versions of the key and checks the current version can encrypt and decrypt.
+func (p *Plugin) Status(_ context.Context) (*service.StatusResponse, error) {
+	p.mu.Lock()
+	err := p.refresh()
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	current, err := p.currentKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := p.encrypt(current, healthCheck)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := p.decrypt(current, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(plaintext, healthCheck) {
+		return nil, errors.New("health check decrypted to the wrong plaintext")
+	}
+	return &service.StatusResponse{Version: APIVersion, Healthz: "ok", KeyID: current.name}, nil
+}
+
+// Encrypt encrypts data with the current version of the key.  The ciphertext is a random nonce followed by the
+// AEAD ciphertext, and the key ID is authenticated as additional data.
+func (p *Plugin) Encrypt(_ context.Context, _ string, data []byte) (*service.EncryptResponse, error) {
+	current, err := p.currentKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := p.encrypt(current, data)
+	if err != nil {
+		return nil, err
+	}
+	return &service.EncryptResponse{
+		Ciphertext:  ciphertext,
+		KeyID:       current.name,
+		Annotations: map[string][]byte{KeyIDAnnotation: []byte(current.name)},
+	}, nil
+}
+
+// Decrypt decrypts a ciphertext made by Encrypt with the version of the key named by its annotation.
+func (p *Plugin) Decrypt(_ context.Context, _ string, req *service.DecryptRequest) ([]byte, error) {
+	keyID := string(req.Annotations[KeyIDAnnotation])
+	if keyID == "" {
+		return nil, fmt.Errorf("ciphertext has no %v annotation", KeyIDAnnotation)
+	}
+	if req.KeyID != "" && req.KeyID != keyID {
+		return nil, fmt.Errorf("key ID %q does not match the %v annotation %q", req.KeyID, KeyIDAnnotation, keyID)
+	}
+	k, err := p.lookup(keyID)
+	if err != nil {
+		return nil, err
+	}
+	return p.decrypt(k, req.Ciphertext)
+}
+
+func (p *Plugin) encrypt(k *key, plaintext []byte) ([]byte, error) {
+	nonce, err := p.client.PsaGenerateRandom(NonceSize, p.keyOptions(k)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != NonceSize {
+		return nil, fmt.Errorf("service returned %d random bytes, expected %d", len(nonce), NonceSize)
+	}
+	ciphertext, err := p.client.PsaAeadEncrypt(k.name, k.alg, nonce, []byte(k.name), plaintext, p.keyOptions(k)...)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, ciphertext...), nil
+}
+
+func (p *Plugin) decrypt(k *key, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < NonceSize {
+		return nil, fmt.Errorf("%d byte ciphertext is too short to hold a nonce", len(ciphertext))
+	}
+	return p.client.PsaAeadDecrypt(k.name, k.alg, ciphertext[:NonceSize], []byte(k.name), ciphertext[NonceSize:], p.keyOptions(k)...)
+}
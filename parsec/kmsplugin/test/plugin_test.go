@@ -0,0 +1,245 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package kmsplugin_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeadencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/kmsplugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	kmsapi "k8s.io/kms/apis/v2"
+	"k8s.io/kms/pkg/service"
+)
+
+// nonces returns the nonces used to encrypt, excluding Status health checks.
+func nonces(s *parsectest.Service) [][]byte {
+	var nonces [][]byte
+	for _, r := range s.Received() {
+		if r.OpCode != requests.OpPsaAeadEncrypt {
+			continue
+		}
+		op := &psaaeadencrypt.Operation{}
+		Expect(proto.Unmarshal(r.Body, op)).To(Succeed())
+		if string(op.Plaintext) != "parsec kms plugin health check" {
+			nonces = append(nonces, op.Nonce)
+		}
+	}
+	return nonces
+}
+
+var _ = Describe("KMS plugin", func() {
+	var (
+		provider      *parsectest.Provider
+		parsecService *parsectest.Service
+		ctx           context.Context
+	)
+
+	newClient := func() *parsec.BasicClient {
+		bc, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderPKCS11).
+			Authenticator(parsec.NewDirectAuthenticator("kube-apiserver")).
+			Connection(parsecService))
+		Expect(err).NotTo(HaveOccurred())
+		return bc
+	}
+
+	newPlugin := func(opts ...parsec.CallOption) *kmsplugin.Plugin {
+		plugin, err := kmsplugin.New(newClient(), "kube-kms", nil, opts...)
+		Expect(err).NotTo(HaveOccurred())
+		return plugin
+	}
+
+	BeforeEach(func() {
+		provider = parsectest.NewProvider()
+		parsecService = provider.Service()
+		ctx = context.Background()
+	})
+
+	Context("Served over gRPC", func() {
+		var (
+			plugin *kmsplugin.Plugin
+			server *service.GRPCService
+			conn   *grpc.ClientConn
+			client kmsapi.KeyManagementServiceClient
+			dir    string
+		)
+
+		BeforeEach(func() {
+			plugin = newPlugin()
+			var err error
+			dir, err = os.MkdirTemp("", "kmsplugin")
+			Expect(err).NotTo(HaveOccurred())
+			socket := filepath.Join(dir, "kms.sock")
+			server = service.NewGRPCService(socket, 10*time.Second, plugin)
+			go func() {
+				defer GinkgoRecover()
+				Expect(server.ListenAndServe()).To(Succeed())
+			}()
+			Eventually(func() error {
+				_, err := os.Stat(socket)
+				return err
+			}).Should(Succeed())
+			conn, err = grpc.NewClient("unix://"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			Expect(err).NotTo(HaveOccurred())
+			client = kmsapi.NewKeyManagementServiceClient(conn)
+		})
+
+		AfterEach(func() {
+			conn.Close()
+			server.Shutdown()
+			os.RemoveAll(dir)
+		})
+
+		It("Should report its status with the current key", func() {
+			status, err := client.Status(ctx, &kmsapi.StatusRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Version).To(Equal("v2"))
+			Expect(status.Healthz).To(Equal("ok"))
+			Expect(status.KeyId).To(Equal("kube-kms-1"))
+			Expect(provider.KeyNames()).To(ConsistOf("kube-kms-1"))
+		})
+
+		It("Should encrypt with random nonces and decrypt", func() {
+			plaintext := []byte("data encryption key")
+			first, err := client.Encrypt(ctx, &kmsapi.EncryptRequest{Uid: "1", Plaintext: plaintext})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first.KeyId).To(Equal("kube-kms-1"))
+			Expect(first.Annotations).To(Equal(map[string][]byte{kmsplugin.KeyIDAnnotation: []byte("kube-kms-1")}))
+			second, err := client.Encrypt(ctx, &kmsapi.EncryptRequest{Uid: "2", Plaintext: plaintext})
+			Expect(err).NotTo(HaveOccurred())
+
+			nonces := nonces(parsecService)
+			Expect(nonces).To(HaveLen(2))
+			Expect(nonces[0]).NotTo(Equal(nonces[1]))
+			Expect(first.Ciphertext[:kmsplugin.NonceSize]).To(Equal(nonces[0]))
+			Expect(second.Ciphertext[:kmsplugin.NonceSize]).To(Equal(nonces[1]))
+			Expect(parsecService.Count(requests.OpPsaGenerateRandom)).To(Equal(2))
+
+			for _, encrypted := range []*kmsapi.EncryptResponse{first, second} {
+				decrypted, err := client.Decrypt(ctx, &kmsapi.DecryptRequest{
+					Uid:         "3",
+					Ciphertext:  encrypted.Ciphertext,
+					KeyId:       encrypted.KeyId,
+					Annotations: encrypted.Annotations,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(decrypted.Plaintext).To(Equal(plaintext))
+			}
+		})
+
+		It("Should rotate keys and decrypt with old versions", func() {
+			old, err := client.Encrypt(ctx, &kmsapi.EncryptRequest{Uid: "1", Plaintext: []byte("old")})
+			Expect(err).NotTo(HaveOccurred())
+
+			// The key is rotated by another process, and seen by the next status call
+			keyID, err := newPlugin().Rotate()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keyID).To(Equal("kube-kms-2"))
+			status, err := client.Status(ctx, &kmsapi.StatusRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.KeyId).To(Equal("kube-kms-2"))
+
+			encrypted, err := client.Encrypt(ctx, &kmsapi.EncryptRequest{Uid: "2", Plaintext: []byte("new")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(encrypted.KeyId).To(Equal("kube-kms-2"))
+			decrypted, err := client.Decrypt(ctx, &kmsapi.DecryptRequest{Ciphertext: old.Ciphertext, KeyId: old.KeyId, Annotations: old.Annotations})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decrypted.Plaintext).To(Equal([]byte("old")))
+
+			keyID, err = plugin.Rotate()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keyID).To(Equal("kube-kms-3"))
+			encrypted, err = client.Encrypt(ctx, &kmsapi.EncryptRequest{Uid: "3", Plaintext: []byte("newer")})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(encrypted.KeyId).To(Equal("kube-kms-3"))
+		})
+
+		It("Should report an unhealthy status when the key is gone", func() {
+			Expect(newClient().PsaDestroyKey("kube-kms-1")).To(Succeed())
+			_, err := client.Status(ctx, &kmsapi.StatusRequest{})
+			Expect(err).To(MatchError(ContainSubstring("no versions of key")))
+		})
+	})
+
+	It("Should encrypt with the highest version of the key", func() {
+		bc := newClient()
+		for _, name := range []string{"kube-kms-2", "kube-kms-10", "kube-kms-010", "kube-kms-x", "other-kms-11"} {
+			Expect(bc.PsaGenerateKey(name, parsec.DefaultKeyAttribute().AesGcmKey())).To(Succeed())
+		}
+		// Keys that cannot be used are ignored
+		Expect(bc.PsaGenerateKey("kube-kms-12", parsec.DefaultKeyAttribute().HmacKey())).To(Succeed())
+
+		plugin := newPlugin()
+		Expect(plugin.KeyID()).To(Equal("kube-kms-10"))
+		encrypted, err := plugin.Encrypt(ctx, "1", []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(encrypted.KeyID).To(Equal("kube-kms-10"))
+		keyID, err := plugin.Rotate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyID).To(Equal("kube-kms-11"))
+	})
+
+	It("Should use the provider holding each key", func() {
+		plugin := newPlugin(parsec.WithProvider(parsec.ProviderTPM))
+		encrypted, err := plugin.Encrypt(ctx, "1", []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+		for _, r := range parsecService.Received() {
+			if r.OpCode == requests.OpPsaAeadEncrypt || r.OpCode == requests.OpPsaGenerateKey {
+				Expect(r.Provider).To(Equal(requests.ProviderTPM))
+			}
+		}
+
+		// A plugin whose calls default to another provider still finds the key
+		decrypted, err := newPlugin().Decrypt(ctx, "2", &service.DecryptRequest{Ciphertext: encrypted.Ciphertext, Annotations: encrypted.Annotations})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decrypted).To(Equal([]byte("secret")))
+	})
+
+	It("Should refuse ciphertexts it did not make", func() {
+		plugin := newPlugin()
+		old, err := plugin.Encrypt(ctx, "1", []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = plugin.Rotate()
+		Expect(err).NotTo(HaveOccurred())
+
+		decrypt := func(ciphertext []byte, keyID string, annotation string) error {
+			req := &service.DecryptRequest{Ciphertext: ciphertext, KeyID: keyID}
+			if annotation != "" {
+				req.Annotations = map[string][]byte{kmsplugin.KeyIDAnnotation: []byte(annotation)}
+			}
+			_, err := plugin.Decrypt(ctx, "2", req)
+			return err
+		}
+		Expect(decrypt(old.Ciphertext, "kube-kms-1", "kube-kms-1")).To(Succeed())
+		Expect(decrypt(old.Ciphertext, "kube-kms-1", "")).To(MatchError(ContainSubstring("no " + kmsplugin.KeyIDAnnotation)))
+		Expect(decrypt(old.Ciphertext, "kube-kms-2", "kube-kms-1")).To(MatchError(ContainSubstring("does not match")))
+		// The key ID is authenticated, so the ciphertext cannot be decrypted as another version's
+		Expect(decrypt(old.Ciphertext, "", "kube-kms-2")).NotTo(Succeed())
+		Expect(decrypt(old.Ciphertext, "", "other-1")).To(MatchError(ContainSubstring("not a version")))
+		Expect(decrypt(old.Ciphertext, "", "kube-kms-7")).To(MatchError(parsec.ErrKeyNotFound))
+		Expect(decrypt(old.Ciphertext[:kmsplugin.NonceSize-1], "", "kube-kms-1")).To(MatchError(ContainSubstring("too short")))
+		tampered := append([]byte{}, old.Ciphertext...)
+		tampered[len(tampered)-1] ^= 1
+		Expect(decrypt(tampered, "", "kube-kms-1")).NotTo(Succeed())
+	})
+
+	It("Should refuse keys that cannot encrypt with an AEAD", func() {
+		_, err := kmsplugin.New(newClient(), "kube-kms", parsec.DefaultKeyAttribute().HmacKey())
+		Expect(err).To(HaveOccurred())
+		_, err = kmsplugin.New(newClient(), "", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(provider.KeyNames()).To(BeEmpty())
+	})
+})
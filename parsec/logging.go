@@ -0,0 +1,42 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+)
+
+// newLoggingInterceptor returns an interceptor tracing each operation to logger at debug level.  Request and
+// response bodies are logged with key material and plaintexts redacted.
+func newLoggingInterceptor(logger *slog.Logger) operations.Interceptor {
+	return func(op *operations.Operation, next operations.Invoker) error {
+		ctx := context.Background()
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			return next(op)
+		}
+		start := time.Now()
+		err := next(op)
+		attrs := []slog.Attr{
+			slog.String("opcode", op.OpCode.String()),
+			slog.String("provider", op.Provider.String()),
+			slog.String("authenticator", op.AuthType().String()),
+			slog.Int("request_bytes", op.RequestSize),
+			slog.Int("response_bytes", op.ResponseSize),
+			slog.Duration("duration", time.Since(start)),
+			slog.Any("request", operations.Redacted{Message: op.Request}),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			logger.LogAttrs(ctx, slog.LevelDebug, "parsec operation failed", attrs...)
+			return err
+		}
+		attrs = append(attrs, slog.Any("response", operations.Redacted{Message: op.Response}))
+		logger.LogAttrs(ctx, slog.LevelDebug, "parsec operation", attrs...)
+		return nil
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaalgorithm"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"google.golang.org/protobuf/proto"
+)
+
+// The JSON encodings below are the JSON form of the wire representation, using the field and enum names of the
+// parsec operations, so are stable and may be stored and compared.  The text encodings are the same as the JSON
+// encodings.
+
+// MarshalJSON encodes the key type, for example {"ecc_key_pair":{"curve_family":"SECP_R1"}}.
+func (k *KeyType) MarshalJSON() ([]byte, error) {
+	wire, ok := k.ToWireInterface().(*psakeyattributes.KeyType)
+	if !ok || wire == nil {
+		return nil, fmt.Errorf("key type has no variant")
+	}
+	return operations.MarshalJSON(wire)
+}
+
+// UnmarshalJSON decodes a key type encoded by MarshalJSON, returning an error if it is not a valid key type.
+func (k *KeyType) UnmarshalJSON(data []byte) error {
+	wire := &psakeyattributes.KeyType{}
+	if err := operations.UnmarshalJSON(data, wire); err != nil {
+		return fmt.Errorf("could not decode key type: %w", err)
+	}
+	kt, err := newKeyTypeFromWire(wire)
+	if err != nil {
+		return err
+	}
+	*k = *kt
+	return nil
+}
+
+// MarshalText encodes the key type in the same way as MarshalJSON.
+func (k *KeyType) MarshalText() ([]byte, error) {
+	return k.MarshalJSON()
+}
+
+// UnmarshalText decodes a key type encoded by MarshalText.
+func (k *KeyType) UnmarshalText(text []byte) error {
+	return k.UnmarshalJSON(text)
+}
+
+// MarshalJSON encodes the usage flags that are set, for example {"sign_hash":true,"verify_hash":true}.
+func (u *UsageFlags) MarshalJSON() ([]byte, error) {
+	return operations.MarshalJSON(u.toNativeWireInterface())
+}
+
+// UnmarshalJSON decodes usage flags encoded by MarshalJSON.  Flags that are not present are cleared.
+func (u *UsageFlags) UnmarshalJSON(data []byte) error {
+	wire := &psakeyattributes.UsageFlags{}
+	if err := operations.UnmarshalJSON(data, wire); err != nil {
+		return fmt.Errorf("could not decode usage flags: %w", err)
+	}
+	*u = *newUsageFlagsFromOp(wire)
+	return nil
+}
+
+// MarshalText encodes the usage flags in the same way as MarshalJSON.
+func (u *UsageFlags) MarshalText() ([]byte, error) {
+	return u.MarshalJSON()
+}
+
+// UnmarshalText decodes usage flags encoded by MarshalText.
+func (u *UsageFlags) UnmarshalText(text []byte) error {
+	return u.UnmarshalJSON(text)
+}
+
+// MarshalJSON encodes the key attributes.  A policy without an algorithm is encoded with the none algorithm.
+func (ka *KeyAttributes) MarshalJSON() ([]byte, error) {
+	wire, err := ka.toMarshalWire()
+	if err != nil {
+		return nil, err
+	}
+	return operations.MarshalJSON(wire)
+}
+
+// UnmarshalJSON decodes key attributes encoded by MarshalJSON, returning an error if they are not valid.
+func (ka *KeyAttributes) UnmarshalJSON(data []byte) error {
+	wire := &psakeyattributes.KeyAttributes{}
+	if err := operations.UnmarshalJSON(data, wire); err != nil {
+		return fmt.Errorf("could not decode key attributes: %w", err)
+	}
+	attributes, err := newKeyAttributesFromOp(wire)
+	if err != nil {
+		return err
+	}
+	// Check nothing was lost in the conversion, for example a field from another algorithm variant.
+	roundTrip, err := attributes.toMarshalWire()
+	if err != nil {
+		return err
+	}
+	if !proto.Equal(wire, roundTrip) {
+		return fmt.Errorf("invalid key attributes %s", data)
+	}
+	*ka = *attributes
+	return nil
+}
+
+// MarshalText encodes the key attributes in the same way as MarshalJSON.
+func (ka *KeyAttributes) MarshalText() ([]byte, error) {
+	return ka.MarshalJSON()
+}
+
+// UnmarshalText decodes key attributes encoded by MarshalText.
+func (ka *KeyAttributes) UnmarshalText(text []byte) error {
+	return ka.UnmarshalJSON(text)
+}
+
+// toMarshalWire is toWireInterface, except that a policy without an algorithm is given the none algorithm, as
+// newKeyPolicyFromOp does in reverse.
+func (ka *KeyAttributes) toMarshalWire() (*psakeyattributes.KeyAttributes, error) {
+	if ka == nil || ka.KeyPolicy == nil || ka.KeyPolicy.KeyAlgorithm != nil {
+		return ka.toWireInterface()
+	}
+	if ka.KeyType == nil {
+		return nil, fmt.Errorf("key attributes must have a key type")
+	}
+	keyType, ok := ka.KeyType.ToWireInterface().(*psakeyattributes.KeyType)
+	if !ok || keyType == nil {
+		return nil, fmt.Errorf("key attributes must have a key type")
+	}
+	if ka.KeyPolicy.KeyUsageFlags == nil {
+		return nil, fmt.Errorf("key policy must have usage flags")
+	}
+	return &psakeyattributes.KeyAttributes{
+		KeyType: keyType,
+		KeyBits: ka.KeyBits,
+		KeyPolicy: &psakeyattributes.KeyPolicy{
+			KeyUsageFlags: ka.KeyPolicy.KeyUsageFlags.toNativeWireInterface(),
+			KeyAlgorithm:  &psaalgorithm.Algorithm{Variant: &psaalgorithm.Algorithm_None_{None: &psaalgorithm.Algorithm_None{}}},
+		},
+	}, nil
+}
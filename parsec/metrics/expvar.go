@@ -0,0 +1,149 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"sort"
+	"sync"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency histogram buckets.
+var DefaultLatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram holds a distribution of observed values.  Counts[i] is the number of observations less than or equal
+// to Buckets[i] and greater than Buckets[i-1]; observations greater than the last bucket are only included in Count.
+type Histogram struct {
+	Buckets []float64 `json:"buckets"`
+	Counts  []uint64  `json:"counts"`
+	Count   uint64    `json:"count"`
+	Sum     float64   `json:"sum"`
+}
+
+func (h *Histogram) observe(v float64) {
+	h.Count++
+	h.Sum += v
+	for i, upper := range h.Buckets {
+		if v <= upper {
+			h.Counts[i]++
+			return
+		}
+	}
+}
+
+// OperationStats holds the metrics for one operation on one provider.
+type OperationStats struct {
+	Operation     string `json:"operation"`
+	Provider      string `json:"provider"`
+	Requests      uint64 `json:"requests"`
+	BytesSent     uint64 `json:"bytes_sent"`
+	BytesReceived uint64 `json:"bytes_received"`
+	// Errors counts failed requests keyed by status code name, see Observation.Status.
+	Errors map[string]uint64 `json:"errors"`
+	// Latency is the distribution of request durations in seconds.
+	Latency Histogram `json:"latency_seconds"`
+}
+
+func (s *OperationStats) copy() OperationStats {
+	c := *s
+	c.Errors = make(map[string]uint64, len(s.Errors))
+	for k, v := range s.Errors {
+		c.Errors[k] = v
+	}
+	c.Latency.Buckets = append([]float64(nil), s.Latency.Buckets...)
+	c.Latency.Counts = append([]uint64(nil), s.Latency.Counts...)
+	return c
+}
+
+type seriesKey struct {
+	opCode   requests.OpCode
+	provider requests.ProviderID
+}
+
+// ExpvarCollector is a Collector that aggregates observations in memory.  It implements expvar.Var, rendering
+// its statistics as JSON, so can be published on the expvar endpoint with Publish.
+type ExpvarCollector struct {
+	mu      sync.Mutex
+	buckets []float64
+	stats   map[seriesKey]*OperationStats
+}
+
+// NewExpvarCollector creates an ExpvarCollector using DefaultLatencyBuckets.
+func NewExpvarCollector() *ExpvarCollector {
+	return NewExpvarCollectorWithBuckets(DefaultLatencyBuckets)
+}
+
+// NewExpvarCollectorWithBuckets creates an ExpvarCollector with the latency histogram bucket upper bounds, in
+// seconds, supplied.  Buckets must be in increasing order.
+func NewExpvarCollectorWithBuckets(buckets []float64) *ExpvarCollector {
+	return &ExpvarCollector{
+		buckets: append([]float64(nil), buckets...),
+		stats:   make(map[seriesKey]*OperationStats),
+	}
+}
+
+// Observe records an observation.
+func (c *ExpvarCollector) Observe(o *Observation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := seriesKey{opCode: o.OpCode, provider: o.Provider}
+	stats, ok := c.stats[key]
+	if !ok {
+		stats = &OperationStats{
+			Operation: o.OpCode.String(),
+			Provider:  o.Provider.String(),
+			Errors:    make(map[string]uint64),
+			Latency: Histogram{
+				Buckets: c.buckets,
+				Counts:  make([]uint64, len(c.buckets)),
+			},
+		}
+		c.stats[key] = stats
+	}
+	stats.Requests++
+	stats.BytesSent += uint64(o.BytesSent)
+	stats.BytesReceived += uint64(o.BytesReceived)
+	if o.Err != nil {
+		stats.Errors[o.Status()]++
+	}
+	stats.Latency.observe(o.Duration.Seconds())
+}
+
+// Snapshot returns a copy of the current statistics, ordered by opcode then provider.
+func (c *ExpvarCollector) Snapshot() []OperationStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]seriesKey, 0, len(c.stats))
+	for k := range c.stats {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].opCode != keys[j].opCode {
+			return keys[i].opCode < keys[j].opCode
+		}
+		return keys[i].provider < keys[j].provider
+	})
+	snapshot := make([]OperationStats, len(keys))
+	for i, k := range keys {
+		snapshot[i] = c.stats[k].copy()
+	}
+	return snapshot
+}
+
+// String renders the statistics as JSON, implementing expvar.Var.
+func (c *ExpvarCollector) String() string {
+	b, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
+
+// Publish publishes the collector as an expvar variable.  As with expvar.Publish, this panics if name is already in use.
+func (c *ExpvarCollector) Publish(name string) {
+	expvar.Publish(name, c)
+}
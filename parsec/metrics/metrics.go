@@ -0,0 +1,64 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics collects request counts, latencies, error counts and traffic volumes for operations sent to
+// the parsec service.  Metrics are gathered by an interceptor added to the client configuration, e.g:
+//
+//	collector := metrics.NewExpvarCollector()
+//	collector.Publish("parsec")
+//	config := parsec.NewClientConfig().Interceptors(metrics.NewInterceptor(collector))
+package metrics
+
+import (
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+)
+
+// Observation is a record of a single operation sent to the parsec service.
+type Observation struct {
+	OpCode   requests.OpCode
+	Provider requests.ProviderID
+	Duration time.Duration
+	// BytesSent and BytesReceived are the sizes of the request and response on the wire, including headers.
+	BytesSent     int
+	BytesReceived int
+	// Err is the error returned by the operation, nil if it succeeded.
+	Err error
+}
+
+// Status returns the name of the response status code for the observation.  If the operation failed without
+// the service returning a status code, for instance due to a connection failure, ClientError is returned.
+func (o *Observation) Status() string {
+	code, ok := requests.StatusCodeFromError(o.Err)
+	if !ok {
+		return StatusClientError
+	}
+	return code.String()
+}
+
+// StatusClientError is the status reported for operations that failed before a response status was received.
+const StatusClientError = "ClientError"
+
+// Collector receives observations of operations.  Implementations must be safe for concurrent use.
+type Collector interface {
+	Observe(o *Observation)
+}
+
+// NewInterceptor returns an interceptor that times each operation and reports it to collector.
+func NewInterceptor(collector Collector) operations.Interceptor {
+	return func(op *operations.Operation, next operations.Invoker) error {
+		start := time.Now()
+		err := next(op)
+		collector.Observe(&Observation{
+			OpCode:        op.OpCode,
+			Provider:      op.Provider,
+			Duration:      time.Since(start),
+			BytesSent:     op.RequestSize,
+			BytesReceived: op.ResponseSize,
+			Err:           err,
+		})
+		return err
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Source provides the statistics rendered by the Prometheus handler.  ExpvarCollector is a Source.
+type Source interface {
+	Snapshot() []OperationStats
+}
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// NewPrometheusHandler returns an http.Handler serving the statistics from source in the Prometheus text exposition format.
+func NewPrometheusHandler(source Source) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		if err := WritePrometheus(w, source.Snapshot()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WritePrometheus writes stats to w in the Prometheus text exposition format.
+func WritePrometheus(w io.Writer, stats []OperationStats) error {
+	bw := bufio.NewWriter(w)
+
+	writeHeader(bw, "parsec_requests_total", "counter", "Number of requests sent to the parsec service.")
+	for i := range stats {
+		fmt.Fprintf(bw, "parsec_requests_total%s %d\n", labels(&stats[i]), stats[i].Requests)
+	}
+
+	writeHeader(bw, "parsec_request_errors_total", "counter", "Number of failed requests by response status.")
+	for i := range stats {
+		statuses := make([]string, 0, len(stats[i].Errors))
+		for status := range stats[i].Errors {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(bw, "parsec_request_errors_total%s %d\n", labels(&stats[i], "status", status), stats[i].Errors[status])
+		}
+	}
+
+	writeHeader(bw, "parsec_request_duration_seconds", "histogram", "Latency of requests to the parsec service.")
+	for i := range stats {
+		h := &stats[i].Latency
+		var cumulative uint64
+		for b, upper := range h.Buckets {
+			cumulative += h.Counts[b]
+			le := strconv.FormatFloat(upper, 'g', -1, 64)
+			fmt.Fprintf(bw, "parsec_request_duration_seconds_bucket%s %d\n", labels(&stats[i], "le", le), cumulative)
+		}
+		fmt.Fprintf(bw, "parsec_request_duration_seconds_bucket%s %d\n", labels(&stats[i], "le", "+Inf"), h.Count)
+		fmt.Fprintf(bw, "parsec_request_duration_seconds_sum%s %s\n", labels(&stats[i]), strconv.FormatFloat(h.Sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "parsec_request_duration_seconds_count%s %d\n", labels(&stats[i]), h.Count)
+	}
+
+	writeHeader(bw, "parsec_request_bytes_sent_total", "counter", "Bytes sent to the parsec service.")
+	for i := range stats {
+		fmt.Fprintf(bw, "parsec_request_bytes_sent_total%s %d\n", labels(&stats[i]), stats[i].BytesSent)
+	}
+
+	writeHeader(bw, "parsec_response_bytes_received_total", "counter", "Bytes received from the parsec service.")
+	for i := range stats {
+		fmt.Fprintf(bw, "parsec_response_bytes_received_total%s %d\n", labels(&stats[i]), stats[i].BytesReceived)
+	}
+
+	return bw.Flush()
+}
+
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// labels formats the operation and provider labels for s, followed by any extra name, value pairs.
+func labels(s *OperationStats, extra ...string) string {
+	l := fmt.Sprintf("{operation=%s,provider=%s", strconv.Quote(s.Operation), strconv.Quote(s.Provider))
+	for i := 0; i+1 < len(extra); i += 2 {
+		l += fmt.Sprintf(",%s=%s", extra[i], strconv.Quote(extra[i+1]))
+	}
+	return l + "}"
+}
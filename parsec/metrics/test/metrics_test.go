@@ -0,0 +1,113 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/parsec/metrics"
+)
+
+func sendOps(t *testing.T, collector metrics.Collector) {
+	t.Helper()
+	interceptor := metrics.NewInterceptor(collector)
+	results := []error{
+		nil,
+		nil,
+		requests.StatusPsaErrorNotPermitted.ToErr(),
+		fmt.Errorf("connection refused"),
+	}
+	for _, result := range results {
+		op := &operations.Operation{OpCode: requests.OpPsaSignHash, Provider: requests.ProviderTPM}
+		err := interceptor(op, func(op *operations.Operation) error {
+			op.RequestSize = 100
+			op.ResponseSize = 50
+			return result
+		})
+		if err != result { //nolint:errorlint // must be passed through unchanged
+			t.Fatalf("expected interceptor to return %v, got %v", result, err)
+		}
+	}
+	collector.Observe(&metrics.Observation{OpCode: requests.OpPing, Provider: requests.ProviderCore, Duration: 2 * time.Second})
+}
+
+func TestCollectorAggregates(t *testing.T) {
+	c := metrics.NewExpvarCollector()
+	sendOps(t, c)
+	stats := c.Snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 operations, got %v", len(stats))
+	}
+	if stats[0].Operation != "Ping" || stats[1].Operation != "PsaSignHash" || stats[1].Provider != "TPM" {
+		t.Fatalf("unexpected series %+v", stats)
+	}
+	sign := stats[1]
+	if sign.Requests != 4 || sign.BytesSent != 400 || sign.BytesReceived != 200 {
+		t.Errorf("unexpected counts %+v", sign)
+	}
+	if sign.Errors["PsaErrorNotPermitted"] != 1 || sign.Errors[metrics.StatusClientError] != 1 || len(sign.Errors) != 2 {
+		t.Errorf("unexpected errors %v", sign.Errors)
+	}
+	if sign.Latency.Count != 4 {
+		t.Errorf("expected 4 latency observations, got %v", sign.Latency.Count)
+	}
+	ping := stats[0]
+	bucket := -1
+	for i, upper := range ping.Latency.Buckets {
+		if upper == 2.5 {
+			bucket = i
+		}
+	}
+	if bucket < 0 || ping.Latency.Counts[bucket] != 1 || ping.Latency.Sum != 2 {
+		t.Errorf("expected 2 second ping in the 2.5 second bucket, got %+v", ping.Latency)
+	}
+}
+
+func TestExpvarJSON(t *testing.T) {
+	c := metrics.NewExpvarCollector()
+	sendOps(t, c)
+	var decoded []metrics.OperationStats
+	if err := json.Unmarshal([]byte(c.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[1].Requests != 4 {
+		t.Errorf("unexpected expvar output %v", c.String())
+	}
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	c := metrics.NewExpvarCollectorWithBuckets([]float64{1, 5})
+	sendOps(t, c)
+	rec := httptest.NewRecorder()
+	metrics.NewPrometheusHandler(c).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %v", ct)
+	}
+	body := rec.Body.String()
+	expected := []string{
+		"# TYPE parsec_requests_total counter",
+		`parsec_requests_total{operation="PsaSignHash",provider="TPM"} 4`,
+		`parsec_request_errors_total{operation="PsaSignHash",provider="TPM",status="PsaErrorNotPermitted"} 1`,
+		`parsec_request_errors_total{operation="PsaSignHash",provider="TPM",status="ClientError"} 1`,
+		"# TYPE parsec_request_duration_seconds histogram",
+		`parsec_request_duration_seconds_bucket{operation="Ping",provider="Core",le="1"} 0`,
+		`parsec_request_duration_seconds_bucket{operation="Ping",provider="Core",le="5"} 1`,
+		`parsec_request_duration_seconds_bucket{operation="Ping",provider="Core",le="+Inf"} 1`,
+		`parsec_request_duration_seconds_sum{operation="Ping",provider="Core"} 2`,
+		`parsec_request_bytes_sent_total{operation="PsaSignHash",provider="TPM"} 400`,
+		`parsec_response_bytes_received_total{operation="PsaSignHash",provider="TPM"} 200`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected output to contain %q, got:\n%v", line, body)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"sort"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+)
+
+// OpCode identifies an operation supported by the parsec service
+type OpCode uint32
+
+// Operation codes
+const (
+	OpPing                 = OpCode(requests.OpPing)
+	OpPsaGenerateKey       = OpCode(requests.OpPsaGenerateKey)
+	OpPsaDestroyKey        = OpCode(requests.OpPsaDestroyKey)
+	OpPsaSignHash          = OpCode(requests.OpPsaSignHash)
+	OpPsaVerifyHash        = OpCode(requests.OpPsaVerifyHash)
+	OpPsaImportKey         = OpCode(requests.OpPsaImportKey)
+	OpPsaExportPublicKey   = OpCode(requests.OpPsaExportPublicKey)
+	OpListProviders        = OpCode(requests.OpListProviders)
+	OpListOpcodes          = OpCode(requests.OpListOpcodes)
+	OpPsaAsymmetricEncrypt = OpCode(requests.OpPsaAsymmetricEncrypt)
+	OpPsaAsymmetricDecrypt = OpCode(requests.OpPsaAsymmetricDecrypt)
+	OpPsaExportKey         = OpCode(requests.OpPsaExportKey)
+	OpPsaGenerateRandom    = OpCode(requests.OpPsaGenerateRandom)
+	OpListAuthenticators   = OpCode(requests.OpListAuthenticators)
+	OpPsaHashCompute       = OpCode(requests.OpPsaHashCompute)
+	OpPsaHashCompare       = OpCode(requests.OpPsaHashCompare)
+	OpPsaAeadEncrypt       = OpCode(requests.OpPsaAeadEncrypt)
+	OpPsaAeadDecrypt       = OpCode(requests.OpPsaAeadDecrypt)
+	OpPsaRawKeyAgreement   = OpCode(requests.OpPsaRawKeyAgreement)
+	OpPsaCipherEncrypt     = OpCode(requests.OpPsaCipherEncrypt)
+	OpPsaCipherDecrypt     = OpCode(requests.OpPsaCipherDecrypt)
+	OpPsaMacCompute        = OpCode(requests.OpPsaMacCompute)
+	OpPsaMacVerify         = OpCode(requests.OpPsaMacVerify)
+	OpPsaSignMessage       = OpCode(requests.OpPsaSignMessage)
+	OpPsaVerifyMessage     = OpCode(requests.OpPsaVerifyMessage)
+	OpListKeys             = OpCode(requests.OpListKeys)
+	OpListClients          = OpCode(requests.OpListClients)
+	OpDeleteClient         = OpCode(requests.OpDeleteClient)
+)
+
+func (o OpCode) String() string {
+	return requests.OpCode(o).String()
+}
+
+// OpCodeSet is a set of operations, such as those supported by a provider.
+type OpCodeSet map[OpCode]struct{}
+
+func newOpCodeSet(opcodes []uint32) OpCodeSet {
+	set := make(OpCodeSet, len(opcodes))
+	for _, op := range opcodes {
+		set[OpCode(op)] = struct{}{}
+	}
+	return set
+}
+
+// Contains returns true if op is in the set.
+func (s OpCodeSet) Contains(op OpCode) bool {
+	_, ok := s[op]
+	return ok
+}
+
+// List returns the operations in the set in opcode order.
+func (s OpCodeSet) List() []OpCode {
+	ops := make([]OpCode, 0, len(s))
+	for op := range s {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	return ops
+}
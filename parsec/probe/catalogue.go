@@ -0,0 +1,77 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package probe
+
+import (
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// Combination is a key type and size with the algorithm the key is used with.
+type Combination struct {
+	// Key describes the key type and size, such as RSA-2048 or AES-128.
+	Key string
+	// Attributes are used to generate the key, so they must permit the operations the algorithm is probed with.
+	Attributes *parsec.KeyAttributes
+}
+
+// Algorithm returns the PSA name of the combination's algorithm.
+func (c Combination) Algorithm() string {
+	return c.Attributes.KeyPolicy.KeyAlgorithm.String()
+}
+
+func cipherKey(mode algorithm.CipherModeType, bits uint32) *parsec.KeyAttributes {
+	return &parsec.KeyAttributes{
+		KeyBits: bits,
+		KeyType: parsec.NewKeyType().Aes(),
+		KeyPolicy: &parsec.KeyPolicy{
+			KeyAlgorithm:  algorithm.NewCipher(mode),
+			KeyUsageFlags: &parsec.UsageFlags{Encrypt: true, Decrypt: true},
+		},
+	}
+}
+
+func aeadKey(keyType *parsec.KeyType, alg algorithm.AeadAlgorithmType, bits uint32) *parsec.KeyAttributes {
+	return &parsec.KeyAttributes{
+		KeyBits: bits,
+		KeyType: keyType,
+		KeyPolicy: &parsec.KeyPolicy{
+			KeyAlgorithm:  algorithm.NewAead().Aead(alg),
+			KeyUsageFlags: &parsec.UsageFlags{Encrypt: true, Decrypt: true},
+		},
+	}
+}
+
+// Catalogue returns the combinations commonly supported by parsec providers, in order of preference within each
+// kind of key.
+func Catalogue() []Combination {
+	d := parsec.DefaultKeyAttribute()
+	rsaPkcs1V15Crypt := d.RsaOaepEncryptionKey()
+	rsaPkcs1V15Crypt.KeyPolicy.KeyAlgorithm = algorithm.NewAsymmetricEncryption().RsaPkcs1V15Crypt()
+	return []Combination{
+		{"SECP_R1-256", d.EcdsaSigningKey()},
+		{"SECP_R1-384", d.EcdsaSigningKey(parsec.WithKeyBits(384))},
+		{"SECP_R1-521", d.EcdsaSigningKey(parsec.WithKeyBits(521))},
+		{"RSA-2048", d.RsaPssSigningKey()},
+		{"RSA-2048", d.SigningKey()},
+		{"RSA-3072", d.SigningKey(parsec.WithKeyBits(3072), parsec.WithHash(algorithm.HashAlgorithmTypeSHA384))},
+		{"RSA-4096", d.SigningKey(parsec.WithKeyBits(4096), parsec.WithHash(algorithm.HashAlgorithmTypeSHA512))},
+		{"RSA-2048", d.RsaOaepEncryptionKey()},
+		{"RSA-2048", rsaPkcs1V15Crypt},
+		{"AES-256", d.AesGcmKey()},
+		{"AES-128", d.AesGcmKey(parsec.WithKeyBits(128))},
+		{"ChaCha20-256", d.Chacha20Poly1305Key()},
+		{"AES-128", aeadKey(parsec.NewKeyType().Aes(), algorithm.AeadAlgorithmCCM, 128)},
+		{"AES-256", cipherKey(algorithm.CipherModeCBCPKCS7, 256)},
+		{"AES-256", cipherKey(algorithm.CipherModeCBCNOPADDING, 256)},
+		{"AES-256", cipherKey(algorithm.CipherModeCTR, 256)},
+		{"HMAC-256", d.HmacKey()},
+		{"HMAC-384", d.HmacKey(parsec.WithHash(algorithm.HashAlgorithmTypeSHA384))},
+		{"HMAC-512", d.HmacKey(parsec.WithHash(algorithm.HashAlgorithmTypeSHA512))},
+		{"AES-256", d.CmacKey()},
+		{"SECP_R1-256", d.EcdhKey()},
+		{"MONTGOMERY-255", d.X25519Key()},
+		{"FFDH-2048", d.FfdhKey()},
+	}
+}
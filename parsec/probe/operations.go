@@ -0,0 +1,121 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package probe
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+type prober struct {
+	client *parsec.BasicClient
+	opts   []parsec.CallOption
+}
+
+func randomBytes(size int) []byte {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	return data
+}
+
+// probe generates a key for the combination, tries the operations of its algorithm and destroys the key.
+func (p *prober) probe(name string, c Combination) Result {
+	res := &Result{Key: c.Key, Algorithm: c.Algorithm()}
+	// Remove any key left by an earlier run that was interrupted
+	_ = p.client.PsaDestroyKey(name, p.opts...)
+	k, err := p.client.GenerateKey(name, c.Attributes, p.opts...)
+	if err != nil {
+		res.Support = support(err)
+		res.Detail = err.Error()
+		return *res
+	}
+	defer func() { _ = k.Destroy() }()
+
+	alg := c.Attributes.KeyPolicy.KeyAlgorithm
+	switch {
+	case alg.GetAsymmetricSignature() != nil:
+		p.signature(res, k, alg.GetAsymmetricSignature())
+	case alg.GetAsymmetricEncryption() != nil:
+		p.asymmetricEncryption(res, k)
+	case alg.GetCipher() != nil:
+		p.cipher(res, k)
+	case alg.GetAead() != nil:
+		p.aead(res, k, alg.GetAead())
+	case alg.GetMac() != nil:
+		p.mac(res, k)
+	case alg.GetKeyAgreement() != nil && alg.GetKeyAgreement().GetRaw() != nil:
+		p.keyAgreement(res, k, alg.GetKeyAgreement().GetRaw())
+	default:
+		res.Support = Error
+		res.Detail = fmt.Sprintf("no operations to probe %v with", alg)
+	}
+	return res.finish()
+}
+
+func (p *prober) signature(res *Result, k *parsec.Key, alg *algorithm.AsymmetricSignatureAlgorithm) {
+	hash := randomBytes(alg.HashAlg().Size())
+	signature, err := k.SignHash(hash)
+	res.add("sign hash", err)
+	if err == nil {
+		res.add("verify hash", k.VerifyHash(hash, signature))
+	}
+	_, err = k.ExportPublic()
+	res.add("export public key", err)
+}
+
+func (p *prober) asymmetricEncryption(res *Result, k *parsec.Key) {
+	_, err := k.ExportPublic()
+	res.add("export public key", err)
+	ciphertext, err := k.Encrypt(randomBytes(16))
+	res.add("encrypt", err)
+	if err == nil {
+		_, err = k.Decrypt(ciphertext)
+		res.add("decrypt", err)
+	}
+}
+
+func (p *prober) cipher(res *Result, k *parsec.Key) {
+	// Two blocks suit the modes without padding
+	ciphertext, err := k.Encrypt(randomBytes(32))
+	res.add("encrypt", err)
+	if err == nil {
+		_, err = k.Decrypt(ciphertext)
+		res.add("decrypt", err)
+	}
+}
+
+func (p *prober) aead(res *Result, k *parsec.Key, alg *algorithm.AeadAlgorithm) {
+	nonce := randomBytes(parsec.AeadNonceLength)
+	if d := alg.GetAeadDefaultLengthTag(); d != nil && d.AeadAlg == algorithm.AeadAlgorithmCCM {
+		nonce = randomBytes(13)
+	}
+	ciphertext, err := k.AeadEncrypt(nonce, nil, randomBytes(32))
+	res.add("encrypt", err)
+	if err == nil {
+		_, err = k.AeadDecrypt(nonce, nil, ciphertext)
+		res.add("decrypt", err)
+	}
+}
+
+func (p *prober) mac(res *Result, k *parsec.Key) {
+	input := randomBytes(32)
+	mac, err := k.MAC(input)
+	res.add("compute", err)
+	if err == nil {
+		res.add("verify", k.VerifyMAC(input, mac))
+	}
+}
+
+// keyAgreement agrees a secret between the key and its own public key, which is as valid a peer key as any.
+func (p *prober) keyAgreement(res *Result, k *parsec.Key, alg *algorithm.KeyAgreementRaw) {
+	public, err := k.ExportPublic()
+	res.add("export public key", err)
+	if err == nil {
+		_, err = p.client.PsaRawKeyAgreement(alg, k.Name(), public, p.opts...)
+		res.add("raw key agreement", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package probe discovers which key types, sizes and algorithms each provider supports.  ListOpcodes only says which
+// operations a provider has, so for each combination in a catalogue a temporary key is generated, each operation
+// the algorithm is used for is tried, and the key is destroyed.  The resulting support matrix can be reported, or
+// used to select the first supported combination from a list of preferences.
+//
+// The keys used are named with the prefix parsec-probe-.
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+// Support says whether a provider supports a combination or operation.
+type Support string
+
+const (
+	Supported    Support = "supported"
+	NotSupported Support = "not_supported"
+	// Error means an operation failed for a reason other than not being supported.
+	Error Support = "error"
+)
+
+// Operation is the outcome of trying one operation with a combination's key.
+type Operation struct {
+	Name    string  `json:"name"`
+	Support Support `json:"support"`
+	Detail  string  `json:"detail,omitempty"`
+}
+
+// Result is the support of one combination by a provider.
+type Result struct {
+	Key       string `json:"key"`
+	Algorithm string `json:"algorithm"`
+	// Support is Supported if the key was generated and every operation succeeded.  Otherwise it is Error if
+	// generating the key or any operation failed, or NotSupported.
+	Support Support `json:"support"`
+	// Detail explains why the key could not be generated.
+	Detail     string      `json:"detail,omitempty"`
+	Operations []Operation `json:"operations"`
+}
+
+// ProviderResults holds the results of probing one provider.
+type ProviderResults struct {
+	ID      parsec.ProviderID `json:"id"`
+	Name    string            `json:"name"`
+	Results []Result          `json:"results"`
+}
+
+// Matrix is the support of each combination by each provider.
+type Matrix struct {
+	Providers []ProviderResults `json:"providers"`
+}
+
+// Support returns the support of the combination by the provider, or NotSupported if it was not probed.
+func (m *Matrix) Support(provider parsec.ProviderID, c Combination) Support {
+	for _, p := range m.Providers {
+		if p.ID != provider {
+			continue
+		}
+		for _, res := range p.Results {
+			if res.Key == c.Key && res.Algorithm == c.Algorithm() {
+				return res.Support
+			}
+		}
+	}
+	return NotSupported
+}
+
+// Select returns the first of the combinations, in order of preference, that the provider supports.  The boolean
+// return is false if none are supported.
+func (m *Matrix) Select(provider parsec.ProviderID, preferences []Combination) (Combination, bool) {
+	for _, c := range preferences {
+		if m.Support(provider, c) == Supported {
+			return c, true
+		}
+	}
+	return Combination{}, false
+}
+
+// WriteText writes the matrix as a table for people to read, with a row for each combination and a column for
+// each provider.
+func (m *Matrix) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprint(table, "KEY\tALGORITHM")
+	for _, p := range m.Providers {
+		fmt.Fprintf(table, "\t%v", p.Name)
+	}
+	fmt.Fprintln(table)
+	if len(m.Providers) != 0 {
+		for i, res := range m.Providers[0].Results {
+			fmt.Fprintf(table, "%v\t%v", res.Key, res.Algorithm)
+			for _, p := range m.Providers {
+				fmt.Fprintf(table, "\t%v", p.Results[i].Support)
+			}
+			fmt.Fprintln(table)
+		}
+	}
+	return table.Flush()
+}
+
+// Probe tries each combination with each of the providers, or with every provider other than the core provider if
+// none are given.  An error is only returned if the providers cannot be listed: failures of the combinations are
+// recorded in the matrix.
+func Probe(client *parsec.BasicClient, providers []parsec.ProviderID, combinations []Combination, opts ...parsec.CallOption) (*Matrix, error) {
+	if len(providers) == 0 {
+		infos, err := client.ListProviders(opts...)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if info.ID.HasCrypto() {
+				providers = append(providers, info.ID)
+			}
+		}
+	}
+	m := &Matrix{}
+	for _, provider := range providers {
+		p := &prober{
+			client: client,
+			opts:   append(append([]parsec.CallOption{}, opts...), parsec.WithProvider(provider)),
+		}
+		results := ProviderResults{ID: provider, Name: provider.String()}
+		for i, c := range combinations {
+			results.Results = append(results.Results, p.probe(fmt.Sprintf("parsec-probe-%d", i), c))
+		}
+		m.Providers = append(m.Providers, results)
+	}
+	return m, nil
+}
+
+// support classifies the outcome of an operation.
+func support(err error) Support {
+	if err == nil {
+		return Supported
+	}
+	if errors.Is(err, parsec.ErrOperationNotSupported) {
+		return NotSupported
+	}
+	code, _ := requests.StatusCodeFromError(err)
+	if code == requests.StatusPsaErrorNotSupported || code == requests.StatusOpcodeDoesNotExist {
+		return NotSupported
+	}
+	return Error
+}
+
+func (res *Result) add(name string, err error) {
+	op := Operation{Name: name, Support: support(err)}
+	if err != nil {
+		op.Detail = err.Error()
+	}
+	res.Operations = append(res.Operations, op)
+}
+
+func (res *Result) finish() Result {
+	if res.Support != "" {
+		return *res
+	}
+	res.Support = Supported
+	for _, op := range res.Operations {
+		switch {
+		case op.Support == Error:
+			res.Support = Error
+			return *res
+		case op.Support == NotSupported:
+			res.Support = NotSupported
+		}
+	}
+	return *res
+}
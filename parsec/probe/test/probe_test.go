@@ -0,0 +1,282 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package probe_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listproviders"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeaddecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaaeadencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaasymmetricdecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaasymmetricencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psacipherdecrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psacipherencrypt"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psadestroykey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaexportpublickey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psamaccompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psamacverify"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psarawkeyagreement"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifyhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/probe"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeService has two providers.  The TPM provider has no cipher or AEAD operations and does not support P-521,
+// ChaCha20 or Montgomery keys.  Raw key agreement fails on the MBed provider.
+type fakeService struct {
+	mu   sync.Mutex
+	keys map[requests.ProviderID]map[string]bool
+}
+
+func (f *fakeService) keyCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, keys := range f.keys {
+		n += len(keys)
+	}
+	return n
+}
+
+// withKey calls h if the provider has the key named by the operation.
+func (f *fakeService) withKey(op interface {
+	proto.Message
+	GetKeyName() string
+}, h func(provider requests.ProviderID) (proto.Message, requests.StatusCode)) parsectest.Handler {
+	return func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+		if err := proto.Unmarshal(body, op); err != nil {
+			return nil, requests.StatusDeserializingBodyFailed
+		}
+		f.mu.Lock()
+		exists := f.keys[provider][op.GetKeyName()]
+		f.mu.Unlock()
+		if !exists {
+			return nil, requests.StatusPsaErrorDoesNotExist
+		}
+		return h(provider)
+	}
+}
+
+func result(m proto.Message) func(requests.ProviderID) (proto.Message, requests.StatusCode) {
+	return func(requests.ProviderID) (proto.Message, requests.StatusCode) {
+		return m, requests.StatusSuccess
+	}
+}
+
+func (f *fakeService) service() *parsectest.Service {
+	f.keys = map[requests.ProviderID]map[string]bool{requests.ProviderMBed: {}, requests.ProviderTPM: {}}
+	all := []uint32{}
+	for _, op := range []requests.OpCode{
+		requests.OpPsaGenerateKey, requests.OpPsaDestroyKey, requests.OpPsaExportPublicKey, requests.OpPsaSignHash,
+		requests.OpPsaVerifyHash, requests.OpPsaAsymmetricEncrypt, requests.OpPsaAsymmetricDecrypt, requests.OpPsaMacCompute,
+		requests.OpPsaMacVerify, requests.OpPsaRawKeyAgreement,
+	} {
+		all = append(all, uint32(op))
+	}
+	tpm := append([]uint32{}, all...)
+	for _, op := range []requests.OpCode{requests.OpPsaCipherEncrypt, requests.OpPsaCipherDecrypt, requests.OpPsaAeadEncrypt, requests.OpPsaAeadDecrypt} {
+		all = append(all, uint32(op))
+	}
+
+	s := parsectest.NewService()
+	s.Handle(requests.OpListProviders, func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+		return &listproviders.Result{Providers: []*listproviders.ProviderInfo{{Id: 1}, {Id: 3}, {Id: 0}}}, requests.StatusSuccess
+	})
+	s.Handle(requests.OpListOpcodes, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+		op := &listopcodes.Operation{}
+		if err := proto.Unmarshal(body, op); err != nil {
+			return nil, requests.StatusDeserializingBodyFailed
+		}
+		if op.ProviderId == uint32(requests.ProviderTPM) {
+			return &listopcodes.Result{Opcodes: tpm}, requests.StatusSuccess
+		}
+		return &listopcodes.Result{Opcodes: all}, requests.StatusSuccess
+	})
+	generate := &psageneratekey.Operation{}
+	s.Handle(requests.OpPsaGenerateKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+		if err := proto.Unmarshal(body, generate); err != nil {
+			return nil, requests.StatusDeserializingBodyFailed
+		}
+		kt := generate.Attributes.KeyType
+		if provider == requests.ProviderTPM && (generate.Attributes.KeyBits == 521 || kt.GetChacha20() != nil ||
+			kt.GetEccKeyPair().GetCurveFamily().String() == "MONTGOMERY") {
+			return nil, requests.StatusPsaErrorNotSupported
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if f.keys[provider][generate.KeyName] {
+			return nil, requests.StatusPsaErrorAlreadyExists
+		}
+		f.keys[provider][generate.KeyName] = true
+		return &psageneratekey.Result{}, requests.StatusSuccess
+	})
+	destroy := &psadestroykey.Operation{}
+	s.Handle(requests.OpPsaDestroyKey, f.withKey(destroy, func(provider requests.ProviderID) (proto.Message, requests.StatusCode) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.keys[provider], destroy.KeyName)
+		return &psadestroykey.Result{}, requests.StatusSuccess
+	}))
+	s.Handle(requests.OpPsaExportPublicKey, f.withKey(&psaexportpublickey.Operation{}, result(&psaexportpublickey.Result{Data: []byte{4, 1, 2}})))
+	s.Handle(requests.OpPsaSignHash, f.withKey(&psasignhash.Operation{}, result(&psasignhash.Result{Signature: []byte{1}})))
+	s.Handle(requests.OpPsaVerifyHash, f.withKey(&psaverifyhash.Operation{}, result(&psaverifyhash.Result{})))
+	s.Handle(requests.OpPsaAsymmetricEncrypt, f.withKey(&psaasymmetricencrypt.Operation{}, result(&psaasymmetricencrypt.Result{Ciphertext: []byte{1}})))
+	s.Handle(requests.OpPsaAsymmetricDecrypt, f.withKey(&psaasymmetricdecrypt.Operation{}, result(&psaasymmetricdecrypt.Result{Plaintext: []byte{1}})))
+	s.Handle(requests.OpPsaCipherEncrypt, f.withKey(&psacipherencrypt.Operation{}, result(&psacipherencrypt.Result{Ciphertext: []byte{1}})))
+	s.Handle(requests.OpPsaCipherDecrypt, f.withKey(&psacipherdecrypt.Operation{}, result(&psacipherdecrypt.Result{Plaintext: []byte{1}})))
+	s.Handle(requests.OpPsaAeadEncrypt, f.withKey(&psaaeadencrypt.Operation{}, result(&psaaeadencrypt.Result{Ciphertext: []byte{1}})))
+	s.Handle(requests.OpPsaAeadDecrypt, f.withKey(&psaaeaddecrypt.Operation{}, result(&psaaeaddecrypt.Result{Plaintext: []byte{1}})))
+	s.Handle(requests.OpPsaMacCompute, f.withKey(&psamaccompute.Operation{}, result(&psamaccompute.Result{Mac: []byte{1}})))
+	s.Handle(requests.OpPsaMacVerify, f.withKey(&psamacverify.Operation{}, result(&psamacverify.Result{})))
+	s.Handle(requests.OpPsaRawKeyAgreement, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+		if provider == requests.ProviderMBed {
+			return nil, requests.StatusPsaErrorGenericError
+		}
+		return &psarawkeyagreement.Result{SharedSecret: []byte{1}}, requests.StatusSuccess
+	})
+	return s
+}
+
+func find(catalogue []probe.Combination, key, alg string) probe.Combination {
+	for _, c := range catalogue {
+		if c.Key == key && c.Algorithm() == alg {
+			return c
+		}
+	}
+	Fail("no combination " + key + " " + alg)
+	return probe.Combination{}
+}
+
+var _ = Describe("support matrix", func() {
+	var dir string
+	var listener net.Listener
+	var fake *fakeService
+	var client *parsec.BasicClient
+	catalogue := probe.Catalogue()
+	p256 := find(catalogue, "SECP_R1-256", "PSA_ALG_ECDSA(PSA_ALG_SHA_256)")
+	p384 := find(catalogue, "SECP_R1-384", "PSA_ALG_ECDSA(PSA_ALG_SHA_384)")
+	p521 := find(catalogue, "SECP_R1-521", "PSA_ALG_ECDSA(PSA_ALG_SHA_512)")
+	gcm := find(catalogue, "AES-256", "PSA_ALG_GCM")
+	chacha := find(catalogue, "ChaCha20-256", "PSA_ALG_CHACHA20_POLY1305")
+	ecdh := find(catalogue, "SECP_R1-256", "PSA_ALG_ECDH")
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "parsec-probe")
+		Expect(err).NotTo(HaveOccurred())
+		fake = &fakeService{}
+		listener, err = net.Listen("unix", filepath.Join(dir, "parsec.sock"))
+		Expect(err).NotTo(HaveOccurred())
+		go func(l net.Listener, s *parsectest.Service) {
+			_ = s.Serve(l)
+		}(listener, fake.service())
+		Expect(os.Setenv("PARSEC_SERVICE_ENDPOINT", "unix:"+filepath.Join(dir, "parsec.sock"))).To(Succeed())
+		client, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("probe")))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		Expect(client.Close()).To(Succeed())
+		Expect(listener.Close()).To(Succeed())
+		Expect(os.Unsetenv("PARSEC_SERVICE_ENDPOINT")).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("Should probe every provider with crypto", func() {
+		m, err := probe.Probe(client, nil, catalogue)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Providers).To(HaveLen(2))
+		Expect(m.Providers[0].Name).To(Equal("MBed"))
+		Expect(m.Providers[1].Name).To(Equal("TPM"))
+		Expect(m.Providers[1].Results).To(HaveLen(len(catalogue)))
+		Expect(fake.keyCount()).To(BeZero())
+	})
+	It("Should record the support of each combination", func() {
+		m, err := probe.Probe(client, []parsec.ProviderID{parsec.ProviderMBed, parsec.ProviderTPM}, catalogue)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Support(parsec.ProviderMBed, p521)).To(Equal(probe.Supported))
+		Expect(m.Support(parsec.ProviderMBed, gcm)).To(Equal(probe.Supported))
+		Expect(m.Support(parsec.ProviderTPM, p256)).To(Equal(probe.Supported))
+		Expect(m.Support(parsec.ProviderTPM, p521)).To(Equal(probe.NotSupported))
+		Expect(m.Support(parsec.ProviderTPM, gcm)).To(Equal(probe.NotSupported))
+		Expect(m.Support(parsec.ProviderMBed, ecdh)).To(Equal(probe.Error))
+		Expect(m.Support(parsec.ProviderPKCS11, p256)).To(Equal(probe.NotSupported))
+
+		mbed, tpm := m.Providers[0].Results, m.Providers[1].Results
+		Expect(tpm[2].Key).To(Equal("SECP_R1-521"))
+		Expect(tpm[2].Detail).To(ContainSubstring("not supported"))
+		Expect(tpm[2].Operations).To(BeEmpty())
+		Expect(mbed[0].Operations).To(Equal([]probe.Operation{
+			{Name: "sign hash", Support: probe.Supported},
+			{Name: "verify hash", Support: probe.Supported},
+			{Name: "export public key", Support: probe.Supported},
+		}))
+		for _, res := range tpm {
+			if res.Algorithm == "PSA_ALG_GCM" {
+				Expect(res.Operations).To(HaveLen(1))
+				Expect(res.Operations[0].Name).To(Equal("encrypt"))
+				Expect(res.Operations[0].Support).To(Equal(probe.NotSupported))
+			}
+		}
+		Expect(fake.keyCount()).To(BeZero())
+	})
+	It("Should select the first supported combination", func() {
+		m, err := probe.Probe(client, []parsec.ProviderID{parsec.ProviderMBed, parsec.ProviderTPM}, catalogue)
+		Expect(err).NotTo(HaveOccurred())
+		c, ok := m.Select(parsec.ProviderTPM, []probe.Combination{p521, p384, p256})
+		Expect(ok).To(BeTrue())
+		Expect(c).To(Equal(p384))
+		c, ok = m.Select(parsec.ProviderMBed, []probe.Combination{chacha, gcm})
+		Expect(ok).To(BeTrue())
+		Expect(c).To(Equal(chacha))
+		_, ok = m.Select(parsec.ProviderTPM, []probe.Combination{chacha, gcm})
+		Expect(ok).To(BeFalse())
+	})
+	It("Should write the matrix as text and JSON", func() {
+		m, err := probe.Probe(client, []parsec.ProviderID{parsec.ProviderMBed, parsec.ProviderTPM}, []probe.Combination{p256, p521})
+		Expect(err).NotTo(HaveOccurred())
+		var text bytes.Buffer
+		Expect(m.WriteText(&text)).To(Succeed())
+		Expect(text.String()).To(MatchRegexp(`KEY +ALGORITHM +MBed +TPM\n`))
+		Expect(text.String()).To(MatchRegexp(`SECP_R1-521 +PSA_ALG_ECDSA\(PSA_ALG_SHA_512\) +supported +not_supported\n`))
+		encoded, err := json.Marshal(m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(encoded)).To(ContainSubstring(`"support":"not_supported"`))
+	})
+	It("Should remove keys left by an earlier run", func() {
+		fake.mu.Lock()
+		fake.keys[requests.ProviderMBed]["parsec-probe-0"] = true
+		fake.mu.Unlock()
+		m, err := probe.Probe(client, []parsec.ProviderID{parsec.ProviderMBed}, []probe.Combination{p256})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Support(parsec.ProviderMBed, p256)).To(Equal(probe.Supported))
+		Expect(fake.keyCount()).To(BeZero())
+	})
+	It("Should report combinations with no operations to probe as errors", func() {
+		hash := probe.Combination{Key: "AES-128", Attributes: &parsec.KeyAttributes{
+			KeyBits:   128,
+			KeyType:   parsec.NewKeyType().Aes(),
+			KeyPolicy: &parsec.KeyPolicy{KeyAlgorithm: algorithm.NewHashAlgorithm(algorithm.HashAlgorithmTypeSHA256), KeyUsageFlags: &parsec.UsageFlags{}},
+		}}
+		m, err := probe.Probe(client, []parsec.ProviderID{parsec.ProviderMBed}, []probe.Combination{hash})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Providers[0].Results[0].Support).To(Equal(probe.Error))
+	})
+})
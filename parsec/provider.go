@@ -4,6 +4,9 @@
 package parsec
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/parallaxsecond/parsec-client-go/interface/operations/listproviders"
 	"github.com/parallaxsecond/parsec-client-go/interface/requests"
 )
@@ -42,6 +45,16 @@ func (p ProviderID) String() string {
 	}
 }
 
+// ParseProviderID returns the provider named s, as returned by ProviderID.String.  Case is ignored.
+func ParseProviderID(s string) (ProviderID, error) {
+	for _, p := range []ProviderID{ProviderCore, ProviderMBed, ProviderPKCS11, ProviderTPM, ProviderTrustedService} {
+		if strings.EqualFold(s, p.String()) {
+			return p, nil
+		}
+	}
+	return ProviderCore, fmt.Errorf("unknown provider %q", s)
+}
+
 type ProviderInfo struct {
 	UUID        string
 	Description string
@@ -63,6 +76,7 @@ func newProviderInfoFromOp(inf *listproviders.ProviderInfo) *ProviderInfo {
 		Vendor:      inf.Vendor,
 		VersionMaj:  inf.VersionMaj,
 		VersionMin:  inf.VersionMin,
+		VersionRev:  inf.VersionRev,
 		ID:          newProviderIDFromOp(requests.ProviderID(inf.Id)),
 	}
 }
@@ -0,0 +1,128 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoMatchingProvider is returned, wrapped with the reason each provider was rejected, when no provider offered
+// by the parsec service meets the requirements in the client config.  Test for it using errors.Is.
+var ErrNoMatchingProvider = errors.New("no provider matches client requirements")
+
+type providerVersion struct {
+	major, minor, rev uint32
+}
+
+func (v providerVersion) less(other providerVersion) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	if v.minor != other.minor {
+		return v.minor < other.minor
+	}
+	return v.rev < other.rev
+}
+
+func (v providerVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.rev)
+}
+
+// providerSelection holds the preferences and requirements used to select a provider, set using ClientConfig.
+type providerSelection struct {
+	preferred  []ProviderID
+	vendor     *string
+	uuid       *string
+	minVersion *providerVersion
+	operations []OpCode
+}
+
+func (s *providerSelection) hasRequirements() bool {
+	return s.vendor != nil || s.uuid != nil || s.minVersion != nil || len(s.operations) > 0
+}
+
+// order returns the available providers with preferred providers first, in order of preference, followed by
+// the remaining providers in the order the parsec service returned them.
+func (s *providerSelection) order(available []*ProviderInfo) []*ProviderInfo {
+	ordered := make([]*ProviderInfo, 0, len(available))
+	used := make(map[ProviderID]bool, len(available))
+	for _, id := range s.preferred {
+		for _, p := range available {
+			if p.ID == id && !used[id] {
+				ordered = append(ordered, p)
+				used[id] = true
+			}
+		}
+	}
+	for _, p := range available {
+		if !used[p.ID] {
+			ordered = append(ordered, p)
+			used[p.ID] = true
+		}
+	}
+	return ordered
+}
+
+// reject returns the reason provider p does not meet the requirements, or the empty string if it does.
+func (s *providerSelection) reject(c *BasicClient, p *ProviderInfo) string {
+	if !p.ID.HasCrypto() {
+		return "does not provide cryptographic operations"
+	}
+	if s.vendor != nil && p.Vendor != *s.vendor {
+		return fmt.Sprintf("vendor %q is not %q", p.Vendor, *s.vendor)
+	}
+	if s.uuid != nil && !strings.EqualFold(p.UUID, *s.uuid) {
+		return fmt.Sprintf("uuid %v is not %v", p.UUID, *s.uuid)
+	}
+	version := providerVersion{major: p.VersionMaj, minor: p.VersionMin, rev: p.VersionRev}
+	if s.minVersion != nil && version.less(*s.minVersion) {
+		return fmt.Sprintf("version %v is older than %v", version, *s.minVersion)
+	}
+	if len(s.operations) > 0 {
+		ops, err := c.Capabilities(p.ID)
+		if err != nil {
+			return fmt.Sprintf("could not list supported operations: %v", err)
+		}
+		var missing []string
+		for _, op := range s.operations {
+			if !ops.Contains(op) {
+				missing = append(missing, op.String())
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Sprintf("does not support %v", strings.Join(missing, ", "))
+		}
+	}
+	return ""
+}
+
+// selectDefaultProvider will request the list of providers from the parsec service and then select the first one
+// offering cryptographic operations and meeting the preferences and requirements in selection.  The core provider
+// is left selected if the service offers no other provider and there are no requirements.
+func (c *BasicClient) selectDefaultProvider(selection *providerSelection) error {
+	c.SetImplicitProvider(ProviderCore) // We know this one is always present.
+	availableProviders, err := c.ListProviders()
+	if err != nil {
+		return err
+	}
+	candidates := selection.order(availableProviders)
+	reasons := make([]string, 0, len(candidates))
+	for _, p := range candidates {
+		reason := selection.reject(c, p)
+		if reason == "" {
+			c.SetImplicitProvider(p.ID)
+			return nil
+		}
+		reasons = append(reasons, fmt.Sprintf("%v: %v", p.ID, reason))
+	}
+	if !selection.hasRequirements() {
+		return nil
+	}
+	if len(reasons) == 0 {
+		return fmt.Errorf("%w: parsec service returned no providers", ErrNoMatchingProvider)
+	}
+	return fmt.Errorf("%w: %v", ErrNoMatchingProvider, strings.Join(reasons, "; "))
+}
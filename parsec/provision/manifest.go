@@ -0,0 +1,281 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package provision creates and deletes keys held by the parsec service to match a manifest.
+//
+// A manifest lists keys by name, with their attributes and whether they are generated or imported from a PEM file.
+// Key types and usage flags take the JSON encodings of parsec.KeyType and parsec.UsageFlags, and algorithms are
+// named as in the PSA specification, as accepted by algorithm.Parse.  Manifests may be written in YAML or JSON, for
+// example:
+//
+//	keys:
+//	  - name: device-identity
+//	    provider: TPM
+//	    type: {ecc_key_pair: {curve_family: SECP_R1}}
+//	    bits: 256
+//	    usage: {sign_hash: true, verify_hash: true, sign_message: true, verify_message: true}
+//	    algorithm: PSA_ALG_ECDSA(PSA_ALG_SHA_256)
+//	  - name: backup-wrapping
+//	    type: {rsa_public_key: {}}
+//	    usage: {encrypt: true}
+//	    algorithm: PSA_ALG_RSA_OAEP(PSA_ALG_SHA_256)
+//	    import: wrapping-key.pem
+//
+// NewPlan compares a manifest with the keys listed by the parsec service, and Plan.Apply makes the changes.
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest lists the keys that should exist.
+type Manifest struct {
+	Keys []KeySpec `yaml:"keys"`
+	// dir is the directory relative paths in the manifest are resolved against.
+	dir string
+}
+
+// KeySpec describes a key in a manifest.
+type KeySpec struct {
+	// Name of the key.
+	Name string
+	// Provider holding the key, e.g. TPM.  If empty, the client's implicit provider is used.
+	Provider string
+	// Type of the key.
+	Type *parsec.KeyType
+	// Bits is the size of the key.  It may be omitted for imported keys.
+	Bits uint32
+	// Usage flags permitted for the key.
+	Usage *parsec.UsageFlags
+	// Algorithm permitted for the key.
+	Algorithm *algorithm.Algorithm
+	// Import is the path of a PEM file to import the key from.  If empty the key is generated.  Relative paths
+	// are resolved against the directory containing the manifest.
+	Import string
+}
+
+// keySpecFields holds the fields of a KeySpec as they are written in a manifest.
+type keySpecFields struct {
+	Name      string      `yaml:"name"`
+	Provider  string      `yaml:"provider,omitempty"`
+	Type      interface{} `yaml:"type"`
+	Bits      uint32      `yaml:"bits,omitempty"`
+	Usage     interface{} `yaml:"usage"`
+	Algorithm string      `yaml:"algorithm"`
+	Import    string      `yaml:"import,omitempty"`
+}
+
+// UnmarshalYAML decodes a key in a manifest.  Errors in the name and provider are left to Manifest.Validate, so that
+// they can be reported with the position of the key.
+func (k *KeySpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	fields := &keySpecFields{}
+	if err := unmarshal(fields); err != nil {
+		return err
+	}
+	spec := KeySpec{Name: fields.Name, Provider: fields.Provider, Bits: fields.Bits, Import: fields.Import}
+	if fields.Type != nil {
+		spec.Type = &parsec.KeyType{}
+		if err := unmarshalJSONValue(fields.Type, spec.Type); err != nil {
+			return fmt.Errorf("key %q type: %w", fields.Name, err)
+		}
+	}
+	if fields.Usage != nil {
+		spec.Usage = &parsec.UsageFlags{}
+		if err := unmarshalJSONValue(fields.Usage, spec.Usage); err != nil {
+			return fmt.Errorf("key %q usage: %w", fields.Name, err)
+		}
+	}
+	if fields.Algorithm != "" {
+		alg, err := algorithm.Parse(fields.Algorithm)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", fields.Name, err)
+		}
+		spec.Algorithm = alg
+	}
+	*k = spec
+	return nil
+}
+
+// MarshalYAML encodes the key as it is written in a manifest.
+func (k KeySpec) MarshalYAML() (interface{}, error) {
+	fields := &keySpecFields{Name: k.Name, Provider: k.Provider, Bits: k.Bits, Import: k.Import}
+	var err error
+	if k.Type != nil {
+		if fields.Type, err = marshalJSONValue(k.Type); err != nil {
+			return nil, err
+		}
+	}
+	if k.Usage != nil {
+		if fields.Usage, err = marshalJSONValue(k.Usage); err != nil {
+			return nil, err
+		}
+	}
+	if k.Algorithm != nil {
+		fields.Algorithm = k.Algorithm.String()
+	}
+	return fields, nil
+}
+
+// unmarshalJSONValue decodes v, as decoded from YAML, into out using its JSON encoding.
+func unmarshalJSONValue(v interface{}, out json.Unmarshaler) error {
+	data, err := json.Marshal(jsonCompatible(v))
+	if err != nil {
+		return err
+	}
+	return out.UnmarshalJSON(data)
+}
+
+// marshalJSONValue returns the JSON encoding of v as a value that encodes to the same in YAML.
+func marshalJSONValue(v json.Marshaler) (interface{}, error) {
+	data, err := v.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err = json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// jsonCompatible converts the map[interface{}]interface{} values made by the YAML decoder, which encoding/json
+// cannot encode, to map[string]interface{}.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(value)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, value := range v {
+			list[i] = jsonCompatible(value)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// Parse parses a YAML or JSON manifest and checks that each key is valid.  Relative import paths are resolved
+// against the current directory.
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	// YAML is a superset of JSON so this handles both.
+	if err := yaml.UnmarshalStrict(data, m); err != nil {
+		return nil, fmt.Errorf("could not parse manifest: %w", err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Load reads and parses the manifest in the named file.
+func Load(filename string) (*Manifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", filename, err)
+	}
+	m.dir = filepath.Dir(filename)
+	return m, nil
+}
+
+// Validate checks that every key in the manifest is valid and that names are not repeated for a provider.  Keys
+// without a provider are only compared with each other, as the provider they are created in is not known until the
+// manifest is planned.
+func (m *Manifest) Validate() error {
+	return m.validate(nil)
+}
+
+// ValidateFor is like Validate, but keys without a provider are compared as keys of defaultProvider.
+func (m *Manifest) ValidateFor(defaultProvider parsec.ProviderID) error {
+	return m.validate(&defaultProvider)
+}
+
+func (m *Manifest) validate(defaultProvider *parsec.ProviderID) error {
+	type keyID struct {
+		provider parsec.ProviderID
+		// resolved is false for keys without a provider when the default provider is not known
+		resolved bool
+		name     string
+	}
+	seen := make(map[keyID]bool, len(m.Keys))
+	for i := range m.Keys {
+		k := &m.Keys[i]
+		if k.Name == "" {
+			return fmt.Errorf("key %d has no name", i)
+		}
+		if _, err := k.Attributes(); err != nil {
+			return fmt.Errorf("key %q: %w", k.Name, err)
+		}
+		id := keyID{name: k.Name}
+		if k.Provider != "" || defaultProvider != nil {
+			var p parsec.ProviderID
+			if defaultProvider != nil {
+				p = *defaultProvider
+			}
+			provider, err := k.provider(p)
+			if err != nil {
+				return fmt.Errorf("key %q: %w", k.Name, err)
+			}
+			id.provider, id.resolved = provider, true
+		}
+		if seen[id] {
+			if id.resolved {
+				return fmt.Errorf("key %q appears more than once for provider %v", k.Name, id.provider)
+			}
+			return fmt.Errorf("key %q appears more than once", k.Name)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// provider returns the provider holding the key, which is defaultProvider if the spec does not name one.
+func (k *KeySpec) provider(defaultProvider parsec.ProviderID) (parsec.ProviderID, error) {
+	if k.Provider == "" {
+		return defaultProvider, nil
+	}
+	return parsec.ParseProviderID(k.Provider)
+}
+
+// Attributes returns the key attributes described by the spec, checking that they are valid.
+func (k *KeySpec) Attributes() (*parsec.KeyAttributes, error) {
+	switch {
+	case k.Type == nil:
+		return nil, fmt.Errorf("type must be given")
+	case k.Usage == nil:
+		return nil, fmt.Errorf("usage must be given")
+	case k.Algorithm == nil:
+		return nil, fmt.Errorf("algorithm must be given")
+	case k.Bits == 0 && k.Import == "":
+		return nil, fmt.Errorf("bits must be given for generated keys")
+	}
+	usage := *k.Usage
+	attributes := &parsec.KeyAttributes{
+		KeyType: k.Type,
+		KeyBits: k.Bits,
+		KeyPolicy: &parsec.KeyPolicy{
+			KeyUsageFlags: &usage,
+			KeyAlgorithm:  k.Algorithm,
+		},
+	}
+	if err := attributes.Validate(); err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
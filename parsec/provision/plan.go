@@ -0,0 +1,289 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package provision
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+// Action is the change planned for a key.
+type Action int
+
+// Actions
+const (
+	// ActionKeep is planned for keys that exist with the attributes in the manifest.
+	ActionKeep Action = iota
+	// ActionCreate is planned for keys in the manifest that do not exist.
+	ActionCreate
+	// ActionReplace is planned, if Options.Replace is set, for keys whose attributes differ from the manifest.
+	ActionReplace
+	// ActionConflict is planned, unless Options.Replace is set, for keys whose attributes differ from the manifest.
+	ActionConflict
+	// ActionDelete is planned, if Options.Prune is set, for keys not in the manifest.
+	ActionDelete
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionKeep:
+		return "keep"
+	case ActionCreate:
+		return "create"
+	case ActionReplace:
+		return "replace"
+	case ActionConflict:
+		return "conflict"
+	case ActionDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is the action planned for a single key.
+type Change struct {
+	Action   Action
+	Name     string
+	Provider parsec.ProviderID
+	// Differences between the existing key and the manifest, for ActionReplace and ActionConflict.
+	Differences []string
+
+	attributes *parsec.KeyAttributes
+	// importData is the key to import, or nil to generate the key.
+	importData []byte
+}
+
+func (c *Change) String() string {
+	s := fmt.Sprintf("%-8v %v/%v", c.Action, c.Provider, c.Name)
+	if len(c.Differences) > 0 {
+		s += ": " + strings.Join(c.Differences, "; ")
+	}
+	return s
+}
+
+// Options control how a plan is made.
+type Options struct {
+	// Prune deletes keys that are not in the manifest, from the providers the manifest uses.
+	Prune bool
+	// Replace destroys and recreates keys whose attributes differ from the manifest.  Otherwise they are conflicts,
+	// which stop the plan being applied.
+	Replace bool
+}
+
+// Plan lists the changes needed to bring the keys held by the parsec service in line with a manifest.
+type Plan struct {
+	Changes []Change
+}
+
+// NewPlan compares the manifest with the keys listed by the parsec service for the client's authenticator.
+// Keys without a provider in the manifest are planned for the client's implicit provider.  Files to import are
+// read while planning, so missing files are reported before any change is made.
+func NewPlan(client *parsec.BasicClient, m *Manifest, opts Options) (*Plan, error) {
+	defaultProvider := client.GetImplicitProvider()
+	if err := m.ValidateFor(defaultProvider); err != nil {
+		return nil, err
+	}
+	existing, err := client.ListKeys()
+	if err != nil {
+		return nil, err
+	}
+	type keyID struct {
+		provider parsec.ProviderID
+		name     string
+	}
+	found := make(map[keyID]*parsec.KeyInfo, len(existing))
+	for _, k := range existing {
+		found[keyID{k.ProviderID, k.Name}] = k
+	}
+
+	plan := &Plan{}
+	inManifest := make(map[keyID]bool, len(m.Keys))
+	providers := make(map[parsec.ProviderID]bool)
+	for i := range m.Keys {
+		spec := &m.Keys[i]
+		provider, err := spec.provider(defaultProvider)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", spec.Name, err)
+		}
+		attributes, err := spec.Attributes()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", spec.Name, err)
+		}
+		id := keyID{provider, spec.Name}
+		inManifest[id] = true
+		providers[provider] = true
+
+		change := Change{Action: ActionCreate, Name: spec.Name, Provider: provider, attributes: attributes}
+		if info, ok := found[id]; ok {
+			change.Differences, err = info.Attributes.Differences(attributes)
+			if err != nil {
+				change.Differences = []string{err.Error()}
+			}
+			switch {
+			case len(change.Differences) == 0:
+				change.Action = ActionKeep
+			case opts.Replace:
+				change.Action = ActionReplace
+			default:
+				change.Action = ActionConflict
+			}
+		}
+		if (change.Action == ActionCreate || change.Action == ActionReplace) && spec.Import != "" {
+			change.importData, err = m.readImport(spec, attributes)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", spec.Name, err)
+			}
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	if opts.Prune {
+		var deletions []Change
+		for _, k := range existing {
+			id := keyID{k.ProviderID, k.Name}
+			if providers[k.ProviderID] && !inManifest[id] {
+				deletions = append(deletions, Change{Action: ActionDelete, Name: k.Name, Provider: k.ProviderID})
+			}
+		}
+		sort.Slice(deletions, func(i, j int) bool {
+			if deletions[i].Provider != deletions[j].Provider {
+				return deletions[i].Provider < deletions[j].Provider
+			}
+			return deletions[i].Name < deletions[j].Name
+		})
+		plan.Changes = append(plan.Changes, deletions...)
+	}
+	return plan, nil
+}
+
+func (m *Manifest) readImport(spec *KeySpec, attributes *parsec.KeyAttributes) ([]byte, error) {
+	path := spec.Import
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.dir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := parsec.KeyDataFromPEM(data, attributes.KeyType, attributes.KeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return keyData, nil
+}
+
+// HasChanges returns true if applying the plan would change any keys.
+func (p *Plan) HasChanges() bool {
+	for i := range p.Changes {
+		if p.Changes[i].Action != ActionKeep {
+			return true
+		}
+	}
+	return false
+}
+
+// Conflicts returns the changes with ActionConflict.
+func (p *Plan) Conflicts() []Change {
+	var conflicts []Change
+	for _, c := range p.Changes {
+		if c.Action == ActionConflict {
+			conflicts = append(conflicts, c)
+		}
+	}
+	return conflicts
+}
+
+// String describes the plan, one change per line.
+func (p *Plan) String() string {
+	b := &strings.Builder{}
+	for i := range p.Changes {
+		fmt.Fprintln(b, p.Changes[i].String())
+	}
+	return b.String()
+}
+
+// Apply makes the changes in the plan using client.  Nothing is changed if the plan has conflicts.  Keys are
+// created and replaced before keys are deleted, and a replaced key is destroyed just before it is recreated.  Apply
+// stops at the first failure, returning an error that lists the changes made and not made.
+func (p *Plan) Apply(client *parsec.BasicClient) error {
+	if conflicts := p.Conflicts(); len(conflicts) > 0 {
+		return fmt.Errorf("plan has %d conflicting keys, first %v", len(conflicts), conflicts[0].String())
+	}
+	var order []*Change
+	for _, deletions := range []bool{false, true} {
+		for i := range p.Changes {
+			if c := &p.Changes[i]; c.Action != ActionKeep && (c.Action == ActionDelete) == deletions {
+				order = append(order, c)
+			}
+		}
+	}
+	for i, c := range order {
+		if err := c.apply(client); err != nil {
+			return &ApplyError{Failed: c, Err: err, Applied: order[:i], NotApplied: order[i+1:]}
+		}
+	}
+	return nil
+}
+
+// apply makes a single change.
+func (c *Change) apply(client *parsec.BasicClient) error {
+	if c.Action == ActionDelete || c.Action == ActionReplace {
+		if err := client.PsaDestroyKey(c.Name, parsec.WithProvider(c.Provider)); err != nil {
+			return fmt.Errorf("could not destroy key: %w", err)
+		}
+	}
+	if c.Action == ActionDelete {
+		return nil
+	}
+	var err error
+	if c.importData != nil {
+		_, err = client.ImportKey(c.Name, c.attributes, c.importData, parsec.WithProvider(c.Provider))
+	} else {
+		_, err = client.GenerateKey(c.Name, c.attributes, parsec.WithProvider(c.Provider))
+	}
+	if err != nil && c.Action == ActionReplace {
+		return fmt.Errorf("key was destroyed but could not be recreated: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("could not create key: %w", err)
+	}
+	return nil
+}
+
+// ApplyError is returned by Plan.Apply when a change fails, describing the keys left as they were planned and
+// those left unchanged.
+type ApplyError struct {
+	// Failed is the change that failed, with Err the reason.
+	Failed *Change
+	Err    error
+	// Applied are the changes made before the failure, and NotApplied those that were not attempted.
+	Applied    []*Change
+	NotApplied []*Change
+}
+
+func (e *ApplyError) Error() string {
+	return fmt.Sprintf("%v %v/%v: %v; applied: %v; not applied: %v", e.Failed.Action, e.Failed.Provider, e.Failed.Name,
+		e.Err, describeChanges(e.Applied), describeChanges(e.NotApplied))
+}
+
+func describeChanges(changes []*Change) string {
+	if len(changes) == 0 {
+		return "none"
+	}
+	names := make([]string, len(changes))
+	for i, c := range changes {
+		names[i] = fmt.Sprintf("%v %v/%v", c.Action, c.Provider, c.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/provision"
+	"gopkg.in/yaml.v2"
+)
+
+var _ = Describe("Manifest", func() {
+	It("Should parse YAML", func() {
+		m, err := provision.Parse([]byte(`
+keys:
+  - name: device-identity
+    provider: tpm
+    type: {ecc_key_pair: {curve_family: SECP_R1}}
+    bits: 256
+    usage: {sign_hash: true, verify_hash: true}
+    algorithm: PSA_ALG_ECDSA(PSA_ALG_SHA_256)
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Keys).To(HaveLen(1))
+		attrs, err := m.Keys[0].Attributes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attrs.KeyType).To(Equal(parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1)))
+		Expect(attrs.KeyBits).To(Equal(uint32(256)))
+		Expect(attrs.KeyPolicy.KeyUsageFlags).To(Equal(&parsec.UsageFlags{SignHash: true, VerifyHash: true}))
+		Expect(attrs.KeyPolicy.KeyAlgorithm).To(Equal(algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA256)))
+	})
+
+	It("Should parse JSON", func() {
+		m, err := provision.Parse([]byte(`{"keys": [{"name": "k", "type": {"aes": {}}, "bits": 128,
+			"usage": {"encrypt": true, "decrypt": true}, "algorithm": "PSA_ALG_AEAD_WITH_SHORTENED_TAG(PSA_ALG_GCM, 12)"}]}`))
+		Expect(err).NotTo(HaveOccurred())
+		attrs, err := m.Keys[0].Attributes()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attrs.KeyPolicy.KeyAlgorithm).To(Equal(algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmGCM, 12)))
+	})
+
+	It("Should write keys as they are read", func() {
+		spec := provision.KeySpec{
+			Name:      "agreement",
+			Type:      parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1),
+			Bits:      256,
+			Usage:     &parsec.UsageFlags{Derive: true},
+			Algorithm: algorithm.NewKeyAgreement().ECDH(algorithm.NewKeyDerivation().Hkdf(algorithm.HashAlgorithmTypeSHA256).GetKeyDerivation()),
+		}
+		data, err := yaml.Marshal(&provision.Manifest{Keys: []provision.KeySpec{spec}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("algorithm: PSA_ALG_KEY_AGREEMENT(PSA_ALG_ECDH, PSA_ALG_HKDF(PSA_ALG_SHA_256))"))
+		m, err := provision.Parse(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.Keys).To(Equal([]provision.KeySpec{spec}))
+	})
+
+	DescribeTable("Should reject invalid manifests",
+		func(manifest, message string) {
+			_, err := provision.Parse([]byte(manifest))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown field", `keys: [{name: k, type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_GCM, colour: red}]`, "colour"),
+		Entry("missing name", `keys: [{type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}]`, "key 0 has no name"),
+		Entry("unknown type", `keys: [{name: k, type: {blowfish: {}}, bits: 64, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}]`, "blowfish"),
+		Entry("unknown curve", `keys: [{name: k, type: {ecc_key_pair: {curve_family: NIST}}, bits: 256, usage: {sign_hash: true}, algorithm: PSA_ALG_ECDSA(PSA_ALG_SHA_256)}]`, "NIST"),
+		Entry("unknown usage", `keys: [{name: k, type: {aes: {}}, bits: 128, usage: {wrap: true}, algorithm: PSA_ALG_GCM}]`, "wrap"),
+		Entry("unknown algorithm", `keys: [{name: k, type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_SIV}]`, "SIV"),
+		Entry("unknown hash", `keys: [{name: k, type: {rsa_key_pair: {}}, bits: 2048, usage: {sign_hash: true}, algorithm: PSA_ALG_RSA_PSS(PSA_ALG_SHA_999)}]`, "SHA_999"),
+		Entry("algorithm for another key type", `keys: [{name: k, type: {aes: {}}, bits: 128, usage: {sign_hash: true}, algorithm: PSA_ALG_ECDSA(PSA_ALG_SHA_256)}]`, "PSA_ALG_ECDSA"),
+		Entry("missing algorithm", `keys: [{name: k, type: {aes: {}}, bits: 128, usage: {encrypt: true}}]`, "algorithm must be given"),
+		Entry("missing bits", `keys: [{name: k, type: {aes: {}}, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}]`, "bits"),
+		Entry("unknown provider", `keys: [{name: k, provider: HSM, type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}]`, "HSM"),
+		Entry("duplicate", `keys: [{name: k, provider: TPM, type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_GCM},
+			{name: k, provider: tpm, type: {aes: {}}, bits: 256, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}]`, "more than once for provider TPM"),
+		Entry("duplicate without a provider", `keys: [{name: k, type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_GCM},
+			{name: k, type: {aes: {}}, bits: 256, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}]`, `key "k" appears more than once`),
+	)
+
+	It("Should compare keys without a provider as keys of the default provider", func() {
+		m, err := provision.Parse([]byte(`
+keys:
+  - {name: k, type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}
+  - {name: k, provider: TPM, type: {aes: {}}, bits: 128, usage: {encrypt: true}, algorithm: PSA_ALG_GCM}
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m.ValidateFor(parsec.ProviderMBed)).To(Succeed())
+		Expect(m.ValidateFor(parsec.ProviderTPM)).To(MatchError(`key "k" appears more than once for provider TPM`))
+	})
+})
@@ -0,0 +1,276 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listkeys"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psadestroykey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaimportkey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/provision"
+	"google.golang.org/protobuf/proto"
+)
+
+const testManifest = `
+keys:
+  - name: signing
+    type: {ecc_key_pair: {curve_family: SECP_R1}}
+    bits: 256
+    usage: {sign_hash: true, verify_hash: true}
+    algorithm: PSA_ALG_ECDSA(PSA_ALG_SHA_256)
+  - name: sealing
+    provider: TPM
+    type: {aes: {}}
+    bits: 128
+    usage: {encrypt: true, decrypt: true}
+    algorithm: PSA_ALG_GCM
+  - name: imported
+    type: {ecc_public_key: {curve_family: SECP_R1}}
+    usage: {verify_hash: true}
+    algorithm: PSA_ALG_ECDSA(PSA_ALG_SHA_256)
+    import: public.pem
+`
+
+// keyOperations describes the requests that create and destroy keys, as the operation and the key.
+func keyOperations(received []parsectest.Request) []string {
+	var result []string
+	for _, r := range received {
+		var name string
+		switch r.OpCode {
+		case requests.OpPsaGenerateKey:
+			op := &psageneratekey.Operation{}
+			Expect(proto.Unmarshal(r.Body, op)).To(Succeed())
+			name = op.KeyName
+		case requests.OpPsaImportKey:
+			op := &psaimportkey.Operation{}
+			Expect(proto.Unmarshal(r.Body, op)).To(Succeed())
+			name = op.KeyName
+		case requests.OpPsaDestroyKey:
+			op := &psadestroykey.Operation{}
+			Expect(proto.Unmarshal(r.Body, op)).To(Succeed())
+			name = op.KeyName
+		default:
+			continue
+		}
+		result = append(result, r.OpCode.String()+" "+r.Provider.String()+"/"+name)
+	}
+	return result
+}
+
+var _ = Describe("Plan", func() {
+	var service *parsectest.Service
+	var bc *parsec.BasicClient
+	var stored []*listkeys.KeyInfo
+	var imported []byte
+	var manifest *provision.Manifest
+	var dir string
+
+	BeforeEach(func() {
+		stored, imported = nil, nil
+		service = parsectest.NewService().
+			Handle(requests.OpListKeys, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &listkeys.Result{Keys: stored}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaGenerateKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				op := &psageneratekey.Operation{}
+				Expect(proto.Unmarshal(body, op)).To(Succeed())
+				stored = append(stored, &listkeys.KeyInfo{ProviderId: uint32(provider), Name: op.KeyName, Attributes: op.Attributes})
+				return &psageneratekey.Result{}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaImportKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				op := &psaimportkey.Operation{}
+				Expect(proto.Unmarshal(body, op)).To(Succeed())
+				imported = op.Data
+				stored = append(stored, &listkeys.KeyInfo{ProviderId: uint32(provider), Name: op.KeyName, Attributes: op.Attributes})
+				return &psaimportkey.Result{}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaDestroyKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				op := &psadestroykey.Operation{}
+				Expect(proto.Unmarshal(body, op)).To(Succeed())
+				for i, k := range stored {
+					if k.Name == op.KeyName && k.ProviderId == uint32(provider) {
+						stored = append(stored[:i], stored[i+1:]...)
+						return &psadestroykey.Result{}, requests.StatusSuccess
+					}
+				}
+				return &psadestroykey.Result{}, requests.StatusPsaErrorDoesNotExist
+			})
+		var err error
+		bc, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("app")).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+
+		dir, err = os.MkdirTemp("", "provision")
+		Expect(err).NotTo(HaveOccurred())
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(dir, "public.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "keys.yaml"), []byte(testManifest), 0o600)).To(Succeed())
+		manifest, err = provision.Load(filepath.Join(dir, "keys.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	actions := func(plan *provision.Plan) []string {
+		var result []string
+		for _, c := range plan.Changes {
+			result = append(result, c.Action.String()+" "+c.Provider.String()+"/"+c.Name)
+		}
+		return result
+	}
+
+	It("Should create missing keys and then keep them", func() {
+		plan, err := provision.NewPlan(bc, manifest, provision.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions(plan)).To(Equal([]string{"create MBed/signing", "create TPM/sealing", "create MBed/imported"}))
+		Expect(plan.Apply(bc)).To(Succeed())
+		Expect(service.Count(requests.OpPsaGenerateKey)).To(Equal(2))
+		Expect(service.Count(requests.OpPsaImportKey)).To(Equal(1))
+		Expect(imported).To(HaveLen(65))
+		Expect(imported[0]).To(Equal(byte(0x04)))
+
+		plan, err = provision.NewPlan(bc, manifest, provision.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.HasChanges()).To(BeFalse())
+		Expect(actions(plan)).To(Equal([]string{"keep MBed/signing", "keep TPM/sealing", "keep MBed/imported"}))
+	})
+
+	Context("With keys that differ from the manifest", func() {
+		BeforeEach(func() {
+			plan, err := provision.NewPlan(bc, manifest, provision.Options{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.Apply(bc)).To(Succeed())
+			manifest.Keys[1].Bits = 256
+		})
+
+		It("Should report conflicts and refuse to apply", func() {
+			plan, err := provision.NewPlan(bc, manifest, provision.Options{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan.Conflicts()).To(HaveLen(1))
+			Expect(plan.Conflicts()[0].Differences).To(ConsistOf("key bits is 128, want 256"))
+			Expect(plan.String()).To(ContainSubstring("conflict TPM/sealing: key bits is 128, want 256"))
+			Expect(plan.Apply(bc)).To(MatchError(ContainSubstring("conflict")))
+			Expect(service.Count(requests.OpPsaDestroyKey)).To(Equal(0))
+		})
+
+		It("Should replace each key just before recreating it, and prune keys last", func() {
+			Expect(bc.PsaDestroyKey("signing")).To(Succeed())
+			Expect(bc.PsaGenerateKey("old", parsec.DefaultKeyAttribute().AesGcmKey(), parsec.WithProvider(parsec.ProviderTPM))).To(Succeed())
+			plan, err := provision.NewPlan(bc, manifest, provision.Options{Replace: true, Prune: true})
+			Expect(err).NotTo(HaveOccurred())
+			before := len(service.Received())
+			Expect(plan.Apply(bc)).To(Succeed())
+			Expect(keyOperations(service.Received()[before:])).To(Equal([]string{
+				"PsaGenerateKey MBed/signing", "PsaDestroyKey TPM/sealing", "PsaGenerateKey TPM/sealing", "PsaDestroyKey TPM/old",
+			}))
+		})
+
+		It("Should describe what was changed when a change fails", func() {
+			Expect(bc.PsaDestroyKey("signing")).To(Succeed())
+			Expect(bc.PsaDestroyKey("imported")).To(Succeed())
+			Expect(bc.PsaGenerateKey("old", parsec.DefaultKeyAttribute().AesGcmKey(), parsec.WithProvider(parsec.ProviderTPM))).To(Succeed())
+			generate := service.Handler(requests.OpPsaGenerateKey)
+			service.Handle(requests.OpPsaGenerateKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				if provider == requests.ProviderTPM {
+					return nil, requests.StatusPsaErrorInsufficientStorage
+				}
+				return generate(provider, body)
+			})
+			plan, err := provision.NewPlan(bc, manifest, provision.Options{Replace: true, Prune: true})
+			Expect(err).NotTo(HaveOccurred())
+			err = plan.Apply(bc)
+			var applyErr *provision.ApplyError
+			Expect(errors.As(err, &applyErr)).To(BeTrue())
+			Expect(applyErr.Failed.Name).To(Equal("sealing"))
+			Expect(err).To(MatchError("replace TPM/sealing: key was destroyed but could not be recreated: " +
+				"insufficient storage; applied: create MBed/signing; not applied: create MBed/imported, delete TPM/old"))
+			code, ok := requests.StatusCodeFromError(err)
+			Expect(ok).To(BeTrue())
+			Expect(code).To(Equal(requests.StatusPsaErrorInsufficientStorage))
+			var names []string
+			for _, k := range stored {
+				names = append(names, k.Name)
+			}
+			Expect(names).To(ConsistOf("signing", "old"))
+		})
+
+		It("Should replace them when asked", func() {
+			plan, err := provision.NewPlan(bc, manifest, provision.Options{Replace: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actions(plan)).To(ContainElement("replace TPM/sealing"))
+			Expect(plan.Apply(bc)).To(Succeed())
+			Expect(service.Count(requests.OpPsaDestroyKey)).To(Equal(1))
+			Expect(stored[len(stored)-1].Name).To(Equal("sealing"))
+			Expect(stored[len(stored)-1].Attributes.KeyBits).To(Equal(uint32(256)))
+		})
+	})
+
+	It("Should only prune keys on providers used by the manifest", func() {
+		attributes := &psakeyattributes.KeyAttributes{
+			KeyType:   &psakeyattributes.KeyType{Variant: &psakeyattributes.KeyType_RawData_{RawData: &psakeyattributes.KeyType_RawData{}}},
+			KeyBits:   128,
+			KeyPolicy: &psakeyattributes.KeyPolicy{KeyUsageFlags: &psakeyattributes.UsageFlags{Export: true}},
+		}
+		stored = []*listkeys.KeyInfo{
+			{ProviderId: uint32(parsec.ProviderTPM), Name: "old", Attributes: attributes},
+			{ProviderId: uint32(parsec.ProviderPKCS11), Name: "other", Attributes: attributes},
+		}
+		plan, err := provision.NewPlan(bc, manifest, provision.Options{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions(plan)).NotTo(ContainElement(HavePrefix("delete")))
+
+		plan, err = provision.NewPlan(bc, manifest, provision.Options{Prune: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions(plan)).To(ContainElement("delete TPM/old"))
+		Expect(actions(plan)).NotTo(ContainElement("delete PKCS11/other"))
+		Expect(plan.Apply(bc)).To(Succeed())
+		var names []string
+		for _, k := range stored {
+			names = append(names, k.Name)
+		}
+		Expect(names).To(ConsistOf("other", "signing", "sealing", "imported"))
+	})
+
+	It("Should report missing import files when planning", func() {
+		manifest.Keys[2].Import = "missing.pem"
+		_, err := provision.NewPlan(bc, manifest, provision.Options{})
+		Expect(err).To(MatchError(ContainSubstring("missing.pem")))
+	})
+
+	It("Should report import files holding a key of another size when planning", func() {
+		manifest.Keys[2].Bits = 384
+		_, err := provision.NewPlan(bc, manifest, provision.Options{})
+		Expect(err).To(MatchError(ContainSubstring("PEM key is on 256 bit curve P-256, expected 384 bits")))
+	})
+
+	It("Should refuse a key named both with and without the client's implicit provider", func() {
+		manifest.Keys[1].Name = "signing"
+		manifest.Keys[1].Provider = "mbed"
+		_, err := provision.NewPlan(bc, manifest, provision.Options{})
+		Expect(err).To(MatchError(`key "signing" appears more than once for provider MBed`))
+		Expect(service.Count(requests.OpListKeys)).To(BeZero())
+	})
+})
@@ -0,0 +1,255 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm/gocrypto"
+	"github.com/parallaxsecond/parsec-client-go/parsec/verify"
+)
+
+// signedMessage is hashed locally to give the hash signed by the provider.
+var signedMessage = []byte("parsec provider self-test")
+
+type runner struct {
+	client *parsec.BasicClient
+	opts   []parsec.CallOption
+}
+
+func keyName(name string) string {
+	return "parsec-selftest-" + name
+}
+
+// tamper returns a copy of data with one bit changed.
+func tamper(data []byte) []byte {
+	tampered := append([]byte{}, data...)
+	if len(tampered) != 0 {
+		tampered[len(tampered)-1] ^= 1
+	}
+	return tampered
+}
+
+// importKey imports a key, first destroying any key of the same name left by an earlier run.  It returns a function
+// destroying the key.
+func (r *runner) importKey(name string, attributes *parsec.KeyAttributes, data []byte) (func(), error) {
+	_ = r.client.PsaDestroyKey(name, r.opts...)
+	if err := r.client.PsaImportKey(name, attributes, data, r.opts...); err != nil {
+		return nil, err
+	}
+	return func() { _ = r.client.PsaDestroyKey(name, r.opts...) }, nil
+}
+
+// generateKey generates a key, in the same way as importKey.
+func (r *runner) generateKey(name string, attributes *parsec.KeyAttributes) (func(), error) {
+	_ = r.client.PsaDestroyKey(name, r.opts...)
+	if err := r.client.PsaGenerateKey(name, attributes, r.opts...); err != nil {
+		return nil, err
+	}
+	return func() { _ = r.client.PsaDestroyKey(name, r.opts...) }, nil
+}
+
+// expect returns err, or an error if got is not want.
+func expect(got, want []byte, err error) error {
+	if err == nil && !bytes.Equal(got, want) {
+		return mismatch(got, want)
+	}
+	return err
+}
+
+func (r *runner) hash(v hashVector) Result {
+	res := &Result{Algorithm: algorithm.NewHashAlgorithm(v.alg).String()}
+	digest, err := r.client.PsaHashCompute(v.message, v.alg, r.opts...)
+	res.record("compute", expect(digest, v.digest, err))
+	return res.finish()
+}
+
+func (r *runner) mac(v macVector) Result {
+	bits := uint32(len(v.key) * 8)
+	var attributes *parsec.KeyAttributes
+	res := &Result{Algorithm: v.alg.String()}
+	if hmac := v.alg.GetMac().GetFullLength().GetHmac(); hmac != nil {
+		attributes = parsec.DefaultKeyAttribute().HmacKey(parsec.WithHash(hmac.HashAlg), parsec.WithKeyBits(bits))
+		res.Key = fmt.Sprintf("HMAC-%d", bits)
+	} else {
+		attributes = parsec.DefaultKeyAttribute().CmacKey(parsec.WithKeyBits(bits))
+		res.Key = fmt.Sprintf("AES-%d", bits)
+	}
+	name := keyName("mac")
+	destroy, err := r.importKey(name, attributes, v.key)
+	if err != nil {
+		res.record("import key", err)
+		return res.finish()
+	}
+	defer destroy()
+
+	alg := v.alg.GetMac()
+	mac, err := r.client.PsaMACCompute(name, alg, v.message, r.opts...)
+	res.record("compute", expect(mac, v.mac, err))
+	res.record("verify", r.client.PsaMACVerify(name, alg, v.message, v.mac, r.opts...))
+	res.recordRejected("verify rejects tampered MAC", r.client.PsaMACVerify(name, alg, v.message, tamper(v.mac), r.opts...))
+	return res.finish()
+}
+
+// goEncrypt encrypts with Go's crypto library, to check the ciphertexts of the provider.
+func (v cipherVector) goEncrypt(iv, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := make([]byte, len(plaintext))
+	switch v.mode {
+	case algorithm.CipherModeCBCNOPADDING:
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+	case algorithm.CipherModeCTR:
+		cipher.NewCTR(block, iv).XORKeyStream(ciphertext, plaintext)
+	default:
+		return nil, fmt.Errorf("no check for cipher mode %v", v.mode)
+	}
+	return ciphertext, nil
+}
+
+func (r *runner) cipher(v cipherVector) Result {
+	alg := algorithm.NewCipher(v.mode)
+	bits := uint32(len(v.key) * 8)
+	res := &Result{Algorithm: alg.String(), Key: fmt.Sprintf("AES-%d", bits)}
+	attributes := &parsec.KeyAttributes{
+		KeyBits: bits,
+		KeyType: parsec.NewKeyType().Aes(),
+		KeyPolicy: &parsec.KeyPolicy{
+			KeyAlgorithm:  alg,
+			KeyUsageFlags: &parsec.UsageFlags{Encrypt: true, Decrypt: true},
+		},
+	}
+	name := keyName("cipher")
+	destroy, err := r.importKey(name, attributes, v.key)
+	if err != nil {
+		res.record("import key", err)
+		return res.finish()
+	}
+	defer destroy()
+
+	// The IV is at the start of the ciphertext.  The provider chooses it when encrypting, so the ciphertext is
+	// checked by encrypting with the same IV using Go's crypto library.
+	plaintext, err := r.client.PsaCipherDecrypt(name, alg.GetCipher(), append(append([]byte{}, v.iv...), v.ciphertext...), r.opts...)
+	res.record("decrypt", expect(plaintext, v.plaintext, err))
+	ciphertext, err := r.client.PsaCipherEncrypt(name, alg.GetCipher(), v.plaintext, r.opts...)
+	if err == nil {
+		if len(ciphertext) != len(v.iv)+len(v.plaintext) {
+			err = fmt.Errorf("got %d bytes, want a %d byte IV and %d byte ciphertext", len(ciphertext), len(v.iv), len(v.plaintext))
+		} else {
+			var want []byte
+			want, err = v.goEncrypt(ciphertext[:len(v.iv)], v.plaintext)
+			err = expect(ciphertext[len(v.iv):], want, err)
+		}
+	}
+	res.record("encrypt", err)
+	return res.finish()
+}
+
+func (r *runner) aead(v aeadVector) Result {
+	bits := uint32(len(v.key) * 8)
+	var attributes *parsec.KeyAttributes
+	var res *Result
+	if v.alg == algorithm.AeadAlgorithmChacha20Poly1305 {
+		attributes = parsec.DefaultKeyAttribute().Chacha20Poly1305Key(parsec.WithKeyBits(bits))
+		res = &Result{Key: fmt.Sprintf("ChaCha20-%d", bits)}
+	} else {
+		attributes = parsec.DefaultKeyAttribute().AesGcmKey(parsec.WithKeyBits(bits))
+		res = &Result{Key: fmt.Sprintf("AES-%d", bits)}
+	}
+	res.Algorithm = attributes.KeyPolicy.KeyAlgorithm.String()
+	name := keyName("aead")
+	destroy, err := r.importKey(name, attributes, v.key)
+	if err != nil {
+		res.record("import key", err)
+		return res.finish()
+	}
+	defer destroy()
+
+	alg := attributes.KeyPolicy.KeyAlgorithm.GetAead()
+	ciphertext, err := r.client.PsaAeadEncrypt(name, alg, v.nonce, v.additionalData, v.plaintext, r.opts...)
+	res.record("encrypt", expect(ciphertext, v.ciphertext, err))
+	plaintext, err := r.client.PsaAeadDecrypt(name, alg, v.nonce, v.additionalData, v.ciphertext, r.opts...)
+	res.record("decrypt", expect(plaintext, v.plaintext, err))
+	_, err = r.client.PsaAeadDecrypt(name, alg, v.nonce, v.additionalData, tamper(v.ciphertext), r.opts...)
+	res.recordRejected("decrypt rejects tampered tag", err)
+	return res.finish()
+}
+
+type signingKey struct {
+	// kind describes the key type, to which the size is added.
+	kind       string
+	attributes *parsec.KeyAttributes
+}
+
+// signingKeys are the keys whose signatures are checked.
+func signingKeys() []signingKey {
+	return []signingKey{
+		{"RSA", parsec.DefaultKeyAttribute().SigningKey()},
+		{"RSA", parsec.DefaultKeyAttribute().RsaPssSigningKey()},
+		{"SECP_R1", parsec.DefaultKeyAttribute().EcdsaSigningKey()},
+		{"SECP_R1", parsec.DefaultKeyAttribute().EcdsaSigningKey(parsec.WithKeyBits(384))},
+	}
+}
+
+func (r *runner) signature(key signingKey) Result {
+	attributes := key.attributes
+	res := &Result{
+		Algorithm: attributes.KeyPolicy.KeyAlgorithm.String(),
+		Key:       fmt.Sprintf("%v-%d", key.kind, attributes.KeyBits),
+	}
+	alg := attributes.KeyPolicy.KeyAlgorithm.GetAsymmetricSignature()
+	h, err := gocrypto.ToCryptoHash(alg.HashAlg())
+	if err != nil {
+		res.record("hash", err)
+		return res.finish()
+	}
+	digest := h.New()
+	digest.Write(signedMessage)
+	hash := digest.Sum(nil)
+
+	name := keyName("sign")
+	destroy, err := r.generateKey(name, attributes)
+	if err != nil {
+		res.record("generate key", err)
+		return res.finish()
+	}
+	defer destroy()
+
+	signature, err := r.client.PsaSignHash(name, hash, alg, r.opts...)
+	res.record("sign", err)
+	if err != nil {
+		return res.finish()
+	}
+	r.goVerify(res, name, attributes, hash, signature)
+	res.record("verify", r.client.PsaVerifyHash(name, hash, signature, alg, r.opts...))
+	res.recordRejected("verify rejects tampered hash", r.client.PsaVerifyHash(name, tamper(hash), signature, alg, r.opts...))
+	res.recordRejected("verify rejects tampered signature", r.client.PsaVerifyHash(name, hash, tamper(signature), alg, r.opts...))
+	return res.finish()
+}
+
+// goVerify verifies a signature made by the provider using the exported public key and Go's crypto library.  PSS
+// signatures with any salt length are accepted here, and a salt of the wrong length is reported as a separate
+// failing check, so that a provider choosing its own salt length is not reported as making bad signatures.
+func (r *runner) goVerify(res *Result, name string, attributes *parsec.KeyAttributes, hash, signature []byte) {
+	alg := attributes.KeyPolicy.KeyAlgorithm.GetAsymmetricSignature()
+	data, err := r.client.PsaExportPublicKey(name, r.opts...)
+	if err == nil {
+		err = verify.VerifyHash(attributes.KeyType, data, alg, hash, signature, verify.AnySaltLength())
+	}
+	res.record("signature verifies with Go", err)
+	if err != nil || alg.GetRsaPss() == nil {
+		return
+	}
+	if err = verify.VerifyHash(attributes.KeyType, data, alg, hash, signature); err != nil {
+		err = fmt.Errorf("salt is not the length of the hash, as PSA requires: %w", err)
+	}
+	res.record("PSS salt is the hash length", err)
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package selftest checks that a provider computes correct results before it is trusted.  Hashes, MACs, ciphers and
+// AEADs are computed over published test vectors using imported keys.  Signatures made by the provider are checked
+// with Go's crypto library, and the provider's verification is checked to reject tampered hashes and signatures.
+//
+// The keys used are named with the prefix parsec-selftest- and are destroyed afterwards.
+package selftest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+// Status is the outcome of a check, or of all the checks of an algorithm.
+type Status string
+
+const (
+	Pass Status = "pass"
+	Fail Status = "fail"
+	// Unsupported means the provider does not support the algorithm, operation or key.
+	Unsupported Status = "unsupported"
+)
+
+// Check is the outcome of one check of an algorithm.
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	// Detail explains a failure or why the check is unsupported.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Result holds the checks of one algorithm.
+type Result struct {
+	// Algorithm is the PSA name of the algorithm.
+	Algorithm string `json:"algorithm"`
+	// Key describes the key used, if any, such as AES-128.
+	Key string `json:"key,omitempty"`
+	// Status is Fail if any check failed, Pass if none failed and at least one passed, and Unsupported otherwise.
+	Status Status  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+// Report holds the results of the self-test of a provider.
+type Report struct {
+	Provider string   `json:"provider"`
+	Results  []Result `json:"results"`
+}
+
+// Failed returns true if any check failed.
+func (r *Report) Failed() bool {
+	for _, res := range r.Results {
+		if res.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the report as a table for people to read, with the checks of each algorithm that did not pass.
+func (r *Report) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(table, "provider %v\n", r.Provider)
+	for _, res := range r.Results {
+		name := res.Algorithm
+		if res.Key != "" {
+			name += " " + res.Key
+		}
+		fmt.Fprintf(table, "%v\t%v\n", name, res.Status)
+		for _, c := range res.Checks {
+			if c.Status != Pass {
+				fmt.Fprintf(table, "  %v\t%v\t%v\n", c.Name, c.Status, c.Detail)
+			}
+		}
+	}
+	return table.Flush()
+}
+
+func (res *Result) add(name string, status Status, detail string) {
+	res.Checks = append(res.Checks, Check{Name: name, Status: status, Detail: detail})
+}
+
+// record adds a check that passed if err is nil.
+func (res *Result) record(name string, err error) {
+	switch {
+	case err == nil:
+		res.add(name, Pass, "")
+	case unsupported(err):
+		res.add(name, Unsupported, err.Error())
+	default:
+		res.add(name, Fail, err.Error())
+	}
+}
+
+// recordRejected adds a check that passed if the parsec service rejected tampered data, so err must be a status
+// returned by the service.
+func (res *Result) recordRejected(name string, err error) {
+	_, isStatus := requests.StatusCodeFromError(err)
+	switch {
+	case err == nil:
+		res.add(name, Fail, "tampered data was accepted")
+	case unsupported(err):
+		res.add(name, Unsupported, err.Error())
+	case isStatus:
+		res.add(name, Pass, "")
+	default:
+		res.add(name, Fail, err.Error())
+	}
+}
+
+func (res *Result) finish() Result {
+	passed := false
+	for _, c := range res.Checks {
+		switch c.Status {
+		case Fail:
+			res.Status = Fail
+			return *res
+		case Pass:
+			passed = true
+		}
+	}
+	res.Status = Unsupported
+	if passed {
+		res.Status = Pass
+	}
+	return *res
+}
+
+// unsupported returns true if err means the provider does not support an operation, algorithm or key.
+func unsupported(err error) bool {
+	if errors.Is(err, parsec.ErrOperationNotSupported) {
+		return true
+	}
+	code, _ := requests.StatusCodeFromError(err)
+	return code == requests.StatusPsaErrorNotSupported || code == requests.StatusOpcodeDoesNotExist
+}
+
+// mismatch returns an error describing a result that differs from the test vector.
+func mismatch(got, want []byte) error {
+	return fmt.Errorf("got %x, want %x", got, want)
+}
+
+// Run runs the self-test of the provider.  Failures are recorded in the report, so no error is returned.
+func Run(client *parsec.BasicClient, provider parsec.ProviderID, opts ...parsec.CallOption) *Report {
+	r := &runner{
+		client: client,
+		opts:   append(append([]parsec.CallOption{}, opts...), parsec.WithProvider(provider)),
+	}
+	report := &Report{Provider: provider.String()}
+	for _, v := range hashVectors {
+		report.Results = append(report.Results, r.hash(v))
+	}
+	for _, v := range macVectors {
+		report.Results = append(report.Results, r.mac(v))
+	}
+	for _, v := range cipherVectors {
+		report.Results = append(report.Results, r.cipher(v))
+	}
+	for _, v := range aeadVectors {
+		report.Results = append(report.Results, r.aead(v))
+	}
+	for _, key := range signingKeys() {
+		report.Results = append(report.Results, r.signature(key))
+	}
+	return report
+}
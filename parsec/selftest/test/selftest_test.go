@@ -0,0 +1,201 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psahashcompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifyhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/selftest"
+	"google.golang.org/protobuf/proto"
+)
+
+var allOpcodes = []requests.OpCode{
+	requests.OpPsaHashCompute, requests.OpPsaImportKey, requests.OpPsaGenerateKey, requests.OpPsaDestroyKey,
+	requests.OpPsaExportPublicKey, requests.OpPsaMacCompute, requests.OpPsaMacVerify, requests.OpPsaCipherEncrypt,
+	requests.OpPsaCipherDecrypt, requests.OpPsaAeadEncrypt, requests.OpPsaAeadDecrypt, requests.OpPsaSignHash,
+	requests.OpPsaVerifyHash,
+}
+
+// newService returns a service answering with the provider, which lists only opcodes as supported.
+func newService(provider *parsectest.Provider, opcodes []requests.OpCode) *parsectest.Service {
+	var supported []uint32
+	for _, op := range opcodes {
+		supported = append(supported, uint32(op))
+	}
+	return provider.Service().
+		Handle(requests.OpListOpcodes, func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+			return &listopcodes.Result{Opcodes: supported}, requests.StatusSuccess
+		})
+}
+
+func result(report *selftest.Report, alg, key string) selftest.Result {
+	for _, res := range report.Results {
+		if res.Algorithm == alg && res.Key == key {
+			return res
+		}
+	}
+	Fail("no result for " + alg + " " + key)
+	return selftest.Result{}
+}
+
+func check(res selftest.Result, name string) selftest.Check {
+	for _, c := range res.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	Fail("no check " + name + " for " + res.Algorithm)
+	return selftest.Check{}
+}
+
+var _ = Describe("provider self-test", func() {
+	var dir string
+	var listener net.Listener
+	var provider *parsectest.Provider
+	var opcodes []requests.OpCode
+	var service *parsectest.Service
+	var client *parsec.BasicClient
+
+	BeforeEach(func() {
+		opcodes = allOpcodes
+		provider = parsectest.NewProvider()
+	})
+	JustBeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "parsec-selftest")
+		Expect(err).NotTo(HaveOccurred())
+		listener, err = net.Listen("unix", filepath.Join(dir, "parsec.sock"))
+		Expect(err).NotTo(HaveOccurred())
+		go func(l net.Listener, s *parsectest.Service) {
+			_ = s.Serve(l)
+		}(listener, service)
+		Expect(os.Setenv("PARSEC_SERVICE_ENDPOINT", "unix:"+filepath.Join(dir, "parsec.sock"))).To(Succeed())
+		client, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("selftest")))
+		Expect(err).NotTo(HaveOccurred())
+	})
+	AfterEach(func() {
+		Expect(client.Close()).To(Succeed())
+		Expect(listener.Close()).To(Succeed())
+		Expect(os.Unsetenv("PARSEC_SERVICE_ENDPOINT")).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Context("With a correct provider", func() {
+		BeforeEach(func() {
+			service = newService(provider, opcodes)
+		})
+		It("Should pass the algorithms the provider supports", func() {
+			report := selftest.Run(client, parsec.ProviderMBed)
+			Expect(report.Provider).To(Equal("MBed"))
+			Expect(report.Failed()).To(BeFalse())
+			for _, res := range report.Results {
+				Expect(res.Checks).NotTo(BeEmpty())
+				switch res.Algorithm {
+				case "PSA_ALG_CMAC", "PSA_ALG_CHACHA20_POLY1305":
+					Expect(res.Status).To(Equal(selftest.Unsupported), res.Algorithm)
+				default:
+					Expect(res.Status).To(Equal(selftest.Pass), "%v %+v", res.Algorithm, res.Checks)
+				}
+			}
+			Expect(result(report, "PSA_ALG_SHA_256", "").Checks).To(ConsistOf(selftest.Check{Name: "compute", Status: selftest.Pass}))
+			gcm := result(report, "PSA_ALG_GCM", "AES-128")
+			Expect(check(gcm, "decrypt rejects tampered tag").Status).To(Equal(selftest.Pass))
+			ecdsa := result(report, "PSA_ALG_ECDSA(PSA_ALG_SHA_384)", "SECP_R1-384")
+			Expect(check(ecdsa, "signature verifies with Go").Status).To(Equal(selftest.Pass))
+			Expect(check(ecdsa, "verify rejects tampered signature").Status).To(Equal(selftest.Pass))
+		})
+		It("Should destroy the keys it uses", func() {
+			selftest.Run(client, parsec.ProviderMBed)
+			Expect(provider.KeyNames()).To(BeEmpty())
+		})
+		It("Should write the report as text and JSON", func() {
+			report := selftest.Run(client, parsec.ProviderMBed)
+			var text bytes.Buffer
+			Expect(report.WriteText(&text)).To(Succeed())
+			Expect(text.String()).To(ContainSubstring("PSA_ALG_RSA_PSS(PSA_ALG_SHA_256) RSA-2048"))
+			Expect(text.String()).To(MatchRegexp(`PSA_ALG_CMAC AES-128 +unsupported`))
+			encoded, err := json.Marshal(report)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(encoded)).To(ContainSubstring(`"status":"unsupported"`))
+		})
+	})
+	Context("With a provider computing a wrong hash", func() {
+		BeforeEach(func() {
+			service = newService(provider, opcodes).
+				Handle(requests.OpPsaHashCompute, func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+					return &psahashcompute.Result{Hash: []byte{1, 2, 3}}, requests.StatusSuccess
+				})
+		})
+		It("Should fail the hash algorithms", func() {
+			report := selftest.Run(client, parsec.ProviderMBed)
+			Expect(report.Failed()).To(BeTrue())
+			sha := result(report, "PSA_ALG_SHA_256", "")
+			Expect(sha.Status).To(Equal(selftest.Fail))
+			Expect(sha.Checks[0].Detail).To(HavePrefix("got 010203, want ba7816bf"))
+			Expect(result(report, "PSA_ALG_GCM", "AES-128").Status).To(Equal(selftest.Pass))
+		})
+	})
+	Context("With a provider accepting any signature", func() {
+		BeforeEach(func() {
+			service = newService(provider, opcodes).
+				Handle(requests.OpPsaVerifyHash, func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+					return &psaverifyhash.Result{}, requests.StatusSuccess
+				})
+		})
+		It("Should fail the signature algorithms", func() {
+			report := selftest.Run(client, parsec.ProviderMBed)
+			res := result(report, "PSA_ALG_ECDSA(PSA_ALG_SHA_256)", "SECP_R1-256")
+			Expect(res.Status).To(Equal(selftest.Fail))
+			Expect(check(res, "verify").Status).To(Equal(selftest.Pass))
+			Expect(check(res, "verify rejects tampered hash")).To(Equal(selftest.Check{
+				Name: "verify rejects tampered hash", Status: selftest.Fail, Detail: "tampered data was accepted",
+			}))
+		})
+	})
+	Context("With a provider using a PSS salt shorter than the hash", func() {
+		BeforeEach(func() {
+			provider.PSSSaltLength = 20
+			service = newService(provider, opcodes)
+		})
+		It("Should fail the salt length check but not the signature", func() {
+			report := selftest.Run(client, parsec.ProviderMBed)
+			res := result(report, "PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)", "RSA-2048")
+			Expect(res.Status).To(Equal(selftest.Fail))
+			Expect(check(res, "signature verifies with Go").Status).To(Equal(selftest.Pass))
+			salt := check(res, "PSS salt is the hash length")
+			Expect(salt.Status).To(Equal(selftest.Fail))
+			Expect(salt.Detail).To(HavePrefix("salt is not the length of the hash"))
+			Expect(result(report, "PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_SHA_256)", "RSA-2048").Status).To(Equal(selftest.Pass))
+		})
+	})
+	Context("With a provider without signing", func() {
+		BeforeEach(func() {
+			opcodes = allOpcodes[:len(allOpcodes)-2]
+			service = newService(provider, opcodes)
+		})
+		It("Should report signature algorithms as unsupported", func() {
+			report := selftest.Run(client, parsec.ProviderMBed)
+			Expect(report.Failed()).To(BeFalse())
+			res := result(report, "PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_SHA_256)", "RSA-2048")
+			Expect(res.Status).To(Equal(selftest.Unsupported))
+			Expect(res.Checks).To(HaveLen(1))
+			Expect(res.Checks[0].Name).To(Equal("sign"))
+			Expect(provider.KeyNames()).To(BeEmpty())
+		})
+	})
+})
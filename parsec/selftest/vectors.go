@@ -0,0 +1,102 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package selftest
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// unhex decodes a hex test vector, which may be split by spaces for readability.
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type hashVector struct {
+	alg     algorithm.HashAlgorithmType
+	message []byte
+	digest  []byte
+}
+
+// hashVectors are the "abc" examples from FIPS 180-4.
+var hashVectors = []hashVector{
+	{algorithm.HashAlgorithmTypeSHA224, []byte("abc"), unhex("23097d22 3405d822 8642a477 bda255b3 2aadbce4 bda0b3f7 e36c9da7")},
+	{algorithm.HashAlgorithmTypeSHA256, []byte("abc"), unhex("ba7816bf 8f01cfea 414140de 5dae2223 b00361a3 96177a9c b410ff61 f20015ad")},
+	{algorithm.HashAlgorithmTypeSHA384, []byte("abc"), unhex(
+		"cb00753f 45a35e8b b5a03d69 9ac65007 272c32ab 0eded163 1a8b605a 43ff5bed 8086072b a1e7cc23 58baeca1 34c825a7")},
+	{algorithm.HashAlgorithmTypeSHA512, []byte("abc"), unhex(
+		"ddaf35a1 93617aba cc417349 ae204131 12e6fa4e 89a97ea2 0a9eeee6 4b55d39a 2192992a 274fc1a8 36ba3c23 a3feebbd" +
+			"454d4423 643ce80e 2a9ac94f a54ca49f")},
+}
+
+type macVector struct {
+	alg     *algorithm.Algorithm
+	key     []byte
+	message []byte
+	mac     []byte
+}
+
+// macVectors are test case 1 from RFC 4231 for HMAC and example 2 from NIST SP 800-38B for CMAC.
+var macVectors = []macVector{
+	{algorithm.NewMAC().HMAC(algorithm.HashAlgorithmTypeSHA256), unhex("0b0b0b0b 0b0b0b0b 0b0b0b0b 0b0b0b0b 0b0b0b0b"),
+		[]byte("Hi There"), unhex("b0344c61 d8db3853 5ca8afce af0bf12b 881dc200 c9833da7 26e9376c 2e32cff7")},
+	{algorithm.NewMAC().HMAC(algorithm.HashAlgorithmTypeSHA384), unhex("0b0b0b0b 0b0b0b0b 0b0b0b0b 0b0b0b0b 0b0b0b0b"),
+		[]byte("Hi There"), unhex(
+			"afd03944 d8489562 6b0825f4 ab46907f 15f9dadb e4101ec6 82aa034c 7cebc59c faea9ea9 076ede7f 4af152e8 b2fa9cb6")},
+	{algorithm.NewMAC().HMAC(algorithm.HashAlgorithmTypeSHA512), unhex("0b0b0b0b 0b0b0b0b 0b0b0b0b 0b0b0b0b 0b0b0b0b"),
+		[]byte("Hi There"), unhex(
+			"87aa7cde a5ef619d 4ff0b424 1a1d6cb0 2379f4e2 ce4ec278 7ad0b305 45e17cde daa833b7 d6b8a702 038b274e aea3f4e4" +
+				"be9d914e eb61f170 2e696c20 3a126854")},
+	{algorithm.NewMAC().CMAC(), unhex("2b7e1516 28aed2a6 abf71588 09cf4f3c"),
+		unhex("6bc1bee2 2e409f96 e93d7e11 7393172a"), unhex("070a16b4 6b4d4144 f79bdd9d d04a287c")},
+}
+
+type cipherVector struct {
+	mode       algorithm.CipherModeType
+	key        []byte
+	iv         []byte
+	plaintext  []byte
+	ciphertext []byte
+}
+
+// cipherVectors are the first two blocks of the AES-128 examples F.2.1 and F.5.1 from NIST SP 800-38A.
+var cipherVectors = []cipherVector{
+	{algorithm.CipherModeCBCNOPADDING, unhex("2b7e1516 28aed2a6 abf71588 09cf4f3c"), unhex("00010203 04050607 08090a0b 0c0d0e0f"),
+		unhex("6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51"),
+		unhex("7649abac 8119b246 cee98e9b 12e9197d 5086cb9b 507219ee 95db113a 917678b2")},
+	{algorithm.CipherModeCTR, unhex("2b7e1516 28aed2a6 abf71588 09cf4f3c"), unhex("f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff"),
+		unhex("6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51"),
+		unhex("874d6191 b620e326 1bef6864 990db6ce 9806f66b 7970fdff 8617187b b9fffdff")},
+}
+
+type aeadVector struct {
+	alg            algorithm.AeadAlgorithmType
+	key            []byte
+	nonce          []byte
+	additionalData []byte
+	plaintext      []byte
+	// ciphertext is followed by the tag, as PsaAeadEncrypt returns them.
+	ciphertext []byte
+}
+
+// aeadVectors are test case 2 from the GCM specification by McGrew and Viega and the example in section 2.8.2 of
+// RFC 8439 for ChaCha20-Poly1305.
+var aeadVectors = []aeadVector{
+	{algorithm.AeadAlgorithmGCM, make([]byte, 16), make([]byte, 12), nil, make([]byte, 16),
+		unhex("0388dace 60b6a392 f328c2b9 71b2fe78 ab6e47d4 2cec13bd f53a67b2 1257bddf")},
+	{algorithm.AeadAlgorithmChacha20Poly1305,
+		unhex("80818283 84858687 88898a8b 8c8d8e8f 90919293 94959697 98999a9b 9c9d9e9f"),
+		unhex("07000000 40414243 44454647"), unhex("50515253 c0c1c2c3 c4c5c6c7"),
+		[]byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it."),
+		unhex("d31a8d34 648e60db 7b86afbc 53ef7ec2 a4aded51 296e08fe a9e2b5a7 36ee62d6 3dbea45e 8ca96712 82fafb69 da92728b" +
+			"1a71de0a 9e060b29 05d6a5b6 7ecd3b36 92ddbd7f 2d778b8c 9803aee3 28091b58 fab324e4 fad67594 5585808b 4831d7bc" +
+			"3ff4def0 8e4b7a9d e576d265 86cec64b 6116" +
+			"1ae10b59 4f09e26a 7e902ecb d0600691")},
+}
@@ -0,0 +1,346 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sshagent serves keys held by the parsec service, for example in a TPM, to SSH clients as an SSH agent.
+// Every RSA and NIST P-256, P-384 and P-521 ECDSA key pair whose policy permits signing is offered, and signing is
+// done by the service so the private keys never leave it.
+package sshagent
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// KeyPrefix starts the names of the keys created by Add and Generate.  Only keys with names starting with KeyPrefix
+// are destroyed by Remove and RemoveAll; other keys are left for their owners to manage.
+const KeyPrefix = "ssh-"
+
+// ErrLocked is returned by operations on a locked agent.
+var ErrLocked = errors.New("agent is locked")
+
+// Agent is an SSH agent backed by parsec keys.  It implements agent.ExtendedAgent, so can be served to SSH clients
+// with agent.ServeAgent.  The keys are looked up with ListKeys on each request, so keys created or destroyed by
+// other clients are seen immediately.
+type Agent struct {
+	client *parsec.BasicClient
+	opts   []parsec.CallOption
+	logger *slog.Logger
+
+	mu sync.Mutex
+	// passphrase is set while the agent is locked
+	passphrase []byte
+}
+
+var _ agent.ExtendedAgent = (*Agent)(nil)
+
+// New returns an agent serving the keys client can use.  The options are used for every call, so can select the
+// authenticator, and the provider keys are created in by Add and Generate.  Keys from every provider are offered.
+func New(client *parsec.BasicClient, opts ...parsec.CallOption) *Agent {
+	return &Agent{client: client, opts: opts, logger: slog.Default()}
+}
+
+// Logger sets the logger warned about keys that cannot be offered, returning the agent so calls can be chained.  The
+// default is slog.Default().
+func (a *Agent) Logger(logger *slog.Logger) *Agent {
+	a.logger = logger
+	return a
+}
+
+// key is a parsec key offered by the agent.
+type key struct {
+	info *parsec.KeyInfo
+	pub  ssh.PublicKey
+}
+
+func (a *Agent) locked() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.passphrase != nil
+}
+
+// keyOptions returns the options for an operation on k, which is sent to the provider holding it.
+func (a *Agent) keyOptions(k *parsec.KeyInfo) []parsec.CallOption {
+	return append(a.opts[:len(a.opts):len(a.opts)], parsec.WithProvider(k.ProviderID))
+}
+
+// keys returns the keys that can be used for SSH signatures.  A key whose public key cannot be exported or converted
+// is logged and left out, so that one unusable key does not stop the others from being used.
+func (a *Agent) keys() ([]*key, error) {
+	infos, err := a.client.ListKeys(a.opts...)
+	if err != nil {
+		return nil, err
+	}
+	var keys []*key
+	for _, info := range infos {
+		if !canSign(info.Attributes) {
+			continue
+		}
+		pub, err := a.publicKey(info.Name, info.Attributes, a.keyOptions(info))
+		if err != nil {
+			a.logger.Warn("skipping parsec key that cannot be used for SSH",
+				slog.String("key", info.Name), slog.String("provider", info.ProviderID.String()), slog.String("error", err.Error()))
+			continue
+		}
+		keys = append(keys, &key{info: info, pub: pub})
+	}
+	return keys, nil
+}
+
+// find returns the key whose public key is pub.
+func (a *Agent) find(pub ssh.PublicKey) (*key, error) {
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	blob := pub.Marshal()
+	for _, k := range keys {
+		if bytes.Equal(k.pub.Marshal(), blob) {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("no parsec key for %v public key %v", pub.Type(), ssh.FingerprintSHA256(pub))
+}
+
+// List returns the public keys of the parsec keys that can be used for SSH signatures, with the key name as the
+// comment.  A locked agent has no keys.
+func (a *Agent) List() ([]*agent.Key, error) {
+	if a.locked() {
+		return nil, nil
+	}
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*agent.Key, len(keys))
+	for i, k := range keys {
+		list[i] = &agent.Key{Format: k.pub.Type(), Blob: k.pub.Marshal(), Comment: k.info.Name}
+	}
+	return list, nil
+}
+
+// Sign signs data with the key whose public key is pub.  RSA keys make ssh-rsa signatures, with SHA-1.
+func (a *Agent) Sign(pub ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(pub, data, 0)
+}
+
+// SignWithFlags signs data with the key whose public key is pub.  RSA keys make rsa-sha2-256 or rsa-sha2-512
+// signatures if flags asks for them.  The signature algorithm must be permitted by the key's policy.
+func (a *Agent) SignWithFlags(pub ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	if a.locked() {
+		return nil, ErrLocked
+	}
+	k, err := a.find(pub)
+	if err != nil {
+		return nil, err
+	}
+	format := pub.Type()
+	if format == ssh.KeyAlgoRSA {
+		switch {
+		case flags&agent.SignatureFlagRsaSha512 != 0:
+			format = ssh.KeyAlgoRSASHA512
+		case flags&agent.SignatureFlagRsaSha256 != 0:
+			format = ssh.KeyAlgoRSASHA256
+		}
+	}
+	return a.sign(k, format, data)
+}
+
+// sign makes a signature of data in the given SSH signature format.
+func (a *Agent) sign(k *key, format string, data []byte) (*ssh.Signature, error) {
+	alg, hash, ok := signingAlgorithm(k.info.Attributes, format)
+	if !ok {
+		return nil, fmt.Errorf("policy of key %q does not permit %v signatures", k.info.Name, format)
+	}
+	opts := a.keyOptions(k.info)
+	var signature []byte
+	var err error
+	if k.info.Attributes.KeyPolicy.KeyUsageFlags.SignHash {
+		h := hash.New()
+		h.Write(data)
+		signature, err = a.client.PsaSignHash(k.info.Name, h.Sum(nil), alg, opts...)
+	} else {
+		signature, err = a.client.PsaSignMessage(k.info.Name, data, alg, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	blob, err := signatureBlob(k.pub, signature)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.Signature{Format: format, Blob: blob}, nil
+}
+
+// Add imports an RSA or ECDSA private key into parsec, naming it KeyPrefix followed by the key's comment, or its
+// fingerprint if there is no comment.  The key can then only be used for signing.  Lifetimes, confirmation and
+// certificates are not supported.
+func (a *Agent) Add(added agent.AddedKey) error {
+	if a.locked() {
+		return ErrLocked
+	}
+	switch {
+	case added.LifetimeSecs != 0:
+		return fmt.Errorf("key lifetimes are not supported")
+	case added.ConfirmBeforeUse:
+		return fmt.Errorf("confirmation before use is not supported")
+	case added.Certificate != nil:
+		return fmt.Errorf("certificates are not supported")
+	}
+	attributes, data, err := importData(added.PrivateKey)
+	if err != nil {
+		return err
+	}
+	signer, err := ssh.NewSignerFromKey(added.PrivateKey)
+	if err != nil {
+		return err
+	}
+	return a.client.PsaImportKey(keyName(added.Comment, signer.PublicKey()), attributes, data, a.opts...)
+}
+
+// Generate creates a key named KeyPrefix followed by name with attributes, returning its SSH public key.  The
+// attributes must describe a key the agent can offer, such as parsec.DefaultKeyAttribute().EcdsaSigningKey().
+func (a *Agent) Generate(name string, attributes *parsec.KeyAttributes) (ssh.PublicKey, error) {
+	if a.locked() {
+		return nil, ErrLocked
+	}
+	if !canSign(attributes) {
+		return nil, fmt.Errorf("attributes do not describe an RSA or ECDSA key that can sign")
+	}
+	name = KeyPrefix + name
+	if err := a.client.PsaGenerateKey(name, attributes, a.opts...); err != nil {
+		return nil, err
+	}
+	return a.publicKey(name, attributes, a.opts)
+}
+
+// Remove destroys the key whose public key is pub, if it was created by Add or Generate.
+func (a *Agent) Remove(pub ssh.PublicKey) error {
+	if a.locked() {
+		return ErrLocked
+	}
+	k, err := a.find(pub)
+	if err != nil {
+		return err
+	}
+	if !isAgentKey(k.info) {
+		return fmt.Errorf("key %q was not created by the agent so is not destroyed", k.info.Name)
+	}
+	return a.client.PsaDestroyKey(k.info.Name, a.keyOptions(k.info)...)
+}
+
+// RemoveAll destroys every key created by Add or Generate.  Other keys are left in place.
+func (a *Agent) RemoveAll() error {
+	if a.locked() {
+		return ErrLocked
+	}
+	keys, err := a.keys()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, k := range keys {
+		if isAgentKey(k.info) {
+			errs = append(errs, a.client.PsaDestroyKey(k.info.Name, a.keyOptions(k.info)...))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isAgentKey(k *parsec.KeyInfo) bool {
+	return strings.HasPrefix(k.Name, KeyPrefix)
+}
+
+// Lock locks the agent with passphrase.  A locked agent lists no keys and refuses all operations except Unlock.
+func (a *Agent) Lock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase != nil {
+		return ErrLocked
+	}
+	a.passphrase = append([]byte{}, passphrase...)
+	return nil
+}
+
+// Unlock unlocks an agent locked with passphrase.
+func (a *Agent) Unlock(passphrase []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.passphrase == nil {
+		return fmt.Errorf("agent is not locked")
+	}
+	if subtle.ConstantTimeCompare(a.passphrase, passphrase) != 1 {
+		return fmt.Errorf("incorrect passphrase")
+	}
+	a.passphrase = nil
+	return nil
+}
+
+// Signers returns an ssh.Signer for each key, for use by an SSH client in the same process.
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	if a.locked() {
+		return nil, ErrLocked
+	}
+	keys, err := a.keys()
+	if err != nil {
+		return nil, err
+	}
+	signers := make([]ssh.Signer, len(keys))
+	for i, k := range keys {
+		signers[i] = &signer{agent: a, pub: k.pub}
+	}
+	return signers, nil
+}
+
+// Extension is not supported.
+func (a *Agent) Extension(string, []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
+
+// publicKey exports the public part of the key called name and converts it to an SSH public key.
+func (a *Agent) publicKey(name string, attributes *parsec.KeyAttributes, opts []parsec.CallOption) (ssh.PublicKey, error) {
+	data, err := a.client.PsaExportPublicKey(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return sshPublicKey(attributes, data)
+}
+
+// signer signs with one of the agent's keys.
+type signer struct {
+	agent *Agent
+	pub   ssh.PublicKey
+}
+
+var _ ssh.AlgorithmSigner = (*signer)(nil)
+
+func (s *signer) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *signer) Sign(_ io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.agent.Sign(s.pub, data)
+}
+
+func (s *signer) SignWithAlgorithm(_ io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	var flags agent.SignatureFlags
+	switch algorithm {
+	case ssh.KeyAlgoRSASHA256:
+		flags = agent.SignatureFlagRsaSha256
+	case ssh.KeyAlgoRSASHA512:
+		flags = agent.SignatureFlagRsaSha512
+	case "", s.pub.Type():
+	default:
+		return nil, fmt.Errorf("%v signatures cannot be made with %v keys", algorithm, s.pub.Type())
+	}
+	return s.agent.SignWithFlags(s.pub, data, flags)
+}
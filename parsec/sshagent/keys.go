@@ -0,0 +1,195 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package sshagent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	_ "crypto/sha1" //nolint:gosec // ssh-rsa signatures use SHA-1, and some servers still ask for them
+	_ "crypto/sha512"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm/gocrypto"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshSignature is an SSH signature format and the hash it uses.
+type sshSignature struct {
+	format string
+	hash   algorithm.HashAlgorithmType
+}
+
+// rsaSignatures are the signature formats of RSA keys, best first.
+var rsaSignatures = []sshSignature{
+	{ssh.KeyAlgoRSASHA512, algorithm.HashAlgorithmTypeSHA512},
+	{ssh.KeyAlgoRSASHA256, algorithm.HashAlgorithmTypeSHA256},
+	{ssh.KeyAlgoRSA, algorithm.HashAlgorithmTypeSHA1},
+}
+
+// ecdsaSignatures are the signature formats of ECDSA keys on the SECP R1 curve of each size.
+var ecdsaSignatures = map[uint32]sshSignature{
+	256: {ssh.KeyAlgoECDSA256, algorithm.HashAlgorithmTypeSHA256},
+	384: {ssh.KeyAlgoECDSA384, algorithm.HashAlgorithmTypeSHA384},
+	521: {ssh.KeyAlgoECDSA521, algorithm.HashAlgorithmTypeSHA512},
+}
+
+// signatures returns the SSH signature formats that could be made with a key with attributes, whether or not the
+// key's policy permits them.
+func signatures(attributes *parsec.KeyAttributes) []sshSignature {
+	wire, ok := attributes.KeyType.ToWireInterface().(*psakeyattributes.KeyType)
+	if !ok || wire == nil {
+		return nil
+	}
+	switch kt := wire.Variant.(type) {
+	case *psakeyattributes.KeyType_RsaKeyPair_:
+		return rsaSignatures
+	case *psakeyattributes.KeyType_EccKeyPair_:
+		if sig, ok := ecdsaSignatures[attributes.KeyBits]; ok && kt.EccKeyPair.CurveFamily == psakeyattributes.KeyType_SECP_R1 {
+			return []sshSignature{sig}
+		}
+	}
+	return nil
+}
+
+// candidates returns the parsec algorithms that make SSH signatures using hash, in the order they are tried.
+func candidates(format string, hash algorithm.HashAlgorithmType) []*algorithm.Algorithm {
+	sig := algorithm.NewAsymmetricSignature()
+	if strings.HasPrefix(format, "ecdsa-") {
+		return []*algorithm.Algorithm{sig.Ecdsa(hash), sig.DeterministicEcdsa(hash), sig.EcdsaAny()}
+	}
+	return []*algorithm.Algorithm{sig.RsaPkcs1V15Sign(hash)}
+}
+
+// signingAlgorithm returns the algorithm to make a signature in the SSH format with a key with attributes, and
+// the hash to apply to the data, if the key's policy permits it.
+func signingAlgorithm(attributes *parsec.KeyAttributes, format string) (*algorithm.AsymmetricSignatureAlgorithm, crypto.Hash, bool) {
+	policy := attributes.KeyPolicy.KeyAlgorithm
+	usage := attributes.KeyPolicy.KeyUsageFlags
+	for _, sig := range signatures(attributes) {
+		if sig.format != format {
+			continue
+		}
+		hash, err := gocrypto.ToCryptoHash(sig.hash)
+		if err != nil {
+			return nil, 0, false
+		}
+		for _, alg := range candidates(format, sig.hash) {
+			// ECDSA_ANY signs a hash, so cannot be used to sign a message
+			if alg.GetAsymmetricSignature().GetEcdsaAny() != nil && !usage.SignHash {
+				continue
+			}
+			if algorithm.Permits(policy, alg) {
+				return alg.GetAsymmetricSignature(), hash, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// canSign returns true if a key with attributes can make at least one SSH signature.
+func canSign(attributes *parsec.KeyAttributes) bool {
+	if attributes == nil || attributes.KeyType == nil || attributes.KeyPolicy == nil ||
+		attributes.KeyPolicy.KeyUsageFlags == nil || attributes.KeyPolicy.KeyAlgorithm == nil {
+		return false
+	}
+	usage := attributes.KeyPolicy.KeyUsageFlags
+	if !usage.SignHash && !usage.SignMessage {
+		return false
+	}
+	for _, sig := range signatures(attributes) {
+		if _, _, ok := signingAlgorithm(attributes, sig.format); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sshPublicKey converts a public key exported from parsec to an SSH public key.
+func sshPublicKey(attributes *parsec.KeyAttributes, data []byte) (ssh.PublicKey, error) {
+	pub, err := gocrypto.PublicKey(attributes, data)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewPublicKey(pub)
+}
+
+// signatureBlob converts a parsec signature to the blob of an SSH signature.  RSA signatures are the same, and
+// ECDSA signatures are converted from r followed by s to the SSH encoding of the two integers.
+func signatureBlob(pub ssh.PublicKey, signature []byte) ([]byte, error) {
+	if !strings.HasPrefix(pub.Type(), "ecdsa-") {
+		return signature, nil
+	}
+	if len(signature) == 0 || len(signature)%2 != 0 {
+		return nil, fmt.Errorf("%d byte ECDSA signature is not r and s", len(signature))
+	}
+	n := len(signature) / 2
+	return ssh.Marshal(struct {
+		R, S *big.Int
+	}{new(big.Int).SetBytes(signature[:n]), new(big.Int).SetBytes(signature[n:])}), nil
+}
+
+// signUsage permits the keys added to the agent to sign and verify, and nothing else.
+func signUsage() *parsec.UsageFlags {
+	return &parsec.UsageFlags{SignHash: true, SignMessage: true, VerifyHash: true, VerifyMessage: true}
+}
+
+// importData returns the attributes and the data for PsaImportKey of an RSA or ECDSA private key.  RSA keys can be
+// used with any hash, so for all the RSA signature formats.
+func importData(key interface{}) (*parsec.KeyAttributes, []byte, error) {
+	sig := algorithm.NewAsymmetricSignature()
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &parsec.KeyAttributes{
+			KeyType:   parsec.NewKeyType().RsaKeyPair(),
+			KeyBits:   uint32(k.N.BitLen()),
+			KeyPolicy: &parsec.KeyPolicy{KeyAlgorithm: sig.RsaPkcs1V15SignAny(), KeyUsageFlags: signUsage()},
+		}, x509.MarshalPKCS1PrivateKey(k), nil
+	case *ecdsa.PrivateKey:
+		family, bits, err := gocrypto.FromEllipticCurve(k.Curve)
+		if err != nil {
+			return nil, nil, err
+		}
+		ssig, ok := ecdsaSignatures[bits]
+		if !ok {
+			return nil, nil, fmt.Errorf("%v keys cannot be used with SSH", k.Curve.Params().Name)
+		}
+		ecdhKey, err := k.ECDH()
+		if err != nil {
+			return nil, nil, err
+		}
+		return &parsec.KeyAttributes{
+			KeyType:   parsec.NewKeyType().EccKeyPair(family),
+			KeyBits:   bits,
+			KeyPolicy: &parsec.KeyPolicy{KeyAlgorithm: sig.Ecdsa(ssig.hash), KeyUsageFlags: signUsage()},
+		}, ecdhKey.Bytes(), nil
+	default:
+		return nil, nil, fmt.Errorf("%T keys are not supported by parsec", key)
+	}
+}
+
+// keyName returns the name for a key added with comment, with any characters other than letters, digits, '.',
+// '_', '-' and '@' replaced.  Keys with no comment are named after the fingerprint of their public key.
+func keyName(comment string, pub ssh.PublicKey) string {
+	if comment == "" {
+		sum := sha256.Sum256(pub.Marshal())
+		return KeyPrefix + hex.EncodeToString(sum[:8])
+	}
+	return KeyPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("._-@", r):
+			return r
+		}
+		return '-'
+	}, comment)
+}
@@ -0,0 +1,275 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package sshagent_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"log/slog"
+	"net"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/sshagent"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"google.golang.org/protobuf/proto"
+)
+
+var data = []byte("session data")
+
+// rsaKey is generated once, as generating RSA keys is slow.
+var rsaKey struct {
+	once sync.Once
+	key  *rsa.PrivateKey
+}
+
+func testRSAKey() *rsa.PrivateKey {
+	rsaKey.once.Do(func() {
+		rsaKey.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	})
+	return rsaKey.key
+}
+
+func rsaAttributes(alg *algorithm.Algorithm) *parsec.KeyAttributes {
+	attributes := parsec.DefaultKeyAttribute().SigningKey()
+	attributes.KeyPolicy.KeyAlgorithm = alg
+	return attributes
+}
+
+func listed(client agent.ExtendedAgent) []string {
+	keys, err := client.List()
+	Expect(err).NotTo(HaveOccurred())
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.Comment
+	}
+	return names
+}
+
+func publicKey(client agent.ExtendedAgent, name string) ssh.PublicKey {
+	keys, err := client.List()
+	Expect(err).NotTo(HaveOccurred())
+	for _, k := range keys {
+		if k.Comment == name {
+			return k
+		}
+	}
+	Fail("no key " + name)
+	return nil
+}
+
+var _ = Describe("SSH agent", func() {
+	var (
+		provider *parsectest.Provider
+		service  *parsectest.Service
+		a        *sshagent.Agent
+		client   agent.ExtendedAgent
+		conn     net.Conn
+	)
+
+	generate := func(name string, attributes *parsec.KeyAttributes, opts ...parsec.CallOption) {
+		bc, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("app")).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.PsaGenerateKey(name, attributes, opts...)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		provider = parsectest.NewProvider()
+		service = provider.Service()
+		bc, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("app")).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+		a = sshagent.New(bc)
+
+		var agentConn net.Conn
+		conn, agentConn = net.Pipe()
+		go func() {
+			defer agentConn.Close()
+			_ = agent.ServeAgent(a, agentConn)
+		}()
+		client = agent.NewClient(conn)
+	})
+
+	AfterEach(func() {
+		conn.Close()
+	})
+
+	It("Should list only the keys that can make SSH signatures", func() {
+		sig := algorithm.NewAsymmetricSignature()
+		generate("ecdsa", parsec.DefaultKeyAttribute().EcdsaSigningKey())
+		generate("rsa", rsaAttributes(sig.RsaPkcs1V15SignAny()))
+		generate("tpm", parsec.DefaultKeyAttribute().EcdsaSigningKey(parsec.WithKeyBits(384)), parsec.WithProvider(parsec.ProviderTPM))
+		generate("pss", rsaAttributes(sig.RsaPss(algorithm.HashAlgorithmTypeSHA256)))
+		generate("aes", parsec.DefaultKeyAttribute().AesGcmKey())
+		verifyOnly := parsec.DefaultKeyAttribute().EcdsaSigningKey()
+		verifyOnly.KeyPolicy.KeyUsageFlags = &parsec.UsageFlags{VerifyHash: true}
+		generate("verify", verifyOnly)
+
+		Expect(listed(client)).To(ConsistOf("ecdsa", "rsa", "tpm"))
+		Expect(publicKey(client, "ecdsa").Type()).To(Equal(ssh.KeyAlgoECDSA256))
+		Expect(publicKey(client, "tpm").Type()).To(Equal(ssh.KeyAlgoECDSA384))
+		Expect(publicKey(client, "rsa").Type()).To(Equal(ssh.KeyAlgoRSA))
+	})
+
+	It("Should offer the other keys when one cannot be exported", func() {
+		var logged bytes.Buffer
+		a.Logger(slog.New(slog.NewTextHandler(&logged, nil)))
+		export := service.Handler(requests.OpPsaExportPublicKey)
+		service.Handle(requests.OpPsaExportPublicKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+			if provider == requests.ProviderTPM {
+				return nil, requests.StatusPsaErrorHardwareFailure
+			}
+			return export(provider, body)
+		})
+		generate("ecdsa", parsec.DefaultKeyAttribute().EcdsaSigningKey())
+		generate("broken", parsec.DefaultKeyAttribute().EcdsaSigningKey(), parsec.WithProvider(parsec.ProviderTPM))
+
+		Expect(listed(client)).To(ConsistOf("ecdsa"))
+		Expect(logged.String()).To(ContainSubstring("key=broken provider=TPM"))
+		pub := publicKey(client, "ecdsa")
+		signature, err := client.Sign(pub, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Verify(data, signature)).To(Succeed())
+		signers, err := a.Signers()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signers).To(HaveLen(1))
+	})
+
+	It("Should make ECDSA signatures", func() {
+		generate("p256", parsec.DefaultKeyAttribute().EcdsaSigningKey())
+		generate("p384", parsec.DefaultKeyAttribute().EcdsaSigningKey(parsec.WithKeyBits(384)), parsec.WithProvider(parsec.ProviderTPM))
+		for _, name := range []string{"p256", "p384"} {
+			pub := publicKey(client, name)
+			signature, err := client.Sign(pub, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signature.Format).To(Equal(pub.Type()))
+			Expect(pub.Verify(data, signature)).To(Succeed())
+		}
+	})
+
+	It("Should make the RSA signatures asked for", func() {
+		generate("rsa", rsaAttributes(algorithm.NewAsymmetricSignature().RsaPkcs1V15SignAny()))
+		pub := publicKey(client, "rsa")
+		for flags, format := range map[agent.SignatureFlags]string{
+			0:                            ssh.KeyAlgoRSA,
+			agent.SignatureFlagRsaSha256: ssh.KeyAlgoRSASHA256,
+			agent.SignatureFlagRsaSha512: ssh.KeyAlgoRSASHA512,
+		} {
+			signature, err := client.SignWithFlags(pub, data, flags)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signature.Format).To(Equal(format))
+			Expect(pub.Verify(data, signature)).To(Succeed())
+		}
+	})
+
+	It("Should refuse signatures the key policy does not permit", func() {
+		generate("rsa", rsaAttributes(algorithm.NewAsymmetricSignature().RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256)))
+		pub := publicKey(client, "rsa")
+		signature, err := client.SignWithFlags(pub, data, agent.SignatureFlagRsaSha256)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Verify(data, signature)).To(Succeed())
+
+		_, err = a.SignWithFlags(pub, data, agent.SignatureFlagRsaSha512)
+		Expect(err).To(MatchError(ContainSubstring("does not permit rsa-sha2-512")))
+		Expect(service.Count(requests.OpPsaSignHash)).To(Equal(1))
+	})
+
+	It("Should sign messages with keys that cannot sign hashes", func() {
+		attributes := parsec.DefaultKeyAttribute().EcdsaSigningKey()
+		attributes.KeyPolicy.KeyUsageFlags = &parsec.UsageFlags{SignMessage: true}
+		generate("message", attributes)
+		pub := publicKey(client, "message")
+		signature, err := client.Sign(pub, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Verify(data, signature)).To(Succeed())
+		Expect(service.Count(requests.OpPsaSignMessage)).To(Equal(1))
+	})
+
+	It("Should import added keys and destroy only its own keys", func() {
+		ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Add(agent.AddedKey{PrivateKey: ecdsaKey, Comment: "me@host key"})).To(Succeed())
+		Expect(client.Add(agent.AddedKey{PrivateKey: testRSAKey()})).To(Succeed())
+		generate("other", parsec.DefaultKeyAttribute().EcdsaSigningKey())
+
+		ecdsaPub, err := ssh.NewPublicKey(&ecdsaKey.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publicKey(client, "ssh-me@host-key").Marshal()).To(Equal(ecdsaPub.Marshal()))
+		Expect(listed(client)).To(HaveLen(3))
+		signature, err := client.Sign(ecdsaPub, data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ecdsaPub.Verify(data, signature)).To(Succeed())
+		rsaPub, err := ssh.NewPublicKey(&testRSAKey().PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		signature, err = client.SignWithFlags(rsaPub, data, agent.SignatureFlagRsaSha512)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rsaPub.Verify(data, signature)).To(Succeed())
+
+		Expect(a.Remove(publicKey(client, "other"))).To(MatchError(ContainSubstring("not created by the agent")))
+		Expect(client.Remove(ecdsaPub)).To(Succeed())
+		Expect(listed(client)).To(HaveLen(2))
+		Expect(client.RemoveAll()).To(Succeed())
+		Expect(provider.KeyNames()).To(ConsistOf("other"))
+	})
+
+	It("Should refuse keys with constraints", func() {
+		ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a.Add(agent.AddedKey{PrivateKey: ecdsaKey, LifetimeSecs: 60})).To(MatchError(ContainSubstring("lifetimes")))
+		Expect(a.Add(agent.AddedKey{PrivateKey: ecdsaKey, ConfirmBeforeUse: true})).To(MatchError(ContainSubstring("confirmation")))
+		Expect(provider.KeyNames()).To(BeEmpty())
+	})
+
+	It("Should generate keys", func() {
+		pub, err := a.Generate("laptop", parsec.DefaultKeyAttribute().EcdsaSigningKey(parsec.WithKeyBits(521)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pub.Type()).To(Equal(ssh.KeyAlgoECDSA521))
+		Expect(publicKey(client, "ssh-laptop").Marshal()).To(Equal(pub.Marshal()))
+
+		_, err = a.Generate("aes", parsec.DefaultKeyAttribute().AesGcmKey())
+		Expect(err).To(HaveOccurred())
+		Expect(provider.KeyNames()).To(ConsistOf("ssh-laptop"))
+	})
+
+	It("Should hide its keys while locked", func() {
+		generate("ecdsa", parsec.DefaultKeyAttribute().EcdsaSigningKey())
+		pub := publicKey(client, "ecdsa")
+		Expect(client.Lock([]byte("secret"))).To(Succeed())
+		Expect(listed(client)).To(BeEmpty())
+		_, err := client.Sign(pub, data)
+		Expect(err).To(HaveOccurred())
+		Expect(client.Unlock([]byte("wrong"))).NotTo(Succeed())
+		Expect(client.Unlock([]byte("secret"))).To(Succeed())
+		Expect(listed(client)).To(ConsistOf("ecdsa"))
+	})
+
+	It("Should provide signers for SSH clients", func() {
+		generate("rsa", rsaAttributes(algorithm.NewAsymmetricSignature().RsaPkcs1V15SignAny()))
+		signers, err := a.Signers()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signers).To(HaveLen(1))
+		algorithmSigner, ok := signers[0].(ssh.AlgorithmSigner)
+		Expect(ok).To(BeTrue())
+		signature, err := algorithmSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoRSASHA256)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature.Format).To(Equal(ssh.KeyAlgoRSASHA256))
+		Expect(signers[0].PublicKey().Verify(data, signature)).To(Succeed())
+		_, err = algorithmSigner.SignWithAlgorithm(rand.Reader, data, ssh.KeyAlgoED25519)
+		Expect(err).To(HaveOccurred())
+	})
+})
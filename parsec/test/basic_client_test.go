@@ -3,16 +3,22 @@
 package test
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"testing"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/parallaxsecond/parsec-client-go/interface/connection"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
 	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
 )
 
 // loadTestData loads a list of test case json files and parses them into a map of TestCase objects, keyed by the testcase name.
@@ -159,6 +165,70 @@ var _ = Describe("Basic Client provider behaviour", func() {
 			})
 		})
 	})
+	Describe("Interceptors in client config", func() {
+		It("Should see the operations used to configure the client", func() {
+			var opcodes []requests.OpCode
+			recorder := func(op *operations.Operation, next operations.Invoker) error {
+				opcodes = append(opcodes, op.OpCode)
+				return next(op)
+			}
+			bc, err := parsec.CreateConfiguredClient(parsec.DirectAuthConfigData("testapp").Connection(connection).Interceptors(recorder))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bc).NotTo(BeNil())
+			Expect(opcodes).To(Equal([]requests.OpCode{requests.OpListProviders, requests.OpListAuthenticators}))
+		})
+	})
+	Describe("Logger in client config", func() {
+		It("Should trace operations at debug level", func() {
+			logged := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			bc, err := parsec.CreateConfiguredClient(parsec.DirectAuthConfigData("testapp").Connection(connection).Logger(logger))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bc).NotTo(BeNil())
+			Expect(logged.String()).To(ContainSubstring("level=DEBUG msg=\"parsec operation\" opcode=ListProviders provider=Core"))
+			Expect(logged.String()).To(ContainSubstring("opcode=ListAuthenticators"))
+		})
+		It("Should not log above debug level", func() {
+			logged := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(logged, nil))
+			_, err := parsec.CreateConfiguredClient(parsec.DirectAuthConfigData("testapp").Connection(connection).Logger(logger))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logged.Len()).To(BeZero())
+		})
+	})
+	Describe("Invalid key attributes", func() {
+		It("Should return an error without calling the parsec service", func() {
+			config := parsec.NewClientConfig().
+				Provider(parsec.ProviderTPM).
+				Authenticator(parsec.NewUnixPeerAuthenticator()).
+				Connection(newNoopConnection())
+			bc, err := parsec.CreateConfiguredClient(config)
+			Expect(err).NotTo(HaveOccurred())
+			err = bc.PsaGenerateKey("key", &parsec.KeyAttributes{KeyType: parsec.NewKeyType().RsaKeyPair(), KeyBits: 2048})
+			Expect(err).To(MatchError("invalid key attributes: KeyPolicy: must be set"))
+			err = bc.PsaGenerateKey("key", &parsec.KeyAttributes{KeyBits: 2048})
+			Expect(err).To(MatchError(parsec.ErrInvalidKeyAttributes))
+			Expect(err.Error()).To(ContainSubstring("KeyType: must be set"))
+		})
+	})
+	Describe("MAC operations", func() {
+		It("Should send MAC verification as PsaMacVerify", func() {
+			service := parsectest.NewProvider().Service()
+			bc, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+				Provider(parsec.ProviderMBed).
+				Authenticator(parsec.NewDirectAuthenticator("app")).
+				Connection(service))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bc.PsaGenerateKey("hmac", parsec.DefaultKeyAttribute().HmacKey())).To(Succeed())
+			alg := algorithm.NewMAC().HMAC(algorithm.HashAlgorithmTypeSHA256).GetMac()
+			mac, err := bc.PsaMACCompute("hmac", alg, []byte("message"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bc.PsaMACVerify("hmac", alg, []byte("message"), mac)).To(Succeed())
+			Expect(bc.PsaMACVerify("hmac", alg, []byte("massage"), mac)).NotTo(Succeed())
+			Expect(service.Count(requests.OpPsaMacCompute)).To(Equal(1))
+			Expect(service.Count(requests.OpPsaMacVerify)).To(Equal(2))
+		})
+	})
 	Describe("Test naked creation", func() {
 		It("Should be configured with core provider and no auth authenticator", func() {
 			bc, err := parsec.CreateNakedClient()
@@ -0,0 +1,243 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/auth"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listkeys"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaalgorithm"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneraterandom"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"google.golang.org/protobuf/proto"
+)
+
+// Implements the Connection interface over a pipe to a service that never responds.
+type unresponsiveConnection struct {
+	net.Conn
+	// opened, if not nil, is closed when the connection is first opened
+	opened chan struct{}
+}
+
+func (c *unresponsiveConnection) Open() error {
+	client, service := net.Pipe()
+	go func() {
+		_, _ = io.Copy(io.Discard, service)
+	}()
+	c.Conn = client
+	if c.opened != nil {
+		close(c.opened)
+		c.opened = nil
+	}
+	return nil
+}
+
+var _ = Describe("Call options", func() {
+	var service *parsectest.Service
+	var bc *parsec.BasicClient
+
+	BeforeEach(func() {
+		service = parsectest.NewService().
+			Handle(requests.OpListOpcodes, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &listopcodes.Result{Opcodes: []uint32{uint32(requests.OpPsaGenerateRandom)}}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaGenerateRandom, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &psageneraterandom.Result{RandomBytes: []byte{byte(provider)}}, requests.StatusSuccess
+			})
+		var err error
+		bc, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("gateway")).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should use the client configuration by default", func() {
+		random, err := bc.PsaGenerateRandom(1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(random).To(Equal([]byte{byte(parsec.ProviderMBed)}))
+		last := service.Received()[len(service.Received())-1]
+		Expect(last.AuthType).To(Equal(auth.AuthDirect))
+		Expect(string(last.Auth)).To(Equal("gateway"))
+	})
+	It("Should override the provider and authenticator for one call", func() {
+		random, err := bc.PsaGenerateRandom(1, parsec.WithProvider(parsec.ProviderTPM), parsec.WithAuthenticator(parsec.NewDirectAuthenticator("app1")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(random).To(Equal([]byte{byte(parsec.ProviderTPM)}))
+		for _, r := range service.Received() {
+			Expect(string(r.Auth)).To(Equal("app1"))
+		}
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderMBed))
+	})
+	It("Should serve several identities concurrently", func() {
+		wg := sync.WaitGroup{}
+		for _, app := range []string{"app1", "app2", "app3", "app4"} {
+			wg.Add(1)
+			go func(app string) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := bc.PsaGenerateRandom(1, parsec.WithAuthenticator(parsec.NewDirectAuthenticator(app)))
+				Expect(err).NotTo(HaveOccurred())
+			}(app)
+		}
+		wg.Wait()
+		Expect(service.Count(requests.OpPsaGenerateRandom)).To(Equal(4))
+	})
+	It("Should time out if the service does not respond", func() {
+		unresponsive, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewNoAuthAuthenticator()).
+			Connection(&unresponsiveConnection{}))
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = unresponsive.Ping(parsec.WithTimeout(10 * time.Millisecond))
+		Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+	})
+	It("Should time out waiting for the connection to be free", func() {
+		opened := make(chan struct{})
+		unresponsive, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewNoAuthAuthenticator()).
+			Connection(&unresponsiveConnection{opened: opened}))
+		Expect(err).NotTo(HaveOccurred())
+		hung := make(chan error)
+		go func() {
+			_, _, err := unresponsive.Ping()
+			hung <- err
+		}()
+		<-opened
+
+		start := time.Now()
+		_, _, err = unresponsive.Ping(parsec.WithTimeout(10 * time.Millisecond))
+		Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+
+		// Closing the connection ends the hung operation
+		Expect(unresponsive.Close()).To(Succeed())
+		Eventually(hung).Should(Receive(HaveOccurred()))
+	})
+	It("Should run operations concurrently when the connection can be cloned", func() {
+		// Each operation waits for the other to arrive, so would time out if they were sent one at a time
+		arrived := sync.WaitGroup{}
+		arrived.Add(2)
+		both := make(chan struct{})
+		go func() {
+			arrived.Wait()
+			close(both)
+		}()
+		service.Handle(requests.OpPsaGenerateRandom, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+			arrived.Done()
+			select {
+			case <-both:
+				return &psageneraterandom.Result{RandomBytes: []byte{1}}, requests.StatusSuccess
+			case <-time.After(5 * time.Second):
+				return nil, requests.StatusPsaErrorGenericError
+			}
+		})
+		wg := sync.WaitGroup{}
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := bc.PsaGenerateRandom(1)
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+	})
+	Context("Served slowly over a socket", func() {
+		var dir string
+		var listener net.Listener
+		var slow *parsec.BasicClient
+		hash := make([]byte, 32)
+		ecdsaAlg := algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA256).GetAsymmetricSignature()
+
+		BeforeEach(func() {
+			// Each request takes 60ms, so the three requests made to sign take longer than the 100ms timeout
+			delay := func(h parsectest.Handler) parsectest.Handler {
+				return func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+					time.Sleep(60 * time.Millisecond)
+					return h(provider, body)
+				}
+			}
+			service.
+				Handle(requests.OpListOpcodes, delay(func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+					return &listopcodes.Result{Opcodes: []uint32{uint32(requests.OpPsaSignHash)}}, requests.StatusSuccess
+				})).
+				Handle(requests.OpListKeys, delay(func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+					return &listkeys.Result{Keys: []*listkeys.KeyInfo{{
+						ProviderId: uint32(requests.ProviderMBed),
+						Name:       "ecckey",
+						Attributes: &psakeyattributes.KeyAttributes{
+							KeyType: &psakeyattributes.KeyType{Variant: &psakeyattributes.KeyType_EccKeyPair_{
+								EccKeyPair: &psakeyattributes.KeyType_EccKeyPair{CurveFamily: psakeyattributes.KeyType_SECP_R1},
+							}},
+							KeyBits: 256,
+							KeyPolicy: &psakeyattributes.KeyPolicy{
+								KeyAlgorithm:  ecdsaAlg.ToWireInterface().(*psaalgorithm.Algorithm),
+								KeyUsageFlags: &psakeyattributes.UsageFlags{SignHash: true},
+							},
+						},
+					}}}, requests.StatusSuccess
+				})).
+				Handle(requests.OpPsaSignHash, delay(func(requests.ProviderID, []byte) (proto.Message, requests.StatusCode) {
+					return &psasignhash.Result{Signature: bytes.Repeat([]byte{1}, 64)}, requests.StatusSuccess
+				}))
+			var err error
+			dir, err = os.MkdirTemp("", "parsec-timeout")
+			Expect(err).NotTo(HaveOccurred())
+			listener, err = net.Listen("unix", filepath.Join(dir, "parsec.sock"))
+			Expect(err).NotTo(HaveOccurred())
+			go func(l net.Listener, s *parsectest.Service) {
+				_ = s.Serve(l)
+			}(listener, service)
+			Expect(os.Setenv("PARSEC_SERVICE_ENDPOINT", "unix:"+filepath.Join(dir, "parsec.sock"))).To(Succeed())
+			slow, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+				Provider(parsec.ProviderMBed).
+				Authenticator(parsec.NewDirectAuthenticator("app")))
+			Expect(err).NotTo(HaveOccurred())
+		})
+		AfterEach(func() {
+			Expect(slow.Close()).To(Succeed())
+			Expect(listener.Close()).To(Succeed())
+			Expect(os.Unsetenv("PARSEC_SERVICE_ENDPOINT")).To(Succeed())
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("Should bound the capability and key lookups and the operation by one timeout", func() {
+			start := time.Now()
+			_, err := slow.PsaSignHash("ecckey", hash, ecdsaAlg, parsec.WithDERSignature(), parsec.WithTimeout(100*time.Millisecond))
+			Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue(), "%v", err)
+			Expect(time.Since(start)).To(BeNumerically("<", 160*time.Millisecond))
+			Expect(service.Count(requests.OpPsaSignHash)).To(BeZero())
+		})
+		It("Should succeed when the timeout is long enough for every request", func() {
+			_, err := slow.PsaSignHash("ecckey", hash, ecdsaAlg, parsec.WithDERSignature(), parsec.WithTimeout(time.Second))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(service.Count(requests.OpListOpcodes)).To(Equal(1))
+			Expect(service.Count(requests.OpListKeys)).To(Equal(1))
+		})
+	})
+	It("Should allow the implicit provider of a zero value client to be set", func() {
+		var zero parsec.BasicClient
+		Expect(zero.GetImplicitProvider()).To(Equal(parsec.ProviderCore))
+		zero.SetImplicitProvider(parsec.ProviderTPM)
+		Expect(zero.GetImplicitProvider()).To(Equal(parsec.ProviderTPM))
+	})
+})
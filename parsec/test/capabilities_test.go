@@ -0,0 +1,116 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneraterandom"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = Describe("Provider capabilities", func() {
+	var service *parsectest.Service
+	var bc *parsec.BasicClient
+	listOpcodesStatus := requests.StatusSuccess
+
+	BeforeEach(func() {
+		listOpcodesStatus = requests.StatusSuccess
+		service = parsectest.NewService().
+			Handle(requests.OpListOpcodes, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &listopcodes.Result{
+					Opcodes: []uint32{uint32(requests.OpPsaGenerateRandom), uint32(requests.OpPsaSignHash)},
+				}, listOpcodesStatus
+			}).
+			Handle(requests.OpPsaGenerateRandom, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				op := &psageneraterandom.Operation{}
+				Expect(proto.Unmarshal(body, op)).To(Succeed())
+				return &psageneraterandom.Result{RandomBytes: make([]byte, op.Size)}, requests.StatusSuccess
+			})
+		var err error
+		bc, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderTPM).
+			Authenticator(parsec.NewNoAuthAuthenticator()).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should list and cache supported operations", func() {
+		ops, err := bc.Capabilities(parsec.ProviderTPM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ops.List()).To(Equal([]parsec.OpCode{parsec.OpPsaSignHash, parsec.OpPsaGenerateRandom}))
+		Expect(bc.Supports(parsec.OpPsaSignHash)).To(BeTrue())
+		Expect(bc.Supports(parsec.OpPsaAeadEncrypt)).To(BeFalse())
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(1))
+
+		bc.RefreshCapabilities()
+		Expect(bc.Supports(parsec.OpPsaSignHash)).To(BeTrue())
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(2))
+	})
+	It("Should send supported operations", func() {
+		random, err := bc.PsaGenerateRandom(8)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(random).To(HaveLen(8))
+	})
+	It("Should refuse unsupported operations without calling the parsec service", func() {
+		_, err := bc.PsaAeadEncrypt("key", algorithm.NewAead().Aead(algorithm.AeadAlgorithmGCM).GetAead(), nil, nil, []byte("hello"))
+		Expect(errors.Is(err, parsec.ErrOperationNotSupported)).To(BeTrue())
+		var notSupported *parsec.OperationNotSupportedError
+		Expect(errors.As(err, &notSupported)).To(BeTrue())
+		Expect(notSupported.Op).To(Equal(parsec.OpPsaAeadEncrypt))
+		Expect(notSupported.Provider).To(Equal(parsec.ProviderTPM))
+		Expect(service.Count(requests.OpPsaAeadEncrypt)).To(BeZero())
+	})
+	It("Should send operations if capabilities cannot be retrieved", func() {
+		listOpcodesStatus = requests.StatusWrongProviderID
+		Expect(bc.Supports(parsec.OpPsaGenerateRandom)).To(BeFalse())
+		random, err := bc.PsaGenerateRandom(4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(random).To(HaveLen(4))
+	})
+	It("Should remember failures to retrieve capabilities until refreshed", func() {
+		listOpcodesStatus = requests.StatusWrongProviderID
+		for i := 0; i < 3; i++ {
+			_, err := bc.PsaGenerateRandom(4)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := bc.Capabilities(parsec.ProviderTPM)
+		Expect(err).To(HaveOccurred())
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(1))
+
+		listOpcodesStatus = requests.StatusSuccess
+		bc.RefreshCapabilities()
+		Expect(bc.Supports(parsec.OpPsaGenerateRandom)).To(BeTrue())
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(2))
+	})
+	It("Should retry retrieving capabilities after the retry interval", func() {
+		var err error
+		bc, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderTPM).
+			Authenticator(parsec.NewNoAuthAuthenticator()).
+			CapabilityRetryInterval(50 * time.Millisecond).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+		listOpcodesStatus = requests.StatusWrongProviderID
+		Expect(bc.Supports(parsec.OpPsaGenerateRandom)).To(BeFalse())
+		Expect(bc.Supports(parsec.OpPsaGenerateRandom)).To(BeFalse())
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(1))
+
+		listOpcodesStatus = requests.StatusSuccess
+		Eventually(func() bool { return bc.Supports(parsec.OpPsaGenerateRandom) }).Should(BeTrue())
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(2))
+		// Capabilities retrieved after a failure are cached as usual
+		Consistently(func() int {
+			bc.Supports(parsec.OpPsaGenerateRandom)
+			return service.Count(requests.OpListOpcodes)
+		}, 100*time.Millisecond).Should(Equal(2))
+	})
+})
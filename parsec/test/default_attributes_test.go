@@ -0,0 +1,69 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+var _ = Describe("DefaultKeyAttribute", func() {
+	presets := parsec.DefaultKeyAttribute()
+
+	DescribeTable("Presets should be valid keys to generate",
+		func(ka *parsec.KeyAttributes, bits int, alg string) {
+			Expect(ka.Validate()).To(Succeed())
+			Expect(ka.KeyBits).To(BeEquivalentTo(bits))
+			Expect(ka.KeyPolicy.KeyAlgorithm.String()).To(Equal(alg))
+			Expect(ka.KeyPolicy.KeyUsageFlags.Export).To(BeFalse())
+		},
+		Entry("RSA PKCS#1 v1.5 signing", presets.SigningKey(), 2048, "PSA_ALG_RSA_PKCS1V15_SIGN(PSA_ALG_SHA_256)"),
+		Entry("RSA PSS signing", presets.RsaPssSigningKey(), 2048, "PSA_ALG_RSA_PSS(PSA_ALG_SHA_256)"),
+		Entry("ECDSA P-256 signing", presets.EcdsaSigningKey(), 256, "PSA_ALG_ECDSA(PSA_ALG_SHA_256)"),
+		Entry("ECDSA P-384 signing", presets.EcdsaSigningKey(parsec.WithKeyBits(384)), 384, "PSA_ALG_ECDSA(PSA_ALG_SHA_384)"),
+		Entry("ECDSA P-521 signing", presets.EcdsaSigningKey(parsec.WithKeyBits(521)), 521, "PSA_ALG_ECDSA(PSA_ALG_SHA_512)"),
+		Entry("RSA OAEP encryption", presets.RsaOaepEncryptionKey(), 2048, "PSA_ALG_RSA_OAEP(PSA_ALG_SHA_256)"),
+		Entry("AES-256-GCM", presets.AesGcmKey(), 256, "PSA_ALG_GCM"),
+		Entry("AES-128-GCM", presets.AesGcmKey(parsec.WithKeyBits(128)), 128, "PSA_ALG_GCM"),
+		Entry("ChaCha20-Poly1305", presets.Chacha20Poly1305Key(), 256, "PSA_ALG_CHACHA20_POLY1305"),
+		Entry("HMAC-SHA-256", presets.HmacKey(), 256, "PSA_ALG_HMAC(PSA_ALG_SHA_256)"),
+		Entry("HMAC-SHA-512", presets.HmacKey(parsec.WithHash(algorithm.HashAlgorithmTypeSHA512)), 512, "PSA_ALG_HMAC(PSA_ALG_SHA_512)"),
+		Entry("CMAC", presets.CmacKey(), 256, "PSA_ALG_CMAC"),
+		Entry("ECDH secp256r1", presets.EcdhKey(), 256, "PSA_ALG_ECDH"),
+		Entry("X25519", presets.X25519Key(), 255, "PSA_ALG_ECDH"),
+		Entry("FFDH", presets.FfdhKey(), 2048, "PSA_ALG_FFDH"),
+	)
+
+	It("Should keep the original signing key", func() {
+		Expect(presets.SigningKey()).To(Equal(&parsec.KeyAttributes{
+			KeyBits: 2048,
+			KeyType: parsec.NewKeyType().RsaKeyPair(),
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyAlgorithm: algorithm.NewAsymmetricSignature().RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256),
+				KeyUsageFlags: &parsec.UsageFlags{
+					SignHash:      true,
+					SignMessage:   true,
+					VerifyHash:    true,
+					VerifyMessage: true,
+				},
+			},
+		}))
+	})
+
+	It("Should apply options in order", func() {
+		ka := presets.RsaPssSigningKey(parsec.WithExport(), parsec.WithKeyBits(4096),
+			parsec.WithHash(algorithm.HashAlgorithmTypeSHA256), parsec.WithHash(algorithm.HashAlgorithmTypeSHA384))
+		Expect(ka.KeyBits).To(BeEquivalentTo(4096))
+		Expect(ka.KeyPolicy.KeyUsageFlags.Export).To(BeTrue())
+		Expect(ka.KeyPolicy.KeyAlgorithm).To(Equal(algorithm.NewAsymmetricSignature().RsaPss(algorithm.HashAlgorithmTypeSHA384)))
+	})
+
+	It("Should not share usage flags between keys", func() {
+		first := presets.AesGcmKey()
+		first.KeyPolicy.KeyUsageFlags.Decrypt = false
+		Expect(presets.AesGcmKey().KeyPolicy.KeyUsageFlags.Decrypt).To(BeTrue())
+	})
+})
@@ -0,0 +1,228 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listkeys"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignmessage"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaverifyhash"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"google.golang.org/protobuf/proto"
+)
+
+// rawECDSA signs hash with key, returning r followed by s as PSA does.
+func rawECDSA(key *ecdsa.PrivateKey, hash []byte) []byte {
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash)
+	Expect(err).NotTo(HaveOccurred())
+	size := (key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	return sig
+}
+
+var _ = Describe("ECDSA signature encoding", func() {
+	hash := sha256.Sum256([]byte("message"))
+
+	Describe("conversion", func() {
+		It("Should size signatures by curve", func() {
+			for _, c := range []struct {
+				family parsec.EccFamily
+				bits   uint32
+				size   int
+			}{
+				{parsec.KeyTypeSECPR1, 256, 64},
+				{parsec.KeyTypeSECPR1, 384, 96},
+				{parsec.KeyTypeSECPR1, 521, 132},
+				{parsec.KeyTypeSECPK1, 256, 64},
+				{parsec.KeyTypeBRAINPOOLPR1, 320, 80},
+				{parsec.KeyTypeSECTK1, 233, 60},
+			} {
+				size, err := parsec.EcdsaSignatureSize(c.family, c.bits)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(size).To(Equal(c.size))
+			}
+			_, err := parsec.EcdsaSignatureSize(parsec.KeyTypeSECPR1, 255)
+			Expect(err).To(MatchError("no 255 bit curve in ECC family SECP_R1"))
+			_, err = parsec.EcdsaSignatureSize(parsec.KeyTypeMONTGOMERY, 255)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Should convert between PSA and DER signatures", func() {
+			for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+				key, err := ecdsa.GenerateKey(curve, rand.Reader)
+				Expect(err).NotTo(HaveOccurred())
+				bits := uint32(curve.Params().BitSize)
+				raw := rawECDSA(key, hash[:])
+				der, err := parsec.EcdsaSignatureToDER(parsec.KeyTypeSECPR1, bits, raw)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ecdsa.VerifyASN1(&key.PublicKey, hash[:], der)).To(BeTrue())
+				back, err := parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, bits, der)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(back).To(Equal(raw))
+
+				der, err = ecdsa.SignASN1(rand.Reader, key, hash[:])
+				Expect(err).NotTo(HaveOccurred())
+				raw, err = parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, bits, der)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(raw).To(HaveLen(2 * ((int(bits) + 7) / 8)))
+				Expect(parsec.EcdsaSignatureToDER(parsec.KeyTypeSECPR1, bits, raw)).To(Equal(der))
+			}
+		})
+		It("Should keep leading zeros in PSA signatures", func() {
+			raw := make([]byte, 64)
+			raw[31], raw[63] = 1, 2
+			der, err := parsec.EcdsaSignatureToDER(parsec.KeyTypeSECPR1, 256, raw)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(der).To(Equal([]byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02}))
+			back, err := parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, 256, der)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(back).To(Equal(raw))
+		})
+		It("Should reject signatures that do not fit the curve", func() {
+			_, err := parsec.EcdsaSignatureToDER(parsec.KeyTypeSECPR1, 256, make([]byte, 96))
+			Expect(err).To(MatchError("96 byte ECDSA signature, expected 64 bytes for a 256 bit key"))
+			key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+			Expect(err).NotTo(HaveOccurred())
+			_, err = parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, 256, der)
+			Expect(err).To(HaveOccurred())
+			_, err = parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, 384, append(der, 0))
+			Expect(err).To(MatchError(ContainSubstring("trailing data")))
+			_, err = parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, 384, der[:len(der)-1])
+			Expect(err).To(HaveOccurred())
+			_, err = parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, 256, []byte{0x30, 0x06, 0x02, 0x01, 0x00, 0x02, 0x01, 0x02})
+			Expect(err).To(MatchError(ContainSubstring("out of range")))
+		})
+	})
+
+	Describe("client option", func() {
+		var service *parsectest.Service
+		var bc *parsec.BasicClient
+		var key *ecdsa.PrivateKey
+		var verified *psaverifyhash.Operation
+		var attributes *psageneratekey.Operation
+
+		eccKeyAttrs := &parsec.KeyAttributes{
+			KeyType: parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1),
+			KeyBits: 384,
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyAlgorithm:  algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA256),
+				KeyUsageFlags: &parsec.UsageFlags{SignHash: true, VerifyHash: true, SignMessage: true},
+			},
+		}
+		ecdsaAlg := algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA256).GetAsymmetricSignature()
+
+		BeforeEach(func() {
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			verified, attributes = nil, nil
+			service = parsectest.NewService().
+				Handle(requests.OpPsaGenerateKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+					attributes = &psageneratekey.Operation{}
+					Expect(proto.Unmarshal(body, attributes)).To(Succeed())
+					return &psageneratekey.Result{}, requests.StatusSuccess
+				}).
+				Handle(requests.OpListKeys, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+					return &listkeys.Result{Keys: []*listkeys.KeyInfo{{
+						ProviderId: uint32(requests.ProviderMBed),
+						Name:       "ecckey",
+						Attributes: attributes.GetAttributes(),
+					}}}, requests.StatusSuccess
+				}).
+				Handle(requests.OpPsaSignHash, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+					op := &psasignhash.Operation{}
+					Expect(proto.Unmarshal(body, op)).To(Succeed())
+					return &psasignhash.Result{Signature: rawECDSA(key, op.Hash)}, requests.StatusSuccess
+				}).
+				Handle(requests.OpPsaSignMessage, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+					op := &psasignmessage.Operation{}
+					Expect(proto.Unmarshal(body, op)).To(Succeed())
+					h := sha256.Sum256(op.Message)
+					return &psasignmessage.Result{Signature: rawECDSA(key, h[:])}, requests.StatusSuccess
+				}).
+				Handle(requests.OpPsaVerifyHash, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+					verified = &psaverifyhash.Operation{}
+					Expect(proto.Unmarshal(body, verified)).To(Succeed())
+					return &psaverifyhash.Result{}, requests.StatusSuccess
+				})
+			bc, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+				Provider(parsec.ProviderMBed).
+				Authenticator(parsec.NewDirectAuthenticator("app")).
+				Connection(service))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("Should return PSA signatures by default", func() {
+			Expect(bc.PsaGenerateKey("ecckey", eccKeyAttrs)).To(Succeed())
+			sig, err := bc.PsaSignHash("ecckey", hash[:], ecdsaAlg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sig).To(HaveLen(96))
+			Expect(service.Count(requests.OpListKeys)).To(BeZero())
+		})
+		It("Should produce and accept DER signatures on request", func() {
+			Expect(bc.PsaGenerateKey("ecckey", eccKeyAttrs)).To(Succeed())
+			der, err := bc.PsaSignHash("ecckey", hash[:], ecdsaAlg, parsec.WithDERSignature())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ecdsa.VerifyASN1(&key.PublicKey, hash[:], der)).To(BeTrue())
+			Expect(service.Count(requests.OpListKeys)).To(Equal(1))
+			received := service.Received()
+			Expect(received[len(received)-2].OpCode).To(Equal(requests.OpListKeys))
+			Expect(received[len(received)-1].OpCode).To(Equal(requests.OpPsaSignHash))
+
+			der, err = bc.PsaSignMessage("ecckey", []byte("message"), ecdsaAlg, parsec.WithDERSignature())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ecdsa.VerifyASN1(&key.PublicKey, hash[:], der)).To(BeTrue())
+
+			Expect(bc.PsaVerifyHash("ecckey", hash[:], der, ecdsaAlg, parsec.WithDERSignature())).To(Succeed())
+			raw, err := parsec.EcdsaSignatureFromDER(parsec.KeyTypeSECPR1, 384, der)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verified.Signature).To(Equal(raw))
+		})
+		It("Should use the attributes of a Key without looking them up", func() {
+			k, err := bc.GenerateKey("ecckey", eccKeyAttrs)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := k.SignHash(hash[:], parsec.WithDERSignature())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ecdsa.VerifyASN1(&key.PublicKey, hash[:], der)).To(BeTrue())
+			Expect(k.VerifyHash(hash[:], der, parsec.WithDERSignature())).To(Succeed())
+			Expect(service.Count(requests.OpListKeys)).To(BeZero())
+		})
+		It("Should leave other signatures unchanged", func() {
+			service.Handle(requests.OpPsaSignHash, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &psasignhash.Result{Signature: []byte("rsa signature")}, requests.StatusSuccess
+			})
+			rsaAlg := algorithm.NewAsymmetricSignature().RsaPss(algorithm.HashAlgorithmTypeSHA256).GetAsymmetricSignature()
+			sig, err := bc.PsaSignHash("rsakey", hash[:], rsaAlg, parsec.WithDERSignature())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sig).To(Equal([]byte("rsa signature")))
+			Expect(service.Count(requests.OpListKeys)).To(BeZero())
+		})
+		It("Should fail if the key cannot be found", func() {
+			Expect(bc.PsaGenerateKey("ecckey", eccKeyAttrs)).To(Succeed())
+			_, err := bc.PsaSignHash("otherkey", hash[:], ecdsaAlg, parsec.WithDERSignature())
+			Expect(errors.Is(err, parsec.ErrKeyNotFound)).To(BeTrue())
+			_, err = bc.PsaSignMessage("otherkey", []byte("message"), ecdsaAlg, parsec.WithDERSignature())
+			Expect(errors.Is(err, parsec.ErrKeyNotFound)).To(BeTrue())
+			Expect(service.Count(requests.OpPsaSignHash)).To(BeZero())
+			Expect(service.Count(requests.OpPsaSignMessage)).To(BeZero())
+			err = bc.PsaVerifyHash("ecckey", hash[:], []byte("not DER"), ecdsaAlg, parsec.WithDERSignature())
+			Expect(err).To(MatchError(ContainSubstring("invalid DER ECDSA signature")))
+			Expect(service.Count(requests.OpPsaVerifyHash)).To(BeZero())
+		})
+	})
+})
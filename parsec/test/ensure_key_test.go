@@ -0,0 +1,107 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listkeys"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psaalgorithm"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = Describe("EnsureKey", func() {
+	var service *parsectest.Service
+	var bc *parsec.BasicClient
+	// keys held by the fake service, and keys created by another client after the next ListKeys.
+	var stored, racing []*listkeys.KeyInfo
+
+	BeforeEach(func() {
+		stored, racing = nil, nil
+		service = parsectest.NewService().
+			Handle(requests.OpListKeys, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				result := &listkeys.Result{Keys: stored}
+				stored = append(stored, racing...)
+				racing = nil
+				return result, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaGenerateKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				op := &psageneratekey.Operation{}
+				Expect(proto.Unmarshal(body, op)).To(Succeed())
+				for _, k := range stored {
+					if k.Name == op.KeyName && k.ProviderId == uint32(provider) {
+						return &psageneratekey.Result{}, requests.StatusPsaErrorAlreadyExists
+					}
+				}
+				stored = append(stored, &listkeys.KeyInfo{ProviderId: uint32(provider), Name: op.KeyName, Attributes: op.Attributes})
+				return &psageneratekey.Result{}, requests.StatusSuccess
+			})
+		var err error
+		bc, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("app")).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	wireAttrs := func(keyBits uint32, signHash bool) *psakeyattributes.KeyAttributes {
+		return &psakeyattributes.KeyAttributes{
+			KeyType: &psakeyattributes.KeyType{Variant: &psakeyattributes.KeyType_RsaKeyPair_{RsaKeyPair: &psakeyattributes.KeyType_RsaKeyPair{}}},
+			KeyBits: keyBits,
+			KeyPolicy: &psakeyattributes.KeyPolicy{
+				KeyUsageFlags: &psakeyattributes.UsageFlags{SignHash: signHash, SignMessage: true, VerifyHash: true, VerifyMessage: true},
+				KeyAlgorithm:  algorithm.NewAsymmetricSignature().RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256).ToWireInterface().(*psaalgorithm.Algorithm),
+			},
+		}
+	}
+
+	It("Should generate the key if it does not exist", func() {
+		key, err := bc.EnsureKey("signer", parsec.DefaultKeyAttribute().SigningKey())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Name()).To(Equal("signer"))
+		Expect(service.Count(requests.OpPsaGenerateKey)).To(Equal(1))
+	})
+	It("Should return the existing key if it matches", func() {
+		stored = []*listkeys.KeyInfo{{ProviderId: uint32(requests.ProviderMBed), Name: "signer", Attributes: wireAttrs(2048, true)}}
+		key, err := bc.EnsureKey("signer", parsec.DefaultKeyAttribute().SigningKey())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Attributes()).To(Equal(parsec.DefaultKeyAttribute().SigningKey()))
+		Expect(service.Count(requests.OpPsaGenerateKey)).To(BeZero())
+	})
+	It("Should describe how an existing key differs", func() {
+		stored = []*listkeys.KeyInfo{{ProviderId: uint32(requests.ProviderMBed), Name: "signer", Attributes: wireAttrs(1024, false)}}
+		_, err := bc.EnsureKey("signer", parsec.DefaultKeyAttribute().SigningKey())
+		Expect(errors.Is(err, parsec.ErrKeyMismatch)).To(BeTrue())
+		var mismatch *parsec.KeyMismatchError
+		Expect(errors.As(err, &mismatch)).To(BeTrue())
+		Expect(mismatch.Differences).To(Equal([]string{
+			"key bits is 1024, want 2048",
+			"usage flag sign_hash is false, want true",
+		}))
+	})
+	It("Should ignore keys held by other providers", func() {
+		stored = []*listkeys.KeyInfo{{ProviderId: uint32(requests.ProviderTPM), Name: "signer", Attributes: wireAttrs(1024, false)}}
+		_, err := bc.EnsureKey("signer", parsec.DefaultKeyAttribute().SigningKey())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(service.Count(requests.OpPsaGenerateKey)).To(Equal(1))
+	})
+	It("Should check the key created by another client first", func() {
+		racing = []*listkeys.KeyInfo{{ProviderId: uint32(requests.ProviderMBed), Name: "signer", Attributes: wireAttrs(2048, true)}}
+		key, err := bc.EnsureKey("signer", parsec.DefaultKeyAttribute().SigningKey())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Name()).To(Equal("signer"))
+		Expect(service.Count(requests.OpListKeys)).To(Equal(2))
+
+		racing = []*listkeys.KeyInfo{{ProviderId: uint32(requests.ProviderMBed), Name: "other", Attributes: wireAttrs(1024, true)}}
+		_, err = bc.EnsureKey("other", parsec.DefaultKeyAttribute().SigningKey())
+		Expect(errors.Is(err, parsec.ErrKeyMismatch)).To(BeTrue())
+	})
+})
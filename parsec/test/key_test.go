@@ -0,0 +1,154 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listkeys"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psadestroykey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psageneratekey"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psamaccompute"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psasignmessage"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = Describe("Key handles", func() {
+	var service *parsectest.Service
+	var bc *parsec.BasicClient
+	var generated *psageneratekey.Operation
+	var signed *psasignmessage.Operation
+	var maced *psamaccompute.Operation
+
+	eccKeyAttrs := func() *parsec.KeyAttributes {
+		return &parsec.KeyAttributes{
+			KeyType: parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1),
+			KeyBits: 256,
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyAlgorithm:  algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA256),
+				KeyUsageFlags: &parsec.UsageFlags{SignMessage: true, VerifyMessage: true},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		generated, signed, maced = nil, nil, nil
+		service = parsectest.NewService().
+			Handle(requests.OpPsaGenerateKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				generated = &psageneratekey.Operation{}
+				Expect(proto.Unmarshal(body, generated)).To(Succeed())
+				return &psageneratekey.Result{}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaSignMessage, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				signed = &psasignmessage.Operation{}
+				Expect(proto.Unmarshal(body, signed)).To(Succeed())
+				return &psasignmessage.Result{Signature: []byte("signature")}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaMacCompute, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				maced = &psamaccompute.Operation{}
+				Expect(proto.Unmarshal(body, maced)).To(Succeed())
+				return &psamaccompute.Result{Mac: []byte("mac")}, requests.StatusSuccess
+			}).
+			Handle(requests.OpPsaDestroyKey, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &psadestroykey.Result{}, requests.StatusSuccess
+			}).
+			Handle(requests.OpListKeys, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &listkeys.Result{Keys: []*listkeys.KeyInfo{{
+					ProviderId: uint32(requests.ProviderTPM),
+					Name:       "tpmkey",
+					Attributes: generated.GetAttributes(),
+				}}}, requests.StatusSuccess
+			})
+		var err error
+		bc, err = parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderTPM).
+			Authenticator(parsec.NewDirectAuthenticator("app")).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("Should sign with the algorithm from the key policy", func() {
+		key, err := bc.GenerateKey("tpmkey", eccKeyAttrs())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Name()).To(Equal("tpmkey"))
+		Expect(key.Provider()).To(Equal(parsec.ProviderTPM))
+		Expect(generated.GetAttributes().GetKeyType().GetEccKeyPair().GetCurveFamily()).To(Equal(psakeyattributes.KeyType_SECP_R1))
+
+		// The key stays bound to the provider it was created with.
+		bc.SetImplicitProvider(parsec.ProviderMBed)
+		signature, err := key.Sign([]byte("message"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature).To(Equal([]byte("signature")))
+		Expect(signed.GetKeyName()).To(Equal("tpmkey"))
+		Expect(signed.GetAlg().GetEcdsa().GetHashAlg().GetSpecific().String()).To(Equal("SHA_256"))
+		Expect(service.Received()[len(service.Received())-1].Provider).To(Equal(requests.ProviderTPM))
+	})
+	It("Should refuse operations not permitted by the key policy", func() {
+		key, err := bc.GenerateKey("tpmkey", eccKeyAttrs())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = key.Encrypt([]byte("plaintext"))
+		Expect(errors.Is(err, parsec.ErrKeyPolicy)).To(BeTrue())
+		_, err = key.MAC([]byte("input"))
+		Expect(errors.Is(err, parsec.ErrKeyPolicy)).To(BeTrue())
+		_, err = key.Export()
+		Expect(errors.Is(err, parsec.ErrKeyPolicy)).To(BeTrue())
+		Expect(service.Count(requests.OpPsaAsymmetricEncrypt)).To(BeZero())
+		Expect(service.Count(requests.OpPsaMacCompute)).To(BeZero())
+		Expect(service.Count(requests.OpPsaExportKey)).To(BeZero())
+	})
+	It("Should refuse algorithms not permitted by the key policy", func() {
+		key, err := bc.GenerateKey("tpmkey", eccKeyAttrs())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = key.Sign([]byte("message"), parsec.WithAlgorithm(algorithm.NewAsymmetricSignature().RsaPss(algorithm.HashAlgorithmTypeSHA256)))
+		Expect(errors.Is(err, parsec.ErrKeyPolicy)).To(BeTrue())
+		Expect(err).To(MatchError(ContainSubstring("does not permit")))
+		_, err = key.Sign([]byte("message"), parsec.WithAlgorithm(algorithm.NewAsymmetricSignature().DeterministicEcdsa(algorithm.HashAlgorithmTypeSHA256)))
+		Expect(errors.Is(err, parsec.ErrKeyPolicy)).To(BeTrue())
+		Expect(service.Count(requests.OpPsaSignMessage)).To(BeZero())
+
+		// Either form of ECDSA verifies the same signatures.
+		err = key.Verify([]byte("message"), []byte("signature"), parsec.WithAlgorithm(algorithm.NewAsymmetricSignature().DeterministicEcdsa(algorithm.HashAlgorithmTypeSHA256)))
+		Expect(errors.Is(err, parsec.ErrKeyPolicy)).To(BeFalse())
+		Expect(service.Count(requests.OpPsaVerifyMessage)).To(Equal(1))
+	})
+	It("Should compute MACs with the key policy algorithm", func() {
+		key, err := bc.GenerateKey("mackey", &parsec.KeyAttributes{
+			KeyType: parsec.NewKeyType().Hmac(),
+			KeyBits: 256,
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyAlgorithm:  algorithm.NewMAC().HMAC(algorithm.HashAlgorithmTypeSHA256),
+				KeyUsageFlags: &parsec.UsageFlags{SignMessage: true},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		mac, err := key.MAC([]byte("input"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mac).To(Equal([]byte("mac")))
+		Expect(maced.GetAlg().GetFullLength().GetHmac().GetHashAlg().String()).To(Equal("SHA_256"))
+	})
+	It("Should open existing keys with their attributes", func() {
+		_, err := bc.GenerateKey("tpmkey", eccKeyAttrs())
+		Expect(err).NotTo(HaveOccurred())
+		key, err := bc.OpenKey("tpmkey")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Attributes()).To(Equal(eccKeyAttrs()))
+		_, err = key.Sign([]byte("message"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Destroy()).To(Succeed())
+	})
+	It("Should report keys that do not exist", func() {
+		_, err := bc.GenerateKey("tpmkey", eccKeyAttrs())
+		Expect(err).NotTo(HaveOccurred())
+		_, err = bc.OpenKey("tpmkey", parsec.WithProvider(parsec.ProviderMBed))
+		Expect(errors.Is(err, parsec.ErrKeyNotFound)).To(BeTrue())
+		_, err = bc.OpenKey("otherkey")
+		Expect(errors.Is(err, parsec.ErrKeyNotFound)).To(BeTrue())
+	})
+})
@@ -0,0 +1,105 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+)
+
+func pemBlock(blockType string, der []byte, err error) []byte {
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
+
+var _ = Describe("Key data from PEM", func() {
+	keyPair := parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1)
+	publicKey := parsec.NewKeyType().EccPublicKey(parsec.KeyTypeSECPR1)
+
+	It("Should pad ECC private values to the size of the curve", func() {
+		for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+			size := (curve.Params().BitSize + 7) / 8
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			sec1, err := x509.MarshalECPrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			for _, encoded := range [][]byte{
+				pemBlock("EC PRIVATE KEY", sec1, nil),
+				pemBlock("PRIVATE KEY", pkcs8, nil),
+			} {
+				data, err := parsec.KeyDataFromPEM(encoded, keyPair, uint32(curve.Params().BitSize))
+				Expect(err).NotTo(HaveOccurred(), curve.Params().Name)
+				Expect(data).To(Equal(key.D.FillBytes(make([]byte, size))), curve.Params().Name)
+			}
+
+			pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+			data, err := parsec.KeyDataFromPEM(pemBlock("PUBLIC KEY", pkix, err), publicKey, 0)
+			Expect(err).NotTo(HaveOccurred(), curve.Params().Name)
+			Expect(data).To(HaveLen(1 + 2*size))
+			Expect(data).To(Equal(elliptic.Marshal(curve, key.X, key.Y))) //nolint:staticcheck // comparing with the standard encoding
+		}
+	})
+	It("Should convert X25519 keys", func() {
+		key, err := ecdh.X25519().GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+		data, err := parsec.KeyDataFromPEM(pemBlock("PRIVATE KEY", pkcs8, err), parsec.NewKeyType().EccKeyPair(parsec.KeyTypeMONTGOMERY), 255)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(key.Bytes()))
+
+		pkix, err := x509.MarshalPKIXPublicKey(key.PublicKey())
+		data, err = parsec.KeyDataFromPEM(pemBlock("PUBLIC KEY", pkix, err), parsec.NewKeyType().EccPublicKey(parsec.KeyTypeMONTGOMERY), 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(key.PublicKey().Bytes()))
+	})
+	It("Should refuse keys of the wrong type", func() {
+		key, err := ecdh.X25519().GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+		_, err = parsec.KeyDataFromPEM(pemBlock("PRIVATE KEY", pkcs8, err), parsec.NewKeyType().RsaKeyPair(), 0)
+		Expect(err).To(MatchError(ContainSubstring("expected an RSA private key")))
+		_, err = parsec.KeyDataFromPEM([]byte("not PEM"), keyPair, 0)
+		Expect(err).To(MatchError("no PEM data found"))
+	})
+	It("Should refuse ECC keys on a curve of another family or size", func() {
+		x25519, err := ecdh.X25519().GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		pkcs8, err := x509.MarshalPKCS8PrivateKey(x25519)
+		_, err = parsec.KeyDataFromPEM(pemBlock("PRIVATE KEY", pkcs8, err), keyPair, 0)
+		Expect(err).To(MatchError("PEM key is on curve X25519 of family MONTGOMERY, expected family SECP_R1"))
+
+		p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		pkix, err := x509.MarshalPKIXPublicKey(&p384.PublicKey)
+		encoded := pemBlock("PUBLIC KEY", pkix, err)
+		_, err = parsec.KeyDataFromPEM(encoded, publicKey, 256)
+		Expect(err).To(MatchError("PEM key is on 384 bit curve P-384, expected 256 bits"))
+		_, err = parsec.KeyDataFromPEM(encoded, parsec.NewKeyType().EccPublicKey(parsec.KeyTypeMONTGOMERY), 0)
+		Expect(err).To(MatchError("PEM key is on curve P-384 of family SECP_R1, expected family MONTGOMERY"))
+		_, err = parsec.KeyDataFromPEM(encoded, publicKey, 384)
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("Should refuse RSA keys of another size", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = parsec.KeyDataFromPEM(pemBlock("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), nil), parsec.NewKeyType().RsaKeyPair(), 2048)
+		Expect(err).To(MatchError("PEM key is a 1024 bit RSA key, expected 2048 bits"))
+		data, err := parsec.KeyDataFromPEM(pemBlock("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), nil), parsec.NewKeyType().RsaKeyPair(), 1024)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(x509.MarshalPKCS1PrivateKey(key)))
+		pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		_, err = parsec.KeyDataFromPEM(pemBlock("PUBLIC KEY", pkix, err), parsec.NewKeyType().RsaPublicKey(), 2048)
+		Expect(err).To(MatchError("PEM key is a 1024 bit RSA key, expected 2048 bits"))
+	})
+})
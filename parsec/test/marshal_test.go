@@ -0,0 +1,132 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+var _ = Describe("Marshalling", func() {
+	kt := parsec.NewKeyType()
+
+	DescribeTable("Should round trip key types through JSON and text",
+		func(keyType *parsec.KeyType) {
+			data, err := json.Marshal(keyType)
+			Expect(err).NotTo(HaveOccurred())
+			decoded := &parsec.KeyType{}
+			Expect(json.Unmarshal(data, decoded)).To(Succeed())
+			Expect(decoded).To(Equal(keyType))
+
+			text, err := keyType.MarshalText()
+			Expect(err).NotTo(HaveOccurred())
+			decoded = &parsec.KeyType{}
+			Expect(decoded.UnmarshalText(text)).To(Succeed())
+			Expect(decoded).To(Equal(keyType))
+		},
+		Entry("raw data", kt.RawData()),
+		Entry("hmac", kt.Hmac()),
+		Entry("derive", kt.Derive()),
+		Entry("aes", kt.Aes()),
+		Entry("des", kt.Des()),
+		Entry("camellia", kt.Camellia()),
+		Entry("arc4", kt.Arc4()),
+		Entry("chacha20", kt.Chacha20()),
+		Entry("rsa public key", kt.RsaPublicKey()),
+		Entry("rsa key pair", kt.RsaKeyPair()),
+		Entry("ecc key pair secp k1", kt.EccKeyPair(parsec.KeyTypeSECPK1)),
+		Entry("ecc key pair secp r1", kt.EccKeyPair(parsec.KeyTypeSECPR1)),
+		Entry("ecc key pair secp r2", kt.EccKeyPair(parsec.KeyTypeSECPR2)),
+		Entry("ecc key pair sect k1", kt.EccKeyPair(parsec.KeyTypeSECTK1)),
+		Entry("ecc key pair sect r1", kt.EccKeyPair(parsec.KeyTypeSECTR1)),
+		Entry("ecc key pair sect r2", kt.EccKeyPair(parsec.KeyTypeSECTR2)),
+		Entry("ecc key pair brainpool", kt.EccKeyPair(parsec.KeyTypeBRAINPOOLPR1)),
+		Entry("ecc key pair frp", kt.EccKeyPair(parsec.KeyTypeFRP)),
+		Entry("ecc public key montgomery", kt.EccPublicKey(parsec.KeyTypeMONTGOMERY)),
+		Entry("dh key pair", kt.DhKeyPair(parsec.KeyTypeRFC7919)),
+		Entry("dh public key", kt.DhPublicKey(parsec.KeyTypeRFC7919)),
+	)
+
+	It("Should encode key types with enum names", func() {
+		data, err := json.Marshal(kt.EccKeyPair(parsec.KeyTypeSECPR1))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"ecc_key_pair":{"curve_family":"SECP_R1"}}`))
+	})
+
+	It("Should round trip usage flags", func() {
+		flags := &parsec.UsageFlags{SignHash: true, VerifyHash: true, Export: true}
+		data, err := json.Marshal(flags)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"export":true,"sign_hash":true,"verify_hash":true}`))
+		decoded := &parsec.UsageFlags{Derive: true}
+		Expect(json.Unmarshal(data, decoded)).To(Succeed())
+		Expect(decoded).To(Equal(flags))
+	})
+
+	DescribeTable("Should round trip key attributes",
+		func(attributes *parsec.KeyAttributes) {
+			data, err := json.Marshal(attributes)
+			Expect(err).NotTo(HaveOccurred())
+			decoded := &parsec.KeyAttributes{}
+			Expect(json.Unmarshal(data, decoded)).To(Succeed())
+			Expect(decoded).To(Equal(attributes))
+		},
+		Entry("signing key", &parsec.KeyAttributes{
+			KeyType: kt.EccKeyPair(parsec.KeyTypeSECPR1),
+			KeyBits: 256,
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyUsageFlags: &parsec.UsageFlags{SignHash: true, VerifyHash: true},
+				KeyAlgorithm:  algorithm.NewAsymmetricSignature().DeterministicEcdsa(algorithm.HashAlgorithmTypeSHA256),
+			},
+		}),
+		Entry("key agreement with derivation", &parsec.KeyAttributes{
+			KeyType: kt.EccKeyPair(parsec.KeyTypeMONTGOMERY),
+			KeyBits: 255,
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyUsageFlags: &parsec.UsageFlags{Derive: true},
+				KeyAlgorithm: algorithm.NewKeyAgreement().ECDH(
+					algorithm.NewKeyDerivation().Hkdf(algorithm.HashAlgorithmTypeSHA256).GetKeyDerivation()),
+			},
+		}),
+		Entry("aead with shortened tag", &parsec.KeyAttributes{
+			KeyType: kt.Aes(),
+			KeyBits: 128,
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyUsageFlags: &parsec.UsageFlags{Encrypt: true, Decrypt: true},
+				KeyAlgorithm:  algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmCCM, 8),
+			},
+		}),
+		Entry("truncated mac", &parsec.KeyAttributes{
+			KeyType: kt.Hmac(),
+			KeyBits: 256,
+			KeyPolicy: &parsec.KeyPolicy{
+				KeyUsageFlags: &parsec.UsageFlags{SignMessage: true, VerifyMessage: true},
+				KeyAlgorithm:  algorithm.NewMAC().HMACTruncated(algorithm.HashAlgorithmTypeSHA256, 16),
+			},
+		}),
+		Entry("no algorithm", &parsec.KeyAttributes{
+			KeyType:   kt.RawData(),
+			KeyBits:   128,
+			KeyPolicy: &parsec.KeyPolicy{KeyUsageFlags: &parsec.UsageFlags{Export: true}},
+		}),
+	)
+
+	DescribeTable("Should reject invalid encodings",
+		func(target json.Unmarshaler, data, message string) {
+			Expect(target.UnmarshalJSON([]byte(data))).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("no curve", &parsec.KeyType{}, `{"ecc_key_pair":{}}`, "ECC_FAMILY_NONE must not be used"),
+		Entry("undefined curve", &parsec.KeyType{}, `{"ecc_key_pair":{"curve_family":42}}`, "undefined value 42"),
+		Entry("no key type", &parsec.KeyType{}, `{}`, "key type"),
+		Entry("unknown flag", &parsec.UsageFlags{}, `{"wrap":true}`, "wrap"),
+		Entry("missing policy", &parsec.KeyAttributes{}, `{"key_type":{"aes":{}},"key_bits":128}`, "key policy missing"),
+		Entry("invalid algorithm", &parsec.KeyAttributes{},
+			`{"key_type":{"aes":{}},"key_bits":128,"key_policy":{"key_usage_flags":{},"key_algorithm":{"cipher":"CIPHER_NONE"}}}`,
+			"CIPHER_NONE must not be used"),
+	)
+})
@@ -0,0 +1,107 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listopcodes"
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/listproviders"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ = Describe("Provider selection", func() {
+	var service *parsectest.Service
+	var config *parsec.ClientConfig
+
+	BeforeEach(func() {
+		service = parsectest.NewService().
+			Handle(requests.OpListProviders, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				return &listproviders.Result{Providers: []*listproviders.ProviderInfo{
+					{Id: uint32(requests.ProviderMBed), Uuid: "1c1139dc-ad7c-47dc-ad6b-db6fdb466552", Vendor: "Arm", VersionMaj: 0, VersionMin: 1},
+					{Id: uint32(requests.ProviderTPM), Uuid: "1e4954a4-ff21-46d3-ab0c-661eeb667e1d", Vendor: "Trusted Computing Group (TCG)", VersionMaj: 0, VersionMin: 1, VersionRev: 2},
+					{Id: uint32(requests.ProviderCore), Uuid: "47049873-2a43-4845-9d72-831eab668784", Vendor: "Arm", VersionMaj: 0, VersionMin: 8},
+				}}, requests.StatusSuccess
+			}).
+			Handle(requests.OpListOpcodes, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+				op := &listopcodes.Operation{}
+				Expect(proto.Unmarshal(body, op)).To(Succeed())
+				opcodes := []uint32{uint32(requests.OpPsaSignHash)}
+				if requests.ProviderID(op.ProviderId) == requests.ProviderTPM {
+					opcodes = append(opcodes, uint32(requests.OpPsaAeadEncrypt))
+				}
+				return &listopcodes.Result{Opcodes: opcodes}, requests.StatusSuccess
+			})
+		config = parsec.NewClientConfig().
+			Authenticator(parsec.NewNoAuthAuthenticator()).
+			Connection(service)
+	})
+
+	It("Should select the first provider by default", func() {
+		bc, err := parsec.CreateConfiguredClient(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderMBed))
+	})
+	It("Should select the most preferred available provider", func() {
+		bc, err := parsec.CreateConfiguredClient(config.PreferProviders(parsec.ProviderPKCS11, parsec.ProviderTPM, parsec.ProviderMBed))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderTPM))
+	})
+	It("Should filter by vendor", func() {
+		bc, err := parsec.CreateConfiguredClient(config.PreferProviders(parsec.ProviderTPM).RequireProviderVendor("Arm"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderMBed))
+	})
+	It("Should filter by uuid", func() {
+		bc, err := parsec.CreateConfiguredClient(config.RequireProviderUUID("1E4954A4-FF21-46D3-AB0C-661EEB667E1D"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderTPM))
+	})
+	It("Should filter by minimum version", func() {
+		bc, err := parsec.CreateConfiguredClient(config.RequireProviderMinVersion(0, 1, 1))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderTPM))
+	})
+	It("Should filter by required operations", func() {
+		bc, err := parsec.CreateConfiguredClient(config.RequireOperations(parsec.OpPsaSignHash, parsec.OpPsaAeadEncrypt))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderTPM))
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(2))
+		// Capabilities found during selection are reused.
+		Expect(bc.Supports(parsec.OpPsaAeadEncrypt)).To(BeTrue())
+		Expect(service.Count(requests.OpListOpcodes)).To(Equal(2))
+	})
+	It("Should describe why no provider matched", func() {
+		_, err := parsec.CreateConfiguredClient(config.RequireProviderVendor("Arm").RequireOperations(parsec.OpPsaAeadEncrypt))
+		Expect(errors.Is(err, parsec.ErrNoMatchingProvider)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring(`TPM: vendor "Trusted Computing Group (TCG)" is not "Arm"`))
+		Expect(err.Error()).To(ContainSubstring("MBed: does not support PsaAeadEncrypt"))
+	})
+	It("Should not select the core provider", func() {
+		_, err := parsec.CreateConfiguredClient(config.RequireProviderVendor("Arm").RequireProviderMinVersion(0, 2, 0))
+		Expect(errors.Is(err, parsec.ErrNoMatchingProvider)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("MBed: version 0.1.0 is older than 0.2.0"))
+		Expect(err.Error()).To(ContainSubstring("Core: does not provide cryptographic operations"))
+	})
+	It("Should leave the core provider selected if it is the only provider", func() {
+		service.Handle(requests.OpListProviders, func(provider requests.ProviderID, body []byte) (proto.Message, requests.StatusCode) {
+			return &listproviders.Result{Providers: []*listproviders.ProviderInfo{
+				{Id: uint32(requests.ProviderCore), Uuid: "47049873-2a43-4845-9d72-831eab668784", Vendor: "Arm", VersionMaj: 0, VersionMin: 8},
+			}}, requests.StatusSuccess
+		})
+		bc, err := parsec.CreateConfiguredClient(config.PreferProviders(parsec.ProviderCore))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderCore))
+	})
+	It("Should not select a provider if one is configured", func() {
+		bc, err := parsec.CreateConfiguredClient(config.Provider(parsec.ProviderPKCS11).RequireProviderVendor("Nobody"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bc.GetImplicitProvider()).To(Equal(parsec.ProviderPKCS11))
+		Expect(service.Received()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,107 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+package test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/interface/requests"
+	"github.com/parallaxsecond/parsec-client-go/internal/parsectest"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+var _ = Describe("KeyAttributes.Validate", func() {
+	kt := parsec.NewKeyType()
+	sig := algorithm.NewAsymmetricSignature()
+	attributes := func(keyType *parsec.KeyType, bits uint32, alg *algorithm.Algorithm, usage parsec.UsageFlags) *parsec.KeyAttributes {
+		return &parsec.KeyAttributes{
+			KeyType:   keyType,
+			KeyBits:   bits,
+			KeyPolicy: &parsec.KeyPolicy{KeyUsageFlags: &usage, KeyAlgorithm: alg},
+		}
+	}
+	fields := func(err error) []string {
+		var invalid *parsec.InvalidKeyAttributesError
+		if !errors.As(err, &invalid) {
+			return nil
+		}
+		var result []string
+		for _, f := range invalid.Fields {
+			result = append(result, f.String())
+		}
+		return result
+	}
+
+	DescribeTable("Should accept valid attributes",
+		func(ka *parsec.KeyAttributes) {
+			Expect(ka.Validate()).To(Succeed())
+		},
+		Entry("default signing key", parsec.DefaultKeyAttribute().SigningKey()),
+		Entry("ECDSA P-256", attributes(kt.EccKeyPair(parsec.KeyTypeSECPR1), 256, sig.Ecdsa(algorithm.HashAlgorithmTypeSHA256),
+			parsec.UsageFlags{SignHash: true, VerifyHash: true, SignMessage: true, VerifyMessage: true})),
+		Entry("ECC public key for verification", attributes(kt.EccPublicKey(parsec.KeyTypeSECPR1), 384, sig.EcdsaAny(),
+			parsec.UsageFlags{VerifyHash: true})),
+		Entry("AES GCM", attributes(kt.Aes(), 256, algorithm.NewAead().AeadShortenedTag(algorithm.AeadAlgorithmGCM, 12),
+			parsec.UsageFlags{Encrypt: true, Decrypt: true})),
+		Entry("ChaCha20-Poly1305", attributes(kt.Chacha20(), 256, algorithm.NewAead().Aead(algorithm.AeadAlgorithmChacha20Poly1305),
+			parsec.UsageFlags{Encrypt: true, Decrypt: true})),
+		Entry("HMAC", attributes(kt.Hmac(), 256, algorithm.NewMAC().HMACTruncated(algorithm.HashAlgorithmTypeSHA256, 16),
+			parsec.UsageFlags{SignMessage: true, VerifyMessage: true})),
+		Entry("X25519 ECDH", attributes(kt.EccKeyPair(parsec.KeyTypeMONTGOMERY), 255, algorithm.NewKeyAgreement().RawECDH(),
+			parsec.UsageFlags{Derive: true})),
+		Entry("imported key without bits", attributes(kt.RsaPublicKey(), 0, algorithm.NewAsymmetricEncryption().RsaOaep(algorithm.HashAlgorithmTypeSHA256),
+			parsec.UsageFlags{Encrypt: true})),
+		Entry("exportable raw data", attributes(kt.RawData(), 128, nil, parsec.UsageFlags{Export: true})),
+	)
+
+	DescribeTable("Should report each invalid field",
+		func(ka *parsec.KeyAttributes, want ...string) {
+			err := ka.Validate()
+			Expect(err).To(MatchError(parsec.ErrInvalidKeyAttributes))
+			Expect(fields(err)).To(Equal(want))
+		},
+		Entry("AES key with 2048 bits", attributes(kt.Aes(), 2048, algorithm.NewCipher(algorithm.CipherModeCTR), parsec.UsageFlags{Encrypt: true}),
+			"KeyBits: AES keys must be 128, 192 or 256 bits, not 2048"),
+		Entry("RSA key with ECDSA policy", attributes(kt.RsaKeyPair(), 2048, sig.Ecdsa(algorithm.HashAlgorithmTypeSHA256), parsec.UsageFlags{SignHash: true}),
+			"KeyPolicy.KeyAlgorithm: PSA_ALG_ECDSA(PSA_ALG_SHA_256) cannot be used with RSA key pair keys"),
+		Entry("SignHash with AEAD", attributes(kt.Aes(), 128, algorithm.NewAead().Aead(algorithm.AeadAlgorithmGCM), parsec.UsageFlags{SignHash: true, Encrypt: true}),
+			"KeyPolicy.KeyUsageFlags.SignHash: cannot be used with encryption algorithm PSA_ALG_GCM"),
+		Entry("curve size", attributes(kt.EccKeyPair(parsec.KeyTypeSECPR1), 255, sig.EcdsaAny(), parsec.UsageFlags{SignHash: true}),
+			"KeyBits: ECC family SECP_R1 has no 255 bit curve, use 192, 224, 256, 384 or 521"),
+		Entry("ECDSA on Montgomery curve", attributes(kt.EccKeyPair(parsec.KeyTypeMONTGOMERY), 255, sig.EcdsaAny(), parsec.UsageFlags{SignHash: true}),
+			"KeyPolicy.KeyAlgorithm: PSA_ALG_ECDSA_ANY cannot be used with Montgomery curves"),
+		Entry("signing with a public key", attributes(kt.RsaPublicKey(), 2048, sig.RsaPssAny(), parsec.UsageFlags{SignHash: true, VerifyHash: true}),
+			"KeyPolicy.KeyUsageFlags.SignHash: cannot be used with RSA public keys, which have no private part"),
+		Entry("usage without algorithm", attributes(kt.RawData(), 128, nil, parsec.UsageFlags{Export: true, Derive: true}),
+			"KeyPolicy.KeyUsageFlags.Derive: requires a policy algorithm"),
+		Entry("HMAC truncated beyond hash length", attributes(kt.Hmac(), 256, algorithm.NewMAC().HMACTruncated(algorithm.HashAlgorithmTypeSHA256, 40), parsec.UsageFlags{SignMessage: true}),
+			"KeyPolicy.KeyAlgorithm: truncated MAC length must be from 1 to 32 bytes, not 40"),
+		Entry("stream cipher with AES", attributes(kt.Aes(), 128, algorithm.NewCipher(algorithm.CipherModeSTREAMCIPHER), parsec.UsageFlags{Encrypt: true}),
+			"KeyPolicy.KeyAlgorithm: PSA_ALG_STREAM_CIPHER cannot be used with AES keys"),
+		Entry("several problems", attributes(kt.RsaKeyPair(), 1000, algorithm.NewMAC().CMAC(), parsec.UsageFlags{Decrypt: true}),
+			"KeyBits: RSA keys must be a multiple of 8 bits and at least 1024, not 1000",
+			"KeyPolicy.KeyAlgorithm: PSA_ALG_CMAC cannot be used with RSA key pair keys",
+			"KeyPolicy.KeyUsageFlags.Decrypt: cannot be used with MAC algorithm PSA_ALG_CMAC"),
+		Entry("missing policy", &parsec.KeyAttributes{KeyType: kt.Aes(), KeyBits: 128}, "KeyPolicy: must be set"),
+	)
+
+	It("Should be checked before generating or importing keys", func() {
+		service := parsectest.NewService()
+		bc, err := parsec.CreateConfiguredClient(parsec.NewClientConfig().
+			Provider(parsec.ProviderMBed).
+			Authenticator(parsec.NewDirectAuthenticator("app")).
+			Connection(service))
+		Expect(err).NotTo(HaveOccurred())
+		aes := attributes(kt.Aes(), 2048, algorithm.NewCipher(algorithm.CipherModeCTR), parsec.UsageFlags{Encrypt: true})
+		Expect(bc.PsaGenerateKey("k", aes)).To(MatchError(parsec.ErrInvalidKeyAttributes))
+		Expect(bc.PsaImportKey("k", aes, []byte{1, 2, 3})).To(MatchError(parsec.ErrInvalidKeyAttributes))
+		aes.KeyBits = 0
+		Expect(fields(bc.PsaGenerateKey("k", aes))).To(Equal([]string{"KeyBits: must be set to generate a key"}))
+		Expect(service.Count(requests.OpPsaGenerateKey)).To(BeZero())
+		Expect(service.Count(requests.OpPsaImportKey)).To(BeZero())
+	})
+})
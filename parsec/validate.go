@@ -0,0 +1,421 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package parsec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/parallaxsecond/parsec-client-go/interface/operations/psakeyattributes"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+)
+
+// ErrInvalidKeyAttributes is returned, wrapped in an *InvalidKeyAttributesError, when key attributes fail
+// validation.  Test for it using errors.Is.
+var ErrInvalidKeyAttributes = errors.New("invalid key attributes")
+
+// FieldError describes a problem with one field of the key attributes.
+type FieldError struct {
+	// Field is the path of the field, for example KeyBits or KeyPolicy.KeyUsageFlags.SignHash.
+	Field string
+	Msg   string
+}
+
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Msg
+}
+
+// InvalidKeyAttributesError lists every problem found by KeyAttributes.Validate.
+type InvalidKeyAttributesError struct {
+	Fields []FieldError
+}
+
+func (e *InvalidKeyAttributesError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.String()
+	}
+	return "invalid key attributes: " + strings.Join(msgs, "; ")
+}
+
+// Is allows errors.Is(err, ErrInvalidKeyAttributes) to match.
+func (e *InvalidKeyAttributesError) Is(target error) bool {
+	return target == ErrInvalidKeyAttributes
+}
+
+type attributeValidator struct {
+	fields []FieldError
+}
+
+func (v *attributeValidator) add(field, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks that the key size suits the key type, that the algorithm can be used with the key type and that
+// the usage flags can be used with the algorithm.  These are the combinations the parsec service would reject with
+// StatusPsaErrorInvalidArgument or StatusPsaErrorNotSupported.  A KeyBits of 0 is accepted, as the size of an
+// imported key is taken from the key data.
+func (ka *KeyAttributes) Validate() error {
+	v := &attributeValidator{}
+	v.validate(ka)
+	return v.err()
+}
+
+// validateForGenerate is Validate, also requiring KeyBits since a generated key has no key data to take the size from.
+func (ka *KeyAttributes) validateForGenerate() error {
+	v := &attributeValidator{}
+	v.validate(ka)
+	if ka != nil && ka.KeyType != nil && ka.KeyBits == 0 {
+		v.add("KeyBits", "must be set to generate a key")
+	}
+	return v.err()
+}
+
+func (v *attributeValidator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &InvalidKeyAttributesError{Fields: v.fields}
+}
+
+func (v *attributeValidator) validate(ka *KeyAttributes) {
+	if ka == nil {
+		v.add("KeyAttributes", "must be set")
+		return
+	}
+	if ka.KeyType == nil || ka.KeyType.variant == nil {
+		v.add("KeyType", "must be set")
+	} else if ka.KeyBits != 0 {
+		v.validateKeyBits(ka.KeyType, ka.KeyBits)
+	}
+	if ka.KeyPolicy == nil {
+		v.add("KeyPolicy", "must be set")
+		return
+	}
+	if ka.KeyPolicy.KeyUsageFlags == nil {
+		v.add("KeyPolicy.KeyUsageFlags", "must be set")
+	}
+	alg := ka.KeyPolicy.KeyAlgorithm
+	if alg != nil && ka.KeyType != nil && ka.KeyType.variant != nil {
+		v.validateAlgorithm(ka.KeyType, alg)
+	}
+	if ka.KeyPolicy.KeyUsageFlags != nil {
+		v.validateUsage(ka.KeyType, alg, ka.KeyPolicy.KeyUsageFlags)
+	}
+}
+
+// eccCurveBits are the curve sizes defined by the PSA specification for each ECC family.
+var eccCurveBits = map[EccFamily][]uint32{
+	KeyTypeSECPK1:       {192, 224, 256},
+	KeyTypeSECPR1:       {192, 224, 256, 384, 521},
+	KeyTypeSECPR2:       {160},
+	KeyTypeSECTK1:       {163, 233, 239, 283, 409, 571},
+	KeyTypeSECTR1:       {163, 233, 283, 409, 571},
+	KeyTypeSECTR2:       {163},
+	KeyTypeBRAINPOOLPR1: {160, 192, 224, 256, 320, 384, 512},
+	KeyTypeFRP:          {256},
+	KeyTypeMONTGOMERY:   {255, 448},
+}
+
+var dhGroupBits = map[DhFamily][]uint32{
+	KeyTypeRFC7919: {2048, 3072, 4096, 6144, 8192},
+}
+
+const minRsaKeyBits = 1024
+
+func (v *attributeValidator) validateKeyBits(kt *KeyType, bits uint32) {
+	oneOf := func(name string, allowed []uint32) {
+		for _, b := range allowed {
+			if bits == b {
+				return
+			}
+		}
+		v.add("KeyBits", "%v keys must be %v bits, not %d", name, joinBits(allowed), bits)
+	}
+	switch k := kt.variant.(type) {
+	case *KeyTypeAes, *KeyTypeCamellia:
+		oneOf(kt.name(), []uint32{128, 192, 256})
+	case *KeyTypeDes:
+		oneOf(kt.name(), []uint32{64, 128, 192})
+	case *KeyTypeChacha20:
+		oneOf(kt.name(), []uint32{256})
+	case *KeyTypeArc4:
+		if bits < 40 || bits > 2048 || bits%8 != 0 {
+			v.add("KeyBits", "ARC4 keys must be a multiple of 8 bits from 40 to 2048, not %d", bits)
+		}
+	case *KeyTypeRsaKeyPair, *KeyTypeRsaPublicKey:
+		if bits < minRsaKeyBits || bits%8 != 0 {
+			v.add("KeyBits", "RSA keys must be a multiple of 8 bits and at least %d, not %d", minRsaKeyBits, bits)
+		}
+	case *KeyTypeEccKeyPair:
+		v.validateEccBits(k.CurveFamily, bits)
+	case *KeyTypeEccPublicKey:
+		v.validateEccBits(k.CurveFamily, bits)
+	case *KeyTypeDhKeyPair:
+		v.validateDhBits(k.GroupFamily, bits)
+	case *KeyTypeDhPublicKey:
+		v.validateDhBits(k.GroupFamily, bits)
+	default:
+		if bits%8 != 0 {
+			v.add("KeyBits", "%v keys must be a multiple of 8 bits, not %d", kt.name(), bits)
+		}
+	}
+}
+
+func (v *attributeValidator) validateEccBits(family EccFamily, bits uint32) {
+	allowed, ok := eccCurveBits[family]
+	if !ok {
+		v.add("KeyType", "unknown ECC family %d", family)
+		return
+	}
+	for _, b := range allowed {
+		if bits == b {
+			return
+		}
+	}
+	v.add("KeyBits", "ECC family %v has no %d bit curve, use %v", eccFamilyName(family), bits, joinBits(allowed))
+}
+
+func (v *attributeValidator) validateDhBits(family DhFamily, bits uint32) {
+	allowed, ok := dhGroupBits[family]
+	if !ok {
+		v.add("KeyType", "unknown DH family %d", family)
+		return
+	}
+	for _, b := range allowed {
+		if bits == b {
+			return
+		}
+	}
+	v.add("KeyBits", "DH family RFC7919 has no %d bit group, use %v", bits, joinBits(allowed))
+}
+
+// validateAlgorithm checks that the policy algorithm can be used with the key type.
+//
+//nolint:gocyclo
+func (v *attributeValidator) validateAlgorithm(kt *KeyType, alg *algorithm.Algorithm) {
+	const field = "KeyPolicy.KeyAlgorithm"
+	isBlockCipher := kt.isOneOf(&KeyTypeAes{}, &KeyTypeDes{}, &KeyTypeCamellia{})
+	isRsa := kt.isOneOf(&KeyTypeRsaKeyPair{}, &KeyTypeRsaPublicKey{})
+	isEcc := kt.isOneOf(&KeyTypeEccKeyPair{}, &KeyTypeEccPublicKey{})
+	incompatible := func() {
+		v.add(field, "%v cannot be used with %v keys", alg, kt.name())
+	}
+	switch {
+	case alg.GetHash() != nil:
+		v.add(field, "%v is a hash algorithm, which keys cannot be used with", alg)
+	case alg.GetCipher() != nil:
+		if alg.GetCipher().Mode == algorithm.CipherModeSTREAMCIPHER {
+			if !kt.isOneOf(&KeyTypeArc4{}, &KeyTypeChacha20{}) {
+				incompatible()
+			}
+		} else if !isBlockCipher {
+			incompatible()
+		}
+	case alg.GetAead() != nil:
+		aead := alg.GetAead()
+		aeadType, tagLength := algorithm.AeadAlgorithmGCM, uint32(0)
+		if d := aead.GetAeadDefaultLengthTag(); d != nil {
+			aeadType = d.AeadAlg
+		} else if s := aead.GetAeadShortenedTag(); s != nil {
+			aeadType, tagLength = s.AeadAlg, s.TagLength
+		}
+		if aeadType == algorithm.AeadAlgorithmChacha20Poly1305 {
+			if !kt.isOneOf(&KeyTypeChacha20{}) {
+				incompatible()
+			}
+		} else if !kt.isOneOf(&KeyTypeAes{}, &KeyTypeCamellia{}) {
+			incompatible()
+		}
+		if aead.GetAeadShortenedTag() != nil && (tagLength == 0 || tagLength > 16) {
+			v.add(field, "shortened tag length must be from 1 to 16 bytes, not %d", tagLength)
+		}
+	case alg.GetMac() != nil:
+		mac := alg.GetMac()
+		full := mac.GetFullLength()
+		if t := mac.GetTruncated(); t != nil {
+			full = t.MacAlg
+			if full != nil && (t.MacLength == 0 || int(t.MacLength) > full.Size()) {
+				v.add(field, "truncated MAC length must be from 1 to %d bytes, not %d", full.Size(), t.MacLength)
+			}
+		}
+		switch {
+		case full == nil:
+			v.add(field, "MAC algorithm is incomplete")
+		case full.GetHmac() != nil:
+			if !kt.isOneOf(&KeyTypeHmac{}) {
+				incompatible()
+			}
+		case !isBlockCipher:
+			incompatible()
+		}
+	case alg.GetAsymmetricSignature() != nil:
+		sig := alg.GetAsymmetricSignature()
+		isEcdsa := sig.GetEcdsa() != nil || sig.GetEcdsaAny() != nil || sig.GetDeterministicEcdsa() != nil
+		switch {
+		case isEcdsa && !isEcc:
+			incompatible()
+		case isEcdsa && kt.eccFamily() == KeyTypeMONTGOMERY:
+			v.add(field, "%v cannot be used with Montgomery curves", alg)
+		case !isEcdsa && !isRsa:
+			incompatible()
+		}
+	case alg.GetAsymmetricEncryption() != nil:
+		if !isRsa {
+			incompatible()
+		}
+	case alg.GetKeyDerivation() != nil:
+		if !kt.isOneOf(&KeyTypeDerive{}) {
+			incompatible()
+		}
+	case alg.GetKeyAgreement() != nil:
+		ka := alg.GetKeyAgreement()
+		raw := algorithm.KeyAgreementRAWNONE
+		if r := ka.GetRaw(); r != nil {
+			raw = r.RawAlg
+		} else if d := ka.GetWithKeyDerivation(); d != nil {
+			raw = d.KaAlg
+		}
+		switch raw {
+		case algorithm.KeyAgreementECDH:
+			if !kt.isOneOf(&KeyTypeEccKeyPair{}) {
+				incompatible()
+			}
+		case algorithm.KeyAgreementFFDH:
+			if !kt.isOneOf(&KeyTypeDhKeyPair{}) {
+				incompatible()
+			}
+		default:
+			v.add(field, "key agreement algorithm is incomplete")
+		}
+	}
+}
+
+// validateUsage checks that each usage flag set is permitted by the algorithm and key type.
+func (v *attributeValidator) validateUsage(kt *KeyType, alg *algorithm.Algorithm, flags *UsageFlags) {
+	const field = "KeyPolicy.KeyUsageFlags."
+	isPublic := kt != nil && kt.isOneOf(&KeyTypeRsaPublicKey{}, &KeyTypeEccPublicKey{}, &KeyTypeDhPublicKey{})
+	var category string
+	switch {
+	case alg == nil:
+		category = "no algorithm"
+	case alg.GetAsymmetricSignature() != nil:
+		category = "signature"
+	case alg.GetMac() != nil:
+		category = "MAC"
+	case alg.GetCipher() != nil, alg.GetAead() != nil, alg.GetAsymmetricEncryption() != nil:
+		category = "encryption"
+	case alg.GetKeyDerivation() != nil, alg.GetKeyAgreement() != nil:
+		category = "derivation"
+	}
+	check := func(set bool, name string, categories ...string) {
+		if !set {
+			return
+		}
+		for _, c := range categories {
+			if c == category {
+				return
+			}
+		}
+		if alg == nil {
+			v.add(field+name, "requires a policy algorithm")
+			return
+		}
+		v.add(field+name, "cannot be used with %v algorithm %v", category, alg)
+	}
+	check(flags.SignHash, "SignHash", "signature")
+	check(flags.VerifyHash, "VerifyHash", "signature")
+	check(flags.SignMessage, "SignMessage", "signature", "MAC")
+	check(flags.VerifyMessage, "VerifyMessage", "signature", "MAC")
+	check(flags.Encrypt, "Encrypt", "encryption")
+	check(flags.Decrypt, "Decrypt", "encryption")
+	check(flags.Derive, "Derive", "derivation")
+
+	if isPublic {
+		private := []struct {
+			name string
+			set  bool
+		}{{"SignHash", flags.SignHash}, {"SignMessage", flags.SignMessage}, {"Decrypt", flags.Decrypt}, {"Derive", flags.Derive}}
+		for _, f := range private {
+			if f.set {
+				v.add(field+f.name, "cannot be used with %v keys, which have no private part", kt.name())
+			}
+		}
+	}
+}
+
+// isOneOf returns true if the key type has the same variant type as one of the examples.
+func (k *KeyType) isOneOf(examples ...keyTypeVariant) bool {
+	for _, e := range examples {
+		if reflect.TypeOf(e) == reflect.TypeOf(k.variant) {
+			return true
+		}
+	}
+	return false
+}
+
+// eccFamily returns the curve family of an ECC key type, or KeyTypeECCFAMILYNONE.
+func (k *KeyType) eccFamily() EccFamily {
+	switch v := k.variant.(type) {
+	case *KeyTypeEccKeyPair:
+		return v.CurveFamily
+	case *KeyTypeEccPublicKey:
+		return v.CurveFamily
+	}
+	return KeyTypeECCFAMILYNONE
+}
+
+// name returns a short description of the key type for error messages.
+//
+//nolint:gocyclo
+func (k *KeyType) name() string {
+	switch k.variant.(type) {
+	case *KeyTypeRawData:
+		return "raw data"
+	case *KeyTypeHmac:
+		return "HMAC"
+	case *KeyTypeDerive:
+		return "derivation"
+	case *KeyTypeAes:
+		return "AES"
+	case *KeyTypeDes:
+		return "DES"
+	case *KeyTypeCamellia:
+		return "Camellia"
+	case *KeyTypeArc4:
+		return "ARC4"
+	case *KeyTypeChacha20:
+		return "ChaCha20"
+	case *KeyTypeRsaPublicKey:
+		return "RSA public"
+	case *KeyTypeRsaKeyPair:
+		return "RSA key pair"
+	case *KeyTypeEccKeyPair:
+		return "ECC key pair"
+	case *KeyTypeEccPublicKey:
+		return "ECC public"
+	case *KeyTypeDhKeyPair:
+		return "DH key pair"
+	case *KeyTypeDhPublicKey:
+		return "DH public"
+	}
+	return "unknown"
+}
+
+func eccFamilyName(f EccFamily) string {
+	return psakeyattributes.KeyType_EccFamily(f).String()
+}
+
+func joinBits(bits []uint32) string {
+	s := make([]string, len(bits))
+	for i, b := range bits {
+		s[i] = fmt.Sprint(b)
+	}
+	if len(s) == 1 {
+		return s[0]
+	}
+	return strings.Join(s[:len(s)-1], ", ") + " or " + s[len(s)-1]
+}
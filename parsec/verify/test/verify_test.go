@@ -0,0 +1,187 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+package verify_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm/gocrypto"
+	"github.com/parallaxsecond/parsec-client-go/parsec/verify"
+)
+
+func signatureAlg(alg *algorithm.Algorithm) *algorithm.AsymmetricSignatureAlgorithm {
+	return alg.GetAsymmetricSignature()
+}
+
+// psaECDSA signs hash and encodes the signature as PSA does, r followed by s, each the size of the curve.
+func psaECDSA(key *ecdsa.PrivateKey, hash []byte) []byte {
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash)
+	Expect(err).NotTo(HaveOccurred())
+	size := (key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	return sig
+}
+
+func tamper(b []byte) []byte {
+	t := append([]byte{}, b...)
+	t[len(t)/2] ^= 0x01
+	return t
+}
+
+var _ = Describe("offline verification", func() {
+	msg := []byte("message signed by parsec")
+	hash256 := sha256.Sum256(msg)
+	hash384 := sha512.Sum384(msg)
+
+	Describe("ECDSA", func() {
+		var key *ecdsa.PrivateKey
+		var public []byte
+		BeforeEach(func() {
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			public = elliptic.Marshal(key.Curve, key.X, key.Y) //nolint:staticcheck // the PSA public key format
+		})
+
+		It("Should verify raw r and s signatures", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA384))
+			sig := psaECDSA(key, hash384[:])
+			keyPair := parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1)
+			Expect(verify.VerifyHash(keyPair, public, alg, hash384[:], sig)).To(Succeed())
+			Expect(verify.VerifyMessage(keyPair, public, alg, msg, sig)).To(Succeed())
+			publicKey := parsec.NewKeyType().EccPublicKey(parsec.KeyTypeSECPR1)
+			Expect(verify.VerifyHash(publicKey, public, alg, hash384[:], sig)).To(Succeed())
+		})
+		It("Should verify deterministic ECDSA and ECDSA with any hash", func() {
+			kt := parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1)
+			sig := psaECDSA(key, hash256[:])
+			det := signatureAlg(algorithm.NewAsymmetricSignature().DeterministicEcdsa(algorithm.HashAlgorithmTypeSHA256))
+			Expect(verify.VerifyHash(kt, public, det, hash256[:], sig)).To(Succeed())
+			anyHash := signatureAlg(algorithm.NewAsymmetricSignature().EcdsaAny())
+			Expect(verify.VerifyHash(kt, public, anyHash, hash256[:], sig)).To(Succeed())
+			Expect(verify.VerifyMessage(kt, public, anyHash, msg, sig)).To(MatchError(ContainSubstring("signs raw data")))
+		})
+		It("Should keep leading zeros of r and s", func() {
+			kt := parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1)
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA384))
+			v, err := verify.NewVerifier(kt, public, alg)
+			Expect(err).NotTo(HaveOccurred())
+			// Sign until r has a leading zero byte, which happens once in 256 signatures on average
+			for i := 0; i < 5000; i++ {
+				sig := psaECDSA(key, hash384[:])
+				if sig[0] == 0 {
+					Expect(v.VerifyHash(hash384[:], sig)).To(Succeed())
+					Expect(v.VerifyHash(hash384[:], sig[1:])).To(MatchError(verify.ErrInvalidSignature))
+					return
+				}
+			}
+			Fail("no signature with a leading zero")
+		})
+		It("Should reject bad signatures", func() {
+			kt := parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1)
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA384))
+			v, err := verify.NewVerifier(kt, public, alg)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v.PublicKey()).To(Equal(&key.PublicKey))
+			sig := psaECDSA(key, hash384[:])
+			Expect(errors.Is(v.VerifyHash(tamper(hash384[:]), sig), verify.ErrInvalidSignature)).To(BeTrue())
+			Expect(errors.Is(v.VerifyHash(hash384[:], tamper(sig)), verify.ErrInvalidSignature)).To(BeTrue())
+			Expect(errors.Is(v.VerifyMessage(tamper(msg), sig), verify.ErrInvalidSignature)).To(BeTrue())
+			// The DER encoding used by x509 is not accepted
+			der, err := key.Sign(rand.Reader, hash384[:], crypto.SHA384)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.Is(v.VerifyHash(hash384[:], der), verify.ErrInvalidSignature)).To(BeTrue())
+			Expect(v.VerifyHash(hash256[:], sig)).To(MatchError(ContainSubstring("32 byte hash")))
+		})
+	})
+
+	Describe("RSA", func() {
+		var key *rsa.PrivateKey
+		var public []byte
+		kt := parsec.NewKeyType().RsaKeyPair()
+		BeforeEach(func() {
+			var err error
+			key, err = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			public = x509.MarshalPKCS1PublicKey(&key.PublicKey)
+		})
+
+		It("Should verify PKCS#1 v1.5 signatures", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().RsaPkcs1V15Sign(algorithm.HashAlgorithmTypeSHA256))
+			sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash256[:])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verify.VerifyHash(kt, public, alg, hash256[:], sig)).To(Succeed())
+			Expect(verify.VerifyMessage(parsec.NewKeyType().RsaPublicKey(), public, alg, msg, sig)).To(Succeed())
+			Expect(verify.VerifyHash(kt, public, alg, hash256[:], tamper(sig))).To(MatchError(verify.ErrInvalidSignature))
+		})
+		It("Should verify raw PKCS#1 v1.5 signatures", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().RsaPkcs1V15SignRaw())
+			data := []byte("a short digest or DigestInfo")
+			sig, err := rsa.SignPKCS1v15(rand.Reader, key, 0, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verify.VerifyHash(kt, public, alg, data, sig)).To(Succeed())
+			Expect(verify.VerifyHash(kt, public, alg, tamper(data), sig)).To(MatchError(verify.ErrInvalidSignature))
+		})
+		It("Should require PSS salts the length of the hash unless told otherwise", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().RsaPss(algorithm.HashAlgorithmTypeSHA256))
+			sig, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, hash256[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verify.VerifyHash(kt, public, alg, hash256[:], sig)).To(Succeed())
+			Expect(verify.VerifyMessage(kt, public, alg, msg, sig)).To(Succeed())
+			Expect(verify.VerifyHash(kt, public, alg, hash256[:], sig, verify.AnySaltLength())).To(Succeed())
+
+			maxSalt, err := rsa.SignPSS(rand.Reader, key, crypto.SHA256, hash256[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verify.VerifyHash(kt, public, alg, hash256[:], maxSalt)).To(MatchError(verify.ErrInvalidSignature))
+			Expect(verify.VerifyHash(kt, public, alg, hash256[:], maxSalt, verify.AnySaltLength())).To(Succeed())
+			Expect(verify.VerifyHash(kt, public, alg, tamper(hash256[:]), maxSalt, verify.AnySaltLength())).To(MatchError(verify.ErrInvalidSignature))
+		})
+	})
+
+	Describe("invalid parameters", func() {
+		ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		ecPublic := elliptic.Marshal(ec.Curve, ec.X, ec.Y) //nolint:staticcheck // the PSA public key format
+		ecType := parsec.NewKeyType().EccKeyPair(parsec.KeyTypeSECPR1)
+
+		It("Should reject algorithms that do not match the key", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().RsaPss(algorithm.HashAlgorithmTypeSHA256))
+			_, err := verify.NewVerifier(ecType, ecPublic, alg)
+			Expect(err).To(MatchError("PSA_ALG_RSA_PSS(PSA_ALG_SHA_256) cannot be used with an ECDSA key"))
+		})
+		It("Should reject algorithms that accept any hash", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().DeterministicEcdsaAny())
+			_, err := verify.NewVerifier(ecType, ecPublic, alg)
+			Expect(err).To(MatchError(ContainSubstring("does not name a hash")))
+		})
+		It("Should reject hashes Go does not have", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeMD2))
+			_, err := verify.NewVerifier(ecType, ecPublic, alg)
+			Expect(errors.Is(err, gocrypto.ErrUnsupported)).To(BeTrue())
+		})
+		It("Should reject keys that cannot sign", func() {
+			alg := signatureAlg(algorithm.NewAsymmetricSignature().Ecdsa(algorithm.HashAlgorithmTypeSHA256))
+			_, err := verify.NewVerifier(parsec.NewKeyType().Aes(), ecPublic, alg)
+			Expect(errors.Is(err, gocrypto.ErrUnsupported)).To(BeTrue())
+			_, err = verify.NewVerifier(ecType, ecPublic[1:], alg)
+			Expect(err).To(HaveOccurred())
+			_, err = verify.NewVerifier(ecType, ecPublic, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,175 @@
+// Copyright 2021 Contributors to the Parsec project.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package verify checks signatures made by PsaSignHash and PsaSignMessage without contacting the parsec service,
+// using a public key exported by PsaExportPublicKey and Go's crypto libraries.  This allows signatures to be
+// verified on machines that do not run parsec.
+package verify
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	// The hashes used by parsec signature algorithms, so that messages can be hashed for VerifyMessage
+	_ "crypto/sha1" //nolint:gosec // old SHA-1 signatures may still need to be verified
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/parallaxsecond/parsec-client-go/parsec"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm"
+	"github.com/parallaxsecond/parsec-client-go/parsec/algorithm/gocrypto"
+)
+
+// ErrInvalidSignature is returned, wrapped with the details, when a signature does not verify.  Test for it using
+// errors.Is.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Option changes how signatures are verified.
+type Option func(*options)
+
+// AnySaltLength accepts RSA-PSS signatures with any salt length.  PSA requires the salt to be the length of the
+// hash, and only that is accepted by default, but some implementations use the largest salt that fits in the
+// signature.
+func AnySaltLength() Option {
+	return func(o *options) {
+		o.saltLength = rsa.PSSSaltLengthAuto
+	}
+}
+
+type options struct {
+	saltLength int
+}
+
+// Verifier verifies signatures made with one key and algorithm.
+type Verifier struct {
+	key crypto.PublicKey
+	alg *algorithm.AsymmetricSignatureAlgorithm
+	// hash is the hash named by alg, or 0 for algorithms that sign a hash of any length
+	hash       crypto.Hash
+	saltLength int
+}
+
+// NewVerifier returns a Verifier for signatures made using alg with the key whose public part is publicKey, in the
+// format returned by PsaExportPublicKey.  keyType is the type of the key, either the key pair or its public key.
+// The algorithm must be one that can sign with the key and must name its hash unless it signs raw data, like
+// RsaPkcs1V15SignRaw and EcdsaAny.
+func NewVerifier(keyType *parsec.KeyType, publicKey []byte, alg *algorithm.AsymmetricSignatureAlgorithm, opts ...Option) (*Verifier, error) {
+	if alg == nil {
+		return nil, fmt.Errorf("signature algorithm must be set")
+	}
+	o := options{saltLength: rsa.PSSSaltLengthEqualsHash}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	key, err := gocrypto.PublicKey(&parsec.KeyAttributes{KeyType: keyType}, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	v := &Verifier{key: key, alg: alg, saltLength: o.saltLength}
+
+	rsaAlg := alg.GetRsaPkcs1V15Sign() != nil || alg.GetRsaPkcs1V15SignRaw() != nil || alg.GetRsaPss() != nil
+	ecdsaAlg := alg.GetEcdsa() != nil || alg.GetEcdsaAny() != nil || alg.GetDeterministicEcdsa() != nil
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if !rsaAlg {
+			return nil, fmt.Errorf("%v cannot be used with an RSA key", alg)
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsaAlg {
+			return nil, fmt.Errorf("%v cannot be used with an ECDSA key", alg)
+		}
+	default:
+		return nil, fmt.Errorf("%w: signatures with %T public keys", gocrypto.ErrUnsupported, key)
+	}
+
+	if alg.GetRsaPkcs1V15SignRaw() != nil || alg.GetEcdsaAny() != nil {
+		return v, nil
+	}
+	if alg.HashAlg() == algorithm.HashAlgorithmTypeNONE {
+		return nil, fmt.Errorf("%v does not name a hash", alg)
+	}
+	if v.hash, err = gocrypto.ToCryptoHash(alg.HashAlg()); err != nil {
+		return nil, err
+	}
+	if !v.hash.Available() {
+		return nil, fmt.Errorf("%w: %v is not linked into the binary", gocrypto.ErrUnsupported, v.hash)
+	}
+	return v, nil
+}
+
+// PublicKey returns the key signatures are verified with, an *rsa.PublicKey or an *ecdsa.PublicKey.
+func (v *Verifier) PublicKey() crypto.PublicKey {
+	return v.key
+}
+
+// VerifyHash checks a signature made by PsaSignHash over hash.  If the algorithm names a hash, hash must be the
+// size of its output.
+func (v *Verifier) VerifyHash(hash, signature []byte) error {
+	if v.hash != 0 && len(hash) != v.hash.Size() {
+		return fmt.Errorf("%d byte hash given for %v, which has %d byte hashes", len(hash), v.hash, v.hash.Size())
+	}
+	var err error
+	switch key := v.key.(type) {
+	case *rsa.PublicKey:
+		if v.alg.GetRsaPss() != nil {
+			err = rsa.VerifyPSS(key, v.hash, hash, signature, &rsa.PSSOptions{SaltLength: v.saltLength})
+		} else {
+			// For raw signatures v.hash is 0, so hash is signed without a DigestInfo
+			err = rsa.VerifyPKCS1v15(key, v.hash, hash, signature)
+		}
+	case *ecdsa.PublicKey:
+		err = verifyECDSA(key, hash, signature)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// VerifyMessage checks a signature made by PsaSignMessage over msg.  The message is hashed with the algorithm's
+// hash, so algorithms that sign raw data cannot be used.
+func (v *Verifier) VerifyMessage(msg, signature []byte) error {
+	if v.hash == 0 {
+		return fmt.Errorf("%v signs raw data, not messages", v.alg)
+	}
+	h := v.hash.New()
+	h.Write(msg)
+	return v.VerifyHash(h.Sum(nil), signature)
+}
+
+// verifyECDSA checks a PSA ECDSA signature, which is r followed by s, each the size of the curve in bytes, rather
+// than the ASN.1 encoding used by x509 and TLS.
+func verifyECDSA(key *ecdsa.PublicKey, hash, signature []byte) error {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return fmt.Errorf("%d byte signature, expected %d bytes for %v", len(signature), 2*size, key.Curve.Params().Name)
+	}
+	r := new(big.Int).SetBytes(signature[:size])
+	s := new(big.Int).SetBytes(signature[size:])
+	if !ecdsa.Verify(key, hash, r, s) {
+		return errors.New("ECDSA verification failed")
+	}
+	return nil
+}
+
+// VerifyHash checks a signature made by PsaSignHash over hash.  See NewVerifier for the other parameters.
+func VerifyHash(keyType *parsec.KeyType, publicKey []byte, alg *algorithm.AsymmetricSignatureAlgorithm, hash, signature []byte, opts ...Option) error {
+	v, err := NewVerifier(keyType, publicKey, alg, opts...)
+	if err != nil {
+		return err
+	}
+	return v.VerifyHash(hash, signature)
+}
+
+// VerifyMessage checks a signature made by PsaSignMessage over msg.  See NewVerifier for the other parameters.
+func VerifyMessage(keyType *parsec.KeyType, publicKey []byte, alg *algorithm.AsymmetricSignatureAlgorithm, msg, signature []byte, opts ...Option) error {
+	v, err := NewVerifier(keyType, publicKey, alg, opts...)
+	if err != nil {
+		return err
+	}
+	return v.VerifyMessage(msg, signature)
+}